@@ -0,0 +1,266 @@
+// Command tdls-easy-k8s-provider-example is the reference provider plugin. It shows
+// how an out-of-tree provider is built with the plugin package: it keeps a simulated
+// cluster in a state file instead of creating machines, so the whole protocol can be
+// exercised without cloud credentials.
+//
+// Install it with:
+//
+//	go build -o ~/.tdls-k8s/plugins/tdls-easy-k8s-provider-example ./cmd/tdls-easy-k8s-provider-example
+//
+// and use it from a cluster config:
+//
+//	provider:
+//	  type: example
+//	  settings:
+//	    server: https://127.0.0.1:6443  # API server written to the kubeconfig
+//	    stateDir: /tmp/example-state    # default ~/.tdls-k8s/clusters/<name>/example
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/user/tdls-easy-k8s/pkg/plugin"
+)
+
+const defaultServer = "https://127.0.0.1:6443"
+
+func main() {
+	plugin.Serve(&exampleProvider{})
+}
+
+// exampleProvider implements plugin.Provider with a simulated cluster
+type exampleProvider struct{}
+
+// state is the simulated infrastructure of a cluster
+type state struct {
+	CreatedAt    time.Time `json:"createdAt"`
+	Server       string    `json:"server"`
+	ControlPlane []string  `json:"controlPlane"`
+	Workers      []string  `json:"workers"`
+}
+
+func (p *exampleProvider) Describe() plugin.Description {
+	return plugin.Description{
+		Name:        "example",
+		Description: "Reference plugin with a simulated cluster",
+		Resources: []string{
+			"Simulated control plane and worker nodes",
+			"Generated kubeconfig",
+		},
+	}
+}
+
+func (p *exampleProvider) ValidateField(field, value string) error {
+	if field == "provider.settings.server" {
+		return validateServer(value)
+	}
+	return nil
+}
+
+func (p *exampleProvider) ValidateConfig(cfg *plugin.ClusterConfig) error {
+	if cfg.Provider.Type != "example" {
+		return fmt.Errorf("provider type must be 'example'")
+	}
+	for key, value := range cfg.Provider.Settings {
+		switch key {
+		case "server":
+			s, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("provider.settings.server must be a string")
+			}
+			if err := validateServer(s); err != nil {
+				return err
+			}
+		case "stateDir", "location":
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf("provider.settings.%s must be a string", key)
+			}
+		default:
+			return fmt.Errorf("unknown setting provider.settings.%s", key)
+		}
+	}
+	return nil
+}
+
+func (p *exampleProvider) CreateInfrastructure(cfg *plugin.ClusterConfig) error {
+	if err := p.ValidateConfig(cfg); err != nil {
+		return err
+	}
+
+	s := &state{CreatedAt: time.Now().UTC(), Server: setting(cfg, "server", defaultServer)}
+	for i := 1; i <= cfg.Nodes.ControlPlane.Count; i++ {
+		s.ControlPlane = append(s.ControlPlane, fmt.Sprintf("%s-cp-%d", cfg.Name, i))
+	}
+	for i := 1; i <= cfg.Nodes.Workers.Count; i++ {
+		s.Workers = append(s.Workers, fmt.Sprintf("%s-worker-%d", cfg.Name, i))
+	}
+
+	// Progress output goes to the user; Serve keeps stdout for the response
+	fmt.Printf("Creating %d simulated nodes for cluster %s\n", len(s.ControlPlane)+len(s.Workers), cfg.Name)
+
+	dir := stateDir(cfg)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "state.json"), data, 0600); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+func (p *exampleProvider) DestroyInfrastructure(cfg *plugin.ClusterConfig) error {
+	if err := os.RemoveAll(stateDir(cfg)); err != nil {
+		return fmt.Errorf("failed to remove state: %w", err)
+	}
+	return nil
+}
+
+func (p *exampleProvider) GetKubeconfig(cfg *plugin.ClusterConfig) (string, error) {
+	s, err := loadState(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(stateDir(cfg), "kubeconfig")
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: %[1]s
+  cluster:
+    server: %[2]s
+    insecure-skip-tls-verify: true
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    user: %[1]s
+current-context: %[1]s
+users:
+- name: %[1]s
+  user:
+    token: example
+`, cfg.Name, s.Server)
+	if err := os.WriteFile(path, []byte(kubeconfig), 0600); err != nil {
+		return "", fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return path, nil
+}
+
+func (p *exampleProvider) GetStatus(cfg *plugin.ClusterConfig) (string, error) {
+	if _, err := os.Stat(filepath.Join(stateDir(cfg), "state.json")); os.IsNotExist(err) {
+		return "unknown", nil
+	}
+	return "deployed", nil
+}
+
+func (p *exampleProvider) GetClusterStatus(cfg *plugin.ClusterConfig) (*plugin.ClusterStatus, error) {
+	s, err := loadState(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin.ClusterStatus{
+		Ready:             true,
+		Message:           "Simulated cluster is ready",
+		APIEndpoint:       s.Server,
+		ControlPlaneTotal: len(s.ControlPlane),
+		ControlPlaneReady: len(s.ControlPlane),
+		WorkerTotal:       len(s.Workers),
+		WorkerReady:       len(s.Workers),
+		CreatedAt:         s.CreatedAt,
+	}, nil
+}
+
+func (p *exampleProvider) ValidateAPIServer(cfg *plugin.ClusterConfig) (string, error) {
+	s, err := loadState(cfg)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("API server %s (simulated)", s.Server), nil
+}
+
+func (p *exampleProvider) ValidateNodes(cfg *plugin.ClusterConfig) (string, error) {
+	s, err := loadState(cfg)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d/%d nodes ready (simulated)", len(s.ControlPlane)+len(s.Workers), len(s.ControlPlane)+len(s.Workers)), nil
+}
+
+func (p *exampleProvider) ValidateSystemPods(cfg *plugin.ClusterConfig) (string, error) {
+	return p.simulated(cfg, "all system pods running")
+}
+
+func (p *exampleProvider) ValidateEtcd(cfg *plugin.ClusterConfig) (string, error) {
+	return p.simulated(cfg, "etcd healthy")
+}
+
+func (p *exampleProvider) ValidateDNS(cfg *plugin.ClusterConfig) (string, error) {
+	return p.simulated(cfg, "cluster DNS resolves")
+}
+
+func (p *exampleProvider) ValidateNetworking(cfg *plugin.ClusterConfig) (string, error) {
+	return p.simulated(cfg, "pod networking works")
+}
+
+func (p *exampleProvider) ValidatePodScheduling(cfg *plugin.ClusterConfig) (string, error) {
+	return p.simulated(cfg, "pods can be scheduled")
+}
+
+// simulated returns message for a created cluster
+func (p *exampleProvider) simulated(cfg *plugin.ClusterConfig, message string) (string, error) {
+	if _, err := loadState(cfg); err != nil {
+		return "", err
+	}
+	return message + " (simulated)", nil
+}
+
+func loadState(cfg *plugin.ClusterConfig) (*state, error) {
+	data, err := os.ReadFile(filepath.Join(stateDir(cfg), "state.json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("cluster %s has not been created", cfg.Name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	return &s, nil
+}
+
+func stateDir(cfg *plugin.ClusterConfig) string {
+	if dir := setting(cfg, "stateDir", ""); dir != "" {
+		return dir
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = os.TempDir()
+	}
+	return filepath.Join(homeDir, ".tdls-k8s", "clusters", cfg.Name, "example")
+}
+
+func setting(cfg *plugin.ClusterConfig, key, def string) string {
+	if value, ok := cfg.Provider.Settings[key].(string); ok && value != "" {
+		return value
+	}
+	return def
+}
+
+func validateServer(server string) error {
+	u, err := url.Parse(server)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("provider.settings.server must be an https URL, got %q", server)
+	}
+	return nil
+}
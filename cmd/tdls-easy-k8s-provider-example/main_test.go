@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/user/tdls-easy-k8s/pkg/plugin"
+	"github.com/user/tdls-easy-k8s/pkg/plugin/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the plugin binary")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, plugin.ExecutablePrefix+"example")
+	if out, err := exec.Command("go", "build", "-o", path, ".").CombinedOutput(); err != nil {
+		t.Fatalf("failed to build plugin: %v\n%s", err, out)
+	}
+
+	conformance.Run(t, conformance.Options{
+		Path: path,
+		Config: &plugin.ClusterConfig{
+			Name: "conformance",
+			Provider: plugin.ProviderConfig{
+				Type:     "example",
+				Settings: map[string]interface{}{"stateDir": filepath.Join(dir, "state")},
+			},
+			Nodes: plugin.NodesConfig{
+				ControlPlane: plugin.NodeGroupConfig{Count: 3},
+				Workers:      plugin.NodeGroupConfig{Count: 2},
+			},
+		},
+		Lifecycle: true,
+	})
+}
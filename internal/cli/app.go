@@ -97,6 +97,12 @@ spec:
 }
 
 func generateHelmRepositoryYAML(name, url string) string {
+	// Charts in OCI registries need the repository type set
+	typeLine := ""
+	if strings.HasPrefix(url, "oci://") {
+		typeLine = "  type: oci\n"
+	}
+
 	return fmt.Sprintf(`apiVersion: source.toolkit.fluxcd.io/v1
 kind: HelmRepository
 metadata:
@@ -104,8 +110,8 @@ metadata:
   namespace: flux-system
 spec:
   interval: 1h0m0s
-  url: %s
-`, name, url)
+%s  url: %s
+`, name, typeLine, url)
 }
 
 func generateHelmReleaseYAML(name, namespace, chart, repoName, version, valuesYAML string) string {
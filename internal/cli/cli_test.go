@@ -1,9 +1,25 @@
 package cli
 
 import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+	"github.com/user/tdls-easy-k8s/internal/kubeconfig"
+	"github.com/user/tdls-easy-k8s/internal/provider"
+	"gopkg.in/yaml.v3"
 )
 
 func TestRootCommand_Exists(t *testing.T) {
@@ -22,7 +38,7 @@ func TestRootCommand_HasSubcommands(t *testing.T) {
 		names[cmd.Name()] = true
 	}
 
-	expected := []string{"init", "gitops", "app", "version", "destroy", "status", "validate", "kubeconfig", "monitor", "vault"}
+	expected := []string{"init", "gitops", "app", "version", "destroy", "status", "validate", "kubeconfig", "monitor", "vault", "config", "providers", "user"}
 	for _, name := range expected {
 		if !names[name] {
 			t.Errorf("expected subcommand %q to be registered", name)
@@ -72,10 +88,12 @@ func TestInitCommand_HasFlags(t *testing.T) {
 		defValue string
 	}{
 		{"provider", "aws"},
-		{"region", "us-east-1"},
+		{"region", ""},
 		{"name", ""},
 		{"nodes", "3"},
 		{"generate-config", "false"},
+		{"interactive", "false"},
+		{"output", "cluster.yaml"},
 	}
 
 	for _, tc := range cases {
@@ -100,6 +118,7 @@ func TestGitopsSetupCommand_HasFlags(t *testing.T) {
 		{"repo", ""},
 		{"branch", "main"},
 		{"path", "clusters/production"},
+		{"cluster", ""},
 	}
 
 	for _, tc := range cases {
@@ -234,6 +253,18 @@ func TestGenerateHelmRepositoryYAML(t *testing.T) {
 	}
 }
 
+func TestGenerateHelmRepositoryYAML_OCI(t *testing.T) {
+	yaml := generateHelmRepositoryYAML("aws-ec2", "oci://public.ecr.aws/aws-ec2/helm")
+	if !strings.Contains(yaml, "  type: oci\n  url: oci://public.ecr.aws/aws-ec2/helm") {
+		t.Errorf("expected an OCI repository, got:\n%s", yaml)
+	}
+
+	yaml = generateHelmRepositoryYAML("bitnami", "https://charts.bitnami.com/bitnami")
+	if strings.Contains(yaml, "type:") {
+		t.Errorf("expected no type for an HTTP repository, got:\n%s", yaml)
+	}
+}
+
 func TestGenerateHelmReleaseYAML_NoValues(t *testing.T) {
 	yaml := generateHelmReleaseYAML("my-api", "default", "nginx", "bitnami", "*", "")
 
@@ -553,6 +584,973 @@ func TestKubeconfigCommand_HasFlags(t *testing.T) {
 		{"output", "./kubeconfig"},
 		{"merge", "false"},
 		{"set-context", "false"},
+		{"remove", "false"},
+		{"oidc", "false"},
+	}
+
+	for _, tc := range cases {
+		f := flags.Lookup(tc.name)
+		if f == nil {
+			t.Errorf("expected flag %q to exist", tc.name)
+			continue
+		}
+		if f.DefValue != tc.defValue {
+			t.Errorf("flag %q: expected default %q, got %q", tc.name, tc.defValue, f.DefValue)
+		}
+	}
+}
+
+func TestConfigCommand_HasMigrateSubcommand(t *testing.T) {
+	commands := configCmd.Commands()
+	found := false
+	for _, cmd := range commands {
+		if cmd.Name() == "migrate" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("expected 'migrate' subcommand under 'config'")
+	}
+}
+
+func TestConfigCommand_HasRenderSubcommand(t *testing.T) {
+	commands := configCmd.Commands()
+	found := false
+	for _, cmd := range commands {
+		if cmd.Name() == "render" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("expected 'render' subcommand under 'config'")
+	}
+}
+
+func TestRootCommand_HasOverlayFlag(t *testing.T) {
+	f := rootCmd.PersistentFlags().Lookup("overlay")
+	if f == nil {
+		t.Fatal("expected persistent flag 'overlay' to exist")
+	}
+	if f.DefValue != "[]" {
+		t.Errorf("flag 'overlay': expected default '[]', got %q", f.DefValue)
+	}
+}
+
+func TestConfigMigrateCommand_HasFlags(t *testing.T) {
+	flags := configMigrateCmd.Flags()
+
+	cases := []struct {
+		name     string
+		defValue string
+	}{
+		{"all-clusters", "false"},
+		{"dry-run", "false"},
+	}
+
+	for _, tc := range cases {
+		f := flags.Lookup(tc.name)
+		if f == nil {
+			t.Errorf("expected flag %q to exist", tc.name)
+			continue
+		}
+		if f.DefValue != tc.defValue {
+			t.Errorf("flag %q: expected default %q, got %q", tc.name, tc.defValue, f.DefValue)
+		}
+	}
+}
+
+func TestRunInitWizard_Hetzner(t *testing.T) {
+	answers := strings.Join([]string{
+		"my-cluster", // name
+		"hetzner",    // provider
+		"xyz1",       // invalid location, asked again
+		"hel1",       // location
+		"",           // kubernetes version
+		"",           // control plane count
+		"",           // control plane server type
+		"3",          // workers
+		"cpx42",      // worker server type
+		"",           // traefik
+		"n",          // vault
+		"",           // external secrets
+		"",           // cloud controller manager
+		"n",          // CSI driver
+		"github.com/example/gitops",
+		"", // branch
+		"", // path
+	}, "\n") + "\n"
+
+	var out strings.Builder
+	cfg, err := runInitWizard(bufio.NewReader(strings.NewReader(answers)), &out)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v\noutput:\n%s", err, out.String())
+	}
+
+	if !strings.Contains(out.String(), "invalid Hetzner location") {
+		t.Errorf("expected the provider's validation error for an invalid location, got:\n%s", out.String())
+	}
+	if cfg.Provider.Hetzner.Location != "hel1" {
+		t.Errorf("expected location 'hel1', got %q", cfg.Provider.Hetzner.Location)
+	}
+	if cfg.Nodes.ControlPlane.Count != 1 || cfg.Nodes.ControlPlane.InstanceType != "cpx22" {
+		t.Errorf("expected default control plane, got %+v", cfg.Nodes.ControlPlane)
+	}
+	if cfg.Nodes.Workers.Count != 3 || cfg.Nodes.Workers.InstanceType != "cpx42" {
+		t.Errorf("expected 3 cpx42 workers, got %+v", cfg.Nodes.Workers)
+	}
+	if !cfg.Components.Traefik.Enabled || cfg.Components.Vault.Enabled || !cfg.Components.HCloudCCM.Enabled || cfg.Components.HCloudCSI.Enabled {
+		t.Errorf("unexpected components: %+v", cfg.Components)
+	}
+	if !cfg.GitOps.Enabled || cfg.GitOps.Path != "clusters/my-cluster" {
+		t.Errorf("unexpected gitops settings: %+v", cfg.GitOps)
+	}
+}
+
+func TestRunInitWizard_ProxmoxSizing(t *testing.T) {
+	answers := strings.Join([]string{
+		"lab", "proxmox",
+		"", "not-an-ip", "10.0.0.50", "", "", // node, VIP (retried), bridge, datastore
+		"",       // version
+		"2", "3", // control plane count: even counts are asked again
+		"", "", "", // cpu, memory, disk
+		"0",           // no workers
+		"n", "n", "n", // components
+		"", // no gitops
+	}, "\n") + "\n"
+
+	var out strings.Builder
+	cfg, err := runInitWizard(bufio.NewReader(strings.NewReader(answers)), &out)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v\noutput:\n%s", err, out.String())
+	}
+	if cfg.Provider.Proxmox.VIP != "10.0.0.50" || cfg.Provider.Proxmox.Node != "pve" {
+		t.Errorf("unexpected proxmox settings: %+v", cfg.Provider.Proxmox)
+	}
+	if cfg.Nodes.ControlPlane.CPU != 4 || cfg.Nodes.ControlPlane.MemoryMB != 8192 {
+		t.Errorf("expected default VM sizing, got %+v", cfg.Nodes.ControlPlane)
+	}
+	if cfg.Nodes.ControlPlane.Count != 3 || !strings.Contains(out.String(), "choose one of: 1, 3, 5") {
+		t.Errorf("expected 2 control plane nodes to be refused, got %d\noutput:\n%s", cfg.Nodes.ControlPlane.Count, out.String())
+	}
+	if cfg.GitOps.Enabled {
+		t.Error("expected gitops to stay disabled")
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	p, err := provider.GetProvider("hetzner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.ClusterConfig{Provider: config.ProviderConfig{Type: "hetzner", Hetzner: config.HetznerConfig{Location: "hel1"}}}
+	cfg.Nodes.ControlPlane = config.NodeGroupConfig{Count: 1, InstanceType: "cpx22"}
+	if err := validateFields(cfg, p); err != nil {
+		t.Errorf("expected no error without workers, got: %v", err)
+	}
+
+	cfg.Nodes.Workers.Count = 2
+	if err := validateFields(cfg, p); err == nil || !strings.Contains(err.Error(), "worker server type is required") {
+		t.Errorf("expected the provider's rule for the worker server type, got %v", err)
+	}
+}
+
+func TestRunInitWizard_InputEnds(t *testing.T) {
+	var out strings.Builder
+	_, err := runInitWizard(bufio.NewReader(strings.NewReader("my-cluster\n")), &out)
+	if err == nil {
+		t.Fatal("expected error when input ends early")
+	}
+}
+
+func TestConfigTemplates_AreValid(t *testing.T) {
+	for providerName, template := range configTemplates {
+		t.Run(providerName, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cluster.yaml")
+			if err := os.WriteFile(path, []byte(template), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := config.LoadFromFile(path)
+			if err != nil {
+				t.Fatalf("template does not load: %v", err)
+			}
+			if cfg.Provider.Type != providerName {
+				t.Errorf("expected provider type %q, got %q", providerName, cfg.Provider.Type)
+			}
+
+			p, err := provider.GetProvider(providerName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fv := p.(provider.FieldValidator)
+			fields := map[string]string{
+				"provider.aws.region":             cfg.Provider.AWS.Region,
+				"provider.aws.vpc.cidr":           cfg.Provider.AWS.VPC.CIDR,
+				"provider.hetzner.location":       cfg.Provider.Hetzner.Location,
+				"provider.proxmox.node":           cfg.Provider.Proxmox.Node,
+				"provider.proxmox.vip":            cfg.Provider.Proxmox.VIP,
+				"nodes.controlPlane.instanceType": cfg.Nodes.ControlPlane.InstanceType,
+				"nodes.workers.instanceType":      cfg.Nodes.Workers.InstanceType,
+			}
+			for field, value := range fields {
+				if !strings.HasPrefix(field, "provider.") || strings.HasPrefix(field, "provider."+providerName+".") {
+					if err := fv.ValidateField(field, value); err != nil {
+						t.Errorf("%s: %v", field, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestListProviders(t *testing.T) {
+	var out strings.Builder
+	if err := listProviders(&out); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for _, want := range []string{"aws", "hetzner", "proxmox", "object-storage", "vip"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected provider list to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestMergeAndRemoveKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	kubeConfigPath := filepath.Join(dir, "kube config")
+	t.Setenv("KUBECONFIG", kubeConfigPath)
+
+	source := filepath.Join(dir, "rke2.yaml")
+	os.WriteFile(source, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: default
+  cluster:
+    server: https://203.0.113.10:6443
+contexts:
+- name: default
+  context:
+    cluster: default
+    user: default
+current-context: default
+users:
+- name: default
+  user:
+    token: abc
+`), 0600)
+
+	if err := mergeKubeconfig(source, "prod", true); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	// A second merge replaces the entries and backs up the first result
+	if err := mergeKubeconfig(source, "prod", true); err != nil {
+		t.Fatalf("second merge failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(kubeConfigPath)
+	if strings.Count(string(data), "name: tdls-prod") != 3 || !strings.Contains(string(data), "current-context: tdls-prod") {
+		t.Errorf("unexpected merged kubeconfig:\n%s", data)
+	}
+	if backups, _ := filepath.Glob(kubeConfigPath + ".backup-*"); len(backups) != 1 {
+		t.Errorf("expected one backup, got %v", backups)
+	}
+
+	removed, err := removeKubeconfig("prod")
+	if err != nil || !removed {
+		t.Fatalf("expected entries to be removed, got %v, %v", removed, err)
+	}
+	data, _ = os.ReadFile(kubeConfigPath)
+	if strings.Contains(string(data), "tdls-prod") {
+		t.Errorf("expected no tdls-prod entries, got:\n%s", data)
+	}
+
+	if removed, err := removeKubeconfig("prod"); err != nil || removed {
+		t.Errorf("expected nothing to remove, got %v, %v", removed, err)
+	}
+}
+
+func TestWriteOIDCKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	adminPath := filepath.Join(dir, "kubeconfig")
+	os.WriteFile(adminPath, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: default
+  cluster:
+    server: https://203.0.113.10:6443
+    certificate-authority-data: Q0E=
+contexts:
+- name: default
+  context:
+    cluster: default
+    user: default
+current-context: default
+users:
+- name: default
+  user:
+    token: admin-token
+`), 0600)
+
+	cfg := &config.ClusterConfig{Name: "sso"}
+	if _, err := writeOIDCKubeconfig(cfg, adminPath); err == nil {
+		t.Error("expected an error without auth.oidc")
+	}
+
+	cfg.Auth.OIDC = config.OIDCConfig{
+		IssuerURL:   "https://login.example.com",
+		ClientID:    "kubernetes",
+		CA:          "PEM",
+		ExtraScopes: []string{"groups"},
+	}
+	path, err := writeOIDCKubeconfig(cfg, adminPath)
+	if err != nil {
+		t.Fatalf("writeOIDCKubeconfig failed: %v", err)
+	}
+	if path != filepath.Join(dir, "kubeconfig-oidc") {
+		t.Errorf("unexpected path %s", path)
+	}
+
+	data, _ := os.ReadFile(path)
+	content := string(data)
+	if strings.Contains(content, "admin-token") {
+		t.Errorf("expected no admin credentials, got:\n%s", content)
+	}
+	for _, want := range []string{
+		"server: https://203.0.113.10:6443",
+		"certificate-authority-data: Q0E=",
+		"name: tdls-sso",
+		"- oidc-login",
+		"--oidc-issuer-url=https://login.example.com",
+		"--oidc-client-id=kubernetes",
+		"--oidc-extra-scope=groups",
+		"--certificate-authority-data=" + base64.StdEncoding.EncodeToString([]byte("PEM")),
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in kubeconfig:\n%s", want, content)
+		}
+	}
+}
+
+// fakeTunneler returns a fixed tunnel command
+type fakeTunneler struct {
+	command []string
+	err     error
+}
+
+func (f fakeTunneler) TunnelCommand(cfg *config.ClusterConfig) ([]string, error) {
+	return f.command, f.err
+}
+
+func TestPrintTunnelHint(t *testing.T) {
+	cfg := &config.ClusterConfig{Name: "private"}
+
+	var out strings.Builder
+	if err := printTunnelHint(&out, fakeTunneler{}, cfg); err != nil || out.Len() != 0 {
+		t.Errorf("expected no hint for a public cluster, got %q, %v", out.String(), err)
+	}
+
+	command := []string{"aws", "ssm", "start-session", "--target", "i-0abc"}
+	if err := printTunnelHint(&out, fakeTunneler{command: command}, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "private") || !strings.Contains(out.String(), "  aws ssm start-session --target i-0abc\n") {
+		t.Errorf("unexpected hint: %q", out.String())
+	}
+
+	if err := printTunnelHint(&out, fakeTunneler{err: errors.New("no state")}, cfg); err == nil {
+		t.Error("expected an error")
+	}
+}
+func TestUserAddCommand_HasFlags(t *testing.T) {
+	flags := userAddCmd.Flags()
+
+	cases := []struct {
+		name     string
+		defValue string
+	}{
+		{"user", ""},
+		{"role", "view"},
+		{"namespace", ""},
+		{"expiry", "720h0m0s"},
+		{"output", ""},
+	}
+
+	for _, tc := range cases {
+		f := flags.Lookup(tc.name)
+		if f == nil {
+			t.Errorf("expected flag %q to exist", tc.name)
+			continue
+		}
+		if f.DefValue != tc.defValue {
+			t.Errorf("flag %q: expected default %q, got %q", tc.name, tc.defValue, f.DefValue)
+		}
+	}
+
+	if userCmd.PersistentFlags().Lookup("cluster") == nil {
+		t.Error("expected user command to have a --cluster flag")
+	}
+}
+
+// signUserCertificate signs a CSR with a throwaway CA, as the cluster signer would
+func signUserCertificate(t *testing.T, key *ecdsa.PrivateKey, csrPEM []byte, notAfter time.Time) []byte {
+	t.Helper()
+	block, _ := pem.Decode(csrPEM)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatalf("invalid CSR: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      csr.Subject,
+		NotBefore:    time.Now(),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, csr.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestGenerateUserKey(t *testing.T) {
+	key, csrPEM, err := generateUserKey("alice")
+	if err != nil {
+		t.Fatalf("generateUserKey failed: %v", err)
+	}
+
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		t.Fatalf("expected a PEM certificate request, got %q", csrPEM)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csr.Subject.CommonName != "alice" {
+		t.Errorf("expected CN alice, got %q", csr.Subject.CommonName)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		t.Errorf("CSR signature invalid: %v", err)
+	}
+
+	notAfter := time.Now().Add(time.Hour).Truncate(time.Second)
+	expires, err := certificateExpiry(signUserCertificate(t, key, csrPEM, notAfter))
+	if err != nil || !expires.Equal(notAfter) {
+		t.Errorf("expected expiry %v, got %v (%v)", notAfter, expires, err)
+	}
+}
+
+func TestGenerateUserManifests(t *testing.T) {
+	expires := time.Date(2026, 11, 17, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{"csr", generateCSRYAML("tdls-user-alice-1", "alice", []byte("CSR"), 48*time.Hour), []string{
+			"signerName: kubernetes.io/kube-apiserver-client", "expirationSeconds: 172800", "- client auth", "tdls-easy-k8s/user: alice",
+		}},
+		{"cluster role binding", generateClusterRoleBindingYAML("alice", "cluster-admin", expires), []string{
+			"kind: ClusterRoleBinding", "name: tdls-user-alice", "name: cluster-admin", `tdls-easy-k8s/expires: "2026-11-17T12:00:00Z"`,
+		}},
+		{"role binding", generateRoleBindingYAML("bob", "edit", "shop", expires), []string{
+			"kind: RoleBinding", "namespace: shop", "kind: ClusterRole\n  name: edit", "kind: User\n    name: bob",
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var doc map[string]interface{}
+			if err := yaml.Unmarshal([]byte(tc.yaml), &doc); err != nil {
+				t.Fatalf("invalid YAML: %v\n%s", err, tc.yaml)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(tc.yaml, want) {
+					t.Errorf("expected %q in:\n%s", want, tc.yaml)
+				}
+			}
+		})
+	}
+}
+
+func TestParseUserBindings(t *testing.T) {
+	data := []byte(`{"items": [
+		{"metadata": {"namespace": "shop", "labels": {"tdls-easy-k8s/user": "bob"}, "annotations": {"tdls-easy-k8s/expires": "2000-01-01T00:00:00Z"}}, "roleRef": {"name": "edit"}},
+		{"metadata": {"labels": {"tdls-easy-k8s/user": "alice"}, "annotations": {"tdls-easy-k8s/expires": "2999-01-01T00:00:00Z"}}, "roleRef": {"name": "cluster-admin"}}
+	]}`)
+
+	bindings, err := parseUserBindings(data)
+	if err != nil {
+		t.Fatalf("parseUserBindings failed: %v", err)
+	}
+	if len(bindings) != 2 {
+		t.Fatalf("expected 2 bindings, got %d", len(bindings))
+	}
+	if bindings[0].User != "alice" || bindings[0].Role != "admin" || bindings[0].Namespace != "" {
+		t.Errorf("unexpected first binding: %+v", bindings[0])
+	}
+	if bindings[1].User != "bob" || bindings[1].Namespace != "shop" || !strings.HasSuffix(bindings[1].Expires, "(expired)") {
+		t.Errorf("unexpected second binding: %+v", bindings[1])
+	}
+}
+
+func TestWaitForCertificate(t *testing.T) {
+	calls := 0
+	orig := runKubectl
+	runKubectl = func(kubeconfigPath, stdin string, args ...string) ([]byte, error) {
+		calls++
+		if calls < 2 {
+			return nil, nil
+		}
+		return []byte(base64.StdEncoding.EncodeToString([]byte("CERT"))), nil
+	}
+	certificatePollInterval = time.Millisecond
+	t.Cleanup(func() { runKubectl, certificatePollInterval = orig, 2*time.Second })
+
+	cert, err := waitForCertificate("admin.yaml", "csr", 10*time.Second)
+	if err != nil || string(cert) != "CERT" {
+		t.Errorf("expected the issued certificate, got %q, %v", cert, err)
+	}
+}
+
+func TestWriteUserKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	admin := kubeconfig.New("default", map[string]interface{}{"server": "https://203.0.113.10:6443", "certificate-authority-data": "Q0E="}, map[string]interface{}{"token": "admin"})
+	adminPath := filepath.Join(dir, "admin.yaml")
+	if err := admin.Save(adminPath); err != nil {
+		t.Fatal(err)
+	}
+
+	key, csrPEM, err := generateUserKey("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := signUserCertificate(t, key, csrPEM, time.Now().Add(time.Hour))
+
+	outputPath := filepath.Join(dir, "bob.kubeconfig")
+	if err := writeUserKubeconfig(adminPath, outputPath, "prod", "bob", "shop", certPEM, key); err != nil {
+		t.Fatalf("writeUserKubeconfig failed: %v", err)
+	}
+
+	cfg, err := kubeconfig.Load(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cluster, user, err := cfg.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Name != "tdls-prod-bob" || ctx.Context.Namespace != "shop" {
+		t.Errorf("unexpected context: %+v", ctx)
+	}
+	if cluster.Cluster["server"] != "https://203.0.113.10:6443" {
+		t.Errorf("expected the admin kubeconfig's server, got %v", cluster.Cluster)
+	}
+	if _, ok := user.User["token"]; ok {
+		t.Error("the admin credentials must not be copied")
+	}
+	if user.User["client-certificate-data"] != base64.StdEncoding.EncodeToString(certPEM) || user.User["client-key-data"] == nil {
+		t.Errorf("expected client certificate and key, got %v", user.User)
+	}
+}
+
+func TestAddUser_RejectsInvalidInput(t *testing.T) {
+	cases := []struct {
+		name, user, role string
+		expiry           time.Duration
+		want             string
+	}{
+		{"bad user", "Alice@example.com", "view", time.Hour, "user name must contain"},
+		{"bad role", "alice", "owner", time.Hour, "role must be"},
+		{"short expiry", "alice", "view", time.Minute, "expiry must be at least"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			userName, userRole, userExpiry = tc.user, tc.role, tc.expiry
+			err := addUser()
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+	userName, userRole, userExpiry = "", "view", 30*24*time.Hour
+}
+
+func TestRevokeUser_RejectsInvalidName(t *testing.T) {
+	calls := 0
+	orig := runKubectl
+	runKubectl = func(kubeconfigPath, stdin string, args ...string) ([]byte, error) {
+		calls++
+		return nil, nil
+	}
+	defer func() { runKubectl = orig }()
+
+	// Such a selector would match the bindings of every other user
+	userName = "alice,tdls-easy-k8s/user!=x"
+	defer func() { userName = "" }()
+	if err := revokeUser(); err == nil || !strings.Contains(err.Error(), "user name must contain") {
+		t.Errorf("expected the user name to be rejected, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("expected no kubectl calls, got %d", calls)
+	}
+}
+
+func TestExecCommand_HasFlags(t *testing.T) {
+	cases := []struct {
+		name     string
+		defValue string
+	}{
+		{"cluster", ""},
+		{"nodes", "all"},
+	}
+
+	for _, tc := range cases {
+		f := execCmd.Flags().Lookup(tc.name)
+		if f == nil {
+			t.Errorf("expected flag %q to exist", tc.name)
+			continue
+		}
+		if f.DefValue != tc.defValue {
+			t.Errorf("flag %q: expected default %q, got %q", tc.name, tc.defValue, f.DefValue)
+		}
+	}
+
+	if sshCmd.Flags().Lookup("cluster") == nil {
+		t.Error("expected ssh command to have a --cluster flag")
+	}
+}
+
+func testNodes() []provider.Node {
+	return []provider.Node{
+		{Name: "prod-cp-0", Role: provider.RoleControlPlane, IP: "203.0.113.10"},
+		{Name: "prod-cp-1", Role: provider.RoleControlPlane, IP: "203.0.113.11"},
+		{Name: "prod-worker-0", Role: provider.RoleWorker, IP: "203.0.113.20", InstanceID: "i-0abc"},
+	}
+}
+
+func TestFindNode(t *testing.T) {
+	nodes := testNodes()
+
+	for _, target := range []string{"prod-worker-0", "worker-0", "203.0.113.20", "i-0abc"} {
+		node, err := findNode(nodes, "prod", target)
+		if err != nil || node.Name != "prod-worker-0" {
+			t.Errorf("%s: expected prod-worker-0, got %q, %v", target, node.Name, err)
+		}
+	}
+
+	if _, err := findNode(nodes, "prod", "worker-9"); err == nil || !strings.Contains(err.Error(), "prod-cp-0, prod-cp-1, prod-worker-0") {
+		t.Errorf("expected an error listing the nodes, got %v", err)
+	}
+}
+
+func TestSelectNodes(t *testing.T) {
+	nodes := testNodes()
+
+	cases := []struct {
+		which string
+		want  int
+	}{
+		{"all", 3},
+		{"cp", 2},
+		{"workers", 1},
+	}
+	for _, tc := range cases {
+		selected, err := selectNodes(nodes, tc.which)
+		if err != nil || len(selected) != tc.want {
+			t.Errorf("%s: expected %d nodes, got %d, %v", tc.which, tc.want, len(selected), err)
+		}
+	}
+
+	if _, err := selectNodes(nodes, "etcd"); err == nil {
+		t.Error("expected an error for an invalid --nodes value")
+	}
+}
+
+func TestRunOnNodes(t *testing.T) {
+	run := func(node provider.Node, command string) ([]byte, error) {
+		if node.Role == provider.RoleWorker {
+			return []byte("inactive\n"), errors.New("exit status 3")
+		}
+		return []byte("active\nok\n"), nil
+	}
+
+	var out strings.Builder
+	failed := runOnNodes(testNodes(), run, "systemctl is-active rke2-server", &out)
+	if failed != 1 {
+		t.Errorf("expected 1 failed node, got %d", failed)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	sort.Strings(lines)
+	want := []string{
+		"[prod-cp-0] active",
+		"[prod-cp-0] ok",
+		"[prod-cp-1] active",
+		"[prod-cp-1] ok",
+		"[prod-worker-0] inactive",
+		"[prod-worker-0] ❌ exit status 3",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	// Each node's output is written as one block
+	if !strings.Contains(out.String(), "[prod-cp-0] active\n[prod-cp-0] ok\n") {
+		t.Errorf("expected node output not to be interleaved:\n%s", out.String())
+	}
+}
+
+func TestNodesCommand_HasFlags(t *testing.T) {
+	if nodesCmd.Flags().Lookup("cluster") == nil {
+		t.Error("expected nodes command to have a --cluster flag")
+	}
+}
+
+func TestParseKubeNodes(t *testing.T) {
+	nodes, err := parseKubeNodes([]byte(`{"items": [
+  {
+    "metadata": {
+      "name": "ip-10-0-1-10.ec2.internal",
+      "creationTimestamp": "2026-10-01T10:00:00Z",
+      "labels": {"node-role.kubernetes.io/control-plane": "true", "node-role.kubernetes.io/etcd": "true", "kubernetes.io/os": "linux"}
+    },
+    "spec": {"providerID": "aws:///us-east-1a/i-cp0", "unschedulable": true},
+    "status": {
+      "conditions": [{"type": "MemoryPressure", "status": "False"}, {"type": "Ready", "status": "True"}],
+      "addresses": [{"type": "InternalIP", "address": "10.0.1.10"}, {"type": "Hostname", "address": "ip-10-0-1-10"}],
+      "nodeInfo": {"kubeletVersion": "v1.30.4+rke2r1"}
+    }
+  },
+  {"metadata": {"name": "prod-worker-0"}, "status": {"conditions": [{"type": "Ready", "status": "Unknown"}]}}
+]}`))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(nodes))
+	}
+
+	cp := nodes[0]
+	if cp.Status != "Ready,SchedulingDisabled" || cp.Roles != "control-plane,etcd" || cp.Version != "v1.30.4+rke2r1" {
+		t.Errorf("unexpected node: %+v", cp)
+	}
+	if cp.ProviderID != "aws:///us-east-1a/i-cp0" || len(cp.Addresses) != 2 || cp.Created.IsZero() {
+		t.Errorf("unexpected node: %+v", cp)
+	}
+	if nodes[1].Status != "NotReady" || nodes[1].Roles != "<none>" {
+		t.Errorf("unexpected node: %+v", nodes[1])
+	}
+}
+
+func TestJoinNodes(t *testing.T) {
+	machines := []provider.Node{
+		{Name: "prod-control-plane-0", Role: provider.RoleControlPlane, InstanceID: "i-cp0", PrivateIP: "10.0.1.10"},
+		{Name: "prod-cp-1", Role: provider.RoleControlPlane, IP: "203.0.113.11"},
+		{Name: "prod-worker-0", Role: provider.RoleWorker, IP: "203.0.113.20"},
+		{Name: "prod-worker-1", Role: provider.RoleWorker, IP: "203.0.113.21"},
+	}
+	kubeNodes := []kubeNode{
+		{Name: "ip-10-0-1-10.ec2.internal", ProviderID: "aws:///us-east-1a/i-cp0"},
+		{Name: "prod-worker-0"},
+		{Name: "cp-1-renamed", Addresses: []string{"10.1.0.5", "203.0.113.11"}},
+		{Name: "prod-worker-7"},
+	}
+
+	rows := joinNodes(machines, kubeNodes)
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 rows, got %d", len(rows))
+	}
+
+	want := []struct{ machine, node string }{
+		{"prod-control-plane-0", "ip-10-0-1-10.ec2.internal"},
+		{"prod-cp-1", "cp-1-renamed"},
+		{"prod-worker-0", "prod-worker-0"},
+		{"prod-worker-1", ""},
+		{"", "prod-worker-7"},
+	}
+	for i, w := range want {
+		var machine, node string
+		if rows[i].Machine != nil {
+			machine = rows[i].Machine.Name
+		}
+		if rows[i].Node != nil {
+			node = rows[i].Node.Name
+		}
+		if machine != w.machine || node != w.node {
+			t.Errorf("row %d: expected %s/%s, got %s/%s", i, w.machine, w.node, machine, node)
+		}
+	}
+
+	var out strings.Builder
+	now := time.Now()
+	kubeNodes[1].Status, kubeNodes[1].Created = "Ready", now.Add(-3*time.Hour)
+	printNodeRows(&out, rows, true, now)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 || !strings.HasPrefix(lines[0], "MACHINE") {
+		t.Fatalf("unexpected table:\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[3]); fields[0] != "prod-worker-0" || fields[6] != "Ready" || fields[9] != "3h" {
+		t.Errorf("unexpected row: %s", lines[3])
+	}
+	if fields := strings.Fields(lines[4]); fields[5] != "-" || fields[6] != "NotJoined" {
+		t.Errorf("expected an unjoined machine, got: %s", lines[4])
+	}
+	if fields := strings.Fields(lines[5]); fields[0] != "-" || fields[5] != "prod-worker-7" {
+		t.Errorf("expected a node without machine, got: %s", lines[5])
+	}
+}
+
+func TestShortAge(t *testing.T) {
+	cases := []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Second, "30s"},
+		{5 * time.Minute, "5m"},
+		{30 * time.Hour, "30h"},
+		{72 * time.Hour, "3d"},
+	}
+	for _, tc := range cases {
+		if got := shortAge(tc.d); got != tc.want {
+			t.Errorf("shortAge(%v): expected %s, got %s", tc.d, tc.want, got)
+		}
+	}
+}
+
+func TestNodeReplaceCommand_HasFlags(t *testing.T) {
+	cases := []struct {
+		name     string
+		defValue string
+	}{
+		{"drain-timeout", "5m0s"},
+		{"timeout", "20m0s"},
+	}
+
+	for _, tc := range cases {
+		f := nodeReplaceCmd.Flags().Lookup(tc.name)
+		if f == nil {
+			t.Errorf("expected flag %q to exist", tc.name)
+			continue
+		}
+		if f.DefValue != tc.defValue {
+			t.Errorf("flag %q: expected default %q, got %q", tc.name, tc.defValue, f.DefValue)
+		}
+	}
+
+	if nodeCmd.PersistentFlags().Lookup("cluster") == nil {
+		t.Error("expected node command to have a --cluster flag")
+	}
+}
+
+func TestFindEtcdMember(t *testing.T) {
+	list := `6571fb7574e87dba, started, prod-cp-0-4a1b2c3d, https://10.0.0.2:2380, https://10.0.0.2:2379, false
+8e9e05c52164694d, started, prod-cp-1-9f8e7d6c, https://10.0.0.3:2380, https://10.0.0.3:2379, false
+91bc3c398fb3c146, started, ip-10-0-1-12-0a1b2c3d, https://10.0.1.12:2380, https://10.0.1.12:2379, false
+`
+
+	cases := []struct {
+		name       string
+		names, ips []string
+		wantID     string
+	}{
+		{"by node name", []string{"prod-cp-1"}, nil, "8e9e05c52164694d"},
+		{"by peer IP", []string{"prod-control-plane-2"}, []string{"", "10.0.1.12"}, "91bc3c398fb3c146"},
+		{"name prefix is not enough", []string{"prod-cp"}, nil, ""},
+		{"missing", []string{"prod-cp-5"}, []string{"10.0.0.9"}, ""},
+	}
+	for _, tc := range cases {
+		if id, _ := findEtcdMember(list, tc.names, tc.ips); id != tc.wantID {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.wantID, id)
+		}
+	}
+}
+
+// fakeNodeAccessor lists a fixed set of machines
+type fakeNodeAccessor struct {
+	nodes []provider.Node
+}
+
+func (f *fakeNodeAccessor) ListNodes(*config.ClusterConfig) ([]provider.Node, error) {
+	return f.nodes, nil
+}
+
+func (f *fakeNodeAccessor) Shell(*config.ClusterConfig, provider.Node) error {
+	return nil
+}
+
+func (f *fakeNodeAccessor) CommandRunner(*config.ClusterConfig) (provider.CommandRunner, error) {
+	return nil, nil
+}
+
+func TestWaitForReplacement(t *testing.T) {
+	accessor := &fakeNodeAccessor{nodes: []provider.Node{{Name: "prod-worker-1", Role: provider.RoleWorker, IP: "203.0.113.99"}}}
+
+	calls := 0
+	orig := runKubectl
+	runKubectl = func(kubeconfigPath, stdin string, args ...string) ([]byte, error) {
+		calls++
+		status := "False"
+		if calls >= 3 {
+			status = "True"
+		}
+		return []byte(`{"items": [{"metadata": {"name": "prod-worker-1"}, "status": {"conditions": [{"type": "Ready", "status": "` + status + `"}]}}]}`), nil
+	}
+	nodeReadyInterval = time.Millisecond
+	t.Cleanup(func() { runKubectl, nodeReadyInterval = orig, 15*time.Second })
+
+	cfg := &config.ClusterConfig{Name: "prod"}
+	name, err := waitForReplacement(cfg, accessor, "kubeconfig", "prod-worker-1", time.Minute)
+	if err != nil || name != "prod-worker-1" || calls != 3 {
+		t.Errorf("expected prod-worker-1 after 3 polls, got %q, %v after %d", name, err, calls)
+	}
+
+	if _, err := waitForReplacement(cfg, accessor, "kubeconfig", "prod-worker-2", 0); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
+
+func TestReplaceClusterNode_RefusedBeforeDraining(t *testing.T) {
+	calls := 0
+	orig := runKubectl
+	runKubectl = func(kubeconfigPath, stdin string, args ...string) ([]byte, error) {
+		calls++
+		return nil, nil
+	}
+	t.Cleanup(func() { runKubectl = orig })
+
+	accessor := &fakeNodeAccessor{nodes: []provider.Node{
+		{Name: "prod-cp-0", Role: provider.RoleControlPlane, IP: "203.0.113.10"},
+		{Name: "prod-workers-abc", Role: provider.RoleWorker, IP: "203.0.113.20", Autoscaled: true},
+	}}
+	cfg := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "hetzner"}}
+
+	for target, reason := range map[string]string{"prod-cp-0": "bootstraps the cluster", "prod-workers-abc": "autoscaled group"} {
+		err := replaceClusterNode(cfg, provider.NewHetznerProvider(), accessor, target)
+		if err == nil || !strings.Contains(err.Error(), reason) {
+			t.Errorf("expected %s to be refused with %q, got %v", target, reason, err)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("expected no kubectl calls before refusing, got %d", calls)
+	}
+}
+
+func TestGitopsInfraCommand_HasFlags(t *testing.T) {
+	flags := gitopsInfraCmd.Flags()
+
+	cases := []struct {
+		name     string
+		defValue string
+	}{
+		{"cluster", ""},
+		{"output-dir", ""},
+		{"gitops-path", "clusters/production"},
 	}
 
 	for _, tc := range cases {
@@ -566,3 +1564,201 @@ func TestKubeconfigCommand_HasFlags(t *testing.T) {
 		}
 	}
 }
+
+func TestInfraComponents_SpotWorkers(t *testing.T) {
+	cfg := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "aws"}}
+	if components := infraComponents(cfg); len(components) != 0 {
+		t.Errorf("expected no components without spot workers, got %+v", components)
+	}
+
+	cfg.Nodes.Workers.Spot = true
+	components := infraComponents(cfg)
+	if len(components) != 1 || components[0].Name != "aws-node-termination-handler" {
+		t.Fatalf("expected the node termination handler, got %+v", components)
+	}
+
+	manifests := components[0].manifests("clusters/prod")
+	wantPaths := []string{
+		"clusters/prod/infrastructure/aws-node-termination-handler.yaml",
+		"infrastructure/aws-node-termination-handler/helmrepository.yaml",
+		"infrastructure/aws-node-termination-handler/helmrelease.yaml",
+	}
+	for i, want := range wantPaths {
+		if manifests[i].path != want {
+			t.Errorf("expected manifest %d at %s, got %s", i, want, manifests[i].path)
+		}
+	}
+	release := manifests[2].content
+	for _, want := range []string{"enableSpotInterruptionDraining: true", "node.kubernetes.io/instance-lifecycle: spot", "targetNamespace: kube-system"} {
+		if !strings.Contains(release, want) {
+			t.Errorf("expected HelmRelease to contain %q, got:\n%s", want, release)
+		}
+	}
+}
+
+func TestInfraComponents_ClusterAutoscaler(t *testing.T) {
+	scaling := config.AutoscalingConfig{Min: 1, Max: 5}
+
+	aws := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "aws", AWS: config.AWSConfig{Region: "eu-west-1"}}}
+	aws.Nodes.Workers.Autoscaling = scaling
+	components := infraComponents(aws)
+	if len(components) != 1 || components[0].Name != "cluster-autoscaler" {
+		t.Fatalf("expected the cluster autoscaler, got %+v", components)
+	}
+	for _, want := range []string{"cloudProvider: aws", "awsRegion: eu-west-1", "clusterName: prod", "node-role.kubernetes.io/control-plane"} {
+		if !strings.Contains(components[0].Values, want) {
+			t.Errorf("expected AWS values to contain %q, got:\n%s", want, components[0].Values)
+		}
+	}
+
+	hetzner := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "hetzner", Hetzner: config.HetznerConfig{Location: "nbg1"}}}
+	hetzner.Nodes.Workers = config.NodeGroupConfig{Count: 2, InstanceType: "cpx32", Autoscaling: scaling}
+	components = infraComponents(hetzner)
+	if len(components) != 1 {
+		t.Fatalf("expected the cluster autoscaler, got %+v", components)
+	}
+	for _, want := range []string{
+		"cloudProvider: hetzner",
+		"name: prod-workers\n    minSize: 1\n    maxSize: 5\n    instanceType: cpx32\n    region: nbg1",
+		"HCLOUD_NETWORK: prod-network",
+		"HCLOUD_IMAGE: ubuntu-22.04",
+		"HCLOUD_CLOUD_INIT:\n    name: hcloud\n    key: cloudInit",
+	} {
+		if !strings.Contains(components[0].Values, want) {
+			t.Errorf("expected Hetzner values to contain %q, got:\n%s", want, components[0].Values)
+		}
+	}
+
+	proxmox := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "proxmox"}}
+	proxmox.Nodes.Workers.Autoscaling = scaling
+	if components := infraComponents(proxmox); len(components) != 0 {
+		t.Errorf("expected no cluster autoscaler without provider support, got %+v", components)
+	}
+}
+
+func TestInfraComponents_HetznerCCMAndCSI(t *testing.T) {
+	cfg := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "hetzner", Hetzner: config.HetznerConfig{Location: "hel1"}}}
+	cfg.Components.HCloudCCM.Enabled = true
+	cfg.Components.HCloudCSI = config.HCloudCSIConfig{Enabled: true, Version: "2.10.x"}
+
+	components := infraComponents(cfg)
+	if len(components) != 2 || components[0].Name != "hcloud-cloud-controller-manager" || components[1].Name != "hcloud-csi" {
+		t.Fatalf("expected the cloud controller manager and CSI driver, got %+v", components)
+	}
+	if components[0].RepoName == components[1].RepoName {
+		t.Errorf("expected a HelmRepository per component, got %q for both", components[0].RepoName)
+	}
+	for _, want := range []string{
+		"secretKeyRef:\n        name: hcloud\n        key: network",
+		"HCLOUD_LOAD_BALANCERS_LOCATION:\n    value: hel1",
+		"HCLOUD_NETWORK_ROUTES_ENABLED:\n    value: \"false\"",
+	} {
+		if !strings.Contains(components[0].Values, want) {
+			t.Errorf("expected CCM values to contain %q, got:\n%s", want, components[0].Values)
+		}
+	}
+	if components[0].Version != ">=1.20.0 <2.0.0" || components[1].Version != "2.10.x" {
+		t.Errorf("expected the default CCM and configured CSI versions, got %q and %q", components[0].Version, components[1].Version)
+	}
+}
+
+func TestFormatAutoscaling(t *testing.T) {
+	scaling := config.AutoscalingConfig{Min: 2, Max: 6}
+	if got := formatAutoscaling(3, scaling); got != "  ✓ Autoscaling: 3 workers (min 2, max 6)" {
+		t.Errorf("unexpected line %q", got)
+	}
+	if got := formatAutoscaling(1, scaling); !strings.HasPrefix(got, "  ⚠") {
+		t.Errorf("expected a warning below min, got %q", got)
+	}
+}
+
+func TestGenerateInfra_GitopsPath(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "cluster.yaml")
+	err := os.WriteFile(cfgPath, []byte(`name: staging
+provider:
+  type: hetzner
+  hetzner:
+    location: fsn1
+kubernetes:
+  version: "1.30"
+nodes:
+  controlPlane:
+    count: 1
+gitops:
+  path: clusters/staging
+components:
+  hcloudCCM:
+    enabled: true
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfgFile, infraOutputDir = cfgPath, filepath.Join(dir, "repo")
+	flag := gitopsInfraCmd.Flags().Lookup("gitops-path")
+	t.Cleanup(func() {
+		cfgFile, infraOutputDir = "", ""
+		flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	})
+
+	manifest := func(gitopsPath string) string {
+		return filepath.Join(infraOutputDir, gitopsPath, "infrastructure", "hcloud-cloud-controller-manager.yaml")
+	}
+	if err := generateInfra(gitopsInfraCmd); err != nil {
+		t.Fatalf("generateInfra failed: %v", err)
+	}
+	if _, err := os.Stat(manifest("clusters/staging")); err != nil {
+		t.Errorf("expected the Kustomization under the cluster's gitops.path: %v", err)
+	}
+
+	if err := gitopsInfraCmd.Flags().Set("gitops-path", "clusters/other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := generateInfra(gitopsInfraCmd); err != nil {
+		t.Fatalf("generateInfra failed: %v", err)
+	}
+	if _, err := os.Stat(manifest("clusters/other")); err != nil {
+		t.Errorf("expected --gitops-path to override the cluster's gitops.path: %v", err)
+	}
+}
+
+// failingProvider fails to create the infrastructure
+type failingProvider struct {
+	provider.Provider
+}
+
+func (failingProvider) CreateInfrastructure(*config.ClusterConfig) error {
+	return errors.New("failed to update TLS certificates")
+}
+
+func TestCreateCluster_SavesConfigOnFailure(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	cfg := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "aws"}}
+	err := createCluster(failingProvider{}, cfg)
+	if err == nil || !strings.Contains(err.Error(), "failed to update TLS certificates") {
+		t.Errorf("expected the creation error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".tdls-k8s", "clusters", "prod", "cluster.yaml")); err != nil {
+		t.Errorf("expected the cluster config to be saved for destroy and exec: %v", err)
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"df", "-h", "/"}, "df -h /"},
+		{[]string{"sh", "-c", "echo a b"}, "sh -c 'echo a b'"},
+		{[]string{"echo", "it's", ""}, `echo 'it'\''s' ''`},
+		{[]string{"echo", "$HOME", "a|b"}, "echo '$HOME' 'a|b'"},
+	}
+	for _, tc := range cases {
+		if got := shellJoin(tc.args); got != tc.want {
+			t.Errorf("%q: expected %s, got %s", tc.args, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/user/tdls-easy-k8s/internal/config"
+)
+
+var (
+	configMigrateAll    bool
+	configMigrateDryRun bool
+)
+
+// configCmd represents the config command group
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage cluster configuration files",
+	Long:  `Commands for inspecting and maintaining cluster configuration files.`,
+}
+
+// configMigrateCmd represents the config migrate command
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate [file...]",
+	Short: "Upgrade cluster configuration files to the latest format version",
+	Long: fmt.Sprintf(`Rewrite cluster configuration files to the latest format version (%s).
+Deprecated flat provider fields such as provider.region are moved into the
+section for the provider type.
+
+Older documents are always upgraded in memory when loaded, so migrating is
+optional, but it keeps files on disk in sync with the current schema.
+Comments and key order are preserved.
+
+If no files are given, the file passed with --config (or ./cluster.yaml) is used.
+
+Examples:
+  # Migrate ./cluster.yaml
+  tdls-easy-k8s config migrate
+
+  # Preview the migrated document without writing it
+  tdls-easy-k8s config migrate my-cluster.yaml --dry-run
+
+  # Migrate every saved cluster config under ~/.tdls-k8s/clusters
+  tdls-easy-k8s config migrate --all-clusters`, config.APIVersion),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return migrateConfigs(cmd, args)
+	},
+}
+
+// configRenderCmd represents the config render command
+var configRenderCmd = &cobra.Command{
+	Use:   "render [file]",
+	Short: "Print the effective cluster configuration",
+	Long: `Print the effective cluster configuration after applying overlays and defaults.
+
+The base config is taken from the argument, --config, or ./cluster.yaml. Overlays
+given with --overlay are merged on top of it in order:
+
+  - maps are merged key by key, recursively
+  - a null value in an overlay removes the key
+  - lists of items that all have a "name" key are merged by name
+  - any other list, and every scalar value, is replaced by the overlay
+
+Values set with ${...} references are printed as the reference, not the value.
+
+Examples:
+  # Render the production variant of a shared base config
+  tdls-easy-k8s config render --config base.yaml --overlay prod.yaml
+
+  # Stack several overlays
+  tdls-easy-k8s config render base.yaml --overlay eu.yaml --overlay prod.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return renderConfig(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configMigrateCmd)
+	configCmd.AddCommand(configRenderCmd)
+
+	configMigrateCmd.Flags().BoolVar(&configMigrateAll, "all-clusters", false, "Migrate all saved cluster configs under ~/.tdls-k8s/clusters")
+	configMigrateCmd.Flags().BoolVar(&configMigrateDryRun, "dry-run", false, "Print migrated documents instead of writing them")
+}
+
+func migrateConfigs(cmd *cobra.Command, args []string) error {
+	paths, err := configMigrateTargets(args)
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		fmt.Println("No cluster configuration files found")
+		return nil
+	}
+
+	failed := 0
+	for _, path := range paths {
+		if err := migrateConfigFile(path); err != nil {
+			fmt.Printf("❌ %s: %v\n", path, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to migrate %d file(s)", failed)
+	}
+	return nil
+}
+
+func migrateConfigFile(path string) error {
+	if configMigrateDryRun {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		out, _, err := config.Migrate(data)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# %s\n", path)
+		fmt.Print(string(out))
+		return nil
+	}
+
+	original, changed, err := config.MigrateFile(path)
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		fmt.Printf("✓ %s is already at %s\n", path, config.APIVersion)
+		return nil
+	}
+
+	if original == config.APIVersion {
+		fmt.Printf("✓ Migrated %s (moved deprecated provider fields into provider.<type>)\n", path)
+		return nil
+	}
+	from := original
+	if from == "" {
+		from = "unversioned"
+	}
+	fmt.Printf("✓ Migrated %s (%s → %s)\n", path, from, config.APIVersion)
+	return nil
+}
+
+// configMigrateTargets resolves which files the migrate command should rewrite.
+func configMigrateTargets(args []string) ([]string, error) {
+	if configMigrateAll {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		matches, err := filepath.Glob(filepath.Join(homeDir, ".tdls-k8s", "clusters", "*", "cluster.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		return append(matches, args...), nil
+	}
+
+	if len(args) > 0 {
+		return args, nil
+	}
+
+	if cfgFile != "" {
+		return []string{cfgFile}, nil
+	}
+
+	return []string{"cluster.yaml"}, nil
+}
+
+func renderConfig(cmd *cobra.Command, args []string) error {
+	path := "cluster.yaml"
+	if len(args) > 0 {
+		path = args[0]
+	} else if cfgFile != "" {
+		path = cfgFile
+	}
+
+	cfg, err := config.LoadFromFile(path, overlayFiles...)
+	if err != nil {
+		return err
+	}
+	data, err := config.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(string(data))
+	return nil
+}
@@ -4,10 +4,10 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/user/tdls-easy-k8s/internal/provider"
 )
 
 var (
@@ -61,38 +61,28 @@ func destroyCluster(cmd *cobra.Command) error {
 	}
 
 	fmt.Printf("Provider: %s\n", cfg.Provider.Type)
-	location := cfg.Provider.Region
-	if cfg.Provider.Location != "" {
-		location = cfg.Provider.Location
-	}
-	if location != "" {
+	if location := cfg.Provider.DisplayLocation(); location != "" {
 		fmt.Printf("Location: %s\n", location)
 	}
 	fmt.Println()
 
-	// Show provider-specific warning
+	// Get provider
+	p, err := getProvider(cfg.Provider.Type)
+	if err != nil {
+		return err
+	}
+	reg, _ := provider.Lookup(cfg.Provider.Type)
+
+	// Show the resources this provider creates
 	fmt.Println("⚠️  WARNING: This will permanently delete the following resources:")
-	switch cfg.Provider.Type {
-	case "hetzner":
-		fmt.Println("  - All servers (control plane and workers)")
-		fmt.Println("  - Private network and subnets")
-		fmt.Println("  - Load balancer")
-		fmt.Println("  - Firewall rules")
-		fmt.Println("  - SSH keys")
-	case "aws":
-		fmt.Println("  - All EC2 instances (control plane and workers)")
-		fmt.Println("  - VPC and all networking components (subnets, NAT gateways, IGW)")
-		fmt.Println("  - Network Load Balancer")
-		fmt.Println("  - EBS volumes (including etcd data)")
-		fmt.Println("  - Security groups and IAM roles")
-	default:
-		fmt.Println("  - All compute instances (control plane and workers)")
-		fmt.Println("  - All networking components")
-		fmt.Println("  - Load balancers and firewall rules")
+	for _, resource := range reg.Resources {
+		fmt.Printf("  - %s\n", resource)
 	}
+	storage, hasStorage := p.(provider.ObjectStorage)
+	hasStorage = hasStorage && reg.Capabilities.ObjectStorage
 	if destroyCleanup {
-		if cfg.Provider.Type == "aws" {
-			fmt.Println("  - S3 bucket for kubeconfig and state (with --cleanup)")
+		if hasStorage {
+			fmt.Printf("  - Bucket %s with the kubeconfig and state (with --cleanup)\n", storage.ObjectStorageName(cfg))
 		}
 		fmt.Println("  - Local terraform state and working directory (with --cleanup)")
 	}
@@ -117,39 +107,29 @@ func destroyCluster(cmd *cobra.Command) error {
 		fmt.Println()
 	}
 
-	// Get provider
-	p, err := getProvider(cfg.Provider.Type)
-	if err != nil {
-		return err
-	}
-
 	// Destroy infrastructure
 	fmt.Println("Starting infrastructure destruction...")
 	if err := p.DestroyInfrastructure(cfg); err != nil {
 		return fmt.Errorf("failed to destroy infrastructure: %w", err)
 	}
 
-	// Cleanup local files (and S3 bucket for AWS) if requested
+	// The cluster's kubectl context would point at a server that no longer exists
+	if _, err := removeKubeconfig(cfg.Name); err != nil {
+		fmt.Printf("Warning: failed to remove kubeconfig entries: %v\n", err)
+	}
+
+	// Cleanup local files (and the object storage bucket, if any) if requested
 	if destroyCleanup {
 		fmt.Println("\nCleaning up additional resources...")
 
-		// Delete S3 bucket (AWS only)
-		if cfg.Provider.Type == "aws" {
-			bucketName := fmt.Sprintf("tdls-k8s-%s", cfg.Name)
-			fmt.Printf("Deleting S3 bucket: %s\n", bucketName)
-
-			// Empty bucket first (required before deletion)
-			emptyCmd := fmt.Sprintf("aws s3 rm s3://%s --recursive --region %s 2>/dev/null", bucketName, cfg.Provider.Region)
-			if err := runShellCommandQuiet(emptyCmd); err != nil {
-				fmt.Printf("Note: bucket may already be empty or not exist\n")
-			}
-
-			// Delete bucket
-			deleteCmd := fmt.Sprintf("aws s3 rb s3://%s --region %s 2>/dev/null", bucketName, cfg.Provider.Region)
-			if err := runShellCommandQuiet(deleteCmd); err != nil {
-				fmt.Printf("Note: S3 bucket may already be deleted\n")
+		// Delete the provider's object storage bucket
+		if hasStorage {
+			bucketName := storage.ObjectStorageName(cfg)
+			fmt.Printf("Deleting bucket: %s\n", bucketName)
+			if err := storage.DeleteObjectStorage(cfg); err != nil {
+				fmt.Printf("Note: bucket may already be deleted (%v)\n", err)
 			} else {
-				fmt.Printf("✓ Deleted S3 bucket: %s\n", bucketName)
+				fmt.Printf("✓ Deleted bucket: %s\n", bucketName)
 			}
 		}
 
@@ -173,8 +153,3 @@ func destroyCluster(cmd *cobra.Command) error {
 
 	return nil
 }
-
-func runShellCommandQuiet(cmd string) error {
-	shellCmd := exec.Command("bash", "-c", cmd)
-	return shellCmd.Run()
-}
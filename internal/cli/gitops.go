@@ -11,10 +11,23 @@ import (
 
 const fluxInstallURL = "https://github.com/fluxcd/flux2/releases/latest/download/install.yaml"
 
+// fluxControllers are the deployments of the Flux installation
+var fluxControllers = []string{
+	"source-controller",
+	"kustomize-controller",
+	"helm-controller",
+	"notification-controller",
+}
+
+// fluxTolerationsPatch lets the Flux controllers run on nodes that the cloud
+// controller manager has not initialized yet
+const fluxTolerationsPatch = `{"spec":{"template":{"spec":{"tolerations":[{"key":"node.cloudprovider.kubernetes.io/uninitialized","operator":"Exists","effect":"NoSchedule"}]}}}}`
+
 var (
-	gitopsRepo   string
-	gitopsBranch string
-	gitopsPath   string
+	gitopsRepo        string
+	gitopsBranch      string
+	gitopsPath        string
+	gitopsClusterName string
 )
 
 // gitopsCmd represents the gitops command group
@@ -29,7 +42,12 @@ var gitopsSetupCmd = &cobra.Command{
 	Use:   "setup",
 	Short: "Setup GitOps on the cluster",
 	Long: `Setup GitOps (Flux) on the cluster and configure it to sync with your Git repository.
-This will install Flux controllers and configure them to watch your repository for changes.`,
+This will install Flux controllers and configure them to watch your repository for changes.
+
+kubectl uses the current kubeconfig context, or with --cluster the cluster's admin
+kubeconfig (through an SSM tunnel for a private AWS cluster). Pass --cluster for
+Hetzner clusters with components.hcloudCCM: Flux installs the cloud controller
+manager, so its controllers must tolerate nodes that are not initialized yet.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return setupGitOps(cmd)
 	},
@@ -42,6 +60,7 @@ func init() {
 	gitopsSetupCmd.Flags().StringVar(&gitopsRepo, "repo", "", "Git repository URL (required)")
 	gitopsSetupCmd.Flags().StringVar(&gitopsBranch, "branch", "main", "Git branch to track")
 	gitopsSetupCmd.Flags().StringVar(&gitopsPath, "path", "clusters/production", "Path in repository")
+	gitopsSetupCmd.Flags().StringVarP(&gitopsClusterName, "cluster", "c", "", "Cluster to set up (default: the current kubeconfig context)")
 
 	gitopsSetupCmd.MarkFlagRequired("repo")
 }
@@ -52,11 +71,28 @@ func setupGitOps(cmd *cobra.Command) error {
 	fmt.Printf("  Branch:     %s\n", gitopsBranch)
 	fmt.Printf("  Path:       %s\n\n", gitopsPath)
 
+	// kubectl and flux run below pick the cluster up from KUBECONFIG
+	tolerateUninitialized := false
+	if gitopsClusterName != "" {
+		cfg, err := loadClusterConfig(gitopsClusterName)
+		if err != nil {
+			return fmt.Errorf("failed to load cluster config: %w", err)
+		}
+		tolerateUninitialized = cfg.Components.HCloudCCM.Enabled
+
+		kubeconfigPath, err := adminKubeconfigPath(gitopsClusterName)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(kubeconfigPath)
+		os.Setenv("KUBECONFIG", kubeconfigPath)
+	}
+
 	if err := checkGitOpsPrerequisites(); err != nil {
 		return fmt.Errorf("prerequisite check failed: %w", err)
 	}
 
-	if err := installFluxControllers(); err != nil {
+	if err := installFluxControllers(tolerateUninitialized); err != nil {
 		return fmt.Errorf("failed to install Flux: %w", err)
 	}
 
@@ -100,7 +136,10 @@ func checkGitOpsPrerequisites() error {
 	return nil
 }
 
-func installFluxControllers() error {
+// installFluxControllers installs Flux. With tolerateUninitialized the controllers
+// also run on nodes that wait for an external cloud controller manager, such as
+// Hetzner nodes with components.hcloudCCM, since Flux deploys that manager itself.
+func installFluxControllers(tolerateUninitialized bool) error {
 	fmt.Println("[2/6] Installing Flux controllers...")
 
 	checkCmd := exec.Command("kubectl", "get", "namespace", "flux-system")
@@ -115,6 +154,17 @@ func installFluxControllers() error {
 		return fmt.Errorf("kubectl apply failed: %w", err)
 	}
 
+	if !tolerateUninitialized {
+		fmt.Println("  Flux controllers installed")
+		return nil
+	}
+	for _, deploy := range fluxControllers {
+		cmd := exec.Command("kubectl", "patch", "deployment", deploy, "-n", "flux-system", "-p", fluxTolerationsPatch)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to patch %s: %s", deploy, strings.TrimSpace(string(output)))
+		}
+	}
+
 	fmt.Println("  Flux controllers installed")
 	return nil
 }
@@ -122,14 +172,7 @@ func installFluxControllers() error {
 func waitForFluxReady() error {
 	fmt.Println("[3/6] Waiting for Flux controllers to be ready...")
 
-	deployments := []string{
-		"source-controller",
-		"kustomize-controller",
-		"helm-controller",
-		"notification-controller",
-	}
-
-	for _, deploy := range deployments {
+	for _, deploy := range fluxControllers {
 		fmt.Printf("  Waiting for %s...\n", deploy)
 		cmd := exec.Command("kubectl", "wait", "--for=condition=available",
 			"--timeout=120s",
@@ -0,0 +1,308 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/user/tdls-easy-k8s/internal/config"
+	"github.com/user/tdls-easy-k8s/internal/provider"
+)
+
+var (
+	infraClusterName string
+	infraOutputDir   string
+	infraGitopsPath  string
+)
+
+// infraComponent is a Helm chart in the GitOps infrastructure layer that a
+// cluster's config calls for
+type infraComponent struct {
+	Name      string // Kustomization, HelmRelease and manifest directory name
+	Namespace string
+	RepoName  string
+	RepoURL   string
+	Chart     string
+	Version   string
+	Values    string
+	Reason    string // why the cluster needs it
+}
+
+// gitopsInfraCmd represents the gitops infra command
+var gitopsInfraCmd = &cobra.Command{
+	Use:   "infra",
+	Short: "Generate the infrastructure components the cluster config needs",
+	Long: `Generate Flux CD manifests for the components a cluster needs because of
+its config, e.g. the AWS node termination handler for spot workers, the
+cluster autoscaler for autoscaled workers or the Hetzner cloud controller
+manager and CSI driver. They go in the infrastructure layer, which Flux
+applies before the apps.
+
+If --output-dir is provided, files are written to the local gitops repo.
+Otherwise, YAML is printed to stdout.`,
+	Example: `  tdls-easy-k8s gitops infra --cluster=production --output-dir=../cluster-gitops`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return generateInfra(cmd)
+	},
+}
+
+func init() {
+	gitopsCmd.AddCommand(gitopsInfraCmd)
+
+	gitopsInfraCmd.Flags().StringVarP(&infraClusterName, "cluster", "c", "", "Cluster name (required)")
+	gitopsInfraCmd.MarkFlagRequired("cluster")
+	gitopsInfraCmd.Flags().StringVar(&infraOutputDir, "output-dir", "", "Path to local gitops repo root (prints to stdout if omitted)")
+	gitopsInfraCmd.Flags().StringVar(&infraGitopsPath, "gitops-path", "clusters/production", "Path within repo for Kustomization CRDs (defaults to the cluster's gitops.path when set)")
+}
+
+// infraComponents returns the infrastructure components a cluster's config calls for
+func infraComponents(cfg *config.ClusterConfig) []infraComponent {
+	var components []infraComponent
+	if cfg.Components.HCloudCCM.Enabled {
+		components = append(components, hcloudCloudControllerManager(cfg))
+	}
+	if cfg.Components.HCloudCSI.Enabled {
+		components = append(components, hcloudCSIDriver(cfg))
+	}
+	if cfg.Provider.Type == "aws" && cfg.Nodes.Workers.Spot {
+		components = append(components, nodeTerminationHandler())
+	}
+	if cfg.Nodes.Workers.Autoscaling.Enabled() {
+		if c, ok := clusterAutoscaler(cfg); ok {
+			components = append(components, c)
+		}
+	}
+	return components
+}
+
+// hcloudCloudControllerManager initializes the Hetzner nodes, which run with
+// cloud-provider=external, and creates load balancers for LoadBalancer services.
+// The load balancers reach the nodes over the cluster network; pod traffic stays
+// on the CNI overlay, so the network gets no routes.
+func hcloudCloudControllerManager(cfg *config.ClusterConfig) infraComponent {
+	version := cfg.Components.HCloudCCM.Version
+	if version == "" {
+		version = ">=1.20.0 <2.0.0"
+	}
+	return infraComponent{
+		Name:      "hcloud-cloud-controller-manager",
+		Namespace: "kube-system",
+		RepoName:  "hcloud-ccm",
+		RepoURL:   "https://charts.hetzner.cloud",
+		Chart:     "hcloud-cloud-controller-manager",
+		Version:   version,
+		Values: fmt.Sprintf(`env:
+  HCLOUD_NETWORK:
+    valueFrom:
+      secretKeyRef:
+        name: %s
+        key: network
+  HCLOUD_NETWORK_ROUTES_ENABLED:
+    value: "false"
+  HCLOUD_LOAD_BALANCERS_LOCATION:
+    value: %s
+  HCLOUD_LOAD_BALANCERS_USE_PRIVATE_IP:
+    value: "true"`, provider.HCloudSecretName, cfg.Provider.Hetzner.Location),
+		Reason: "components.hcloudCCM: initializes the nodes and provides LoadBalancer services",
+	}
+}
+
+// hcloudCSIDriver provides PersistentVolumes backed by Hetzner Cloud volumes
+// through its default hcloud-volumes storage class. Like the cloud controller
+// manager, it reads the API token from the hcloud secret. Each component has its
+// own HelmRepository, as each directory is applied by its own Kustomization.
+func hcloudCSIDriver(cfg *config.ClusterConfig) infraComponent {
+	version := cfg.Components.HCloudCSI.Version
+	if version == "" {
+		version = ">=2.9.0 <3.0.0"
+	}
+	return infraComponent{
+		Name:      "hcloud-csi",
+		Namespace: "kube-system",
+		RepoName:  "hcloud-csi",
+		RepoURL:   "https://charts.hetzner.cloud",
+		Chart:     "hcloud-csi",
+		Version:   version,
+		Reason:    "components.hcloudCSI: provides PersistentVolumes on Hetzner Cloud volumes",
+	}
+}
+
+// nodeTerminationHandler cordons and drains spot workers when AWS sends a
+// reclaim notice, two minutes before the instance is terminated. It runs in
+// IMDS mode on the nodes labelled as spot by the worker user data.
+func nodeTerminationHandler() infraComponent {
+	return infraComponent{
+		Name:      "aws-node-termination-handler",
+		Namespace: "kube-system",
+		RepoName:  "aws-ec2",
+		RepoURL:   "oci://public.ecr.aws/aws-ec2/helm",
+		Chart:     "aws-node-termination-handler",
+		Version:   ">=0.21.0 <1.0.0",
+		Values: `enableSpotInterruptionDraining: true
+enableRebalanceMonitoring: false
+enableScheduledEventDraining: true
+nodeSelector:
+  node.kubernetes.io/instance-lifecycle: spot`,
+		Reason: "nodes.workers.spot: drains spot workers before they are reclaimed",
+	}
+}
+
+// clusterAutoscalerPlacement runs the cluster autoscaler on the control plane,
+// whose instances are not scaled down and, on AWS, hold its IAM permissions
+const clusterAutoscalerPlacement = `nodeSelector:
+  node-role.kubernetes.io/control-plane: "true"
+tolerations:
+  - key: node-role.kubernetes.io/control-plane
+    operator: Exists
+    effect: NoSchedule`
+
+// clusterAutoscaler resizes the worker group between nodes.workers.autoscaling
+// min and max. On AWS it finds the Auto Scaling Group by its tags and uses the
+// control plane's instance role; on Hetzner it manages the workers' node group
+// with the token from the hcloud secret created by init. It reports false for
+// providers without autoscaling.
+func clusterAutoscaler(cfg *config.ClusterConfig) (infraComponent, bool) {
+	component := infraComponent{
+		Name:      "cluster-autoscaler",
+		Namespace: "kube-system",
+		RepoName:  "autoscaler",
+		RepoURL:   "https://kubernetes.github.io/autoscaler",
+		Chart:     "cluster-autoscaler",
+		Version:   ">=9.37.0 <10.0.0",
+		Reason:    "nodes.workers.autoscaling: resizes the workers to fit pending pods",
+	}
+
+	scaling := cfg.Nodes.Workers.Autoscaling
+	switch cfg.Provider.Type {
+	case "aws":
+		component.Values = fmt.Sprintf(`cloudProvider: aws
+awsRegion: %s
+autoDiscovery:
+  clusterName: %s
+extraArgs:
+  balance-similar-node-groups: true
+%s`, cfg.Provider.AWS.Region, cfg.Name, clusterAutoscalerPlacement)
+	case "hetzner":
+		image := cfg.Provider.Hetzner.OSImage
+		if image == "" {
+			image = "ubuntu-22.04"
+		}
+		component.Values = fmt.Sprintf(`cloudProvider: hetzner
+autoscalingGroups:
+  - name: %s
+    minSize: %d
+    maxSize: %d
+    instanceType: %s
+    region: %s
+extraEnv:
+  HCLOUD_NETWORK: %s-network
+  HCLOUD_FIREWALL: %s-firewall
+  HCLOUD_SSH_KEY: %s-key
+  HCLOUD_IMAGE: %s
+  HCLOUD_PUBLIC_IPV6: "false"
+extraEnvSecrets:
+  HCLOUD_TOKEN:
+    name: %s
+    key: token
+  HCLOUD_CLOUD_INIT:
+    name: %s
+    key: cloudInit
+%s`, provider.HetznerNodeGroup(cfg.Name), scaling.Min, scaling.Max,
+			cfg.Nodes.Workers.InstanceType, cfg.Provider.Hetzner.Location,
+			cfg.Name, cfg.Name, cfg.Name, image,
+			provider.HCloudSecretName, provider.HCloudSecretName, clusterAutoscalerPlacement)
+	default:
+		return infraComponent{}, false
+	}
+	return component, true
+}
+
+// manifests returns the component's files by path relative to the gitops repo root
+func (c infraComponent) manifests(gitopsPath string) []struct{ path, content string } {
+	dir := filepath.Join("infrastructure", c.Name)
+	return []struct{ path, content string }{
+		{filepath.Join(gitopsPath, "infrastructure", c.Name+".yaml"), generateAppKustomizationYAML(c.Name, "infrastructure", "")},
+		{filepath.Join(dir, "helmrepository.yaml"), generateHelmRepositoryYAML(c.RepoName, c.RepoURL)},
+		{filepath.Join(dir, "helmrelease.yaml"), generateHelmReleaseYAML(c.Name, c.Namespace, c.Chart, c.RepoName, c.Version, c.Values)},
+	}
+}
+
+func generateInfra(cmd *cobra.Command) error {
+	cfg, err := loadClusterConfig(infraClusterName)
+	if err != nil {
+		return fmt.Errorf("failed to load cluster config: %w", err)
+	}
+
+	components := infraComponents(cfg)
+	if len(components) == 0 {
+		fmt.Printf("Cluster '%s' needs no infrastructure components\n", cfg.Name)
+		return nil
+	}
+
+	// The Kustomizations go where Flux looks for the cluster unless
+	// --gitops-path says otherwise
+	gitopsPath := infraGitopsPath
+	if !cmd.Flags().Changed("gitops-path") && cfg.GitOps.Path != "" {
+		gitopsPath = cfg.GitOps.Path
+	}
+
+	if infraOutputDir != "" {
+		if err := writeInfraFiles(components, gitopsPath); err != nil {
+			return err
+		}
+	} else {
+		printInfraYAML(components, gitopsPath)
+	}
+
+	printInfraNextSteps(components)
+	return nil
+}
+
+func writeInfraFiles(components []infraComponent, gitopsPath string) error {
+	fmt.Println("Files written:")
+	for _, c := range components {
+		for _, f := range c.manifests(gitopsPath) {
+			path := filepath.Join(infraOutputDir, f.path)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+			if err := os.WriteFile(path, []byte(f.content), 0o644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+			fmt.Printf("  %s\n", path)
+		}
+	}
+	return nil
+}
+
+func printInfraYAML(components []infraComponent, gitopsPath string) {
+	first := true
+	for _, c := range components {
+		for _, f := range c.manifests(gitopsPath) {
+			if !first {
+				fmt.Println("---")
+			}
+			first = false
+			fmt.Printf("# %s\n", f.path)
+			fmt.Print(f.content)
+		}
+	}
+}
+
+func printInfraNextSteps(components []infraComponent) {
+	fmt.Println("\nComponents:")
+	for _, c := range components {
+		fmt.Printf("  %s (%s)\n", c.Name, c.Reason)
+	}
+
+	fmt.Println("\nNext steps:")
+	if infraOutputDir != "" {
+		fmt.Println("  1. Commit and push the generated files")
+	} else {
+		fmt.Println("  1. Write the manifests above to your gitops repo and push")
+	}
+	fmt.Println("  2. Check the components:")
+	fmt.Println("     kubectl get helmrelease -n flux-system")
+	fmt.Println()
+}
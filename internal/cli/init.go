@@ -4,8 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-
-	"gopkg.in/yaml.v3"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/user/tdls-easy-k8s/internal/config"
@@ -18,6 +17,8 @@ var (
 	clusterName  string
 	nodes        int
 	generateCfg  bool
+	interactive  bool
+	initOutput   string
 )
 
 // initCmd represents the init command
@@ -25,12 +26,19 @@ var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize a new Kubernetes cluster",
 	Long: `Initialize a new Kubernetes cluster on the specified cloud provider.
-This command will create the necessary infrastructure and install Kubernetes.`,
+This command will create the necessary infrastructure and install Kubernetes.
+
+Use --interactive to create a cluster config file by answering questions, or
+--generate-config --provider=<aws|hetzner|proxmox> to print a sample config.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if generateCfg {
 			return generateConfig(cmd)
 		}
 
+		if interactive {
+			return runInteractiveInit(cmd)
+		}
+
 		return initCluster(cmd)
 	},
 }
@@ -38,51 +46,127 @@ This command will create the necessary infrastructure and install Kubernetes.`,
 func init() {
 	rootCmd.AddCommand(initCmd)
 
-	initCmd.Flags().StringVar(&providerType, "provider", "aws", "Cloud provider (aws, vsphere, hetzner)")
-	initCmd.Flags().StringVar(&region, "region", "us-east-1", "Cloud provider region")
+	initCmd.Flags().StringVar(&providerType, "provider", "aws", fmt.Sprintf("Cloud provider (%s)", strings.Join(provider.Names(), ", ")))
+	initCmd.Flags().StringVar(&region, "region", "", "Cloud provider region or location (defaults depend on the provider)")
 	initCmd.Flags().StringVar(&clusterName, "name", "", "Cluster name")
 	initCmd.Flags().IntVar(&nodes, "nodes", 3, "Number of worker nodes")
-	initCmd.Flags().BoolVar(&generateCfg, "generate-config", false, "Generate a sample config file")
+	initCmd.Flags().BoolVar(&generateCfg, "generate-config", false, "Print a sample config file for --provider")
+	initCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Answer questions to create a cluster config file")
+	initCmd.Flags().StringVarP(&initOutput, "output", "o", "cluster.yaml", "File written by --interactive")
+}
+
+// configTemplates holds the sample config printed by --generate-config for each provider
+var configTemplates = map[string]string{
+	"aws": `name: production
+provider:
+  type: aws
+  aws:
+    region: us-east-1
+    vpc:
+      cidr: 10.0.0.0/16
+
+kubernetes:
+  version: "1.30"
+  distribution: rke2
+
+nodes:
+  controlPlane:
+    count: 3
+    instanceType: t3.medium
+  workers:
+    count: 3
+    instanceType: t3.large
+
+gitops:
+  enabled: true
+  repository: github.com/user/cluster-gitops
+  branch: main
+
+components:
+  traefik:
+    enabled: true
+    version: "26.x"
+  vault:
+    enabled: true
+    mode: external  # or "deploy"
+    address: https://vault.example.com
+  externalSecrets:
+    enabled: true
+`,
+	"hetzner": `name: my-hetzner-cluster
+provider:
+  type: hetzner
+  hetzner:
+    location: nbg1    # fsn1, nbg1, hel1, ash, hil
+    network:
+      cidr: 10.0.0.0/16
+    # osImage: ubuntu-22.04
+
+kubernetes:
+  version: "1.30"
+  distribution: rke2
+
+nodes:
+  controlPlane:
+    count: 1
+    instanceType: cpx22    # 2 vCPU AMD, 4 GB RAM
+  workers:
+    count: 2
+    instanceType: cpx32    # 4 vCPU AMD, 8 GB RAM
+
+components:
+  traefik:
+    enabled: true      # also creates a Hetzner load balancer for ingress
+    version: "26.x"
+  hcloudCCM:
+    enabled: true      # LoadBalancer services; add it with: gitops infra
+  hcloudCSI:
+    enabled: true      # PersistentVolumes on Hetzner Cloud volumes
+
+# Requires HCLOUD_TOKEN in the environment
+`,
+	"proxmox": `name: my-proxmox-cluster
+provider:
+  type: proxmox
+  proxmox:
+    node: pve                 # Proxmox node hostname
+    bridge: vmbr0
+    datastore: local-lvm
+    vip: 192.168.1.200        # Free IP on your network for kube-vip
+    # vlanTag: 100
+
+kubernetes:
+  version: "1.30"
+  distribution: rke2
+
+nodes:
+  controlPlane:
+    count: 1
+    cpu: 4
+    memoryMB: 8192
+    diskGB: 50
+  workers:
+    count: 2
+    cpu: 4
+    memoryMB: 8192
+    diskGB: 100
+
+# Requires PROXMOX_VE_ENDPOINT and PROXMOX_VE_API_TOKEN in the environment
+`,
 }
 
 func generateConfig(cmd *cobra.Command) error {
+	template, ok := configTemplates[providerType]
+	if !ok {
+		return fmt.Errorf("no config template for provider %q (available: aws, hetzner, proxmox)", providerType)
+	}
+
 	fmt.Println("# Example cluster configuration")
 	fmt.Println("# Save this to cluster.yaml and customize as needed")
 	fmt.Println("")
-	fmt.Println("name: production")
-	fmt.Println("provider:")
-	fmt.Println("  type: aws")
-	fmt.Println("  region: us-east-1")
-	fmt.Println("  vpc:")
-	fmt.Println("    cidr: 10.0.0.0/16")
-	fmt.Println("")
-	fmt.Println("kubernetes:")
-	fmt.Println("  version: \"1.30\"")
-	fmt.Println("  distribution: rke2")
-	fmt.Println("")
-	fmt.Println("nodes:")
-	fmt.Println("  controlPlane:")
-	fmt.Println("    count: 3")
-	fmt.Println("    instanceType: t3.medium")
-	fmt.Println("  workers:")
-	fmt.Println("    count: 3")
-	fmt.Println("    instanceType: t3.large")
-	fmt.Println("")
-	fmt.Println("gitops:")
-	fmt.Println("  enabled: true")
-	fmt.Println("  repository: github.com/user/cluster-gitops")
-	fmt.Println("  branch: main")
-	fmt.Println("")
-	fmt.Println("components:")
-	fmt.Println("  traefik:")
-	fmt.Println("    enabled: true")
-	fmt.Println("    version: \"26.x\"")
-	fmt.Println("  vault:")
-	fmt.Println("    enabled: true")
-	fmt.Println("    mode: external  # or \"deploy\"")
-	fmt.Println("    address: https://vault.example.com")
-	fmt.Println("  externalSecrets:")
-	fmt.Println("    enabled: true")
+	fmt.Printf("apiVersion: %s\n", config.APIVersion)
+	fmt.Printf("kind: %s\n", config.Kind)
+	fmt.Print(template)
 
 	return nil
 }
@@ -94,13 +178,16 @@ func initCluster(cmd *cobra.Command) error {
 	// Load configuration from file or flags
 	if cfgFile != "" {
 		// Load from config file
-		cfg, err = config.LoadConfig(cfgFile)
+		cfg, err = config.LoadFromFile(cfgFile, overlayFiles...)
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
 		if verbose {
 			fmt.Printf("✓ Loaded configuration from %s\n", cfgFile)
+			for _, overlay := range overlayFiles {
+				fmt.Printf("✓ Applied overlay %s\n", overlay)
+			}
 		}
 	} else {
 		// Use flags (require name when not using config file)
@@ -108,55 +195,74 @@ func initCluster(cmd *cobra.Command) error {
 			return fmt.Errorf("cluster name is required when not using a config file")
 		}
 
-		// Create config from flags (basic config)
+		// Create config from flags (basic config); provider defaults fill in the rest
 		cfg = &config.ClusterConfig{
-			Name: clusterName,
+			APIVersion: config.APIVersion,
+			Kind:       config.Kind,
+			Name:       clusterName,
 			Provider: config.ProviderConfig{
-				Type:   providerType,
-				Region: region,
-				VPC: config.VPCConfig{
-					CIDR: "10.0.0.0/16",
-				},
+				Type: providerType,
 			},
 			Kubernetes: config.KubernetesConfig{
-				Version:      "1.30",
-				Distribution: "rke2",
+				Version: "1.30",
 			},
 			Nodes: config.NodesConfig{
 				ControlPlane: config.NodeGroupConfig{
-					Count:        3,
-					InstanceType: "t3.medium",
+					Count: 3,
 				},
 				Workers: config.NodeGroupConfig{
-					Count:        nodes,
-					InstanceType: "t3.large",
+					Count: nodes,
 				},
 			},
 		}
+		switch providerType {
+		case "aws":
+			cfg.Provider.AWS.Region = region
+		case "hetzner":
+			cfg.Provider.Hetzner.Location = region
+		default:
+			if config.IsPluginType(providerType) && region != "" {
+				cfg.Provider.Settings = map[string]interface{}{"region": region}
+			}
+		}
+		config.ApplyDefaults(cfg)
+
+		// Validate configuration
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
 	}
 
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
-		return fmt.Errorf("invalid configuration: %w", err)
+	// Get the appropriate provider
+	p, err := getProvider(cfg.Provider.Type)
+	if err != nil {
+		return err
 	}
 
 	fmt.Printf("\n🚀 Initializing cluster '%s'\n", cfg.Name)
 	fmt.Printf("   Provider: %s\n", cfg.Provider.Type)
-	fmt.Printf("   Region: %s\n", cfg.Provider.Region)
+	// Show the account up front, so deploying with the wrong credentials is noticed
+	if reporter, ok := p.(provider.AccountReporter); ok {
+		account, err := reporter.Account(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to resolve the %s account: %w", cfg.Provider.Type, err)
+		}
+		fmt.Printf("   Account: %s\n", account)
+	}
+	fmt.Printf("   Location: %s\n", cfg.Provider.DisplayLocation())
 	fmt.Printf("   Control Plane: %d nodes\n", cfg.Nodes.ControlPlane.Count)
-	fmt.Printf("   Workers: %d nodes\n\n", cfg.Nodes.Workers.Count)
+	if scaling := cfg.Nodes.Workers.Autoscaling; scaling.Enabled() {
+		fmt.Printf("   Workers: %d nodes (autoscaling %d-%d)\n\n", cfg.Nodes.Workers.Count, scaling.Min, scaling.Max)
+	} else {
+		fmt.Printf("   Workers: %d nodes\n\n", cfg.Nodes.Workers.Count)
+	}
 
-	// Get the appropriate provider
-	var p provider.Provider
-	switch cfg.Provider.Type {
-	case "aws":
-		p = provider.NewAWSProvider()
-	case "vsphere":
-		return fmt.Errorf("vSphere provider not yet implemented")
-	case "hetzner":
-		p = provider.NewHetznerProvider()
-	default:
-		return fmt.Errorf("unsupported provider: %s", cfg.Provider.Type)
+	reg, _ := provider.Lookup(cfg.Provider.Type)
+	if cfg.Nodes.Workers.Spot && !reg.Capabilities.SpotInstances {
+		return fmt.Errorf("provider %q does not support spot instances (nodes.workers.spot)", cfg.Provider.Type)
+	}
+	if cfg.Nodes.Workers.Autoscaling.Enabled() && !reg.Capabilities.Autoscaling {
+		return fmt.Errorf("provider %q does not support autoscaling (nodes.workers.autoscaling)", cfg.Provider.Type)
 	}
 
 	// Validate provider configuration
@@ -164,17 +270,25 @@ func initCluster(cmd *cobra.Command) error {
 		return fmt.Errorf("provider validation failed: %w", err)
 	}
 
-	// Create infrastructure
-	if err := p.CreateInfrastructure(cfg); err != nil {
-		return fmt.Errorf("infrastructure creation failed: %w", err)
-	}
+	return createCluster(p, cfg)
+}
+
+// createCluster creates the infrastructure and persists the cluster config for
+// subsequent commands (kubeconfig, status, exec, destroy, etc.). The config is
+// saved even when creation fails: a step after tofu apply may fail with the
+// machines already running, and those commands are how they are reached or
+// destroyed.
+func createCluster(p provider.Provider, cfg *config.ClusterConfig) error {
+	createErr := p.CreateInfrastructure(cfg)
 
-	// Persist cluster config for subsequent commands (kubeconfig, status, validate, etc.)
 	if err := saveClusterConfig(cfg); err != nil {
 		fmt.Printf("Warning: failed to save cluster config: %v\n", err)
 		fmt.Println("You may need to pass --config to subsequent commands.")
 	}
 
+	if createErr != nil {
+		return fmt.Errorf("infrastructure creation failed: %w", createErr)
+	}
 	return nil
 }
 
@@ -189,10 +303,5 @@ func saveClusterConfig(cfg *config.ClusterConfig) error {
 		return err
 	}
 
-	data, err := yaml.Marshal(cfg)
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile(filepath.Join(clusterDir, "cluster.yaml"), data, 0644)
+	return config.SaveToFile(cfg, filepath.Join(clusterDir, "cluster.yaml"))
 }
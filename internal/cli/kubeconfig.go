@@ -1,12 +1,18 @@
 package cli
 
 import (
+	"encoding/base64"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/user/tdls-easy-k8s/internal/config"
+	"github.com/user/tdls-easy-k8s/internal/kubeconfig"
+	"github.com/user/tdls-easy-k8s/internal/provider"
 )
 
 var (
@@ -14,6 +20,8 @@ var (
 	kubeconfigOutput      string
 	kubeconfigMerge       bool
 	kubeconfigSetContext  bool
+	kubeconfigRemove      bool
+	kubeconfigOIDC        bool
 )
 
 // kubeconfigCmd represents the kubeconfig command
@@ -24,6 +32,9 @@ var kubeconfigCmd = &cobra.Command{
 
 By default, the kubeconfig is saved to ./kubeconfig with the correct API endpoint.
 You can merge it into your kubectl config or set it as the current context.
+Merged entries are named tdls-<cluster> and replace those of an earlier merge.
+The kubectl config ($KUBECONFIG's first file, or ~/.kube/config) is backed up
+to a timestamped file before every change; the newest 5 backups are kept.
 
 Examples:
   # Download to ./kubeconfig
@@ -33,7 +44,16 @@ Examples:
   tdls-easy-k8s kubeconfig --cluster=production --output=~/.kube/production-config
 
   # Merge into ~/.kube/config and set as current context
-  tdls-easy-k8s kubeconfig --cluster=production --merge --set-context`,
+  tdls-easy-k8s kubeconfig --cluster=production --merge --set-context
+
+  # Remove the cluster's context, cluster and user from ~/.kube/config
+  tdls-easy-k8s kubeconfig --cluster=production --remove
+
+  # Log in through the cluster's OIDC provider (needs the kubelogin plugin)
+  tdls-easy-k8s kubeconfig --cluster=production --oidc --merge
+
+For a private AWS cluster the kubeconfig points at a local port, and the command
+prints the SSM port-forwarding command that opens the tunnel to the API server.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return getKubeconfig(cmd)
 	},
@@ -47,9 +67,29 @@ func init() {
 	kubeconfigCmd.Flags().StringVarP(&kubeconfigOutput, "output", "o", "./kubeconfig", "Output file path")
 	kubeconfigCmd.Flags().BoolVar(&kubeconfigMerge, "merge", false, "Merge into ~/.kube/config")
 	kubeconfigCmd.Flags().BoolVar(&kubeconfigSetContext, "set-context", false, "Set as current kubectl context (requires --merge)")
+	kubeconfigCmd.Flags().BoolVar(&kubeconfigRemove, "remove", false, "Remove the cluster's entries from ~/.kube/config")
+	kubeconfigCmd.Flags().BoolVar(&kubeconfigOIDC, "oidc", false, "Log in through the auth.oidc identity provider instead of using the admin credentials")
 }
 
 func getKubeconfig(cmd *cobra.Command) error {
+	if kubeconfigSetContext && !kubeconfigMerge {
+		return fmt.Errorf("--set-context requires --merge")
+	}
+
+	if kubeconfigRemove {
+		if kubeconfigMerge {
+			return fmt.Errorf("--remove cannot be combined with --merge")
+		}
+		removed, err := removeKubeconfig(kubeconfigClusterName)
+		if err != nil {
+			return fmt.Errorf("failed to remove kubeconfig entries: %w", err)
+		}
+		if !removed {
+			fmt.Printf("No kubeconfig entries found for cluster %s\n", kubeconfigClusterName)
+		}
+		return nil
+	}
+
 	fmt.Printf("Downloading kubeconfig for cluster: %s\n", kubeconfigClusterName)
 
 	// Load cluster config
@@ -70,17 +110,97 @@ func getKubeconfig(cmd *cobra.Command) error {
 		return fmt.Errorf("failed to get kubeconfig: %w", err)
 	}
 
+	// Replace the admin credentials with an OIDC login
+	if kubeconfigOIDC {
+		if kubeconfigPath, err = writeOIDCKubeconfig(cfg, kubeconfigPath); err != nil {
+			return err
+		}
+	}
+
 	// Handle merge vs save to file
 	if kubeconfigMerge {
-		return mergeKubeconfig(kubeconfigPath, cfg.Name, kubeconfigSetContext)
+		err = mergeKubeconfig(kubeconfigPath, cfg.Name, kubeconfigSetContext)
+	} else {
+		err = saveKubeconfig(kubeconfigPath, kubeconfigOutput, cfg.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if tunneler, ok := p.(provider.APITunneler); ok {
+		return printTunnelHint(os.Stdout, tunneler, cfg)
+	}
+	return nil
+}
+
+// printTunnelHint tells how to open the tunnel a private cluster's kubeconfig
+// points at
+func printTunnelHint(out io.Writer, tunneler provider.APITunneler, cfg *config.ClusterConfig) error {
+	command, err := tunneler.TunnelCommand(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to get the API tunnel command: %w", err)
+	}
+	if command == nil {
+		return nil
+	}
+
+	fmt.Fprintf(out, "🔒 The API server of %s is private. Keep this tunnel open while using kubectl:\n", cfg.Name)
+	fmt.Fprintf(out, "  %s\n", strings.Join(command, " "))
+	fmt.Fprintln(out)
+	return nil
+}
+
+// writeOIDCKubeconfig writes a kubeconfig next to the admin kubeconfig that has the
+// same server and CA but gets its token from the kubelogin exec plugin
+func writeOIDCKubeconfig(cfg *config.ClusterConfig, adminPath string) (string, error) {
+	oidc := cfg.Auth.OIDC
+	if !oidc.Enabled() {
+		return "", fmt.Errorf("--oidc requires auth.oidc in the cluster config")
+	}
+
+	admin, err := kubeconfig.Load(adminPath)
+	if err != nil {
+		return "", err
+	}
+	_, cluster, _, err := admin.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to read admin kubeconfig: %w", err)
 	}
 
-	return saveKubeconfig(kubeconfigPath, kubeconfigOutput, cfg.Name)
+	args := []string{
+		"oidc-login",
+		"get-token",
+		"--oidc-issuer-url=" + oidc.IssuerURL,
+		"--oidc-client-id=" + oidc.ClientID,
+	}
+	for _, scope := range oidc.ExtraScopes {
+		args = append(args, "--oidc-extra-scope="+scope)
+	}
+	if oidc.CA != "" {
+		args = append(args, "--certificate-authority-data="+base64.StdEncoding.EncodeToString([]byte(oidc.CA)))
+	}
+
+	oidcConfig := kubeconfig.New(kubeconfig.EntryName(cfg.Name), cluster.Cluster, map[string]interface{}{
+		"exec": map[string]interface{}{
+			"apiVersion":      "client.authentication.k8s.io/v1beta1",
+			"command":         "kubectl",
+			"args":            args,
+			"interactiveMode": "IfAvailable",
+		},
+	})
+
+	path := filepath.Join(filepath.Dir(adminPath), "kubeconfig-oidc")
+	if err := oidcConfig.Save(path); err != nil {
+		return "", err
+	}
+
+	fmt.Println("Using OIDC login; kubectl needs the kubelogin plugin (kubectl krew install oidc-login)")
+	return path, nil
 }
 
 func saveKubeconfig(sourcePath, outputPath, clusterName string) error {
 	// Expand home directory
-	if outputPath[:2] == "~/" {
+	if strings.HasPrefix(outputPath, "~/") {
 		home, err := os.UserHomeDir()
 		if err != nil {
 			return err
@@ -113,46 +233,39 @@ func saveKubeconfig(sourcePath, outputPath, clusterName string) error {
 }
 
 func mergeKubeconfig(sourcePath, clusterName string, setContext bool) error {
-	home, err := os.UserHomeDir()
+	kubeConfigPath, err := kubeconfig.DefaultPath()
 	if err != nil {
 		return err
 	}
 
-	kubeDir := filepath.Join(home, ".kube")
-	kubeConfigPath := filepath.Join(kubeDir, "config")
-
-	// Ensure .kube directory exists
-	if err := os.MkdirAll(kubeDir, 0755); err != nil {
-		return fmt.Errorf("failed to create .kube directory: %w", err)
+	source, err := kubeconfig.Load(sourcePath)
+	if err != nil {
+		return err
 	}
-
-	// Backup existing config if it exists
-	if _, err := os.Stat(kubeConfigPath); err == nil {
-		backupPath := kubeConfigPath + ".backup"
-		fmt.Printf("Backing up existing config to: %s\n", backupPath)
-		if err := copyFile(kubeConfigPath, backupPath); err != nil {
-			return fmt.Errorf("failed to backup config: %w", err)
-		}
+	if err := source.Flatten(filepath.Dir(sourcePath)); err != nil {
+		return fmt.Errorf("failed to read kubeconfig: %w", err)
 	}
 
-	// Use kubectl to merge the configs
-	contextName := fmt.Sprintf("tdls-%s", clusterName)
-
-	// Set KUBECONFIG to include both files
-	mergeCmd := fmt.Sprintf("KUBECONFIG=%s:%s kubectl config view --flatten > %s.tmp && mv %s.tmp %s",
-		kubeConfigPath, sourcePath, kubeConfigPath, kubeConfigPath, kubeConfigPath)
+	merged, err := kubeconfig.Load(kubeConfigPath)
+	if err != nil {
+		return err
+	}
 
+	// Clusters, users and contexts are all named after the cluster, replacing
+	// the entries of an earlier merge
+	contextName := kubeconfig.EntryName(clusterName)
 	fmt.Println("Merging kubeconfig...")
-	if err := runShellCommand(mergeCmd); err != nil {
+	if err := merged.Merge(source, contextName); err != nil {
 		return fmt.Errorf("failed to merge kubeconfig: %w", err)
 	}
+	if setContext {
+		if err := merged.UseContext(contextName); err != nil {
+			return fmt.Errorf("failed to set context: %w", err)
+		}
+	}
 
-	// Rename context to something meaningful
-	renameCmd := fmt.Sprintf("kubectl config rename-context $(kubectl config current-context --kubeconfig=%s) %s",
-		sourcePath, contextName)
-	if err := runShellCommand(renameCmd); err != nil {
-		// Context might already have the right name, not critical
-		fmt.Printf("Note: Could not rename context: %v\n", err)
+	if err := writeKubeconfig(merged, kubeConfigPath); err != nil {
+		return err
 	}
 
 	fmt.Println()
@@ -160,15 +273,9 @@ func mergeKubeconfig(sourcePath, clusterName string, setContext bool) error {
 	fmt.Printf("Context name: %s\n", contextName)
 	fmt.Println()
 
-	// Set context if requested
 	if setContext {
-		fmt.Printf("Setting current context to: %s\n", contextName)
-		setContextCmd := fmt.Sprintf("kubectl config use-context %s", contextName)
-		if err := runShellCommand(setContextCmd); err != nil {
-			return fmt.Errorf("failed to set context: %w", err)
-		}
-		fmt.Println()
-		fmt.Println("✅ Context set! You can now use kubectl:")
+		fmt.Printf("✅ Current context set to: %s\n", contextName)
+		fmt.Println("You can now use kubectl:")
 		fmt.Println("  kubectl get nodes")
 	} else {
 		fmt.Println("To use this cluster, run:")
@@ -179,18 +286,45 @@ func mergeKubeconfig(sourcePath, clusterName string, setContext bool) error {
 	return nil
 }
 
-func copyFile(src, dst string) error {
-	data, err := os.ReadFile(src)
+// removeKubeconfig deletes a cluster's entries from the kubectl config. It reports
+// whether any entries were found.
+func removeKubeconfig(clusterName string) (bool, error) {
+	kubeConfigPath, err := kubeconfig.DefaultPath()
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	cfg, err := kubeconfig.Load(kubeConfigPath)
+	if err != nil {
+		return false, err
+	}
+
+	contextName := kubeconfig.EntryName(clusterName)
+	wasCurrent := cfg.CurrentContext == contextName
+	if !cfg.Remove(contextName) {
+		return false, nil
+	}
+
+	if err := writeKubeconfig(cfg, kubeConfigPath); err != nil {
+		return false, err
+	}
+
+	fmt.Printf("✓ Removed context %s from %s\n", contextName, kubeConfigPath)
+	if wasCurrent {
+		fmt.Println("  The current context was unset; choose another with: kubectl config use-context <name>")
 	}
-	return os.WriteFile(dst, data, 0600)
+	return true, nil
 }
 
-func runShellCommand(cmd string) error {
-	// Use bash to execute the command
-	shellCmd := exec.Command("bash", "-c", cmd)
-	shellCmd.Stdout = os.Stdout
-	shellCmd.Stderr = os.Stderr
-	return shellCmd.Run()
+// writeKubeconfig backs up the existing kubectl config and writes the new one
+func writeKubeconfig(cfg *kubeconfig.Config, path string) error {
+	backupPath, err := kubeconfig.Backup(path, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to backup config: %w", err)
+	}
+	if backupPath != "" {
+		fmt.Printf("Backed up existing config to: %s\n", backupPath)
+	}
+
+	return cfg.Save(path)
 }
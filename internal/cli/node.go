@@ -0,0 +1,256 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/user/tdls-easy-k8s/internal/config"
+	"github.com/user/tdls-easy-k8s/internal/provider"
+)
+
+var (
+	nodeClusterName   string
+	nodeDrainTimeout  time.Duration
+	nodeReadyTimeout  time.Duration
+	nodeReadyInterval = 15 * time.Second
+)
+
+// etcdCertFlags are the etcdctl client certificate flags inside RKE2 etcd pods
+var etcdCertFlags = []string{
+	"--cacert=/var/lib/rancher/rke2/server/tls/etcd/server-ca.crt",
+	"--cert=/var/lib/rancher/rke2/server/tls/etcd/server-client.crt",
+	"--key=/var/lib/rancher/rke2/server/tls/etcd/server-client.key",
+	"--endpoints=https://127.0.0.1:2379",
+}
+
+// nodeCmd represents the node command
+var nodeCmd = &cobra.Command{
+	Use:   "node",
+	Short: "Manage individual cluster nodes",
+}
+
+// nodeReplaceCmd represents the node replace command
+var nodeReplaceCmd = &cobra.Command{
+	Use:   "replace <node>",
+	Short: "Replace a node with a freshly provisioned machine",
+	Long: `Replace a single node, e.g. one whose cloud-init failed.
+
+The node is cordoned and drained, removed from Kubernetes (and from etcd for
+control plane nodes), its machine is recreated with 'tofu apply -replace', and
+the command waits until the replacement has joined and is Ready.
+
+The first control plane node bootstraps the cluster and cannot be replaced.`,
+	Example: `  tdls-easy-k8s node replace --cluster=production worker-2`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return replaceNode(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(nodeCmd)
+	nodeCmd.AddCommand(nodeReplaceCmd)
+
+	nodeCmd.PersistentFlags().StringVarP(&nodeClusterName, "cluster", "c", "", "Cluster name (required)")
+	nodeCmd.MarkPersistentFlagRequired("cluster")
+
+	nodeReplaceCmd.Flags().DurationVar(&nodeDrainTimeout, "drain-timeout", 5*time.Minute, "How long to wait for pods to be evicted")
+	nodeReplaceCmd.Flags().DurationVar(&nodeReadyTimeout, "timeout", 20*time.Minute, "How long to wait for the replacement to be Ready")
+}
+
+func replaceNode(target string) error {
+	cfg, p, accessor, err := nodeAccess(nodeClusterName)
+	if err != nil {
+		return err
+	}
+	return replaceClusterNode(cfg, p, accessor, target)
+}
+
+// replaceClusterNode replaces a node of a loaded cluster. Whether the machine can
+// be replaced is checked before anything is drained or removed.
+func replaceClusterNode(cfg *config.ClusterConfig, p provider.Provider, accessor provider.NodeAccessor, target string) error {
+	replacer, ok := p.(provider.NodeReplacer)
+	if !ok {
+		return fmt.Errorf("provider %q does not support replacing nodes", cfg.Provider.Type)
+	}
+
+	machines, err := accessor.ListNodes(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to list machines: %w", err)
+	}
+	machine, err := findNode(machines, cfg.Name, target)
+	if err != nil {
+		return err
+	}
+	if err := replacer.CanReplace(machine); err != nil {
+		return err
+	}
+
+	kubeconfigPath, err := p.GetKubeconfig(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to get kubeconfig: %w", err)
+	}
+	defer os.Remove(kubeconfigPath)
+
+	kubeNodes, err := getKubeNodes(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	rows := joinNodes(machines, kubeNodes)
+
+	fmt.Printf("Replacing %s (%s)\n", machine.Name, machine.Role)
+
+	var node *kubeNode
+	for _, row := range rows {
+		if row.Machine != nil && row.Machine.Name == machine.Name {
+			node = row.Node
+		}
+	}
+
+	if node == nil {
+		fmt.Println("Node never joined Kubernetes, nothing to drain")
+	} else {
+		fmt.Printf("\nDraining %s...\n", node.Name)
+		if _, err := runKubectl(kubeconfigPath, "", "cordon", node.Name); err != nil {
+			return err
+		}
+		if _, err := runKubectl(kubeconfigPath, "", "drain", node.Name,
+			"--ignore-daemonsets", "--delete-emptydir-data",
+			"--timeout="+nodeDrainTimeout.String()); err != nil {
+			return fmt.Errorf("failed to drain %s (it stays cordoned; uncordon it with 'kubectl uncordon %s'): %w", node.Name, node.Name, err)
+		}
+	}
+
+	if machine.Role == provider.RoleControlPlane {
+		if err := removeEtcdMember(kubeconfigPath, rows, machine, node); err != nil {
+			return err
+		}
+	}
+
+	if node != nil {
+		if _, err := runKubectl(kubeconfigPath, "", "delete", "node", node.Name); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Removed node %s from Kubernetes\n", node.Name)
+	}
+
+	if err := replacer.ReplaceNode(cfg, machine); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", machine.Name, err)
+	}
+
+	fmt.Printf("\nWaiting for the replacement of %s to be Ready (up to %s)...\n", machine.Name, nodeReadyTimeout)
+	replacement, err := waitForReplacement(cfg, accessor, kubeconfigPath, machine.Name, nodeReadyTimeout)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✅ %s replaced; node %s is Ready\n", machine.Name, replacement)
+	return nil
+}
+
+// getKubeNodes lists the cluster's Kubernetes nodes
+func getKubeNodes(kubeconfigPath string) ([]kubeNode, error) {
+	output, err := runKubectl(kubeconfigPath, "", "get", "nodes", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parseKubeNodes(output)
+}
+
+// removeEtcdMember removes a control plane machine's etcd member, running etcdctl
+// in the etcd pod of another control plane node
+func removeEtcdMember(kubeconfigPath string, rows []nodeRow, machine provider.Node, node *kubeNode) error {
+	var peer string
+	for _, row := range rows {
+		if row.Machine != nil && row.Machine.Name != machine.Name && row.Machine.Role == provider.RoleControlPlane &&
+			row.Node != nil && strings.HasPrefix(row.Node.Status, "Ready") {
+			peer = row.Node.Name
+			break
+		}
+	}
+	if peer == "" {
+		return fmt.Errorf("no other Ready control plane node to remove %s from etcd", machine.Name)
+	}
+
+	etcdctl := func(args ...string) ([]byte, error) {
+		cmd := append([]string{"-n", "kube-system", "exec", "etcd-" + peer, "--", "etcdctl"}, etcdCertFlags...)
+		return runKubectl(kubeconfigPath, "", append(cmd, args...)...)
+	}
+
+	members, err := etcdctl("member", "list", "-w", "simple")
+	if err != nil {
+		return fmt.Errorf("failed to list etcd members: %w", err)
+	}
+
+	names := []string{machine.Name}
+	ips := []string{machine.IP, machine.PrivateIP}
+	if node != nil {
+		names = append(names, node.Name)
+		ips = append(ips, node.Addresses...)
+	}
+	id, name := findEtcdMember(string(members), names, ips)
+	if id == "" {
+		fmt.Printf("No etcd member found for %s\n", machine.Name)
+		return nil
+	}
+
+	if _, err := etcdctl("member", "remove", id); err != nil {
+		return fmt.Errorf("failed to remove etcd member %s: %w", name, err)
+	}
+	fmt.Printf("✓ Removed etcd member %s\n", name)
+	return nil
+}
+
+// findEtcdMember finds a member in `etcdctl member list -w simple` output by node
+// name (RKE2 names members <node>-<suffix>) or by peer IP address, and returns
+// its ID and name
+func findEtcdMember(list string, names, ips []string) (string, string) {
+	for _, line := range strings.Split(list, "\n") {
+		fields := strings.Split(line, ", ")
+		if len(fields) < 4 {
+			continue
+		}
+		id, name, peerURLs := fields[0], fields[2], fields[3]
+
+		for _, n := range names {
+			suffix, ok := strings.CutPrefix(name, n+"-")
+			if n != "" && (name == n || (ok && !strings.Contains(suffix, "-"))) {
+				return id, name
+			}
+		}
+		for _, ip := range ips {
+			if ip != "" && strings.Contains(peerURLs, "//"+ip+":") {
+				return id, name
+			}
+		}
+	}
+	return "", ""
+}
+
+// waitForReplacement waits until the machine named machineName runs a Ready
+// Kubernetes node and returns the node name
+func waitForReplacement(cfg *config.ClusterConfig, accessor provider.NodeAccessor, kubeconfigPath, machineName string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		// The new machine may have a new address, so machines are listed again
+		machines, err := accessor.ListNodes(cfg)
+		if err == nil {
+			var kubeNodes []kubeNode
+			if kubeNodes, err = getKubeNodes(kubeconfigPath); err == nil {
+				for _, row := range joinNodes(machines, kubeNodes) {
+					if row.Machine != nil && row.Machine.Name == machineName && row.Node != nil && row.Node.Status == "Ready" {
+						return row.Node.Name, nil
+					}
+				}
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("timed out waiting for the replacement of %s to be Ready; check it with 'tdls-easy-k8s nodes --cluster=%s'", machineName, cfg.Name)
+		}
+		time.Sleep(nodeReadyInterval)
+	}
+}
@@ -0,0 +1,269 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/user/tdls-easy-k8s/internal/provider"
+)
+
+var nodesClusterName string
+
+// nodesCmd represents the nodes command
+var nodesCmd = &cobra.Command{
+	Use:   "nodes",
+	Short: "List machines and Kubernetes nodes side by side",
+	Long: `List the cluster's machines from the infrastructure state next to the
+Kubernetes nodes they run.
+
+Machines that never joined Kubernetes, and Kubernetes nodes without a backing
+machine, are flagged. If the API server cannot be reached, only the machines
+are listed.`,
+	Example: `  tdls-easy-k8s nodes --cluster=production`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listNodes(os.Stdout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(nodesCmd)
+
+	nodesCmd.Flags().StringVarP(&nodesClusterName, "cluster", "c", "", "Cluster name (required)")
+	nodesCmd.MarkFlagRequired("cluster")
+}
+
+// kubeNode is a Kubernetes node as listed by kubectl
+type kubeNode struct {
+	Name       string
+	Status     string
+	Roles      string
+	Version    string
+	Created    time.Time
+	Addresses  []string
+	ProviderID string
+}
+
+// nodeRow joins a machine with the Kubernetes node running on it; either may be nil
+type nodeRow struct {
+	Machine *provider.Node
+	Node    *kubeNode
+}
+
+func listNodes(out io.Writer) error {
+	cfg, p, accessor, err := nodeAccess(nodesClusterName)
+	if err != nil {
+		return err
+	}
+
+	machines, err := accessor.ListNodes(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	var kubeNodes []kubeNode
+	kubeconfigPath, err := p.GetKubeconfig(cfg)
+	if err == nil {
+		defer os.Remove(kubeconfigPath)
+		var output []byte
+		if output, err = runKubectl(kubeconfigPath, "", "get", "nodes", "-o", "json"); err == nil {
+			kubeNodes, err = parseKubeNodes(output)
+		}
+	}
+	joined := err == nil
+	if !joined {
+		fmt.Fprintf(out, "⚠️  Kubernetes API unreachable, showing machines only: %v\n\n", err)
+	}
+
+	rows := joinNodes(machines, kubeNodes)
+	printNodeRows(out, rows, joined, time.Now())
+
+	var notJoined, orphaned []string
+	for _, row := range rows {
+		if row.Node == nil && joined {
+			notJoined = append(notJoined, row.Machine.Name)
+		}
+		if row.Machine == nil {
+			orphaned = append(orphaned, row.Node.Name)
+		}
+	}
+	if len(notJoined) > 0 {
+		fmt.Fprintf(out, "\n⚠️  %d machine(s) never joined Kubernetes: %s\n", len(notJoined), strings.Join(notJoined, ", "))
+		fmt.Fprintf(out, "   Check cloud-init with: tdls-easy-k8s ssh --cluster=%s <machine>\n", cfg.Name)
+	}
+	if len(orphaned) > 0 {
+		fmt.Fprintf(out, "\n⚠️  %d Kubernetes node(s) have no backing machine: %s\n", len(orphaned), strings.Join(orphaned, ", "))
+		fmt.Fprintln(out, "   Remove them with: kubectl delete node <name>")
+	}
+	return nil
+}
+
+// parseKubeNodes reads `kubectl get nodes -o json` output
+func parseKubeNodes(data []byte) ([]kubeNode, error) {
+	var result struct {
+		Items []struct {
+			Metadata struct {
+				Name              string            `json:"name"`
+				Labels            map[string]string `json:"labels"`
+				CreationTimestamp time.Time         `json:"creationTimestamp"`
+			} `json:"metadata"`
+			Spec struct {
+				ProviderID    string `json:"providerID"`
+				Unschedulable bool   `json:"unschedulable"`
+			} `json:"spec"`
+			Status struct {
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+				Addresses []struct {
+					Address string `json:"address"`
+				} `json:"addresses"`
+				NodeInfo struct {
+					KubeletVersion string `json:"kubeletVersion"`
+				} `json:"nodeInfo"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	var nodes []kubeNode
+	for _, item := range result.Items {
+		node := kubeNode{
+			Name:       item.Metadata.Name,
+			Status:     "Unknown",
+			Version:    item.Status.NodeInfo.KubeletVersion,
+			Created:    item.Metadata.CreationTimestamp,
+			ProviderID: item.Spec.ProviderID,
+		}
+
+		for _, condition := range item.Status.Conditions {
+			if condition.Type == "Ready" {
+				if condition.Status == "True" {
+					node.Status = "Ready"
+				} else {
+					node.Status = "NotReady"
+				}
+			}
+		}
+		if item.Spec.Unschedulable {
+			node.Status += ",SchedulingDisabled"
+		}
+
+		var roles []string
+		for label := range item.Metadata.Labels {
+			if role, ok := strings.CutPrefix(label, "node-role.kubernetes.io/"); ok && role != "" {
+				roles = append(roles, role)
+			}
+		}
+		sort.Strings(roles)
+		node.Roles = strings.Join(roles, ",")
+		if node.Roles == "" {
+			node.Roles = "<none>"
+		}
+
+		for _, address := range item.Status.Addresses {
+			node.Addresses = append(node.Addresses, address.Address)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// joinNodes pairs machines with Kubernetes nodes by name, IP address or instance
+// ID. Machines come first in their own order, followed by unmatched nodes.
+func joinNodes(machines []provider.Node, kubeNodes []kubeNode) []nodeRow {
+	matched := make([]bool, len(kubeNodes))
+	var rows []nodeRow
+
+	for i := range machines {
+		machine := &machines[i]
+		row := nodeRow{Machine: machine}
+		for j := range kubeNodes {
+			if !matched[j] && machineRunsNode(machine, &kubeNodes[j]) {
+				matched[j] = true
+				row.Node = &kubeNodes[j]
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	for j := range kubeNodes {
+		if !matched[j] {
+			rows = append(rows, nodeRow{Node: &kubeNodes[j]})
+		}
+	}
+	return rows
+}
+
+// machineRunsNode reports whether a Kubernetes node runs on a machine
+func machineRunsNode(machine *provider.Node, node *kubeNode) bool {
+	if node.Name == machine.Name {
+		return true
+	}
+	if machine.InstanceID != "" && strings.HasSuffix(node.ProviderID, "/"+machine.InstanceID) {
+		return true
+	}
+	for _, address := range node.Addresses {
+		if address != "" && (address == machine.IP || address == machine.PrivateIP) {
+			return true
+		}
+	}
+	return false
+}
+
+// printNodeRows writes rows as a table. Machines without a node are shown as
+// NotJoined when the node list is known (joined is true).
+func printNodeRows(out io.Writer, rows []nodeRow, joined bool, now time.Time) {
+	dash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MACHINE\tPOOL\tINSTANCE\tIP\tPRIVATE IP\tNODE\tSTATUS\tROLES\tVERSION\tAGE")
+	for _, row := range rows {
+		var machine provider.Node
+		if row.Machine != nil {
+			machine = *row.Machine
+		}
+		var node kubeNode
+		var age string
+		if row.Node != nil {
+			node = *row.Node
+			age = shortAge(now.Sub(node.Created))
+		} else if joined {
+			node.Status = "NotJoined"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			dash(machine.Name), dash(machine.Role), dash(machine.InstanceID), dash(machine.IP), dash(machine.PrivateIP),
+			dash(node.Name), dash(node.Status), dash(node.Roles), dash(node.Version), dash(age))
+	}
+	w.Flush()
+}
+
+// shortAge formats a duration the way kubectl shows ages
+func shortAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/user/tdls-easy-k8s/internal/provider"
+)
+
+// providersCmd represents the providers command
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "List available infrastructure providers and their capabilities",
+	Long: `List the registered infrastructure providers and the optional features each supports:
+
+  ingress-lb      a cloud load balancer in front of the ingress controller
+  vip             a virtual IP (kube-vip) for the API server
+  spot            workers on spot/preemptible instances
+  ssm             node access through AWS Systems Manager
+  object-storage  cluster state and kubeconfig kept in a bucket
+  autoscaling     the cluster autoscaler resizes the workers (nodes.workers.autoscaling)
+
+Besides the built-in providers, executables named tdls-easy-k8s-provider-<name> in
+~/.tdls-k8s/plugins or on the PATH are loaded as plugin providers.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listProviders(cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(providersCmd)
+}
+
+func listProviders(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDESCRIPTION\tCAPABILITIES\tSOURCE")
+	for _, name := range provider.Names() {
+		if err := provider.LoadPlugin(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			continue
+		}
+		reg, _ := provider.Lookup(name)
+		source := "built-in"
+		if reg.Plugin != "" {
+			source = reg.Plugin
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", reg.Name, reg.Description, reg.Capabilities, source)
+	}
+	return w.Flush()
+}
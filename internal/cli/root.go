@@ -6,11 +6,13 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/user/tdls-easy-k8s/internal/provider"
 )
 
 var (
-	cfgFile string
-	verbose bool
+	cfgFile      string
+	overlayFiles []string
+	verbose      bool
 )
 
 // rootCmd represents the base command
@@ -26,14 +28,17 @@ like Traefik for ingress and integration with Vault for secrets management.`,
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
+	// Tunnels to private API servers only live as long as the command
+	defer provider.CloseTunnels()
 	return rootCmd.Execute()
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(initConfig, loadPlugins)
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./cluster.yaml)")
+	rootCmd.PersistentFlags().StringSliceVar(&overlayFiles, "overlay", nil, "overlay config file merged on top of the base config (repeatable, applied in order)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 }
 
@@ -58,3 +63,11 @@ func initConfig() {
 		}
 	}
 }
+
+// loadPlugins finds the provider plugins installed in ~/.tdls-k8s/plugins or on the
+// PATH. A plugin only runs when a command uses its provider.
+func loadPlugins() {
+	for _, warning := range provider.LoadPlugins(provider.PluginDirs()) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+}
@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/user/tdls-easy-k8s/internal/config"
+	"github.com/user/tdls-easy-k8s/internal/provider"
+)
+
+var (
+	sshClusterName  string
+	execClusterName string
+	execNodes       string
+)
+
+// sshCmd represents the ssh command
+var sshCmd = &cobra.Command{
+	Use:   "ssh <node>",
+	Short: "Open a shell on a cluster node",
+	Long: `Open an interactive shell on a cluster node.
+
+The node can be given by machine name (with or without the cluster name prefix),
+IP address or instance ID. Hetzner and Proxmox nodes are reached over SSH with
+the cluster's key; AWS nodes through SSM Session Manager, which needs the
+Session Manager plugin for the AWS CLI.`,
+	Example: `  # Open a shell on the first control plane node
+  tdls-easy-k8s ssh --cluster=production cp-0
+
+  # Or by IP address
+  tdls-easy-k8s ssh --cluster=production 203.0.113.10`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSSH(args[0])
+	},
+}
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec -- <command>",
+	Short: "Run a command on cluster nodes",
+	Long: `Run a shell command on several cluster nodes in parallel.
+
+Each node's output is printed when it finishes, with every line prefixed by the
+node name. The command fails if it fails on any node.
+
+The arguments are passed to the node as they are given; use sh -c for pipes and
+other shell syntax.`,
+	Example: `  # Check RKE2 on every node
+  tdls-easy-k8s exec --cluster=production -- systemctl is-active rke2-server rke2-agent
+
+  # Show disk usage on the workers
+  tdls-easy-k8s exec --cluster=production --nodes=workers -- df -h /
+
+  # Use a shell for pipes
+  tdls-easy-k8s exec --cluster=production -- sh -c 'journalctl -u rke2-agent | tail -5'`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExec(shellJoin(args))
+	},
+}
+
+// shellJoin builds a command line that gives the node's shell back exactly args.
+// Arguments with characters the shell would interpret are single-quoted.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes arg for a POSIX shell unless it consists only of safe characters
+func shellQuote(arg string) string {
+	safe := arg != ""
+	for _, r := range arg {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./_-", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func init() {
+	rootCmd.AddCommand(sshCmd)
+	rootCmd.AddCommand(execCmd)
+
+	sshCmd.Flags().StringVarP(&sshClusterName, "cluster", "c", "", "Cluster name (required)")
+	sshCmd.MarkFlagRequired("cluster")
+
+	execCmd.Flags().StringVarP(&execClusterName, "cluster", "c", "", "Cluster name (required)")
+	execCmd.MarkFlagRequired("cluster")
+	execCmd.Flags().StringVar(&execNodes, "nodes", "all", "Nodes to run on: cp, workers or all")
+}
+
+// nodeAccess loads a cluster and returns its provider and the provider's node access
+func nodeAccess(clusterName string) (*config.ClusterConfig, provider.Provider, provider.NodeAccessor, error) {
+	cfg, err := loadClusterConfig(clusterName)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load cluster config: %w", err)
+	}
+
+	p, err := getProvider(cfg.Provider.Type)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	accessor, ok := p.(provider.NodeAccessor)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("provider %q does not support node access", cfg.Provider.Type)
+	}
+	// Providers with the SSM capability reach their nodes through Session Manager only
+	if reg, _ := provider.Lookup(cfg.Provider.Type); reg.Capabilities.SSM {
+		if aws := cfg.Provider.AWS; aws.SessionManager != nil && !*aws.SessionManager {
+			return nil, nil, nil, fmt.Errorf("cluster %s has no node access: nodes are reached through SSM Session Manager, which provider.aws.sessionManager disables", cfg.Name)
+		}
+	}
+	return cfg, p, accessor, nil
+}
+
+func runSSH(target string) error {
+	cfg, _, accessor, err := nodeAccess(sshClusterName)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := accessor.ListNodes(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	node, err := findNode(nodes, cfg.Name, target)
+	if err != nil {
+		return err
+	}
+
+	return accessor.Shell(cfg, node)
+}
+
+func runExec(command string) error {
+	cfg, _, accessor, err := nodeAccess(execClusterName)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := accessor.ListNodes(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	selected, err := selectNodes(nodes, execNodes)
+	if err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no %s nodes found in cluster %s", execNodes, cfg.Name)
+	}
+
+	run, err := accessor.CommandRunner(cfg)
+	if err != nil {
+		return err
+	}
+
+	if failed := runOnNodes(selected, run, command, os.Stdout); failed > 0 {
+		return fmt.Errorf("command failed on %d of %d nodes", failed, len(selected))
+	}
+	return nil
+}
+
+// findNode looks a node up by name, name without the "<cluster>-" prefix, IP
+// address or instance ID
+func findNode(nodes []provider.Node, clusterName, target string) (provider.Node, error) {
+	var names []string
+	for _, node := range nodes {
+		if target == node.Name || clusterName+"-"+target == node.Name ||
+			(target == node.IP && node.IP != "") || (target == node.PrivateIP && node.PrivateIP != "") ||
+			(target == node.InstanceID && node.InstanceID != "") {
+			return node, nil
+		}
+		names = append(names, node.Name)
+	}
+	return provider.Node{}, fmt.Errorf("node %q not found (nodes: %s)", target, strings.Join(names, ", "))
+}
+
+// selectNodes filters nodes by the --nodes value
+func selectNodes(nodes []provider.Node, which string) ([]provider.Node, error) {
+	var role string
+	switch which {
+	case "all":
+		return nodes, nil
+	case "cp", "control-plane":
+		role = provider.RoleControlPlane
+	case "workers", "worker":
+		role = provider.RoleWorker
+	default:
+		return nil, fmt.Errorf("invalid --nodes value %q: must be cp, workers or all", which)
+	}
+
+	var selected []provider.Node
+	for _, node := range nodes {
+		if node.Role == role {
+			selected = append(selected, node)
+		}
+	}
+	return selected, nil
+}
+
+// runOnNodes runs command on all nodes at once and writes each node's output to
+// out as it finishes, with lines prefixed by the node name. It returns the number
+// of nodes where the command failed.
+func runOnNodes(nodes []provider.Node, run provider.CommandRunner, command string, out io.Writer) int {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed int
+	)
+
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(node provider.Node) {
+			defer wg.Done()
+			output, err := run(node, command)
+
+			var buf bytes.Buffer
+			prefix := fmt.Sprintf("[%s] ", node.Name)
+			scanner := bufio.NewScanner(bytes.NewReader(output))
+			for scanner.Scan() {
+				buf.WriteString(prefix + scanner.Text() + "\n")
+			}
+			if err != nil {
+				buf.WriteString(prefix + "❌ " + err.Error() + "\n")
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+			}
+			out.Write(buf.Bytes())
+		}(node)
+	}
+
+	wg.Wait()
+	return failed
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -62,10 +63,8 @@ func showStatus(cmd *cobra.Command) error {
 func displayStatus(p provider.Provider, cfg *config.ClusterConfig) error {
 	fmt.Printf("Cluster: %s\n", cfg.Name)
 	fmt.Printf("Provider: %s\n", cfg.Provider.Type)
-	if cfg.Provider.Location != "" {
-		fmt.Printf("Location: %s\n", cfg.Provider.Location)
-	} else if cfg.Provider.Region != "" {
-		fmt.Printf("Region: %s\n", cfg.Provider.Region)
+	if location := cfg.Provider.DisplayLocation(); location != "" {
+		fmt.Printf("Location: %s\n", location)
 	}
 	fmt.Println()
 
@@ -98,6 +97,9 @@ func displayStatus(p provider.Provider, cfg *config.ClusterConfig) error {
 		}
 		fmt.Printf("  %s Workers: %d/%d ready\n", symbol, status.WorkerReady, status.WorkerTotal)
 	}
+	if scaling := cfg.Nodes.Workers.Autoscaling; scaling.Enabled() {
+		fmt.Println(formatAutoscaling(status.WorkerTotal, scaling))
+	}
 	fmt.Println()
 
 	// Display system components
@@ -148,10 +150,12 @@ func watchStatus(p provider.Provider, cfg *config.ClusterConfig) error {
 	}
 }
 
+// loadClusterConfig loads the config given with --config, or the one saved for the
+// named cluster, with overlays and defaults applied.
 func loadClusterConfig(clusterName string) (*config.ClusterConfig, error) {
 	// First try to load from config file if specified
 	if cfgFile != "" {
-		return config.LoadConfig(cfgFile)
+		return config.LoadFromFile(cfgFile, overlayFiles...)
 	}
 
 	// Try to load from cluster working directory
@@ -161,20 +165,26 @@ func loadClusterConfig(clusterName string) (*config.ClusterConfig, error) {
 	}
 
 	configPath := filepath.Join(homeDir, ".tdls-k8s", "clusters", clusterName, "cluster.yaml")
-	return config.LoadConfig(configPath)
+	return config.LoadFromFile(configPath, overlayFiles...)
 }
 
+// getProvider returns the registered provider for a cluster's provider type
 func getProvider(providerType string) (provider.Provider, error) {
-	switch providerType {
-	case "aws":
-		return provider.NewAWSProvider(), nil
-	case "vsphere":
-		return nil, fmt.Errorf("vSphere provider not yet implemented")
-	case "hetzner":
-		return provider.NewHetznerProvider(), nil
-	default:
-		return nil, fmt.Errorf("unknown provider type: %s", providerType)
+	p, err := provider.GetProvider(providerType)
+	if err != nil {
+		return nil, fmt.Errorf("unknown provider type %q (available: %s)", providerType, strings.Join(provider.Names(), ", "))
+	}
+	return p, nil
+}
+
+// formatAutoscaling shows the current worker count against the autoscaling
+// bounds. Outside them, the cluster autoscaler is not running or cannot add nodes.
+func formatAutoscaling(workers int, scaling config.AutoscalingConfig) string {
+	symbol := "✓"
+	if workers < scaling.Min || workers > scaling.Max {
+		symbol = "⚠"
 	}
+	return fmt.Sprintf("  %s Autoscaling: %d workers (min %d, max %d)", symbol, workers, scaling.Min, scaling.Max)
 }
 
 func formatDuration(d time.Duration) string {
@@ -0,0 +1,492 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/user/tdls-easy-k8s/internal/kubeconfig"
+)
+
+// Labels and annotations on the objects created for users
+const (
+	managedByLabel   = "app.kubernetes.io/managed-by=tdls-easy-k8s"
+	userLabel        = "tdls-easy-k8s/user"
+	expiryAnnotation = "tdls-easy-k8s/expires"
+)
+
+var (
+	userClusterName string
+	userName        string
+	userRole        string
+	userNamespace   string
+	userExpiry      time.Duration
+	userOutput      string
+)
+
+// certificatePollInterval is the time between checks for an issued certificate
+var certificatePollInterval = 2 * time.Second
+
+// userNamePattern matches user names that are also valid label values
+var userNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,61}[a-z0-9])?$`)
+
+// userRoles maps the --role values to ClusterRoles for cluster-wide and namespaced access
+var userRoles = map[string]struct{ cluster, namespace string }{
+	"view":  {"view", "view"},
+	"edit":  {"edit", "edit"},
+	"admin": {"cluster-admin", "admin"},
+}
+
+// runKubectl runs kubectl against a cluster; tests replace it
+var runKubectl = func(kubeconfigPath, stdin string, args ...string) ([]byte, error) {
+	cmd := exec.Command("kubectl", args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath))
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("kubectl %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// userCmd represents the user command
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage per-user cluster access",
+	Long: `Give team members their own kubeconfig instead of sharing the cluster-admin one.
+
+Each user gets a client certificate issued through the Kubernetes CSR API and a
+ClusterRoleBinding (or a RoleBinding with --namespace) to a built-in role:
+
+  view   read-only access
+  edit   read-write access to most resources, no RBAC changes
+  admin  cluster-admin, or namespace admin with --namespace
+
+Kubernetes cannot revoke client certificates. Revoking a user deletes their
+bindings, so the certificate no longer grants any access; it stays valid for
+authentication until it expires.`,
+}
+
+var userAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Issue a client certificate and kubeconfig for a user",
+	Example: `  # Read-only access to the whole cluster for 30 days
+  tdls-easy-k8s user add --cluster=production --user=alice --role=view
+
+  # Edit access to one namespace for a week
+  tdls-easy-k8s user add --cluster=production --user=bob --role=edit --namespace=shop --expiry=168h`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return addUser()
+	},
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List users and their roles",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listUsers()
+	},
+}
+
+var userRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Remove a user's role bindings",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return revokeUser()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(userCmd)
+	userCmd.AddCommand(userAddCmd, userListCmd, userRevokeCmd)
+
+	userCmd.PersistentFlags().StringVarP(&userClusterName, "cluster", "c", "", "Cluster name (required)")
+	userCmd.MarkPersistentFlagRequired("cluster")
+
+	userAddCmd.Flags().StringVar(&userName, "user", "", "User name (required)")
+	userAddCmd.MarkFlagRequired("user")
+	userAddCmd.Flags().StringVar(&userRole, "role", "view", "Role: view, edit or admin")
+	userAddCmd.Flags().StringVarP(&userNamespace, "namespace", "n", "", "Limit access to a namespace (default: cluster-wide)")
+	userAddCmd.Flags().DurationVar(&userExpiry, "expiry", 30*24*time.Hour, "Certificate lifetime")
+	userAddCmd.Flags().StringVarP(&userOutput, "output", "o", "", "Kubeconfig output path (default ./<cluster>-<user>.kubeconfig)")
+
+	userRevokeCmd.Flags().StringVar(&userName, "user", "", "User name (required)")
+	userRevokeCmd.MarkFlagRequired("user")
+}
+
+// validateUserName checks that --user is a valid label value, as it selects the
+// user's role bindings
+func validateUserName(name string) error {
+	if !userNamePattern.MatchString(name) {
+		return fmt.Errorf("user name must contain only lowercase letters, numbers, '-' and '.' (max 63 characters)")
+	}
+	return nil
+}
+
+func addUser() error {
+	if err := validateUserName(userName); err != nil {
+		return err
+	}
+	roles, ok := userRoles[userRole]
+	if !ok {
+		return fmt.Errorf("role must be 'view', 'edit' or 'admin', got %q", userRole)
+	}
+	if userExpiry < 10*time.Minute {
+		return fmt.Errorf("expiry must be at least 10m")
+	}
+
+	adminKubeconfig, err := adminKubeconfigPath(userClusterName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Issuing a client certificate for %s on cluster %s\n", userName, userClusterName)
+	key, csrPEM, err := generateUserKey(userName)
+	if err != nil {
+		return err
+	}
+
+	csrName := fmt.Sprintf("tdls-user-%s-%d", userName, time.Now().Unix())
+	if _, err := runKubectl(adminKubeconfig, generateCSRYAML(csrName, userName, csrPEM, userExpiry), "apply", "-f", "-"); err != nil {
+		return fmt.Errorf("failed to create certificate signing request: %w", err)
+	}
+	defer runKubectl(adminKubeconfig, "", "delete", "csr", csrName, "--ignore-not-found")
+
+	if _, err := runKubectl(adminKubeconfig, "", "certificate", "approve", csrName); err != nil {
+		return fmt.Errorf("failed to approve certificate signing request: %w", err)
+	}
+
+	certPEM, err := waitForCertificate(adminKubeconfig, csrName, time.Minute)
+	if err != nil {
+		return err
+	}
+	expires, err := certificateExpiry(certPEM)
+	if err != nil {
+		return err
+	}
+
+	// roleRef cannot be changed, so an existing binding is replaced
+	kind, binding, namespaceArgs := "clusterrolebinding", generateClusterRoleBindingYAML(userName, roles.cluster, expires), []string{}
+	if userNamespace != "" {
+		kind, binding, namespaceArgs = "rolebinding", generateRoleBindingYAML(userName, roles.namespace, userNamespace, expires), []string{"-n", userNamespace}
+	}
+	if _, err := runKubectl(adminKubeconfig, "", append([]string{"delete", kind, userBindingName(userName), "--ignore-not-found"}, namespaceArgs...)...); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", kind, err)
+	}
+	if _, err := runKubectl(adminKubeconfig, binding, "apply", "-f", "-"); err != nil {
+		return fmt.Errorf("failed to create %s: %w", kind, err)
+	}
+
+	outputPath := userOutput
+	if outputPath == "" {
+		outputPath = fmt.Sprintf("%s-%s.kubeconfig", userClusterName, userName)
+	}
+	if err := writeUserKubeconfig(adminKubeconfig, outputPath, userClusterName, userName, userNamespace, certPEM, key); err != nil {
+		return err
+	}
+
+	scope := "cluster-wide"
+	if userNamespace != "" {
+		scope = "in namespace " + userNamespace
+	}
+	fmt.Println()
+	fmt.Printf("✅ %s has %s access %s until %s\n", userName, userRole, scope, expires.Format(time.RFC3339))
+	fmt.Printf("Kubeconfig written to: %s\n", outputPath)
+	fmt.Println("It contains the user's private key; hand it over through a secure channel.")
+	return nil
+}
+
+func listUsers() error {
+	adminKubeconfig, err := adminKubeconfigPath(userClusterName)
+	if err != nil {
+		return err
+	}
+
+	out, err := runKubectl(adminKubeconfig, "", "get", "clusterrolebindings,rolebindings", "--all-namespaces", "-l", managedByLabel, "-o", "json")
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+
+	bindings, err := parseUserBindings(out)
+	if err != nil {
+		return err
+	}
+	if len(bindings) == 0 {
+		fmt.Printf("No users on cluster %s\n", userClusterName)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "USER\tROLE\tNAMESPACE\tEXPIRES")
+	for _, b := range bindings {
+		namespace := b.Namespace
+		if namespace == "" {
+			namespace = "(cluster-wide)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", b.User, b.Role, namespace, b.Expires)
+	}
+	return w.Flush()
+}
+
+func revokeUser() error {
+	if err := validateUserName(userName); err != nil {
+		return err
+	}
+	adminKubeconfig, err := adminKubeconfigPath(userClusterName)
+	if err != nil {
+		return err
+	}
+
+	out, err := runKubectl(adminKubeconfig, "", "delete", "clusterrolebindings,rolebindings", "--all-namespaces", "-l", userLabel+"="+userName, "-o", "name")
+	if err != nil {
+		return fmt.Errorf("failed to revoke user: %w", err)
+	}
+
+	deleted := strings.Fields(string(out))
+	if len(deleted) == 0 {
+		fmt.Printf("No role bindings found for %s on cluster %s\n", userName, userClusterName)
+		return nil
+	}
+	for _, name := range deleted {
+		fmt.Printf("✓ Deleted %s\n", name)
+	}
+	fmt.Printf("\n✅ Revoked access for %s\n", userName)
+	fmt.Println("The certificate still authenticates until it expires, but grants no permissions.")
+	return nil
+}
+
+// adminKubeconfigPath returns the cluster-admin kubeconfig from the cluster's provider
+func adminKubeconfigPath(clusterName string) (string, error) {
+	cfg, err := loadClusterConfig(clusterName)
+	if err != nil {
+		return "", fmt.Errorf("failed to load cluster config: %w", err)
+	}
+
+	p, err := getProvider(cfg.Provider.Type)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := p.GetKubeconfig(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to get kubeconfig: %w", err)
+	}
+	return path, nil
+}
+
+// generateUserKey creates a private key and a certificate signing request for a user
+func generateUserKey(user string) (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: user},
+	}, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate request: %w", err)
+	}
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}), nil
+}
+
+// waitForCertificate polls the CSR until the signer has issued the certificate
+func waitForCertificate(kubeconfigPath, csrName string, timeout time.Duration) ([]byte, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		out, err := runKubectl(kubeconfigPath, "", "get", "csr", csrName, "-o", "jsonpath={.status.certificate}")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get certificate: %w", err)
+		}
+		if len(out) > 0 {
+			cert, err := base64.StdEncoding.DecodeString(string(out))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode certificate: %w", err)
+			}
+			return cert, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for certificate %s to be issued", csrName)
+		}
+		time.Sleep(certificatePollInterval)
+	}
+}
+
+// certificateExpiry returns the NotAfter time of a PEM certificate
+func certificateExpiry(certPEM []byte) (time.Time, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return time.Time{}, fmt.Errorf("issued certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse issued certificate: %w", err)
+	}
+	return cert.NotAfter, nil
+}
+
+// writeUserKubeconfig writes a kubeconfig for the user with the admin kubeconfig's server and CA
+func writeUserKubeconfig(adminKubeconfig, outputPath, clusterName, user, namespace string, certPEM []byte, key *ecdsa.PrivateKey) error {
+	admin, err := kubeconfig.Load(adminKubeconfig)
+	if err != nil {
+		return err
+	}
+	_, cluster, _, err := admin.Current()
+	if err != nil {
+		return fmt.Errorf("failed to read admin kubeconfig: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode key: %w", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	cfg := kubeconfig.New(fmt.Sprintf("%s-%s", kubeconfig.EntryName(clusterName), user), cluster.Cluster, map[string]interface{}{
+		"client-certificate-data": base64.StdEncoding.EncodeToString(certPEM),
+		"client-key-data":         base64.StdEncoding.EncodeToString(keyPEM),
+	})
+	cfg.Contexts[0].Context.Namespace = namespace
+	return cfg.Save(outputPath)
+}
+
+func userBindingName(user string) string {
+	return "tdls-user-" + user
+}
+
+func generateCSRYAML(name, user string, csrPEM []byte, expiry time.Duration) string {
+	return fmt.Sprintf(`apiVersion: certificates.k8s.io/v1
+kind: CertificateSigningRequest
+metadata:
+  name: %s
+  labels:
+    app.kubernetes.io/managed-by: tdls-easy-k8s
+    %s: %s
+spec:
+  request: %s
+  signerName: kubernetes.io/kube-apiserver-client
+  expirationSeconds: %d
+  usages:
+    - client auth
+`, name, userLabel, user, base64.StdEncoding.EncodeToString(csrPEM), int(expiry.Seconds()))
+}
+
+func generateClusterRoleBindingYAML(user, clusterRole string, expires time.Time) string {
+	return fmt.Sprintf(`apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: %s
+  labels:
+    app.kubernetes.io/managed-by: tdls-easy-k8s
+    %s: %s
+  annotations:
+    %s: %q
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: %s
+subjects:
+  - apiGroup: rbac.authorization.k8s.io
+    kind: User
+    name: %s
+`, userBindingName(user), userLabel, user, expiryAnnotation, expires.UTC().Format(time.RFC3339), clusterRole, user)
+}
+
+func generateRoleBindingYAML(user, clusterRole, namespace string, expires time.Time) string {
+	return fmt.Sprintf(`apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: %s
+  namespace: %s
+  labels:
+    app.kubernetes.io/managed-by: tdls-easy-k8s
+    %s: %s
+  annotations:
+    %s: %q
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: %s
+subjects:
+  - apiGroup: rbac.authorization.k8s.io
+    kind: User
+    name: %s
+`, userBindingName(user), namespace, userLabel, user, expiryAnnotation, expires.UTC().Format(time.RFC3339), clusterRole, user)
+}
+
+// userBinding is a role binding created by user add
+type userBinding struct {
+	User      string
+	Role      string
+	Namespace string
+	Expires   string
+}
+
+// parseUserBindings reads the bindings of a kubectl get -o json list, sorted by user
+func parseUserBindings(data []byte) ([]userBinding, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Namespace   string            `json:"namespace"`
+				Labels      map[string]string `json:"labels"`
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+			RoleRef struct {
+				Name string `json:"name"`
+			} `json:"roleRef"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse role bindings: %w", err)
+	}
+
+	var bindings []userBinding
+	for _, item := range list.Items {
+		b := userBinding{
+			User:      item.Metadata.Labels[userLabel],
+			Role:      roleName(item.RoleRef.Name),
+			Namespace: item.Metadata.Namespace,
+			Expires:   item.Metadata.Annotations[expiryAnnotation],
+		}
+		if t, err := time.Parse(time.RFC3339, b.Expires); err == nil && time.Now().After(t) {
+			b.Expires += " (expired)"
+		}
+		bindings = append(bindings, b)
+	}
+
+	sort.SliceStable(bindings, func(i, j int) bool {
+		if bindings[i].User != bindings[j].User {
+			return bindings[i].User < bindings[j].User
+		}
+		return bindings[i].Namespace < bindings[j].Namespace
+	})
+	return bindings, nil
+}
+
+// roleName maps a bound ClusterRole back to its --role value
+func roleName(clusterRole string) string {
+	if clusterRole == "cluster-admin" {
+		return "admin"
+	}
+	return clusterRole
+}
@@ -0,0 +1,388 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/user/tdls-easy-k8s/internal/config"
+	"github.com/user/tdls-easy-k8s/internal/provider"
+)
+
+// clusterNamePattern matches the cluster names accepted by the Terraform modules
+var clusterNamePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// wizardProviders lists the providers the init wizard can configure
+var wizardProviders = []string{"aws", "hetzner", "proxmox"}
+
+// runInteractiveInit runs the init wizard and writes the resulting config file
+func runInteractiveInit(cmd *cobra.Command) error {
+	in := bufio.NewReader(cmd.InOrStdin())
+	out := cmd.OutOrStdout()
+
+	cfg, err := runInitWizard(in, out)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(initOutput); err == nil {
+		w := &wizard{in: in, out: out}
+		overwrite, err := w.confirm(fmt.Sprintf("%s already exists. Overwrite?", initOutput), false)
+		if err != nil {
+			return err
+		}
+		if !overwrite {
+			return fmt.Errorf("aborted: %s was not changed", initOutput)
+		}
+	}
+
+	if err := config.SaveToFile(cfg, initOutput); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\n✓ Wrote %s\n", initOutput)
+	fmt.Fprintf(out, "Create the cluster with: tdls-easy-k8s init --config %s\n", initOutput)
+	return nil
+}
+
+// runInitWizard asks for the cluster settings and returns a defaulted, validated config
+func runInitWizard(in *bufio.Reader, out io.Writer) (*config.ClusterConfig, error) {
+	w := &wizard{in: in, out: out}
+	cfg := &config.ClusterConfig{
+		APIVersion: config.APIVersion,
+		Kind:       config.Kind,
+	}
+
+	fmt.Fprintln(out, "This wizard creates a cluster configuration file. Press Enter to accept the default shown in brackets.")
+	fmt.Fprintln(out)
+
+	var err error
+	if cfg.Name, err = w.ask("Cluster name", "", checkClusterName); err != nil {
+		return nil, err
+	}
+
+	if cfg.Provider.Type, err = w.choose("Provider", wizardProviders, "aws"); err != nil {
+		return nil, err
+	}
+	p, err := provider.GetProvider(cfg.Provider.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start from the provider's defaults so they can be offered as answers
+	config.ApplyDefaults(cfg)
+	defaults := *cfg
+
+	if err := w.askProviderSettings(cfg, p); err != nil {
+		return nil, err
+	}
+
+	if cfg.Kubernetes.Version, err = w.ask("Kubernetes version", "1.30", required("kubernetes version")); err != nil {
+		return nil, err
+	}
+
+	controlPlaneDefault := 1
+	if cfg.Provider.Type == "aws" {
+		controlPlaneDefault = 3
+	}
+	// etcd needs an odd number of members for quorum
+	controlPlane, err := w.choose("Control plane nodes", []string{"1", "3", "5"}, strconv.Itoa(controlPlaneDefault))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Nodes.ControlPlane.Count, _ = strconv.Atoi(controlPlane)
+	if err := w.askNodeSize("Control plane", "nodes.controlPlane", &cfg.Nodes.ControlPlane, defaults.Nodes.ControlPlane, p); err != nil {
+		return nil, err
+	}
+
+	if cfg.Nodes.Workers.Count, err = w.askInt("Worker nodes", 2, 0, nil); err != nil {
+		return nil, err
+	}
+	if cfg.Nodes.Workers.Count > 0 {
+		if err := w.askNodeSize("Worker", "nodes.workers", &cfg.Nodes.Workers, defaults.Nodes.Workers, p); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.askComponents(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := w.askGitOps(cfg); err != nil {
+		return nil, err
+	}
+
+	config.ApplyDefaults(cfg)
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := validateFields(cfg, p); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// validateFields applies the provider's rules to every field the wizard sets, so
+// the config passes the checks of init apart from the credentials
+func validateFields(cfg *config.ClusterConfig, p provider.Provider) error {
+	fv, ok := p.(provider.FieldValidator)
+	if !ok {
+		return nil
+	}
+
+	fields := [][2]string{
+		{"provider.aws.region", cfg.Provider.AWS.Region},
+		{"provider.hetzner.location", cfg.Provider.Hetzner.Location},
+		{"provider.proxmox.node", cfg.Provider.Proxmox.Node},
+		{"provider.proxmox.vip", cfg.Provider.Proxmox.VIP},
+	}
+	groups := []struct {
+		prefix string
+		group  config.NodeGroupConfig
+	}{
+		{"nodes.controlPlane", cfg.Nodes.ControlPlane},
+		{"nodes.workers", cfg.Nodes.Workers},
+	}
+	for _, g := range groups {
+		if g.group.Count == 0 {
+			continue
+		}
+		fields = append(fields,
+			[2]string{g.prefix + ".instanceType", g.group.InstanceType},
+			[2]string{g.prefix + ".cpu", strconv.Itoa(g.group.CPU)},
+			[2]string{g.prefix + ".memoryMB", strconv.Itoa(g.group.MemoryMB)},
+			[2]string{g.prefix + ".diskGB", strconv.Itoa(g.group.DiskGB)},
+		)
+	}
+
+	// Providers only check their own fields and accept the others
+	for _, f := range fields {
+		if err := fv.ValidateField(f[0], f[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// askProviderSettings asks for the location and other settings of the chosen provider
+func (w *wizard) askProviderSettings(cfg *config.ClusterConfig, p provider.Provider) error {
+	var err error
+	switch cfg.Provider.Type {
+	case "aws":
+		cfg.Provider.AWS.Region, err = w.ask("AWS region", cfg.Provider.AWS.Region, fieldValidator(p, "provider.aws.region"))
+	case "hetzner":
+		cfg.Provider.Hetzner.Location, err = w.ask("Hetzner location (fsn1, nbg1, hel1, ash, hil)", cfg.Provider.Hetzner.Location, fieldValidator(p, "provider.hetzner.location"))
+	case "proxmox":
+		if cfg.Provider.Proxmox.Node, err = w.ask("Proxmox node", "pve", fieldValidator(p, "provider.proxmox.node")); err != nil {
+			return err
+		}
+		if cfg.Provider.Proxmox.VIP, err = w.ask("Free IP for the API server VIP", "", fieldValidator(p, "provider.proxmox.vip")); err != nil {
+			return err
+		}
+		if cfg.Provider.Proxmox.Bridge, err = w.ask("Network bridge", cfg.Provider.Proxmox.Bridge, required("bridge")); err != nil {
+			return err
+		}
+		cfg.Provider.Proxmox.Datastore, err = w.ask("Datastore", cfg.Provider.Proxmox.Datastore, required("datastore"))
+	}
+	return err
+}
+
+// askNodeSize asks for an instance type, or for CPU, memory and disk on Proxmox.
+// prefix is the config path of the node group, e.g. nodes.workers.
+func (w *wizard) askNodeSize(role, prefix string, group *config.NodeGroupConfig, defaults config.NodeGroupConfig, p provider.Provider) error {
+	var err error
+	if p.Name() != "proxmox" {
+		group.InstanceType, err = w.ask(role+" instance type", defaults.InstanceType, fieldValidator(p, prefix+".instanceType"))
+		return err
+	}
+
+	if group.CPU, err = w.askInt(role+" CPU cores", defaults.CPU, 1, fieldValidator(p, prefix+".cpu")); err != nil {
+		return err
+	}
+	if group.MemoryMB, err = w.askInt(role+" memory (MB)", defaults.MemoryMB, 1024, fieldValidator(p, prefix+".memoryMB")); err != nil {
+		return err
+	}
+	group.DiskGB, err = w.askInt(role+" disk (GB)", defaults.DiskGB, 10, fieldValidator(p, prefix+".diskGB"))
+	return err
+}
+
+// askComponents asks which cluster components to enable
+func (w *wizard) askComponents(cfg *config.ClusterConfig) error {
+	var err error
+	if cfg.Components.Traefik.Enabled, err = w.confirm("Enable Traefik ingress?", true); err != nil {
+		return err
+	}
+	if cfg.Components.Traefik.Enabled {
+		cfg.Components.Traefik.Version = "26.x"
+	}
+
+	if cfg.Components.Vault.Enabled, err = w.confirm("Enable Vault integration?", false); err != nil {
+		return err
+	}
+	if cfg.Components.Vault.Enabled {
+		if cfg.Components.Vault.Mode, err = w.choose("Vault mode", []string{"external", "deploy"}, "external"); err != nil {
+			return err
+		}
+		if cfg.Components.Vault.Mode == "external" {
+			if cfg.Components.Vault.Address, err = w.ask("Vault address", "", required("vault address")); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cfg.Components.ExternalSecrets.Enabled, err = w.confirm("Enable External Secrets Operator?", cfg.Components.Vault.Enabled); err != nil {
+		return err
+	}
+
+	if cfg.Provider.Type == "hetzner" {
+		if cfg.Components.HCloudCCM.Enabled, err = w.confirm("Enable the Hetzner cloud controller manager (LoadBalancer services)?", true); err != nil {
+			return err
+		}
+		cfg.Components.HCloudCSI.Enabled, err = w.confirm("Enable the Hetzner CSI driver (PersistentVolumes)?", true)
+	}
+	return err
+}
+
+// askGitOps asks for the GitOps repository; an empty answer leaves GitOps disabled
+func (w *wizard) askGitOps(cfg *config.ClusterConfig) error {
+	repo, err := w.ask("GitOps repository (leave empty to skip)", "", nil)
+	if err != nil || repo == "" {
+		return err
+	}
+
+	cfg.GitOps.Enabled = true
+	cfg.GitOps.Repository = repo
+	if cfg.GitOps.Branch, err = w.ask("GitOps branch", "main", required("branch")); err != nil {
+		return err
+	}
+	cfg.GitOps.Path, err = w.ask("Path in repository", "clusters/"+cfg.Name, required("path"))
+	return err
+}
+
+// wizard reads answers to interactive questions
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask prompts for a value until it passes validation. An empty answer selects the default.
+func (w *wizard) ask(question, def string, validate func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(w.out, "%s: ", question)
+		}
+
+		line, err := w.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return "", fmt.Errorf("input ended before the wizard finished")
+			}
+			return "", err
+		}
+
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = def
+		}
+
+		if validate == nil {
+			return answer, nil
+		}
+		if err := validate(answer); err != nil {
+			fmt.Fprintf(w.out, "  ✗ %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// askInt prompts for an integer of at least min
+func (w *wizard) askInt(question string, def, min int, validate func(string) error) (int, error) {
+	answer, err := w.ask(question, strconv.Itoa(def), func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		if n < min {
+			return fmt.Errorf("must be at least %d", min)
+		}
+		if validate != nil {
+			return validate(s)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(answer)
+}
+
+// choose prompts for one of a fixed set of options
+func (w *wizard) choose(question string, options []string, def string) (string, error) {
+	return w.ask(fmt.Sprintf("%s (%s)", question, strings.Join(options, ", ")), def, func(s string) error {
+		for _, o := range options {
+			if s == o {
+				return nil
+			}
+		}
+		return fmt.Errorf("choose one of: %s", strings.Join(options, ", "))
+	})
+}
+
+// confirm prompts for a yes/no answer
+func (w *wizard) confirm(question string, def bool) (bool, error) {
+	defAnswer := "n"
+	if def {
+		defAnswer = "y"
+	}
+	answer, err := w.ask(question+" (y/n)", defAnswer, func(s string) error {
+		switch strings.ToLower(s) {
+		case "y", "yes", "n", "no":
+			return nil
+		}
+		return fmt.Errorf("answer y or n")
+	})
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(strings.ToLower(answer), "y"), nil
+}
+
+// fieldValidator returns a validation function that applies the provider's rules for a config field
+func fieldValidator(p provider.Provider, field string) func(string) error {
+	fv, ok := p.(provider.FieldValidator)
+	if !ok {
+		return nil
+	}
+	return func(value string) error {
+		return fv.ValidateField(field, value)
+	}
+}
+
+// required returns a validation function that rejects empty answers
+func required(name string) func(string) error {
+	return func(value string) error {
+		if value == "" {
+			return fmt.Errorf("%s is required", name)
+		}
+		return nil
+	}
+}
+
+func checkClusterName(name string) error {
+	if name == "" {
+		return fmt.Errorf("cluster name is required")
+	}
+	if !clusterNamePattern.MatchString(name) {
+		return fmt.Errorf("cluster name must contain only lowercase letters, numbers, and hyphens")
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// dnsNamePattern matches a lowercase DNS name with at least two labels
+var dnsNamePattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// SANs returns the names to add to the API server certificate: the hostname
+// followed by the extra SANs, without duplicates
+func (c *APIServerConfig) SANs() []string {
+	var sans []string
+	seen := map[string]bool{}
+	for _, san := range append([]string{c.Hostname}, c.ExtraSANs...) {
+		if san != "" && !seen[san] {
+			seen[san] = true
+			sans = append(sans, san)
+		}
+	}
+	return sans
+}
+
+// Validate validates the API server names
+func (c *APIServerConfig) Validate() error {
+	if c.Hostname != "" && !isDNSName(c.Hostname) {
+		return &ConfigError{Message: fmt.Sprintf("kubernetes.apiServer.hostname must be a DNS name, got %q", c.Hostname)}
+	}
+
+	for _, san := range c.ExtraSANs {
+		if net.ParseIP(san) == nil && !isDNSName(strings.TrimPrefix(san, "*.")) {
+			return &ConfigError{Message: fmt.Sprintf("kubernetes.apiServer.extraSANs entries must be DNS names or IP addresses, got %q", san)}
+		}
+	}
+
+	return nil
+}
+
+// isDNSName reports whether name is a fully qualified DNS name
+func isDNSName(name string) bool {
+	return len(name) <= 253 && dnsNamePattern.MatchString(name)
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAPIServerConfig_Validate(t *testing.T) {
+	cases := []struct {
+		name      string
+		apiServer APIServerConfig
+		want      string
+	}{
+		{"empty", APIServerConfig{}, ""},
+		{"hostname", APIServerConfig{Hostname: "k8s-prod.example.com"}, ""},
+		{"extra SANs", APIServerConfig{ExtraSANs: []string{"api.internal.example.com", "10.0.0.10", "*.k8s.example.com", "2001:db8::1"}}, ""},
+		{"hostname with scheme", APIServerConfig{Hostname: "https://k8s.example.com"}, "hostname must be a DNS name"},
+		{"single label hostname", APIServerConfig{Hostname: "k8s"}, "hostname must be a DNS name"},
+		{"uppercase hostname", APIServerConfig{Hostname: "K8S.example.com"}, "hostname must be a DNS name"},
+		{"bad SAN", APIServerConfig{ExtraSANs: []string{"not a name"}}, "extraSANs entries must be DNS names or IP addresses"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.apiServer.Validate()
+			if tc.want == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestAPIServerConfig_SANs(t *testing.T) {
+	c := APIServerConfig{
+		Hostname:  "k8s.example.com",
+		ExtraSANs: []string{"10.0.0.10", "k8s.example.com", "", "api.example.com"},
+	}
+	want := []string{"k8s.example.com", "10.0.0.10", "api.example.com"}
+	if got := c.SANs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if got := (&APIServerConfig{}).SANs(); got != nil {
+		t.Errorf("expected no SANs, got %v", got)
+	}
+}
+
+func TestClusterConfig_Validate_Route53NeedsHostname(t *testing.T) {
+	cfg := validConfig()
+	cfg.Provider.AWS.Route53ZoneID = "Z0123456789"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "requires kubernetes.apiServer.hostname") {
+		t.Errorf("expected hostname error, got %v", err)
+	}
+
+	cfg.Kubernetes.APIServer.Hostname = "k8s.example.com"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestLoadFromFile_APIServer(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "cluster.yaml", `name: prod
+provider:
+  type: hetzner
+  hetzner:
+    location: fsn1
+kubernetes:
+  version: "1.30"
+  apiServer:
+    hostname: k8s-prod.example.com
+    extraSANs:
+      - 192.0.2.10
+nodes:
+  controlPlane:
+    count: 3
+`)
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got := cfg.Kubernetes.APIServer.SANs(); !reflect.DeepEqual(got, []string{"k8s-prod.example.com", "192.0.2.10"}) {
+		t.Errorf("unexpected SANs: %v", got)
+	}
+}
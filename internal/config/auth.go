@@ -0,0 +1,37 @@
+package config
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/url"
+)
+
+// Enabled reports whether OIDC authentication is configured
+func (c *OIDCConfig) Enabled() bool {
+	return c.IssuerURL != "" || c.ClientID != ""
+}
+
+// Validate validates the OIDC settings
+func (c *OIDCConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	if c.IssuerURL == "" {
+		return &ConfigError{Message: "auth.oidc.issuerURL is required"}
+	}
+	u, err := url.Parse(c.IssuerURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return &ConfigError{Message: fmt.Sprintf("auth.oidc.issuerURL must be an https URL, got %q", c.IssuerURL)}
+	}
+
+	if c.ClientID == "" {
+		return &ConfigError{Message: "auth.oidc.clientID is required"}
+	}
+
+	if c.CA != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(c.CA)) {
+		return &ConfigError{Message: "auth.oidc.ca must contain a PEM encoded certificate"}
+	}
+
+	return nil
+}
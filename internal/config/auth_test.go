@@ -0,0 +1,94 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCAPEM(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour), IsCA: true}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestOIDCConfig_Validate(t *testing.T) {
+	cases := []struct {
+		name string
+		oidc OIDCConfig
+		want string
+	}{
+		{"disabled", OIDCConfig{}, ""},
+		{"minimal", OIDCConfig{IssuerURL: "https://login.example.com", ClientID: "kubernetes"}, ""},
+		{"with CA", OIDCConfig{IssuerURL: "https://login.example.com", ClientID: "kubernetes", CA: testCAPEM(t)}, ""},
+		{"missing issuer", OIDCConfig{ClientID: "kubernetes"}, "auth.oidc.issuerURL is required"},
+		{"http issuer", OIDCConfig{IssuerURL: "http://login.example.com", ClientID: "kubernetes"}, "must be an https URL"},
+		{"missing client", OIDCConfig{IssuerURL: "https://login.example.com"}, "auth.oidc.clientID is required"},
+		{"bad CA", OIDCConfig{IssuerURL: "https://login.example.com", ClientID: "kubernetes", CA: "not a certificate"}, "auth.oidc.ca must contain"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.oidc.Validate()
+			if tc.want == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromFile_OIDC(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "oidc-ca.pem", testCAPEM(t))
+	path := writeFile(t, dir, "cluster.yaml", `name: sso
+provider:
+  type: proxmox
+  proxmox:
+    node: pve
+kubernetes:
+  version: "1.30"
+nodes:
+  controlPlane:
+    count: 1
+auth:
+  oidc:
+    issuerURL: https://login.example.com
+    clientID: kubernetes
+    usernameClaim: email
+    groupsClaim: groups
+    ca: ${file:oidc-ca.pem}
+    extraScopes: [email, groups]
+`)
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	oidc := cfg.Auth.OIDC
+	if !oidc.Enabled() || oidc.UsernameClaim != "email" || len(oidc.ExtraScopes) != 2 {
+		t.Errorf("unexpected OIDC settings: %+v", oidc)
+	}
+	if !strings.HasPrefix(oidc.CA, "-----BEGIN CERTIFICATE-----") {
+		t.Errorf("expected the CA file to be read, got %q", oidc.CA)
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var amiIDPattern = regexp.MustCompile(`^ami-[0-9a-f]{8,17}$`)
+
+// ebsLimits are the size, IOPS and throughput bounds of an EBS volume type. Zero
+// maximums mean the setting does not apply to the type.
+type ebsLimits struct {
+	minSize, maxSize             int
+	minIOPS, maxIOPS             int
+	minThroughput, maxThroughput int
+}
+
+var ebsVolumeTypes = map[string]ebsLimits{
+	"gp3": {1, 16384, 3000, 16000, 125, 1000},
+	"gp2": {1, 16384, 0, 0, 0, 0},
+	"io1": {4, 16384, 100, 64000, 0, 0},
+	"io2": {4, 65536, 100, 256000, 0, 0},
+}
+
+// validateSettings validates the infrastructure settings: environment, tags,
+// AMI and volumes
+func (c *AWSConfig) validateSettings() error {
+	switch c.Environment {
+	case "", "dev", "staging", "production":
+	default:
+		return &ConfigError{Message: fmt.Sprintf("provider.aws.environment must be dev, staging or production, got %q", c.Environment)}
+	}
+
+	for key, value := range c.Tags {
+		if key == "" || len(key) > 128 || len(value) > 256 {
+			return &ConfigError{Message: fmt.Sprintf("provider.aws.tags %q: keys must be 1-128 and values at most 256 characters", key)}
+		}
+		if strings.HasPrefix(strings.ToLower(key), "aws:") {
+			return &ConfigError{Message: fmt.Sprintf("provider.aws.tags %q: the aws: prefix is reserved", key)}
+		}
+	}
+
+	if c.AMI.ID != "" {
+		if !amiIDPattern.MatchString(c.AMI.ID) {
+			return &ConfigError{Message: fmt.Sprintf("provider.aws.ami.id %q is not an AMI ID (ami-...)", c.AMI.ID)}
+		}
+		if c.AMI.Owner != "" || c.AMI.NameFilter != "" {
+			return &ConfigError{Message: "provider.aws.ami.id cannot be combined with owner or nameFilter, which select the latest matching AMI"}
+		}
+	}
+
+	volumes := []struct {
+		name   string
+		volume EBSVolumeConfig
+	}{
+		{"controlPlaneRoot", c.Volumes.ControlPlaneRoot},
+		{"workerRoot", c.Volumes.WorkerRoot},
+		{"etcd", c.Volumes.Etcd},
+	}
+	for _, v := range volumes {
+		if err := v.volume.validate("provider.aws.volumes." + v.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks a volume against the limits of its type. Unset values take
+// the Terraform defaults (gp3, 3000 IOPS, 125 MB/s), which the checks assume.
+func (v EBSVolumeConfig) validate(name string) error {
+	volumeType := v.Type
+	if volumeType == "" {
+		volumeType = "gp3"
+	}
+	limits, ok := ebsVolumeTypes[volumeType]
+	if !ok {
+		return &ConfigError{Message: fmt.Sprintf("%s.type must be gp3, gp2, io1 or io2, got %q", name, v.Type)}
+	}
+
+	if v.SizeGB != 0 && (v.SizeGB < limits.minSize || v.SizeGB > limits.maxSize) {
+		return &ConfigError{Message: fmt.Sprintf("%s.sizeGB must be between %d and %d for %s", name, limits.minSize, limits.maxSize, volumeType)}
+	}
+
+	if v.IOPS != 0 {
+		if limits.maxIOPS == 0 {
+			return &ConfigError{Message: fmt.Sprintf("%s.iops cannot be set for %s", name, volumeType)}
+		}
+		if v.IOPS < limits.minIOPS || v.IOPS > limits.maxIOPS {
+			return &ConfigError{Message: fmt.Sprintf("%s.iops must be between %d and %d for %s", name, limits.minIOPS, limits.maxIOPS, volumeType)}
+		}
+	}
+
+	if v.Throughput != 0 {
+		if limits.maxThroughput == 0 {
+			return &ConfigError{Message: fmt.Sprintf("%s.throughput can only be set for gp3", name)}
+		}
+		if v.Throughput < limits.minThroughput || v.Throughput > limits.maxThroughput {
+			return &ConfigError{Message: fmt.Sprintf("%s.throughput must be between %d and %d MB/s", name, limits.minThroughput, limits.maxThroughput)}
+		}
+		// gp3 allows at most 0.25 MB/s per provisioned IOPS
+		iops := v.IOPS
+		if iops == 0 {
+			iops = limits.minIOPS
+		}
+		if v.Throughput*4 > iops {
+			return &ConfigError{Message: fmt.Sprintf("%s.throughput of %d MB/s needs at least %d IOPS (0.25 MB/s per IOPS)", name, v.Throughput, v.Throughput*4)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAWSConfig_Validate_Settings(t *testing.T) {
+	valid := AWSConfig{
+		Environment: "staging",
+		Tags:        map[string]string{"CostCenter": "platform"},
+		AMI:         AMIConfig{Owner: "123456789012", NameFilter: "golden-ubuntu-*"},
+		Volumes: AWSVolumesConfig{
+			ControlPlaneRoot: EBSVolumeConfig{SizeGB: 80},
+			WorkerRoot:       EBSVolumeConfig{SizeGB: 200, Type: "gp3", IOPS: 6000, Throughput: 500},
+			Etcd:             EBSVolumeConfig{SizeGB: 100, Type: "io2", IOPS: 10000},
+		},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		aws     AWSConfig
+		wantErr string
+	}{
+		{"environment", AWSConfig{Environment: "prod"}, "provider.aws.environment"},
+		{"reserved tag", AWSConfig{Tags: map[string]string{"aws:owner": "me"}}, "reserved"},
+		{"AMI ID", AWSConfig{AMI: AMIConfig{ID: "ubuntu"}}, "is not an AMI ID"},
+		{"AMI ID with filter", AWSConfig{AMI: AMIConfig{ID: "ami-0123456789abcdef0", NameFilter: "ubuntu-*"}}, "cannot be combined"},
+		{"volume type", AWSConfig{Volumes: AWSVolumesConfig{Etcd: EBSVolumeConfig{Type: "st1"}}}, "provider.aws.volumes.etcd.type"},
+		{"size", AWSConfig{Volumes: AWSVolumesConfig{WorkerRoot: EBSVolumeConfig{SizeGB: 20000}}}, "sizeGB must be between 1 and 16384"},
+		{"gp3 IOPS", AWSConfig{Volumes: AWSVolumesConfig{Etcd: EBSVolumeConfig{IOPS: 2000}}}, "iops must be between 3000 and 16000"},
+		{"gp2 IOPS", AWSConfig{Volumes: AWSVolumesConfig{Etcd: EBSVolumeConfig{Type: "gp2", IOPS: 3000}}}, "iops cannot be set for gp2"},
+		{"gp3 throughput", AWSConfig{Volumes: AWSVolumesConfig{Etcd: EBSVolumeConfig{Throughput: 1200}}}, "throughput must be between 125 and 1000"},
+		{"throughput without IOPS", AWSConfig{Volumes: AWSVolumesConfig{ControlPlaneRoot: EBSVolumeConfig{Throughput: 1000}}}, "needs at least 4000 IOPS"},
+		{"io1 throughput", AWSConfig{Volumes: AWSVolumesConfig{Etcd: EBSVolumeConfig{Type: "io1", Throughput: 200}}}, "can only be set for gp3"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.aws.Validate()
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestClusterConfig_Validate_SessionManagerDisabled(t *testing.T) {
+	disabled := false
+
+	cfg := validConfig()
+	cfg.Provider.AWS.SessionManager = &disabled
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "requires kubernetes.apiServer.hostname") {
+		t.Errorf("expected a hostname error, got %v", err)
+	}
+
+	cfg.Kubernetes.APIServer.Hostname = "k8s.example.com"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected no error with a hostname, got: %v", err)
+	}
+
+	cfg.Provider.AWS.Private = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "private requires provider.aws.sessionManager") {
+		t.Errorf("expected a private cluster error, got %v", err)
+	}
+}
+
+func TestLoadFromFile_AWSSettings(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "cluster.yaml", `name: tuned
+provider:
+  type: aws
+  aws:
+    region: eu-west-1
+    environment: dev
+    encryption: false
+    tags:
+      Team: platform
+    ami:
+      id: ami-0123456789abcdef0
+    volumes:
+      etcd:
+        sizeGB: 100
+        iops: 6000
+        throughput: 250
+kubernetes:
+  version: "1.30"
+nodes:
+  controlPlane:
+    count: 1
+`)
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	aws := cfg.Provider.AWS
+	if aws.Environment != "dev" || aws.Tags["Team"] != "platform" || aws.AMI.ID != "ami-0123456789abcdef0" {
+		t.Errorf("unexpected settings: %+v", aws)
+	}
+	if aws.Encryption == nil || *aws.Encryption {
+		t.Error("expected encryption to be explicitly disabled")
+	}
+	if aws.CloudWatchLogs != nil {
+		t.Error("expected unset cloudWatchLogs to stay unset")
+	}
+	if aws.Volumes.Etcd != (EBSVolumeConfig{SizeGB: 100, IOPS: 6000, Throughput: 250}) {
+		t.Errorf("unexpected etcd volume: %+v", aws.Volumes.Etcd)
+	}
+}
@@ -2,49 +2,168 @@ package config
 
 import (
 	"fmt"
-	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 // ClusterConfig represents the complete cluster configuration
 type ClusterConfig struct {
+	APIVersion string           `yaml:"apiVersion"`
+	Kind       string           `yaml:"kind"`
 	Name       string           `yaml:"name"`
 	Provider   ProviderConfig   `yaml:"provider"`
 	Kubernetes KubernetesConfig `yaml:"kubernetes"`
 	Nodes      NodesConfig      `yaml:"nodes"`
 	GitOps     GitOpsConfig     `yaml:"gitops"`
 	Components ComponentsConfig `yaml:"components"`
+	Auth       AuthConfig       `yaml:"auth,omitempty"`
+
+	// references are the ${...} references the config was loaded with
+	references references
+
+	// deprecations are the warnings for deprecated fields found while loading
+	deprecations []string
 }
 
-// ProviderConfig contains cloud provider configuration
+// ProviderConfig contains cloud provider configuration. Settings live in the
+// section named after the provider type, e.g. provider.aws for type "aws".
 type ProviderConfig struct {
-	Type     string    `yaml:"type"`               // aws, vsphere, hetzner, proxmox
-	Region   string    `yaml:"region,omitempty"`   // For AWS
-	Location string    `yaml:"location,omitempty"` // For Hetzner (fsn1, nbg1, hel1, ash, hil)
-	VPC      VPCConfig `yaml:"vpc"`
+	Type string `yaml:"type"` // aws, vsphere, hetzner, proxmox or a plugin provider
+
+	AWS     AWSConfig     `yaml:"aws,omitempty"`
+	Hetzner HetznerConfig `yaml:"hetzner,omitempty"`
+	Proxmox ProxmoxConfig `yaml:"proxmox,omitempty"`
+	VSphere VSphereConfig `yaml:"vsphere,omitempty"`
+
+	// Settings holds the settings of a plugin provider. They are passed to the
+	// plugin unchanged and are not valid for the built-in providers.
+	Settings map[string]interface{} `yaml:"settings,omitempty"`
+
+	// Deprecated flat fields from before the per-provider sections. They are still
+	// read and moved into the matching section when a config is loaded.
+	Region     string    `yaml:"region,omitempty"`
+	Location   string    `yaml:"location,omitempty"`
+	VPC        VPCConfig `yaml:"vpc,omitempty"`
+	VCenter    string    `yaml:"vcenter,omitempty"`
+	Datacenter string    `yaml:"datacenter,omitempty"`
+	Node       string    `yaml:"node,omitempty"`
+	Bridge     string    `yaml:"bridge,omitempty"`
+	VlanTag    int       `yaml:"vlanTag,omitempty"`
+	Datastore  string    `yaml:"datastore,omitempty"`
+	VIP        string    `yaml:"vip,omitempty"`
+}
+
+// AWSConfig contains AWS-specific configuration
+type AWSConfig struct {
+	Region        string    `yaml:"region,omitempty"` // e.g., us-east-1
+	VPC           VPCConfig `yaml:"vpc,omitempty"`
+	Route53ZoneID string    `yaml:"route53ZoneId,omitempty"` // hosted zone for the API hostname record
+
+	// Private puts the API server behind an internal NLB; the CLI reaches it
+	// through an SSM port-forwarding session to a control plane instance
+	Private         bool     `yaml:"private,omitempty"`
+	APIAllowedCIDRs []string `yaml:"apiAllowedCIDRs,omitempty"` // default: anywhere, or the VPC when private
+
+	// Credentials: a named AWS CLI profile and/or a role assumed for every AWS
+	// CLI and OpenTofu call. Unset, the ambient credentials are used.
+	Profile               string `yaml:"profile,omitempty"`
+	AssumeRoleARN         string `yaml:"assumeRoleArn,omitempty"`
+	AssumeRoleExternalID  string `yaml:"assumeRoleExternalId,omitempty"`
+	AssumeRoleSessionName string `yaml:"assumeRoleSessionName,omitempty"` // default: tdls-easy-k8s
+
+	// Infrastructure settings; unset values keep the Terraform module defaults
+	Environment    string            `yaml:"environment,omitempty"`    // dev, staging or production (default)
+	Tags           map[string]string `yaml:"tags,omitempty"`           // added to all resources
+	AMI            AMIConfig         `yaml:"ami,omitempty"`            // default: latest Ubuntu 22.04
+	Volumes        AWSVolumesConfig  `yaml:"volumes,omitempty"`        // EBS volumes of the nodes
+	Encryption     *bool             `yaml:"encryption,omitempty"`     // KMS encryption of EBS volumes (default true)
+	CloudWatchLogs *bool             `yaml:"cloudWatchLogs,omitempty"` // allow nodes to write CloudWatch Logs (default true)
+	SessionManager *bool             `yaml:"sessionManager,omitempty"` // SSM access to nodes (default true)
+}
+
+// AMIConfig selects the machine image: a fixed AMI, or the most recent one
+// matching an owner and name filter
+type AMIConfig struct {
+	ID         string `yaml:"id,omitempty"`
+	Owner      string `yaml:"owner,omitempty"`      // default: Canonical
+	NameFilter string `yaml:"nameFilter,omitempty"` // e.g. ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-amd64-server-*
+}
+
+// AWSVolumesConfig contains the EBS volume settings of the nodes
+type AWSVolumesConfig struct {
+	ControlPlaneRoot EBSVolumeConfig `yaml:"controlPlaneRoot,omitempty"` // default: 50 GB gp3
+	WorkerRoot       EBSVolumeConfig `yaml:"workerRoot,omitempty"`       // default: 100 GB gp3
+	Etcd             EBSVolumeConfig `yaml:"etcd,omitempty"`             // default: 50 GB gp3
+}
+
+// EBSVolumeConfig describes an EBS volume
+type EBSVolumeConfig struct {
+	SizeGB     int    `yaml:"sizeGB,omitempty"`
+	Type       string `yaml:"type,omitempty"`       // gp3 (default), gp2, io1 or io2
+	IOPS       int    `yaml:"iops,omitempty"`       // gp3, io1 and io2; default 3000
+	Throughput int    `yaml:"throughput,omitempty"` // MB/s, gp3 only; default 125
+}
+
+// HetznerConfig contains Hetzner Cloud-specific configuration
+type HetznerConfig struct {
+	Location string              `yaml:"location,omitempty"` // fsn1, nbg1, hel1, ash, hil
+	Network  VPCConfig           `yaml:"network,omitempty"`  // Private network
+	OSImage  string              `yaml:"osImage,omitempty"`  // e.g., ubuntu-22.04
+	Limits   HetznerLimitsConfig `yaml:"limits,omitempty"`   // Project limits, checked before creating the cluster
+}
+
+// HetznerLimitsConfig holds the resource limits of the Hetzner Cloud project, as
+// shown in the Cloud Console under Limits. The API does not report them, so they
+// must be set for the preflight check; zero leaves a resource unchecked.
+type HetznerLimitsConfig struct {
+	Servers       int `yaml:"servers,omitempty"`
+	LoadBalancers int `yaml:"loadBalancers,omitempty"`
+	Networks      int `yaml:"networks,omitempty"`
+}
+
+// ProxmoxConfig contains Proxmox VE-specific configuration
+type ProxmoxConfig struct {
+	Node              string `yaml:"node,omitempty"`              // Proxmox node name (e.g. "pve")
+	Bridge            string `yaml:"bridge,omitempty"`            // Network bridge (default "vmbr0")
+	VlanTag           int    `yaml:"vlanTag,omitempty"`           // Optional VLAN tag
+	Datastore         string `yaml:"datastore,omitempty"`         // Storage datastore (default "local-lvm")
+	SnippetsDatastore string `yaml:"snippetsDatastore,omitempty"` // Datastore for cloud-init snippets (default "local")
+	VIP               string `yaml:"vip,omitempty"`               // kube-vip virtual IP for API endpoint
+}
 
-	// vSphere-specific fields
+// VSphereConfig contains vSphere-specific configuration
+type VSphereConfig struct {
 	VCenter    string `yaml:"vcenter,omitempty"`
 	Datacenter string `yaml:"datacenter,omitempty"`
-
-	// On-prem provider fields (Proxmox, Harvester)
-	Node      string `yaml:"node,omitempty"`      // Proxmox node name (e.g. "pve")
-	Bridge    string `yaml:"bridge,omitempty"`    // Network bridge (default "vmbr0")
-	VlanTag   int    `yaml:"vlanTag,omitempty"`   // Optional VLAN tag
-	Datastore string `yaml:"datastore,omitempty"` // Storage datastore (default "local-lvm")
-	VIP       string `yaml:"vip,omitempty"`       // kube-vip virtual IP for API endpoint
 }
 
 // VPCConfig contains VPC/network configuration
 type VPCConfig struct {
 	CIDR string `yaml:"cidr"`
+
+	// An existing VPC and its subnets to use instead of creating a VPC (AWS only)
+	ID               string   `yaml:"id,omitempty"`               // e.g. vpc-0abc...
+	PublicSubnetIDs  []string `yaml:"publicSubnetIds,omitempty"`  // control plane and NLB
+	PrivateSubnetIDs []string `yaml:"privateSubnetIds,omitempty"` // workers
+}
+
+// Existing reports whether an existing VPC is used
+func (v *VPCConfig) Existing() bool {
+	return v.ID != ""
 }
 
 // KubernetesConfig contains Kubernetes-specific configuration
 type KubernetesConfig struct {
-	Version      string `yaml:"version"`      // e.g., "1.30"
-	Distribution string `yaml:"distribution"` // rke2, k3s
+	Version      string          `yaml:"version"`      // e.g., "1.30"
+	Distribution string          `yaml:"distribution"` // rke2, k3s
+	APIServer    APIServerConfig `yaml:"apiServer,omitempty"`
+}
+
+// APIServerConfig contains the names the Kubernetes API server is reached by
+type APIServerConfig struct {
+	Hostname  string   `yaml:"hostname,omitempty"`  // e.g. k8s-prod.example.com, used in the kubeconfig
+	ExtraSANs []string `yaml:"extraSANs,omitempty"` // additional DNS names or IPs for the certificate
 }
 
 // NodesConfig contains node configuration for control plane and workers
@@ -56,7 +175,34 @@ type NodesConfig struct {
 // NodeGroupConfig represents a group of nodes
 type NodeGroupConfig struct {
 	Count        int    `yaml:"count"`
-	InstanceType string `yaml:"instanceType"` // e.g., t3.medium
+	InstanceType string `yaml:"instanceType,omitempty"` // e.g., t3.medium (AWS), cpx22 (Hetzner)
+
+	// VM sizing for providers without instance types (Proxmox)
+	CPU      int `yaml:"cpu,omitempty"`      // CPU cores per node
+	MemoryMB int `yaml:"memoryMB,omitempty"` // Memory per node in MB
+	DiskGB   int `yaml:"diskGB,omitempty"`   // Disk size per node in GB
+
+	// Spot runs workers on spot instances (providers with the spot capability).
+	// The first OnDemandBase nodes stay on-demand, so a mixed group keeps some
+	// capacity when spot instances are reclaimed.
+	Spot         bool `yaml:"spot,omitempty"`
+	OnDemandBase int  `yaml:"onDemandBase,omitempty"`
+
+	// Autoscaling lets the cluster autoscaler resize the group between min and
+	// max nodes (providers with the autoscaling capability). Count is the
+	// initial size.
+	Autoscaling AutoscalingConfig `yaml:"autoscaling,omitempty"`
+}
+
+// AutoscalingConfig contains the size bounds of an autoscaled node group
+type AutoscalingConfig struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+}
+
+// Enabled reports whether the node group is autoscaled
+func (a AutoscalingConfig) Enabled() bool {
+	return a != AutoscalingConfig{}
 }
 
 // GitOpsConfig contains GitOps configuration
@@ -72,6 +218,8 @@ type ComponentsConfig struct {
 	Traefik         TraefikConfig         `yaml:"traefik"`
 	Vault           VaultConfig           `yaml:"vault"`
 	ExternalSecrets ExternalSecretsConfig `yaml:"externalSecrets"`
+	HCloudCCM       HCloudCCMConfig       `yaml:"hcloudCCM"`
+	HCloudCSI       HCloudCSIConfig       `yaml:"hcloudCSI"`
 }
 
 // TraefikConfig contains Traefik ingress controller configuration
@@ -92,28 +240,115 @@ type ExternalSecretsConfig struct {
 	Enabled bool `yaml:"enabled"`
 }
 
+// HCloudCCMConfig contains Hetzner cloud controller manager configuration. It
+// provides LoadBalancer services and initializes the nodes, which then run
+// with cloud-provider=external.
+type HCloudCCMConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	Version       string `yaml:"version,omitempty"`       // Helm chart version constraint
+	LoadBalancers int    `yaml:"loadBalancers,omitempty"` // LoadBalancer services the cluster runs, counted against the project limit
+}
+
+// HCloudCSIConfig contains Hetzner CSI driver configuration. It provides
+// PersistentVolumes backed by Hetzner Cloud volumes.
+type HCloudCSIConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Version string `yaml:"version,omitempty"` // Helm chart version constraint
+}
+
+// AuthConfig contains API server authentication configuration
+type AuthConfig struct {
+	OIDC OIDCConfig `yaml:"oidc,omitempty"`
+}
+
+// OIDCConfig configures the API server to accept tokens from an OIDC identity provider
+type OIDCConfig struct {
+	IssuerURL      string   `yaml:"issuerURL,omitempty"`      // e.g., https://login.example.com
+	ClientID       string   `yaml:"clientID,omitempty"`       // Client ID registered with the identity provider
+	UsernameClaim  string   `yaml:"usernameClaim,omitempty"`  // Claim used as the user name (API server default: sub)
+	UsernamePrefix string   `yaml:"usernamePrefix,omitempty"` // Prefix for user names, e.g. "oidc:"
+	GroupsClaim    string   `yaml:"groupsClaim,omitempty"`    // Claim holding the user's groups
+	GroupsPrefix   string   `yaml:"groupsPrefix,omitempty"`   // Prefix for group names
+	CA             string   `yaml:"ca,omitempty"`             // PEM CA bundle of the issuer, if not publicly trusted
+	ExtraScopes    []string `yaml:"extraScopes,omitempty"`    // Scopes requested by kubeconfig --oidc, e.g. [email, groups]
+}
+
 // Validate validates the cluster configuration
 func (c *ClusterConfig) Validate() error {
 	if c.Name == "" {
 		return &ConfigError{Message: "cluster name is required"}
 	}
 
+	if c.APIVersion != "" && c.APIVersion != APIVersion {
+		return &ConfigError{Message: fmt.Sprintf("unsupported apiVersion %q (expected %q)", c.APIVersion, APIVersion)}
+	}
+
+	if c.Kind != "" && c.Kind != Kind {
+		return &ConfigError{Message: fmt.Sprintf("unsupported kind %q (expected %q)", c.Kind, Kind)}
+	}
+
 	if c.Provider.Type == "" {
 		return &ConfigError{Message: "provider type is required"}
 	}
 
-	if c.Provider.Type != "aws" && c.Provider.Type != "vsphere" && c.Provider.Type != "hetzner" && c.Provider.Type != "proxmox" {
-		return &ConfigError{Message: "provider type must be 'aws', 'vsphere', 'hetzner', or 'proxmox'"}
+	if c.Provider.Type != "aws" && c.Provider.Type != "vsphere" && c.Provider.Type != "hetzner" && c.Provider.Type != "proxmox" && !IsPluginType(c.Provider.Type) {
+		types := []string{"'aws'", "'vsphere'", "'hetzner'", "'proxmox'"}
+		for _, t := range PluginTypes() {
+			types = append(types, "'"+t+"'")
+		}
+		return &ConfigError{Message: fmt.Sprintf("provider type %q is not supported; use one of %s (see: tdls-easy-k8s providers)", c.Provider.Type, strings.Join(types, ", "))}
+	}
+
+	if err := c.Provider.validateSections(); err != nil {
+		return err
+	}
+
+	if err := c.Kubernetes.APIServer.Validate(); err != nil {
+		return err
+	}
+
+	if c.Provider.AWS.Route53ZoneID != "" && c.Kubernetes.APIServer.Hostname == "" {
+		return &ConfigError{Message: "provider.aws.route53ZoneId requires kubernetes.apiServer.hostname"}
+	}
+
+	// Without Session Manager the CLI cannot reach the nodes to add the NLB name
+	// to the certificates, or tunnel to a private API server
+	if aws := c.Provider.AWS; aws.SessionManager != nil && !*aws.SessionManager {
+		if aws.Private {
+			return &ConfigError{Message: "provider.aws.private requires provider.aws.sessionManager"}
+		}
+		if c.Kubernetes.APIServer.Hostname == "" {
+			return &ConfigError{Message: "provider.aws.sessionManager: false requires kubernetes.apiServer.hostname"}
+		}
+	}
+
+	if err := c.Auth.OIDC.Validate(); err != nil {
+		return err
 	}
 
 	if c.Nodes.ControlPlane.Count < 1 {
 		return &ConfigError{Message: "at least one control plane node is required"}
 	}
 
+	if err := c.Nodes.validateSpot(); err != nil {
+		return err
+	}
+
+	if err := c.Nodes.validateAutoscaling(); err != nil {
+		return err
+	}
+
 	if c.Kubernetes.Version == "" {
 		return &ConfigError{Message: "kubernetes version is required"}
 	}
 
+	if (c.Components.HCloudCCM.Enabled || c.Components.HCloudCSI.Enabled) && c.Provider.Type != "hetzner" {
+		return &ConfigError{Message: "components.hcloudCCM and components.hcloudCSI require provider type 'hetzner'"}
+	}
+	if c.Components.HCloudCCM.LoadBalancers < 0 {
+		return &ConfigError{Message: fmt.Sprintf("components.hcloudCCM.loadBalancers must not be negative, got %d", c.Components.HCloudCCM.LoadBalancers)}
+	}
+
 	if c.Components.Vault.Enabled {
 		if c.Components.Vault.Mode != "external" && c.Components.Vault.Mode != "deploy" {
 			return &ConfigError{Message: "vault mode must be 'external' or 'deploy'"}
@@ -126,6 +361,42 @@ func (c *ClusterConfig) Validate() error {
 	return nil
 }
 
+// validateSpot checks the spot settings: the control plane holds etcd and must
+// not be reclaimed, and the on-demand base must fit in the worker group
+func (n *NodesConfig) validateSpot() error {
+	if n.ControlPlane.Spot || n.ControlPlane.OnDemandBase != 0 {
+		return &ConfigError{Message: "nodes.controlPlane cannot run on spot instances"}
+	}
+	workers := n.Workers
+	if workers.OnDemandBase != 0 && !workers.Spot {
+		return &ConfigError{Message: "nodes.workers.onDemandBase requires nodes.workers.spot"}
+	}
+	if workers.OnDemandBase < 0 || workers.OnDemandBase > workers.Count {
+		return &ConfigError{Message: fmt.Sprintf("nodes.workers.onDemandBase must be between 0 and the worker count (%d)", workers.Count)}
+	}
+	return nil
+}
+
+// validateAutoscaling checks the autoscaling bounds: only workers are
+// autoscaled, and the initial count must be within them
+func (n *NodesConfig) validateAutoscaling() error {
+	if n.ControlPlane.Autoscaling.Enabled() {
+		return &ConfigError{Message: "nodes.controlPlane cannot be autoscaled"}
+	}
+	workers := n.Workers
+	scaling := workers.Autoscaling
+	if !scaling.Enabled() {
+		return nil
+	}
+	if scaling.Min < 0 || scaling.Max < 1 || scaling.Min > scaling.Max {
+		return &ConfigError{Message: fmt.Sprintf("nodes.workers.autoscaling needs 0 <= min <= max and max >= 1, got min %d, max %d", scaling.Min, scaling.Max)}
+	}
+	if workers.Count < scaling.Min || workers.Count > scaling.Max {
+		return &ConfigError{Message: fmt.Sprintf("nodes.workers.count (%d) must be between autoscaling min (%d) and max (%d)", workers.Count, scaling.Min, scaling.Max)}
+	}
+	return nil
+}
+
 // ConfigError represents a configuration error
 type ConfigError struct {
 	Message string
@@ -135,17 +406,36 @@ func (e *ConfigError) Error() string {
 	return e.Message
 }
 
-// LoadConfig loads cluster configuration from a YAML file
-func LoadConfig(path string) (*ClusterConfig, error) {
-	data, err := os.ReadFile(path)
+// readConfig decodes a cluster configuration file with any overlay files applied
+// on top of it in order. It does not apply defaults or validate; use LoadFromFile.
+func readConfig(path string, overlays ...string) (*ClusterConfig, error) {
+	loaded, err := loadDocument(path, overlays)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
 	}
 
+	cfg, err := decodeConfig(loaded.node)
+	if err != nil {
+		return nil, err
+	}
+	cfg.references = loaded.refs
+	cfg.deprecations = loaded.deprecations
+
+	return cfg, nil
+}
+
+// decodeConfig decodes an upgraded configuration document
+func decodeConfig(doc *yaml.Node) (*ClusterConfig, error) {
 	var cfg ClusterConfig
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := doc.Decode(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
 	return &cfg, nil
 }
+
+// SetVersion stamps the configuration with the latest format version and kind
+func (c *ClusterConfig) SetVersion() {
+	c.APIVersion = APIVersion
+	c.Kind = Kind
+}
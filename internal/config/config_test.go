@@ -1,13 +1,16 @@
 package config
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func validConfig() *ClusterConfig {
 	return &ClusterConfig{
 		Name: "test-cluster",
 		Provider: ProviderConfig{
-			Type:   "aws",
-			Region: "us-east-1",
+			Type: "aws",
+			AWS:  AWSConfig{Region: "us-east-1"},
 		},
 		Kubernetes: KubernetesConfig{
 			Version:      "1.30",
@@ -58,9 +61,15 @@ func TestClusterConfig_Validate_InvalidProviderType(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for invalid provider type")
 	}
-	if err.Error() != "provider type must be 'aws', 'vsphere', 'hetzner', or 'proxmox'" {
+	if err.Error() != `provider type "gcp" is not supported; use one of 'aws', 'vsphere', 'hetzner', 'proxmox' (see: tdls-easy-k8s providers)` {
 		t.Errorf("unexpected error message: %v", err)
 	}
+
+	RegisterPluginType("openstack-test")
+	t.Cleanup(func() { delete(pluginTypes, "openstack-test") })
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "'proxmox', 'openstack-test'") {
+		t.Errorf("expected the plugin type to be listed, got %v", err)
+	}
 }
 
 func TestClusterConfig_Validate_ZeroControlPlaneNodes(t *testing.T) {
@@ -89,7 +98,7 @@ func TestClusterConfig_Validate_MissingKubernetesVersion(t *testing.T) {
 
 func TestClusterConfig_Validate_VSphereProvider(t *testing.T) {
 	cfg := validConfig()
-	cfg.Provider.Type = "vsphere"
+	cfg.Provider = ProviderConfig{Type: "vsphere"}
 	if err := cfg.Validate(); err != nil {
 		t.Errorf("expected vsphere provider to be valid, got: %v", err)
 	}
@@ -97,7 +106,7 @@ func TestClusterConfig_Validate_VSphereProvider(t *testing.T) {
 
 func TestClusterConfig_Validate_ProxmoxProvider(t *testing.T) {
 	cfg := validConfig()
-	cfg.Provider.Type = "proxmox"
+	cfg.Provider = ProviderConfig{Type: "proxmox"}
 	if err := cfg.Validate(); err != nil {
 		t.Errorf("expected proxmox provider to be valid, got: %v", err)
 	}
@@ -158,6 +167,105 @@ func TestClusterConfig_Validate_VaultDisabledSkipsValidation(t *testing.T) {
 	}
 }
 
+func TestClusterConfig_Validate_UnsupportedAPIVersion(t *testing.T) {
+	cfg := validConfig()
+	cfg.APIVersion = "tdls.dev/v2"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for unsupported apiVersion")
+	}
+}
+
+func TestClusterConfig_Validate_CurrentAPIVersion(t *testing.T) {
+	cfg := validConfig()
+	cfg.SetVersion()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected versioned config to pass validation, got: %v", err)
+	}
+}
+
+func TestClusterConfig_Validate_Spot(t *testing.T) {
+	cfg := validConfig()
+	cfg.Nodes.Workers.Spot = true
+	cfg.Nodes.Workers.OnDemandBase = 1
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected mixed spot workers to pass validation, got: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		modify func(*NodesConfig)
+	}{
+		{"spot control plane", func(n *NodesConfig) { n.ControlPlane.Spot = true }},
+		{"on-demand base without spot", func(n *NodesConfig) { n.Workers.OnDemandBase = 1 }},
+		{"on-demand base above count", func(n *NodesConfig) { n.Workers.Spot = true; n.Workers.OnDemandBase = 4 }},
+		{"negative on-demand base", func(n *NodesConfig) { n.Workers.Spot = true; n.Workers.OnDemandBase = -1 }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.modify(&cfg.Nodes)
+			if err := cfg.Validate(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestClusterConfig_Validate_Autoscaling(t *testing.T) {
+	cfg := validConfig()
+	cfg.Nodes.Workers.Autoscaling = AutoscalingConfig{Min: 1, Max: 10}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected autoscaled workers to pass validation, got: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		scaling AutoscalingConfig
+		count   int
+	}{
+		{"max below min", AutoscalingConfig{Min: 5, Max: 2}, 3},
+		{"zero max", AutoscalingConfig{Min: -1, Max: 0}, 0},
+		{"negative min", AutoscalingConfig{Min: -1, Max: 5}, 3},
+		{"count below min", AutoscalingConfig{Min: 4, Max: 10}, 3},
+		{"count above max", AutoscalingConfig{Min: 1, Max: 2}, 3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Nodes.Workers.Count = tc.count
+			cfg.Nodes.Workers.Autoscaling = tc.scaling
+			if err := cfg.Validate(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	cfg = validConfig()
+	cfg.Nodes.ControlPlane.Autoscaling = AutoscalingConfig{Min: 3, Max: 5}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "controlPlane cannot be autoscaled") {
+		t.Errorf("expected control plane autoscaling error, got %v", err)
+	}
+}
+
+func TestClusterConfig_Validate_HetznerComponents(t *testing.T) {
+	cfg := validConfig()
+	cfg.Components.HCloudCSI.Enabled = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "require provider type 'hetzner'") {
+		t.Errorf("expected error for the Hetzner CSI driver on AWS, got %v", err)
+	}
+
+	cfg.Provider = ProviderConfig{Type: "hetzner", Hetzner: HetznerConfig{Location: "fsn1"}}
+	cfg.Components.HCloudCCM.Enabled = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	cfg.Components.HCloudCCM.LoadBalancers = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "components.hcloudCCM.loadBalancers") {
+		t.Errorf("expected error for a negative load balancer count, got %v", err)
+	}
+}
+
 func TestConfigError_Error(t *testing.T) {
 	err := &ConfigError{Message: "something went wrong"}
 	if err.Error() != "something went wrong" {
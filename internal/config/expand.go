@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// referencePattern matches ${...} references and the $${ escape sequence.
+var referencePattern = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+// variableNamePattern matches valid environment variable names.
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExpansionError lists every reference in a config document that could not be resolved
+type ExpansionError struct {
+	Path      string
+	Undefined []string
+	Problems  []string
+}
+
+func (e *ExpansionError) Error() string {
+	var parts []string
+	if len(e.Undefined) > 0 {
+		parts = append(parts, fmt.Sprintf("undefined variables: %s (set them in the environment or use ${VAR:-default})", strings.Join(e.Undefined, ", ")))
+	}
+	parts = append(parts, e.Problems...)
+	return fmt.Sprintf("%s: %s", e.Path, strings.Join(parts, "; "))
+}
+
+// reference is a scalar value that was written with ${...} references, along
+// with what it expanded to
+type reference struct {
+	raw      string
+	expanded string
+}
+
+// references maps the field path of every expanded value to its reference, so
+// a saved config keeps "${file:token}" instead of the token itself
+type references map[string]reference
+
+// move re-keys the reference of a field that was moved to another path
+func (r references) move(from, to string) {
+	if ref, ok := r[from]; ok {
+		delete(r, from)
+		r[to] = ref
+	}
+}
+
+// restore writes the references back into an encoded document, wherever the
+// value is still the one the reference expanded to
+func (r references) restore(n *yaml.Node, path string) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			r.restore(c, path)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			r.restore(c, itemPath(path, i, c))
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			r.restore(n.Content[i+1], fieldPath(path, n.Content[i].Value))
+		}
+	case yaml.ScalarNode:
+		if ref, ok := r[path]; ok && n.Value == ref.expanded {
+			n.Value = ref.raw
+			n.Tag = "!!str"
+			n.Style = 0
+		}
+	}
+}
+
+// fieldPath returns the path of a mapping key below path
+func fieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// itemPath returns the path of a list item below path. Items with a name are
+// keyed by it, as overlays merge them by name.
+func itemPath(path string, index int, item *yaml.Node) string {
+	if item.Kind == yaml.MappingNode {
+		if name := mappingValue(item, "name"); name != nil && name.Kind == yaml.ScalarNode {
+			return fmt.Sprintf("%s[%s]", path, name.Value)
+		}
+	}
+	return fmt.Sprintf("%s[%d]", path, index)
+}
+
+// expander resolves references inside a single config document.
+type expander struct {
+	baseDir   string
+	undefined map[string]bool
+	problems  []string
+	refs      references
+}
+
+// expandDocument expands ${VAR}, ${VAR:-default} and ${file:path} references in every
+// scalar value of a parsed document. Relative file paths are resolved against the
+// directory containing the document. "$${" produces a literal "${". It returns the
+// references it expanded, keyed by field path.
+func expandDocument(doc *yaml.Node, path string) (references, error) {
+	e := &expander{
+		baseDir:   filepath.Dir(path),
+		undefined: map[string]bool{},
+		refs:      references{},
+	}
+	e.walk(doc, "")
+
+	if len(e.undefined) == 0 && len(e.problems) == 0 {
+		return e.refs, nil
+	}
+
+	undefined := make([]string, 0, len(e.undefined))
+	for name := range e.undefined {
+		undefined = append(undefined, name)
+	}
+	sort.Strings(undefined)
+
+	return nil, &ExpansionError{Path: path, Undefined: undefined, Problems: e.problems}
+}
+
+func (e *expander) walk(n *yaml.Node, path string) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			e.walk(c, path)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			e.walk(c, itemPath(path, i, c))
+		}
+	case yaml.MappingNode:
+		// Only values are expanded; keys are part of the schema
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			e.walk(n.Content[i+1], fieldPath(path, n.Content[i].Value))
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "${") {
+			return
+		}
+		raw := n.Value
+		n.Value = e.expand(raw)
+		e.refs[path] = reference{raw: raw, expanded: n.Value}
+		if n.Style == 0 {
+			// Let plain scalars resolve again so "${WORKERS}" can become an int
+			n.Tag = ""
+		}
+	}
+}
+
+func (e *expander) expand(value string) string {
+	return referencePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		return e.resolve(match[2 : len(match)-1])
+	})
+}
+
+func (e *expander) resolve(ref string) string {
+	if strings.HasPrefix(ref, "file:") {
+		return e.readFile(strings.TrimPrefix(ref, "file:"))
+	}
+
+	name, def, hasDefault := strings.Cut(ref, ":-")
+	if !variableNamePattern.MatchString(name) {
+		e.problems = append(e.problems, fmt.Sprintf("invalid reference ${%s}", ref))
+		return ""
+	}
+
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		return value
+	}
+	if hasDefault {
+		return def
+	}
+	if _, ok := os.LookupEnv(name); ok {
+		// Explicitly set to an empty string
+		return ""
+	}
+
+	e.undefined[name] = true
+	return ""
+}
+
+func (e *expander) readFile(path string) string {
+	if path == "" {
+		e.problems = append(e.problems, "invalid reference ${file:}: path is required")
+		return ""
+	}
+
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(e.baseDir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		e.problems = append(e.problems, fmt.Sprintf("cannot read ${file:%s}: %v", path, err))
+		return ""
+	}
+
+	return strings.TrimRight(string(data), "\r\n")
+}
@@ -7,40 +7,49 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// LoadFromFile loads cluster configuration from a YAML file
-func LoadFromFile(filepath string) (*ClusterConfig, error) {
-	data, err := os.ReadFile(filepath)
+// LoadFromFile loads cluster configuration from a YAML file, applies any overlay
+// files on top of it, fills in defaults and validates the result. Every command
+// that reads a cluster config goes through this function.
+func LoadFromFile(filepath string, overlays ...string) (*ClusterConfig, error) {
+	config, err := readConfig(filepath, overlays...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
 	}
 
-	var config ClusterConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	// Deprecated flat provider fields were moved into their section while reading
+	for _, warning := range config.deprecations {
+		fmt.Fprintf(DeprecationOutput, "Warning: %s\n", warning)
+	}
+	if err := config.checkLegacyProvider(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
 	// Apply defaults
-	applyDefaults(&config)
+	ApplyDefaults(config)
 
 	// Validate configuration
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	return &config, nil
+	return config, nil
 }
 
-// applyDefaults applies default values to the configuration
-func applyDefaults(config *ClusterConfig) {
-	// Provider defaults
-	if config.Provider.Region == "" && config.Provider.Type == "aws" {
-		config.Provider.Region = "us-east-1"
-	}
+// DefaultsFunc fills in provider-specific defaults for fields left empty in a config
+type DefaultsFunc func(config *ClusterConfig)
 
-	if config.Provider.VPC.CIDR == "" {
-		config.Provider.VPC.CIDR = "10.0.0.0/16"
-	}
+// providerDefaults holds the defaults registered by each provider, keyed by provider type
+var providerDefaults = map[string]DefaultsFunc{}
+
+// RegisterDefaults registers the default values for a provider type. Providers call
+// this from their init function so the config package does not need to know about them.
+func RegisterDefaults(providerType string, fn DefaultsFunc) {
+	providerDefaults[providerType] = fn
+}
 
+// ApplyDefaults applies provider-independent defaults followed by the defaults
+// registered for the configured provider type
+func ApplyDefaults(config *ClusterConfig) {
 	// Kubernetes defaults
 	if config.Kubernetes.Distribution == "" {
 		config.Kubernetes.Distribution = "rke2"
@@ -54,26 +63,37 @@ func applyDefaults(config *ClusterConfig) {
 		config.GitOps.Path = "clusters/production"
 	}
 
-	// Node defaults
-	if config.Nodes.ControlPlane.InstanceType == "" {
-		config.Nodes.ControlPlane.InstanceType = "t3.medium"
-	}
-
-	if config.Nodes.Workers.InstanceType == "" {
-		config.Nodes.Workers.InstanceType = "t3.large"
+	// Provider defaults
+	if fn, ok := providerDefaults[config.Provider.Type]; ok {
+		fn(config)
 	}
 }
 
 // SaveToFile saves the cluster configuration to a YAML file
 func SaveToFile(config *ClusterConfig, filepath string) error {
-	data, err := yaml.Marshal(config)
+	data, err := Marshal(config)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
 
-	if err := os.WriteFile(filepath, data, 0644); err != nil {
+	// Inline credentials may be set without references, keep them private
+	if err := os.WriteFile(filepath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
 	return nil
 }
+
+// Marshal serializes the cluster configuration, stamped with the latest format version.
+// Values loaded from ${...} references are written as the reference, not the value.
+func Marshal(config *ClusterConfig) ([]byte, error) {
+	config.SetVersion()
+
+	var node yaml.Node
+	if err := node.Encode(config); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	config.references.restore(&node, "")
+
+	return encodeDocument(&node)
+}
@@ -6,41 +6,56 @@ import (
 	"testing"
 )
 
-func TestApplyDefaults_AWSRegion(t *testing.T) {
-	cfg := &ClusterConfig{Provider: ProviderConfig{Type: "aws"}}
-	applyDefaults(cfg)
-	if cfg.Provider.Region != "us-east-1" {
-		t.Errorf("expected default AWS region 'us-east-1', got %q", cfg.Provider.Region)
+// registerTestDefaults registers defaults for a fake provider type for the duration of a test.
+func registerTestDefaults(t *testing.T, providerType string, fn DefaultsFunc) {
+	t.Helper()
+	RegisterDefaults(providerType, fn)
+	t.Cleanup(func() { delete(providerDefaults, providerType) })
+}
+
+func TestApplyDefaults_RegisteredProviderDefaults(t *testing.T) {
+	registerTestDefaults(t, "fake", func(cfg *ClusterConfig) {
+		if cfg.Provider.Region == "" {
+			cfg.Provider.Region = "fake-region-1"
+		}
+	})
+
+	cfg := &ClusterConfig{Provider: ProviderConfig{Type: "fake"}}
+	ApplyDefaults(cfg)
+	if cfg.Provider.Region != "fake-region-1" {
+		t.Errorf("expected registered default region 'fake-region-1', got %q", cfg.Provider.Region)
 	}
 }
 
-func TestApplyDefaults_NoRegionForVSphere(t *testing.T) {
-	cfg := &ClusterConfig{Provider: ProviderConfig{Type: "vsphere"}}
-	applyDefaults(cfg)
-	if cfg.Provider.Region != "" {
-		t.Errorf("expected no default region for vsphere, got %q", cfg.Provider.Region)
+func TestApplyDefaults_OnlyConfiguredProvider(t *testing.T) {
+	registerTestDefaults(t, "fake", func(cfg *ClusterConfig) {
+		cfg.Nodes.ControlPlane.InstanceType = "fake.medium"
+	})
+
+	cfg := &ClusterConfig{Provider: ProviderConfig{Type: "other"}}
+	ApplyDefaults(cfg)
+	if cfg.Nodes.ControlPlane.InstanceType != "" {
+		t.Errorf("expected no instance type for a provider without defaults, got %q", cfg.Nodes.ControlPlane.InstanceType)
 	}
 }
 
 func TestApplyDefaults_PreservesExplicitRegion(t *testing.T) {
-	cfg := &ClusterConfig{Provider: ProviderConfig{Type: "aws", Region: "eu-west-1"}}
-	applyDefaults(cfg)
+	registerTestDefaults(t, "fake", func(cfg *ClusterConfig) {
+		if cfg.Provider.Region == "" {
+			cfg.Provider.Region = "fake-region-1"
+		}
+	})
+
+	cfg := &ClusterConfig{Provider: ProviderConfig{Type: "fake", Region: "eu-west-1"}}
+	ApplyDefaults(cfg)
 	if cfg.Provider.Region != "eu-west-1" {
 		t.Errorf("expected region to remain 'eu-west-1', got %q", cfg.Provider.Region)
 	}
 }
 
-func TestApplyDefaults_VPCCIDR(t *testing.T) {
-	cfg := &ClusterConfig{}
-	applyDefaults(cfg)
-	if cfg.Provider.VPC.CIDR != "10.0.0.0/16" {
-		t.Errorf("expected default VPC CIDR '10.0.0.0/16', got %q", cfg.Provider.VPC.CIDR)
-	}
-}
-
 func TestApplyDefaults_Distribution(t *testing.T) {
 	cfg := &ClusterConfig{}
-	applyDefaults(cfg)
+	ApplyDefaults(cfg)
 	if cfg.Kubernetes.Distribution != "rke2" {
 		t.Errorf("expected default distribution 'rke2', got %q", cfg.Kubernetes.Distribution)
 	}
@@ -48,29 +63,20 @@ func TestApplyDefaults_Distribution(t *testing.T) {
 
 func TestApplyDefaults_GitOpsBranch(t *testing.T) {
 	cfg := &ClusterConfig{}
-	applyDefaults(cfg)
+	ApplyDefaults(cfg)
 	if cfg.GitOps.Branch != "main" {
 		t.Errorf("expected default gitops branch 'main', got %q", cfg.GitOps.Branch)
 	}
 }
 
-func TestApplyDefaults_InstanceTypes(t *testing.T) {
-	cfg := &ClusterConfig{}
-	applyDefaults(cfg)
-	if cfg.Nodes.ControlPlane.InstanceType != "t3.medium" {
-		t.Errorf("expected default control plane instance type 't3.medium', got %q", cfg.Nodes.ControlPlane.InstanceType)
-	}
-	if cfg.Nodes.Workers.InstanceType != "t3.large" {
-		t.Errorf("expected default worker instance type 't3.large', got %q", cfg.Nodes.Workers.InstanceType)
-	}
-}
-
 func TestApplyDefaults_PreservesExplicitValues(t *testing.T) {
 	cfg := &ClusterConfig{
 		Provider: ProviderConfig{
-			Type:   "aws",
-			Region: "ap-southeast-1",
-			VPC:    VPCConfig{CIDR: "172.16.0.0/16"},
+			Type: "aws",
+			AWS: AWSConfig{
+				Region: "ap-southeast-1",
+				VPC:    VPCConfig{CIDR: "172.16.0.0/16"},
+			},
 		},
 		Kubernetes: KubernetesConfig{Distribution: "k3s"},
 		GitOps:     GitOpsConfig{Branch: "develop"},
@@ -79,12 +85,12 @@ func TestApplyDefaults_PreservesExplicitValues(t *testing.T) {
 			Workers:      NodeGroupConfig{InstanceType: "m5.2xlarge"},
 		},
 	}
-	applyDefaults(cfg)
-	if cfg.Provider.Region != "ap-southeast-1" {
-		t.Errorf("region should not be overwritten, got %q", cfg.Provider.Region)
+	ApplyDefaults(cfg)
+	if cfg.Provider.AWS.Region != "ap-southeast-1" {
+		t.Errorf("region should not be overwritten, got %q", cfg.Provider.AWS.Region)
 	}
-	if cfg.Provider.VPC.CIDR != "172.16.0.0/16" {
-		t.Errorf("VPC CIDR should not be overwritten, got %q", cfg.Provider.VPC.CIDR)
+	if cfg.Provider.AWS.VPC.CIDR != "172.16.0.0/16" {
+		t.Errorf("VPC CIDR should not be overwritten, got %q", cfg.Provider.AWS.VPC.CIDR)
 	}
 	if cfg.Kubernetes.Distribution != "k3s" {
 		t.Errorf("distribution should not be overwritten, got %q", cfg.Kubernetes.Distribution)
@@ -103,7 +109,8 @@ func TestApplyDefaults_PreservesExplicitValues(t *testing.T) {
 const validYAML = `name: test-cluster
 provider:
   type: aws
-  region: us-west-2
+  aws:
+    region: us-west-2
 kubernetes:
   version: "1.30"
 nodes:
@@ -127,8 +134,8 @@ func TestLoadFromFile_Valid(t *testing.T) {
 	if cfg.Name != "test-cluster" {
 		t.Errorf("expected name 'test-cluster', got %q", cfg.Name)
 	}
-	if cfg.Provider.Region != "us-west-2" {
-		t.Errorf("expected region 'us-west-2', got %q", cfg.Provider.Region)
+	if cfg.Provider.AWS.Region != "us-west-2" {
+		t.Errorf("expected region 'us-west-2', got %q", cfg.Provider.AWS.Region)
 	}
 	if cfg.Nodes.Workers.Count != 5 {
 		t.Errorf("expected 5 workers, got %d", cfg.Nodes.Workers.Count)
@@ -137,9 +144,6 @@ func TestLoadFromFile_Valid(t *testing.T) {
 	if cfg.Kubernetes.Distribution != "rke2" {
 		t.Errorf("expected default distribution 'rke2', got %q", cfg.Kubernetes.Distribution)
 	}
-	if cfg.Nodes.ControlPlane.InstanceType != "t3.medium" {
-		t.Errorf("expected default instance type 't3.medium', got %q", cfg.Nodes.ControlPlane.InstanceType)
-	}
 }
 
 func TestLoadFromFile_FileNotFound(t *testing.T) {
@@ -186,9 +190,11 @@ func TestSaveToFile(t *testing.T) {
 	cfg := &ClusterConfig{
 		Name: "save-test",
 		Provider: ProviderConfig{
-			Type:   "aws",
-			Region: "us-east-1",
-			VPC:    VPCConfig{CIDR: "10.0.0.0/16"},
+			Type: "aws",
+			AWS: AWSConfig{
+				Region: "us-east-1",
+				VPC:    VPCConfig{CIDR: "10.0.0.0/16"},
+			},
 		},
 		Kubernetes: KubernetesConfig{
 			Version:      "1.30",
@@ -221,6 +227,9 @@ func TestSaveToFile(t *testing.T) {
 	if loaded.Nodes.Workers.Count != cfg.Nodes.Workers.Count {
 		t.Errorf("worker count mismatch: expected %d, got %d", cfg.Nodes.Workers.Count, loaded.Nodes.Workers.Count)
 	}
+	if loaded.APIVersion != APIVersion {
+		t.Errorf("expected saved config to be stamped with %q, got %q", APIVersion, loaded.APIVersion)
+	}
 }
 
 func TestSaveToFile_InvalidPath(t *testing.T) {
@@ -0,0 +1,315 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIVersion is the latest cluster configuration format version
+const APIVersion = "tdls.dev/v1alpha1"
+
+// Kind is the document kind of a cluster configuration
+const Kind = "Cluster"
+
+// migration upgrades a configuration document from one format version to the next.
+// Migrations operate on the YAML node tree so that comments and key order survive.
+type migration struct {
+	from  string
+	to    string
+	apply func(doc *yaml.Node) error
+}
+
+// migrations lists every supported upgrade step in order. An empty "from" version
+// denotes the original, unversioned format.
+var migrations = []migration{
+	{from: "", to: "tdls.dev/v1alpha1", apply: migrateUnversioned},
+}
+
+// supportedVersion reports whether version is a known format version.
+func supportedVersion(version string) bool {
+	if version == APIVersion {
+		return true
+	}
+	for _, m := range migrations {
+		if m.from == version {
+			return true
+		}
+	}
+	return false
+}
+
+// upgradeDocument upgrades a parsed configuration document in place to the latest
+// format version. It returns the version the document started at.
+func upgradeDocument(doc *yaml.Node) (string, error) {
+	root := documentRoot(doc)
+	if root == nil {
+		return "", fmt.Errorf("config document is empty")
+	}
+	if root.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("config document must be a YAML mapping")
+	}
+
+	if kind := mappingValue(root, "kind"); kind != nil && kind.Value != Kind {
+		return "", fmt.Errorf("unsupported config kind %q (expected %q)", kind.Value, Kind)
+	}
+
+	original := ""
+	if v := mappingValue(root, "apiVersion"); v != nil {
+		original = v.Value
+	}
+
+	if !supportedVersion(original) {
+		return "", fmt.Errorf("unsupported config apiVersion %q (latest supported: %s)", original, APIVersion)
+	}
+
+	current := original
+	for _, m := range migrations {
+		if m.from != current {
+			continue
+		}
+		if err := m.apply(doc); err != nil {
+			return "", fmt.Errorf("failed to migrate config from %q to %q: %w", m.from, m.to, err)
+		}
+		setMappingValue(root, "apiVersion", m.to)
+		current = m.to
+	}
+
+	return original, nil
+}
+
+// migrateUnversioned upgrades documents written before apiVersion/kind existed.
+// The schema is otherwise unchanged, so only the header fields are added.
+func migrateUnversioned(doc *yaml.Node) error {
+	root := documentRoot(doc)
+	if mappingValue(root, "kind") == nil {
+		insertMappingValue(root, 0, "kind", Kind)
+	}
+	if mappingValue(root, "apiVersion") == nil {
+		insertMappingValue(root, 0, "apiVersion", APIVersion)
+	}
+	return nil
+}
+
+// Migrate upgrades a raw configuration document to the latest format version and
+// moves deprecated flat provider fields into their section, preserving comments.
+// It returns the rewritten document and the version the document was at before
+// migration; a document that needs no changes is returned as is.
+func Migrate(data []byte) ([]byte, string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, "", fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if emptyDocument(&doc) {
+		return data, APIVersion, nil
+	}
+
+	original, err := upgradeDocument(&doc)
+	if err != nil {
+		return nil, "", err
+	}
+	moved, err := moveLegacyFields(&doc, documentProviderType(&doc), nil)
+	if err != nil {
+		return nil, original, err
+	}
+	if original == APIVersion && len(moved) == 0 {
+		return data, original, nil
+	}
+
+	out, err := encodeDocument(&doc)
+	if err != nil {
+		return nil, original, err
+	}
+	return out, original, nil
+}
+
+// MigrateFile rewrites the configuration file at path with Migrate.
+// It returns the version the file was at before migration and whether it was rewritten.
+func MigrateFile(path string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	out, original, err := Migrate(data)
+	if err != nil {
+		return original, false, err
+	}
+	if bytes.Equal(out, data) {
+		return original, false, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return original, false, err
+	}
+	if err := replaceFile(path, out, info.Mode().Perm()); err != nil {
+		return original, false, fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return original, true, nil
+}
+
+// replaceFile writes data to a temporary file next to path and renames it over
+// path, so an interrupted write cannot leave a truncated config
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// encodeDocument serializes a YAML node tree using the repository's two-space indentation.
+func encodeDocument(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// emptyDocument reports whether a parsed document holds no configuration, as an
+// empty file, one with only comments, or a lone null does. It loads as defaults.
+func emptyDocument(doc *yaml.Node) bool {
+	if doc.Kind == 0 {
+		return true
+	}
+	root := documentRoot(doc)
+	return root == nil || (root.Kind == yaml.ScalarNode && root.Tag == "!!null")
+}
+
+// documentRoot returns the top-level content node of a parsed document.
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets a scalar value for key, appending the key if missing.
+func setMappingValue(m *yaml.Node, key, value string) {
+	if v := mappingValue(m, key); v != nil {
+		v.Kind = yaml.ScalarNode
+		v.Tag = "!!str"
+		v.Value = value
+		return
+	}
+	insertMappingValue(m, len(m.Content)/2, key, value)
+}
+
+// pathValue returns the node at a dotted path below a mapping, or nil.
+func pathValue(m *yaml.Node, path string) *yaml.Node {
+	for _, key := range strings.Split(path, ".") {
+		if m == nil || m.Kind != yaml.MappingNode {
+			return nil
+		}
+		m = mappingValue(m, key)
+	}
+	return m
+}
+
+// pathKey returns the key node of the value at a dotted path below a mapping.
+func pathKey(m *yaml.Node, path string) *yaml.Node {
+	parent, key := m, path
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		parent, key = pathValue(m, path[:i]), path[i+1:]
+	}
+	return parent.Content[mappingIndex(parent, key)]
+}
+
+// setPathValue sets the value at a dotted path below a mapping, creating the
+// mappings on the way. The comments of key are kept on the new key.
+func setPathValue(m *yaml.Node, path string, key, value *yaml.Node) {
+	keys := strings.Split(path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		next := mappingValue(m, k)
+		if next == nil || next.Kind != yaml.MappingNode {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			putMappingNode(m, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, next)
+		}
+		m = next
+	}
+
+	newKey := *key
+	newKey.Value = keys[len(keys)-1]
+	putMappingNode(m, &newKey, value)
+}
+
+// putMappingNode sets the value of key in a mapping, appending the pair if the key is missing.
+func putMappingNode(m, key, value *yaml.Node) {
+	if i := mappingIndex(m, key.Value); i >= 0 {
+		m.Content[i+1] = value
+		return
+	}
+	m.Content = append(m.Content, key, value)
+}
+
+// removePathValue removes the value at a dotted path below a mapping, along with
+// the parent mappings it leaves empty.
+func removePathValue(m *yaml.Node, path string) {
+	parent, key := m, path
+	i := strings.LastIndex(path, ".")
+	if i >= 0 {
+		parent, key = pathValue(m, path[:i]), path[i+1:]
+	}
+	if idx := mappingIndex(parent, key); idx >= 0 {
+		parent.Content = append(parent.Content[:idx], parent.Content[idx+2:]...)
+	}
+	if i >= 0 && len(parent.Content) == 0 {
+		removePathValue(m, path[:i])
+	}
+}
+
+// insertMappingValue inserts a scalar key/value pair at the given pair index.
+func insertMappingValue(m *yaml.Node, index int, key, value string) {
+	pair := []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: value},
+	}
+	pos := index * 2
+	// Keep a leading comment at the top of the mapping when inserting before it.
+	if pos == 0 && len(m.Content) > 0 {
+		pair[0].HeadComment = m.Content[0].HeadComment
+		m.Content[0].HeadComment = ""
+	}
+	content := make([]*yaml.Node, 0, len(m.Content)+2)
+	content = append(content, m.Content[:pos]...)
+	content = append(content, pair...)
+	content = append(content, m.Content[pos:]...)
+	m.Content = content
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const unversionedYAML = `# Production cluster
+name: legacy-cluster
+provider:
+  type: aws # keep in us-east-1
+  region: us-east-1
+kubernetes:
+  version: "1.30"
+nodes:
+  controlPlane:
+    count: 1
+`
+
+func TestMigrate_Unversioned(t *testing.T) {
+	out, original, err := Migrate([]byte(unversionedYAML))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if original != "" {
+		t.Errorf("expected original version to be empty, got %q", original)
+	}
+
+	migrated := string(out)
+	for _, s := range []string{
+		"apiVersion: " + APIVersion,
+		"kind: " + Kind,
+		"# Production cluster",
+		"# keep in us-east-1",
+		"name: legacy-cluster",
+	} {
+		if !strings.Contains(migrated, s) {
+			t.Errorf("expected migrated document to contain %q, got:\n%s", s, migrated)
+		}
+	}
+	if !strings.HasPrefix(migrated, "# Production cluster\napiVersion:") {
+		t.Errorf("expected leading comment to stay at the top, got:\n%s", migrated)
+	}
+}
+
+func TestMigrate_AlreadyLatest(t *testing.T) {
+	data := []byte("apiVersion: " + APIVersion + "\nkind: Cluster\nname: test\n")
+	out, original, err := Migrate(data)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if original != APIVersion {
+		t.Errorf("expected original version %q, got %q", APIVersion, original)
+	}
+	if string(out) != string(data) {
+		t.Errorf("expected document to be unchanged, got:\n%s", out)
+	}
+}
+
+func TestMigrate_MovesLegacyProviderFields(t *testing.T) {
+	data := []byte(`apiVersion: ` + APIVersion + `
+kind: Cluster
+name: legacy
+provider:
+  type: proxmox
+  # the hypervisor
+  node: pve
+  vlanTag: 20 # storage VLAN
+  proxmox:
+    bridge: vmbr1
+`)
+	out, _, err := Migrate(data)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	var cfg ClusterConfig
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Provider.Node != "" || cfg.Provider.VlanTag != 0 {
+		t.Errorf("expected the flat fields to be removed, got:\n%s", out)
+	}
+	if cfg.Provider.Proxmox.Node != "pve" || cfg.Provider.Proxmox.VlanTag != 20 || cfg.Provider.Proxmox.Bridge != "vmbr1" {
+		t.Errorf("expected the flat fields in provider.proxmox, got %+v", cfg.Provider.Proxmox)
+	}
+	for _, comment := range []string{"# the hypervisor", "# storage VLAN"} {
+		if !strings.Contains(string(out), comment) {
+			t.Errorf("expected comment %q to be kept, got:\n%s", comment, out)
+		}
+	}
+
+	// The rewritten document needs no further changes
+	again, _, err := Migrate(out)
+	if err != nil || string(again) != string(out) {
+		t.Errorf("expected a second migration to be a no-op, got err=%v:\n%s", err, again)
+	}
+}
+
+func TestMigrate_UnsupportedVersion(t *testing.T) {
+	_, _, err := Migrate([]byte("apiVersion: tdls.dev/v9\nkind: Cluster\n"))
+	if err == nil {
+		t.Fatal("expected error for unsupported apiVersion")
+	}
+}
+
+func TestMigrate_WrongKind(t *testing.T) {
+	_, _, err := Migrate([]byte("apiVersion: " + APIVersion + "\nkind: Deployment\n"))
+	if err == nil {
+		t.Fatal("expected error for unsupported kind")
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cluster.yaml")
+	if err := os.WriteFile(path, []byte(unversionedYAML), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	original, changed, err := MigrateFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !changed || original != "" {
+		t.Errorf("expected unversioned file to be rewritten, got changed=%v original=%q", changed, original)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("expected file mode to be preserved, got %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left behind, got %d entries", len(entries))
+	}
+
+	// A second run is a no-op
+	_, changed, err = MigrateFile(path)
+	if err != nil {
+		t.Fatalf("expected no error on second run, got: %v", err)
+	}
+	if changed {
+		t.Error("expected migrated file not to change again")
+	}
+}
+
+func TestMigrateFile_Empty(t *testing.T) {
+	for _, content := range []string{"", "# nothing yet\n", "---\n"} {
+		path := filepath.Join(t.TempDir(), "cluster.yaml")
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		_, changed, err := MigrateFile(path)
+		if err != nil || changed {
+			t.Errorf("%q: expected an empty file to be left alone, got changed=%v, %v", content, changed, err)
+		}
+	}
+}
+
+func TestReadConfig_Empty(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cluster.yaml")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	overlay := filepath.Join(dir, "overlay.yaml")
+	if err := os.WriteFile(overlay, []byte("name: prod\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := readConfig(path, overlay)
+	if err != nil {
+		t.Fatalf("expected an empty file to load, got: %v", err)
+	}
+	if cfg.Name != "prod" {
+		t.Errorf("expected the overlay to apply to an empty base, got name %q", cfg.Name)
+	}
+}
+
+func TestReadConfig_UpgradesUnversioned(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cluster.yaml")
+	if err := os.WriteFile(path, []byte(unversionedYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := readConfig(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.APIVersion != APIVersion || cfg.Kind != Kind {
+		t.Errorf("expected config to be upgraded to %s/%s, got %s/%s", APIVersion, Kind, cfg.APIVersion, cfg.Kind)
+	}
+
+	// The file on disk is left untouched
+	data, _ := os.ReadFile(path)
+	if string(data) != unversionedYAML {
+		t.Error("expected readConfig not to rewrite the file")
+	}
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readDocument reads a configuration document from disk, upgrades it to the
+// latest format version in memory and expands variable references.
+func readDocument(path string) (*yaml.Node, references, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if emptyDocument(&doc) {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
+	if _, err := upgradeDocument(&doc); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	refs, err := expandDocument(&doc, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &doc, refs, nil
+}
+
+// loadedDocument is a base configuration with its overlays applied
+type loadedDocument struct {
+	node *yaml.Node
+	refs references
+
+	// deprecations are warnings for deprecated fields, prefixed with the file that set them
+	deprecations []string
+}
+
+// loadDocument reads a base configuration and applies each overlay on top of it in
+// order. Deprecated flat provider fields are moved into their section in each file
+// before merging.
+func loadDocument(path string, overlays []string) (*loadedDocument, error) {
+	paths := append([]string{path}, overlays...)
+	docs := make([]*yaml.Node, len(paths))
+	refs := make([]references, len(paths))
+	providerType := ""
+	for i, p := range paths {
+		var err error
+		if docs[i], refs[i], err = readDocument(p); err != nil {
+			if i > 0 {
+				return nil, fmt.Errorf("failed to load overlay: %w", err)
+			}
+			return nil, err
+		}
+		if t := documentProviderType(docs[i]); t != "" {
+			providerType = t
+		}
+	}
+
+	loaded := &loadedDocument{node: docs[0], refs: references{}}
+	for i, doc := range docs {
+		warnings, err := moveLegacyFields(doc, providerType, refs[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", paths[i], err)
+		}
+		for _, warning := range warnings {
+			loaded.deprecations = append(loaded.deprecations, fmt.Sprintf("%s: %s", paths[i], warning))
+		}
+
+		if i > 0 {
+			mergeNodes(documentRoot(loaded.node), documentRoot(doc))
+		}
+		for path, ref := range refs[i] {
+			loaded.refs[path] = ref
+		}
+	}
+
+	return loaded, nil
+}
+
+// mergeNodes merges overlay into base in place using the overlay semantics:
+//
+//   - mappings are merged key by key, recursively
+//   - a null value in the overlay removes the key from the base
+//   - lists whose items are all mappings with a "name" key are merged by name:
+//     matching items are merged recursively and new items are appended
+//   - any other list, and every scalar, replaces the base value entirely
+func mergeNodes(base, overlay *yaml.Node) {
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key := overlay.Content[i]
+		value := overlay.Content[i+1]
+
+		idx := mappingIndex(base, key.Value)
+
+		if value.Tag == "!!null" {
+			if idx >= 0 {
+				base.Content = append(base.Content[:idx], base.Content[idx+2:]...)
+			}
+			continue
+		}
+
+		if idx < 0 {
+			base.Content = append(base.Content, key, value)
+			continue
+		}
+
+		existing := base.Content[idx+1]
+		switch {
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeNodes(existing, value)
+		case existing.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode && isNamedList(existing) && isNamedList(value):
+			mergeNamedLists(existing, value)
+		default:
+			// Keep the base key's comments, take everything else from the overlay.
+			base.Content[idx+1] = value
+		}
+	}
+}
+
+// mergeNamedLists merges two lists of named mappings by their "name" key.
+func mergeNamedLists(base, overlay *yaml.Node) {
+	for _, item := range overlay.Content {
+		name := mappingValue(item, "name").Value
+		merged := false
+		for _, existing := range base.Content {
+			if mappingValue(existing, "name").Value == name {
+				mergeNodes(existing, item)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			base.Content = append(base.Content, item)
+		}
+	}
+}
+
+// isNamedList reports whether every item of a sequence is a mapping with a scalar "name" key.
+func isNamedList(seq *yaml.Node) bool {
+	if len(seq.Content) == 0 {
+		return false
+	}
+	for _, item := range seq.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+		name := mappingValue(item, "name")
+		if name == nil || name.Kind != yaml.ScalarNode {
+			return false
+		}
+	}
+	return true
+}
+
+// mappingIndex returns the content index of key in a mapping node, or -1.
+func mappingIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const overlayBaseYAML = `apiVersion: tdls.dev/v1alpha1
+kind: Cluster
+name: dev
+provider:
+  type: aws
+  aws:
+    region: us-east-1
+    vpc:
+      cidr: 10.0.0.0/16
+kubernetes:
+  version: "1.30"
+nodes:
+  controlPlane:
+    count: 1
+    instanceType: t3.medium
+  workers:
+    count: 2
+    instanceType: t3.large
+components:
+  vault:
+    enabled: true
+    mode: external
+    address: https://vault.dev.example.com
+`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadConfig_Overlay(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.yaml", overlayBaseYAML)
+	prod := writeFile(t, dir, "prod.yaml", `name: prod
+nodes:
+  controlPlane:
+    count: 3
+  workers:
+    instanceType: m5.xlarge
+`)
+
+	cfg, err := readConfig(base, prod)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Name != "prod" {
+		t.Errorf("expected name 'prod', got %q", cfg.Name)
+	}
+	if cfg.Nodes.ControlPlane.Count != 3 {
+		t.Errorf("expected 3 control plane nodes, got %d", cfg.Nodes.ControlPlane.Count)
+	}
+	if cfg.Nodes.ControlPlane.InstanceType != "t3.medium" {
+		t.Errorf("expected control plane instance type from base, got %q", cfg.Nodes.ControlPlane.InstanceType)
+	}
+	if cfg.Nodes.Workers.Count != 2 {
+		t.Errorf("expected worker count from base, got %d", cfg.Nodes.Workers.Count)
+	}
+	if cfg.Nodes.Workers.InstanceType != "m5.xlarge" {
+		t.Errorf("expected worker instance type from overlay, got %q", cfg.Nodes.Workers.InstanceType)
+	}
+	if cfg.Provider.AWS.Region != "us-east-1" {
+		t.Errorf("expected region from base, got %q", cfg.Provider.AWS.Region)
+	}
+}
+
+func TestReadConfig_OverlaysAppliedInOrder(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.yaml", overlayBaseYAML)
+	first := writeFile(t, dir, "first.yaml", "name: first\nnodes:\n  workers:\n    count: 5\n")
+	second := writeFile(t, dir, "second.yaml", "name: second\n")
+
+	cfg, err := readConfig(base, first, second)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Name != "second" {
+		t.Errorf("expected last overlay to win, got %q", cfg.Name)
+	}
+	if cfg.Nodes.Workers.Count != 5 {
+		t.Errorf("expected worker count from first overlay, got %d", cfg.Nodes.Workers.Count)
+	}
+}
+
+func TestReadConfig_OverlayNullRemovesKey(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.yaml", overlayBaseYAML)
+	overlay := writeFile(t, dir, "overlay.yaml", "components:\n  vault: null\n")
+
+	cfg, err := readConfig(base, overlay)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Components.Vault.Enabled || cfg.Components.Vault.Address != "" {
+		t.Errorf("expected vault section to be removed, got %+v", cfg.Components.Vault)
+	}
+}
+
+func TestReadConfig_MissingOverlay(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.yaml", overlayBaseYAML)
+
+	if _, err := readConfig(base, filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Fatal("expected error for missing overlay file")
+	}
+}
+
+func TestMergeNodes_Lists(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.yaml", `items:
+  - a
+  - b
+pools:
+  - name: general
+    count: 2
+  - name: gpu
+    count: 1
+`)
+	overlay := writeFile(t, dir, "overlay.yaml", `items:
+  - c
+pools:
+  - name: gpu
+    count: 4
+  - name: batch
+    count: 3
+`)
+
+	loaded, err := loadDocument(base, []string{overlay})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	doc := loaded.node
+
+	var out struct {
+		Items []string `yaml:"items"`
+		Pools []struct {
+			Name  string `yaml:"name"`
+			Count int    `yaml:"count"`
+		} `yaml:"pools"`
+	}
+	if err := doc.Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out.Items) != 1 || out.Items[0] != "c" {
+		t.Errorf("expected plain list to be replaced, got %v", out.Items)
+	}
+
+	counts := map[string]int{}
+	for _, p := range out.Pools {
+		counts[p.Name] = p.Count
+	}
+	if len(out.Pools) != 3 || counts["general"] != 2 || counts["gpu"] != 4 || counts["batch"] != 3 {
+		t.Errorf("expected named list to be merged by name, got %+v", out.Pools)
+	}
+}
+
+func TestLoadFromFile_OverlayAppliesDefaults(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.yaml", overlayBaseYAML)
+	overlay := writeFile(t, dir, "overlay.yaml", "kubernetes:\n  distribution: null\n")
+
+	cfg, err := LoadFromFile(base, overlay)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Kubernetes.Distribution != "rke2" {
+		t.Errorf("expected default distribution after overlay, got %q", cfg.Kubernetes.Distribution)
+	}
+}
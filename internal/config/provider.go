@@ -0,0 +1,328 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	vpcIDPattern       = regexp.MustCompile(`^vpc-[0-9a-f]{8,17}$`)
+	subnetIDPattern    = regexp.MustCompile(`^subnet-[0-9a-f]{8,17}$`)
+	roleARNPattern     = regexp.MustCompile(`^arn:aws[a-z-]*:iam::[0-9]{12}:role/[\w+=,.@/-]+$`)
+	sessionNamePattern = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+)
+
+// DeprecationOutput receives warnings about deprecated settings found while loading configs
+var DeprecationOutput io.Writer = os.Stderr
+
+// pluginTypes holds the provider types implemented by out-of-process plugins
+var pluginTypes = map[string]bool{}
+
+// RegisterPluginType makes a plugin provider type valid in cluster configs. The
+// provider package calls this for every plugin it discovers.
+func RegisterPluginType(providerType string) {
+	pluginTypes[providerType] = true
+}
+
+// PluginTypes returns the plugin provider types, sorted
+func PluginTypes() []string {
+	types := make([]string, 0, len(pluginTypes))
+	for t := range pluginTypes {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// IsPluginType reports whether a provider type is implemented by a plugin
+func IsPluginType(providerType string) bool {
+	return pluginTypes[providerType]
+}
+
+// DisplayLocation returns where the cluster runs, for display: the AWS region, the
+// Hetzner location, the Proxmox node, the vSphere datacenter or, for plugin
+// providers, the "region" or "location" setting.
+func (p *ProviderConfig) DisplayLocation() string {
+	switch p.Type {
+	case "aws":
+		return p.AWS.Region
+	case "hetzner":
+		return p.Hetzner.Location
+	case "proxmox":
+		return p.Proxmox.Node
+	case "vsphere":
+		return p.VSphere.Datacenter
+	}
+	for _, key := range []string{"region", "location"} {
+		if value, ok := p.Settings[key].(string); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// validateSections checks that only the section for the configured provider type is
+// set and validates it.
+func (p *ProviderConfig) validateSections() error {
+	sections := []struct {
+		name string
+		set  bool
+	}{
+		{"aws", !reflect.ValueOf(p.AWS).IsZero()},
+		{"hetzner", !reflect.ValueOf(p.Hetzner).IsZero()},
+		{"proxmox", p.Proxmox != ProxmoxConfig{}},
+		{"vsphere", p.VSphere != VSphereConfig{}},
+	}
+	for _, s := range sections {
+		if s.set && s.name != p.Type {
+			return &ConfigError{Message: fmt.Sprintf("provider.%s settings cannot be used with provider type '%s'", s.name, p.Type)}
+		}
+	}
+	if len(p.Settings) > 0 && !IsPluginType(p.Type) {
+		return &ConfigError{Message: fmt.Sprintf("provider.settings is only used by plugin providers; use provider.%s for provider type '%s'", p.Type, p.Type)}
+	}
+
+	switch p.Type {
+	case "aws":
+		return p.AWS.Validate()
+	case "hetzner":
+		return p.Hetzner.Validate()
+	case "proxmox":
+		return p.Proxmox.Validate()
+	}
+	return nil
+}
+
+// Validate validates the AWS settings
+func (c *AWSConfig) Validate() error {
+	if err := validateCIDR("provider.aws.vpc.cidr", c.VPC.CIDR); err != nil {
+		return err
+	}
+	if err := c.validateExistingVPC(); err != nil {
+		return err
+	}
+	if err := c.validateAssumeRole(); err != nil {
+		return err
+	}
+	if err := c.validateSettings(); err != nil {
+		return err
+	}
+	for _, cidr := range c.APIAllowedCIDRs {
+		if err := validateCIDR("provider.aws.apiAllowedCIDRs entry", cidr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateExistingVPC checks the shape of the existing VPC settings; whether the
+// VPC and subnets exist is checked by the AWS provider
+func (c *AWSConfig) validateExistingVPC() error {
+	vpc := c.VPC
+	if !vpc.Existing() {
+		if len(vpc.PublicSubnetIDs) > 0 || len(vpc.PrivateSubnetIDs) > 0 {
+			return &ConfigError{Message: "provider.aws.vpc subnet IDs require provider.aws.vpc.id"}
+		}
+		return nil
+	}
+
+	if !vpcIDPattern.MatchString(vpc.ID) {
+		return &ConfigError{Message: fmt.Sprintf("provider.aws.vpc.id %q is not a VPC ID (vpc-...)", vpc.ID)}
+	}
+	if vpc.CIDR != "" {
+		return &ConfigError{Message: "provider.aws.vpc.cidr cannot be set with provider.aws.vpc.id; the existing VPC's CIDR is used"}
+	}
+	if len(vpc.PrivateSubnetIDs) == 0 {
+		return &ConfigError{Message: "provider.aws.vpc.privateSubnetIds is required with provider.aws.vpc.id"}
+	}
+	if len(vpc.PublicSubnetIDs) == 0 && !c.Private {
+		return &ConfigError{Message: "provider.aws.vpc.publicSubnetIds is required with provider.aws.vpc.id unless provider.aws.private is set"}
+	}
+	for _, id := range append(append([]string{}, vpc.PublicSubnetIDs...), vpc.PrivateSubnetIDs...) {
+		if !subnetIDPattern.MatchString(id) {
+			return &ConfigError{Message: fmt.Sprintf("provider.aws.vpc subnet %q is not a subnet ID (subnet-...)", id)}
+		}
+	}
+	return nil
+}
+
+// validateAssumeRole checks the role ARN and the settings that only apply to it
+func (c *AWSConfig) validateAssumeRole() error {
+	if c.AssumeRoleARN == "" {
+		if c.AssumeRoleExternalID != "" || c.AssumeRoleSessionName != "" {
+			return &ConfigError{Message: "provider.aws.assumeRoleExternalId and assumeRoleSessionName require provider.aws.assumeRoleArn"}
+		}
+		return nil
+	}
+	if !roleARNPattern.MatchString(c.AssumeRoleARN) {
+		return &ConfigError{Message: fmt.Sprintf("provider.aws.assumeRoleArn %q is not an IAM role ARN (arn:aws:iam::<account>:role/<name>)", c.AssumeRoleARN)}
+	}
+	if c.AssumeRoleSessionName != "" && !sessionNamePattern.MatchString(c.AssumeRoleSessionName) {
+		return &ConfigError{Message: fmt.Sprintf("provider.aws.assumeRoleSessionName %q must be 2-64 letters, digits or +=,.@_-", c.AssumeRoleSessionName)}
+	}
+	return nil
+}
+
+// Validate validates the Hetzner settings
+func (c *HetznerConfig) Validate() error {
+	network := c.Network
+	if network.Existing() || len(network.PublicSubnetIDs) > 0 || len(network.PrivateSubnetIDs) > 0 {
+		return &ConfigError{Message: "provider.hetzner.network does not support existing networks (id, subnet IDs)"}
+	}
+	limits := []struct {
+		name  string
+		value int
+	}{
+		{"servers", c.Limits.Servers},
+		{"loadBalancers", c.Limits.LoadBalancers},
+		{"networks", c.Limits.Networks},
+	}
+	for _, l := range limits {
+		if l.value < 0 {
+			return &ConfigError{Message: fmt.Sprintf("provider.hetzner.limits.%s must not be negative, got %d", l.name, l.value)}
+		}
+	}
+	return validateCIDR("provider.hetzner.network.cidr", c.Network.CIDR)
+}
+
+// Validate validates the Proxmox settings
+func (c *ProxmoxConfig) Validate() error {
+	if c.VlanTag < 0 || c.VlanTag > 4094 {
+		return &ConfigError{Message: fmt.Sprintf("provider.proxmox.vlanTag must be between 1 and 4094, got %d", c.VlanTag)}
+	}
+	if c.VIP != "" && net.ParseIP(c.VIP) == nil {
+		return &ConfigError{Message: fmt.Sprintf("provider.proxmox.vip %q is not a valid IP address", c.VIP)}
+	}
+	return nil
+}
+
+// validateCIDR checks that an optional CIDR setting parses
+func validateCIDR(field, cidr string) error {
+	if cidr == "" {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(cidr); err != nil {
+		return &ConfigError{Message: fmt.Sprintf("%s %q is not a valid CIDR block", field, cidr)}
+	}
+	return nil
+}
+
+// legacyField is a deprecated flat provider field and the section field that replaces it
+type legacyField struct {
+	flat    string
+	section string
+}
+
+// legacyProviderFields lists the deprecated flat fields of each provider type,
+// relative to the provider section
+var legacyProviderFields = map[string][]legacyField{
+	"aws": {
+		{"region", "aws.region"},
+		{"vpc.cidr", "aws.vpc.cidr"},
+		{"vpc.id", "aws.vpc.id"},
+	},
+	"hetzner": {
+		{"location", "hetzner.location"},
+		// region was accepted as an alias for the Hetzner location
+		{"region", "hetzner.location"},
+		{"vpc.cidr", "hetzner.network.cidr"},
+	},
+	"proxmox": {
+		{"node", "proxmox.node"},
+		{"bridge", "proxmox.bridge"},
+		{"vlanTag", "proxmox.vlanTag"},
+		{"datastore", "proxmox.datastore"},
+		{"vip", "proxmox.vip"},
+	},
+	"vsphere": {
+		{"vcenter", "vsphere.vcenter"},
+		{"datacenter", "vsphere.datacenter"},
+	},
+}
+
+// documentProviderType returns provider.type of a parsed document, or "" if unset
+func documentProviderType(doc *yaml.Node) string {
+	root := documentRoot(doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return ""
+	}
+	if v := pathValue(root, "provider.type"); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+// moveLegacyFields moves the deprecated flat provider fields of a document into the
+// section for providerType, keeping their comments, and re-keys their references.
+// It returns a warning for every field it moved. Setting a flat field and its
+// section field to different values is an error.
+func moveLegacyFields(doc *yaml.Node, providerType string, refs references) ([]string, error) {
+	root := documentRoot(doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	provider := mappingValue(root, "provider")
+	if provider == nil || provider.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var warnings []string
+	for _, f := range legacyProviderFields[providerType] {
+		flat := pathValue(provider, f.flat)
+		if flat == nil || flat.Tag == "!!null" || flat.Value == "" {
+			continue
+		}
+		if section := pathValue(provider, f.section); section != nil && section.Tag != "!!null" && section.Value != "" {
+			if section.Value != flat.Value {
+				return nil, &ConfigError{Message: fmt.Sprintf("provider.%s conflicts with provider.%s; remove the deprecated provider.%s", f.flat, f.section, f.flat)}
+			}
+		} else {
+			setPathValue(provider, f.section, pathKey(provider, f.flat), flat)
+		}
+		removePathValue(provider, f.flat)
+		refs.move("provider."+f.flat, "provider."+f.section)
+		warnings = append(warnings, fmt.Sprintf("provider.%s is deprecated, use provider.%s instead", f.flat, f.section))
+	}
+	return warnings, nil
+}
+
+// checkLegacyProvider rejects deprecated flat provider fields that do not apply to
+// the provider type. The fields that do apply were moved into the provider section
+// when the config was read.
+func (c *ClusterConfig) checkLegacyProvider() error {
+	p := &c.Provider
+	if _, ok := legacyProviderFields[p.Type]; !ok {
+		// Unknown provider types are reported by Validate
+		return nil
+	}
+
+	leftovers := []struct {
+		name string
+		set  bool
+	}{
+		{"region", p.Region != ""},
+		{"location", p.Location != ""},
+		{"vpc", !reflect.ValueOf(p.VPC).IsZero()},
+		{"vcenter", p.VCenter != ""},
+		{"datacenter", p.Datacenter != ""},
+		{"node", p.Node != ""},
+		{"bridge", p.Bridge != ""},
+		{"vlanTag", p.VlanTag != 0},
+		{"datastore", p.Datastore != ""},
+		{"vip", p.VIP != ""},
+	}
+	for _, l := range leftovers {
+		if l.set {
+			return &ConfigError{Message: fmt.Sprintf("provider.%s is not supported for provider type '%s'", l.name, p.Type)}
+		}
+	}
+
+	return nil
+}
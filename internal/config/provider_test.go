@@ -0,0 +1,363 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// captureDeprecations redirects deprecation warnings into a buffer for the duration of a test.
+func captureDeprecations(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := DeprecationOutput
+	DeprecationOutput = &buf
+	t.Cleanup(func() { DeprecationOutput = previous })
+	return &buf
+}
+
+func TestLoadFromFile_LegacyProviderFields(t *testing.T) {
+	warnings := captureDeprecations(t)
+
+	dir := t.TempDir()
+	path := writeFile(t, dir, "cluster.yaml", `name: legacy
+provider:
+  type: proxmox
+  node: pve
+  bridge: vmbr1
+  vlanTag: 20
+  vip: 192.168.1.200
+kubernetes:
+  version: "1.30"
+nodes:
+  controlPlane:
+    count: 1
+`)
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Provider.Proxmox.Node != "pve" || cfg.Provider.Proxmox.Bridge != "vmbr1" || cfg.Provider.Proxmox.VlanTag != 20 || cfg.Provider.Proxmox.VIP != "192.168.1.200" {
+		t.Errorf("expected legacy fields to move into provider.proxmox, got %+v", cfg.Provider.Proxmox)
+	}
+	if cfg.Provider.Node != "" || cfg.Provider.VlanTag != 0 {
+		t.Error("expected legacy fields to be cleared after normalization")
+	}
+	if !strings.Contains(warnings.String(), "provider.bridge is deprecated, use provider.proxmox.bridge instead") {
+		t.Errorf("expected deprecation warning, got: %q", warnings.String())
+	}
+}
+
+func TestLoadFromFile_LegacyHetznerRegion(t *testing.T) {
+	captureDeprecations(t)
+
+	dir := t.TempDir()
+	path := writeFile(t, dir, "cluster.yaml", `name: legacy
+provider:
+  type: hetzner
+  region: hel1
+kubernetes:
+  version: "1.30"
+nodes:
+  controlPlane:
+    count: 1
+`)
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Provider.Hetzner.Location != "hel1" {
+		t.Errorf("expected region to be used as the Hetzner location, got %q", cfg.Provider.Hetzner.Location)
+	}
+}
+
+func TestCheckLegacyProvider_FlatFieldForOtherProvider(t *testing.T) {
+	cfg := &ClusterConfig{Provider: ProviderConfig{Type: "aws", Bridge: "vmbr0"}}
+	err := cfg.checkLegacyProvider()
+	if err == nil {
+		t.Fatal("expected error for a proxmox field on an aws cluster")
+	}
+	if !strings.Contains(err.Error(), "provider.bridge is not supported for provider type 'aws'") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+// parseDocument parses a YAML document for the node-level tests
+func parseDocument(t *testing.T, content string) *yaml.Node {
+	t.Helper()
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return &doc
+}
+
+func TestMoveLegacyFields_Conflict(t *testing.T) {
+	doc := parseDocument(t, `provider:
+  type: aws
+  region: us-east-1
+  aws:
+    region: eu-west-1
+`)
+	if _, err := moveLegacyFields(doc, "aws", nil); err == nil {
+		t.Fatal("expected error when legacy and section values conflict")
+	}
+}
+
+func TestMoveLegacyFields_NoLegacyFields(t *testing.T) {
+	doc := parseDocument(t, `provider:
+  type: aws
+  aws:
+    region: eu-west-1
+`)
+	warnings, err := moveLegacyFields(doc, "aws", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+}
+
+func TestClusterConfig_Validate_SectionForOtherProvider(t *testing.T) {
+	cfg := validConfig()
+	cfg.Provider.Proxmox.Bridge = "vmbr0"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error for provider.proxmox on an aws cluster")
+	}
+	if err.Error() != "provider.proxmox settings cannot be used with provider type 'aws'" {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestClusterConfig_Validate_SectionRules(t *testing.T) {
+	cfg := validConfig()
+	cfg.Provider.AWS.VPC.CIDR = "not-a-cidr"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for invalid VPC CIDR")
+	}
+
+	cfg = validConfig()
+	cfg.Provider = ProviderConfig{Type: "proxmox", Proxmox: ProxmoxConfig{VlanTag: 5000}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for out-of-range VLAN tag")
+	}
+}
+
+func TestAWSConfig_Validate_ExistingVPC(t *testing.T) {
+	existing := func() AWSConfig {
+		return AWSConfig{Region: "eu-west-1", VPC: VPCConfig{
+			ID:               "vpc-0123456789abcdef0",
+			PublicSubnetIDs:  []string{"subnet-0000000a"},
+			PrivateSubnetIDs: []string{"subnet-0000000b"},
+		}}
+	}
+
+	cfg := existing()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	cfg = existing()
+	cfg.VPC.PublicSubnetIDs = nil
+	cfg.Private = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a private cluster without public subnets to pass, got: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		modify func(*AWSConfig)
+		want   string
+	}{
+		{"subnets without VPC", func(c *AWSConfig) { c.VPC.ID = "" }, "subnet IDs require provider.aws.vpc.id"},
+		{"bad VPC ID", func(c *AWSConfig) { c.VPC.ID = "my-vpc" }, "is not a VPC ID"},
+		{"CIDR with VPC ID", func(c *AWSConfig) { c.VPC.CIDR = "10.0.0.0/16" }, "cannot be set with provider.aws.vpc.id"},
+		{"no private subnets", func(c *AWSConfig) { c.VPC.PrivateSubnetIDs = nil }, "privateSubnetIds is required"},
+		{"no public subnets", func(c *AWSConfig) { c.VPC.PublicSubnetIDs = nil }, "publicSubnetIds is required"},
+		{"bad subnet ID", func(c *AWSConfig) { c.VPC.PrivateSubnetIDs = []string{"subnet-xyz"} }, "is not a subnet ID"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := existing()
+			tc.modify(&cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestAWSConfig_Validate_AssumeRole(t *testing.T) {
+	cases := []struct {
+		name    string
+		aws     AWSConfig
+		wantErr string
+	}{
+		{"profile only", AWSConfig{Profile: "staging"}, ""},
+		{"role", AWSConfig{AssumeRoleARN: "arn:aws:iam::123456789012:role/deploy", AssumeRoleExternalID: "secret", AssumeRoleSessionName: "ci@example.com"}, ""},
+		{"role with path", AWSConfig{AssumeRoleARN: "arn:aws-us-gov:iam::123456789012:role/platform/deploy"}, ""},
+		{"bad ARN", AWSConfig{AssumeRoleARN: "arn:aws:iam::123456789012:user/alice"}, "is not an IAM role ARN"},
+		{"bad session name", AWSConfig{AssumeRoleARN: "arn:aws:iam::123456789012:role/deploy", AssumeRoleSessionName: "has spaces"}, "assumeRoleSessionName"},
+		{"external ID without role", AWSConfig{AssumeRoleExternalID: "secret"}, "require provider.aws.assumeRoleArn"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.aws.Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHetznerConfig_Validate_ExistingNetwork(t *testing.T) {
+	cfg := HetznerConfig{Network: VPCConfig{ID: "vpc-0000000a"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for an existing Hetzner network")
+	}
+}
+
+func TestHetznerConfig_Validate_Limits(t *testing.T) {
+	cfg := HetznerConfig{Limits: HetznerLimitsConfig{Servers: 10, LoadBalancers: 1}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	cfg.Limits.Networks = -1
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "provider.hetzner.limits.networks") {
+		t.Errorf("expected error for a negative network limit, got %v", err)
+	}
+}
+
+func TestMoveLegacyFields_LegacyVPCID(t *testing.T) {
+	doc := parseDocument(t, `provider:
+  type: aws
+  vpc:
+    id: vpc-0000000a
+`)
+	if _, err := moveLegacyFields(doc, "aws", nil); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	var cfg ClusterConfig
+	if err := doc.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Provider.AWS.VPC.ID != "vpc-0000000a" || cfg.Provider.VPC.ID != "" {
+		t.Errorf("expected provider.vpc.id to move into provider.aws.vpc.id, got %+v", cfg.Provider)
+	}
+	if mappingValue(documentRoot(doc).Content[1], "vpc") != nil {
+		t.Error("expected the emptied provider.vpc to be removed")
+	}
+}
+
+func TestLoadFromFile_LegacyFieldInOverlay(t *testing.T) {
+	warnings := captureDeprecations(t)
+
+	dir := t.TempDir()
+	base := writeFile(t, dir, "cluster.yaml", `name: legacy
+provider:
+  type: hetzner
+  hetzner:
+    location: fsn1
+kubernetes:
+  version: "1.30"
+nodes:
+  controlPlane:
+    count: 1
+`)
+	overlay := writeFile(t, dir, "prod.yaml", `provider:
+  location: hel1
+`)
+
+	cfg, err := LoadFromFile(base, overlay)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Provider.Hetzner.Location != "hel1" {
+		t.Errorf("expected the overlay's location to win, got %q", cfg.Provider.Hetzner.Location)
+	}
+	want := "Warning: " + overlay + ": provider.location is deprecated"
+	if !strings.Contains(warnings.String(), want) || strings.Contains(warnings.String(), base+":") {
+		t.Errorf("expected the warning to name the overlay, got: %q", warnings.String())
+	}
+}
+
+func TestProviderConfig_DisplayLocation(t *testing.T) {
+	cases := []struct {
+		provider ProviderConfig
+		want     string
+	}{
+		{ProviderConfig{Type: "aws", AWS: AWSConfig{Region: "eu-west-1"}}, "eu-west-1"},
+		{ProviderConfig{Type: "hetzner", Hetzner: HetznerConfig{Location: "nbg1"}}, "nbg1"},
+		{ProviderConfig{Type: "proxmox", Proxmox: ProxmoxConfig{Node: "pve"}}, "pve"},
+		{ProviderConfig{Type: "vsphere"}, ""},
+	}
+	for _, tc := range cases {
+		if got := tc.provider.DisplayLocation(); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.provider.Type, tc.want, got)
+		}
+	}
+}
+
+func TestLoadFromFile_PluginProvider(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "cluster.yaml", `name: plugged
+provider:
+  type: openstack-test
+  settings:
+    region: RegionOne
+    flavors: [m1.small, m1.large]
+kubernetes:
+  version: "1.30"
+nodes:
+  controlPlane:
+    count: 1
+`)
+
+	if _, err := LoadFromFile(path); err == nil {
+		t.Fatal("expected an unknown provider type to be rejected")
+	}
+
+	RegisterPluginType("openstack-test")
+	t.Cleanup(func() { delete(pluginTypes, "openstack-test") })
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Provider.Settings["region"] != "RegionOne" {
+		t.Errorf("expected settings to be kept, got %v", cfg.Provider.Settings)
+	}
+	if got := cfg.Provider.DisplayLocation(); got != "RegionOne" {
+		t.Errorf("expected location from the region setting, got %q", got)
+	}
+}
+
+func TestClusterConfig_Validate_SettingsForBuiltinProvider(t *testing.T) {
+	cfg := &ClusterConfig{
+		Name:       "test",
+		Provider:   ProviderConfig{Type: "aws", Settings: map[string]interface{}{"region": "us-east-1"}},
+		Kubernetes: KubernetesConfig{Version: "1.30"},
+		Nodes:      NodesConfig{ControlPlane: NodeGroupConfig{Count: 1}},
+	}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "provider.settings is only used by plugin providers") {
+		t.Errorf("expected settings to be rejected for a built-in provider, got %v", err)
+	}
+}
@@ -0,0 +1,338 @@
+// Package kubeconfig reads, merges and writes kubectl config files without
+// shelling out to kubectl.
+package kubeconfig
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BackupsKept is the number of timestamped backups kept next to a kubeconfig
+const BackupsKept = 5
+
+// backupTimeFormat is the timestamp suffix of backup files. It sorts
+// chronologically, and the nanoseconds keep backups made within the same second
+// apart.
+const backupTimeFormat = "20060102-150405.000000000"
+
+// Config is a kubectl config file. Fields this package does not use are kept as they are.
+type Config struct {
+	APIVersion     string                 `yaml:"apiVersion,omitempty"`
+	Kind           string                 `yaml:"kind,omitempty"`
+	Clusters       []NamedCluster         `yaml:"clusters"`
+	Contexts       []NamedContext         `yaml:"contexts"`
+	Users          []NamedUser            `yaml:"users"`
+	CurrentContext string                 `yaml:"current-context"`
+	Extra          map[string]interface{} `yaml:",inline"`
+}
+
+// NamedCluster is an entry of the clusters list
+type NamedCluster struct {
+	Name    string                 `yaml:"name"`
+	Cluster map[string]interface{} `yaml:"cluster"`
+	Extra   map[string]interface{} `yaml:",inline"`
+}
+
+// NamedUser is an entry of the users list
+type NamedUser struct {
+	Name  string                 `yaml:"name"`
+	User  map[string]interface{} `yaml:"user"`
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// NamedContext is an entry of the contexts list
+type NamedContext struct {
+	Name    string                 `yaml:"name"`
+	Context Context                `yaml:"context"`
+	Extra   map[string]interface{} `yaml:",inline"`
+}
+
+// Context binds a cluster to a user
+type Context struct {
+	Cluster   string                 `yaml:"cluster"`
+	User      string                 `yaml:"user"`
+	Namespace string                 `yaml:"namespace,omitempty"`
+	Extra     map[string]interface{} `yaml:",inline"`
+}
+
+// EntryName returns the name used for a cluster's cluster, user and context entries
+func EntryName(clusterName string) string {
+	return "tdls-" + clusterName
+}
+
+// DefaultPath returns the kubeconfig kubectl uses: the first file in $KUBECONFIG,
+// or ~/.kube/config
+func DefaultPath() (string, error) {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 && paths[0] != "" {
+		return paths[0], nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// New creates a kubeconfig with a single cluster, user and context, all named name
+func New(name string, cluster, user map[string]interface{}) *Config {
+	return &Config{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		Clusters:       []NamedCluster{{Name: name, Cluster: cluster}},
+		Users:          []NamedUser{{Name: name, User: user}},
+		Contexts:       []NamedContext{{Name: name, Context: Context{Cluster: name, User: name}}},
+		CurrentContext: name,
+	}
+}
+
+// Load reads a kubeconfig. A missing file yields an empty config.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{APIVersion: "v1", Kind: "Config"}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig %s: %w", path, err)
+	}
+	if cfg.APIVersion == "" {
+		cfg.APIVersion = "v1"
+	}
+	if cfg.Kind == "" {
+		cfg.Kind = "Config"
+	}
+	return &cfg, nil
+}
+
+// Save writes the kubeconfig with owner-only permissions. The file is replaced
+// atomically so an interrupted write cannot leave a truncated config.
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode kubeconfig: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return nil
+}
+
+// Merge adds the current context of src, with its cluster and user, under name.
+// Entries with that name are replaced. File references in src are embedded, so the
+// merged entry does not depend on where src was stored.
+func (c *Config) Merge(src *Config, name string) error {
+	ctx, cluster, user, err := src.Current()
+	if err != nil {
+		return err
+	}
+
+	c.Remove(name)
+	c.Clusters = append(c.Clusters, NamedCluster{Name: name, Cluster: cluster.Cluster, Extra: cluster.Extra})
+	c.Users = append(c.Users, NamedUser{Name: name, User: user.User, Extra: user.Extra})
+	ctx.Context.Cluster = name
+	ctx.Context.User = name
+	c.Contexts = append(c.Contexts, NamedContext{Name: name, Context: ctx.Context, Extra: ctx.Extra})
+	return nil
+}
+
+// Current returns the current context with its cluster and user. A config with a
+// single context and no current context uses that one.
+func (c *Config) Current() (NamedContext, NamedCluster, NamedUser, error) {
+	contextName := c.CurrentContext
+	if contextName == "" && len(c.Contexts) == 1 {
+		contextName = c.Contexts[0].Name
+	}
+	if contextName == "" {
+		return NamedContext{}, NamedCluster{}, NamedUser{}, fmt.Errorf("kubeconfig has %d contexts and no current context", len(c.Contexts))
+	}
+
+	ctx, ok := c.context(contextName)
+	if !ok {
+		return NamedContext{}, NamedCluster{}, NamedUser{}, fmt.Errorf("kubeconfig has no context %q", contextName)
+	}
+	cluster, ok := c.cluster(ctx.Context.Cluster)
+	if !ok {
+		return NamedContext{}, NamedCluster{}, NamedUser{}, fmt.Errorf("kubeconfig has no cluster %q", ctx.Context.Cluster)
+	}
+	user, ok := c.user(ctx.Context.User)
+	if !ok {
+		return NamedContext{}, NamedCluster{}, NamedUser{}, fmt.Errorf("kubeconfig has no user %q", ctx.Context.User)
+	}
+	return ctx, cluster, user, nil
+}
+
+// Remove deletes the cluster, user and context entries with the given name and
+// clears the current context if it pointed at them. It reports whether anything
+// was removed.
+func (c *Config) Remove(name string) bool {
+	removed := false
+
+	clusters := c.Clusters[:0]
+	for _, e := range c.Clusters {
+		if e.Name == name {
+			removed = true
+			continue
+		}
+		clusters = append(clusters, e)
+	}
+	c.Clusters = clusters
+
+	users := c.Users[:0]
+	for _, e := range c.Users {
+		if e.Name == name {
+			removed = true
+			continue
+		}
+		users = append(users, e)
+	}
+	c.Users = users
+
+	contexts := c.Contexts[:0]
+	for _, e := range c.Contexts {
+		if e.Name == name {
+			removed = true
+			continue
+		}
+		contexts = append(contexts, e)
+	}
+	c.Contexts = contexts
+
+	if c.CurrentContext == name {
+		c.CurrentContext = ""
+	}
+	return removed
+}
+
+// UseContext makes an existing context the current one
+func (c *Config) UseContext(name string) error {
+	if _, ok := c.context(name); !ok {
+		return fmt.Errorf("kubeconfig has no context %q", name)
+	}
+	c.CurrentContext = name
+	return nil
+}
+
+// Flatten replaces certificate and key file references with embedded data. Relative
+// paths are resolved against baseDir, the directory of the file they were read from.
+func (c *Config) Flatten(baseDir string) error {
+	for _, e := range c.Clusters {
+		if err := embed(e.Cluster, "certificate-authority", baseDir); err != nil {
+			return err
+		}
+	}
+	for _, e := range c.Users {
+		if err := embed(e.User, "client-certificate", baseDir); err != nil {
+			return err
+		}
+		if err := embed(e.User, "client-key", baseDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// embed replaces the file reference key with key-data
+func embed(entry map[string]interface{}, key, baseDir string) error {
+	path, ok := entry[key].(string)
+	if !ok || path == "" {
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	entry[key+"-data"] = base64.StdEncoding.EncodeToString(data)
+	delete(entry, key)
+	return nil
+}
+
+func (c *Config) context(name string) (NamedContext, bool) {
+	for _, e := range c.Contexts {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return NamedContext{}, false
+}
+
+func (c *Config) cluster(name string) (NamedCluster, bool) {
+	for _, e := range c.Clusters {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return NamedCluster{}, false
+}
+
+func (c *Config) user(name string) (NamedUser, bool) {
+	for _, e := range c.Users {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return NamedUser{}, false
+}
+
+// Backup copies the file at path to path.backup-<timestamp> and removes all but
+// the newest BackupsKept backups. It returns the backup path, or "" if there was
+// no file to back up.
+func Backup(path string, now time.Time) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	backupPath := path + ".backup-" + now.Format(backupTimeFormat)
+	if err := os.WriteFile(backupPath, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	backups, err := filepath.Glob(path + ".backup-*")
+	if err != nil {
+		return backupPath, nil
+	}
+	sort.Strings(backups)
+	for _, old := range backups[:max(len(backups)-BackupsKept, 0)] {
+		os.Remove(old)
+	}
+	return backupPath, nil
+}
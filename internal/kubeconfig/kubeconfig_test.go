@@ -0,0 +1,244 @@
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rke2Kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: default
+  cluster:
+    server: https://203.0.113.10:6443
+    certificate-authority-data: Q0EK
+contexts:
+- name: default
+  context:
+    cluster: default
+    user: default
+current-context: default
+users:
+- name: default
+  user:
+    client-certificate-data: Q0VSVAo=
+    client-key-data: S0VZCg==
+`
+
+const existingKubeconfig = `apiVersion: v1
+kind: Config
+preferences:
+  colors: true
+clusters:
+- name: other
+  cluster:
+    server: https://other:6443
+    insecure-skip-tls-verify: true
+  x-cluster-owner: platform
+contexts:
+- name: other
+  x-context-note: shared
+  context:
+    cluster: other
+    user: other
+    namespace: apps
+current-context: other
+users:
+- name: other
+  x-user-source: sso
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func load(t *testing.T, path string) *Config {
+	t.Helper()
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return cfg
+}
+
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	dst := load(t, writeFile(t, dir, "config", existingKubeconfig))
+	src := load(t, writeFile(t, dir, "rke2.yaml", rke2Kubeconfig))
+
+	if err := dst.Merge(src, "tdls-prod"); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	ctx, ok := dst.context("tdls-prod")
+	if !ok || ctx.Context.Cluster != "tdls-prod" || ctx.Context.User != "tdls-prod" {
+		t.Fatalf("expected context tdls-prod pointing at renamed entries, got %+v", ctx)
+	}
+	cluster, _ := dst.cluster("tdls-prod")
+	if cluster.Cluster["server"] != "https://203.0.113.10:6443" {
+		t.Errorf("unexpected cluster entry: %v", cluster.Cluster)
+	}
+	if _, ok := dst.user("tdls-prod"); !ok {
+		t.Error("expected user tdls-prod")
+	}
+	if dst.CurrentContext != "other" {
+		t.Errorf("expected current context to be unchanged, got %q", dst.CurrentContext)
+	}
+	if len(dst.Contexts) != 2 {
+		t.Errorf("expected 2 contexts, got %d", len(dst.Contexts))
+	}
+}
+
+func TestMerge_ReplacesExistingEntry(t *testing.T) {
+	dir := t.TempDir()
+	dst := load(t, writeFile(t, dir, "config", existingKubeconfig))
+	src := load(t, writeFile(t, dir, "rke2.yaml", rke2Kubeconfig))
+
+	if err := dst.Merge(src, "tdls-prod"); err != nil {
+		t.Fatal(err)
+	}
+	src.Clusters[0].Cluster = map[string]interface{}{"server": "https://198.51.100.7:6443"}
+	if err := dst.Merge(src, "tdls-prod"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dst.Clusters) != 2 || len(dst.Users) != 2 || len(dst.Contexts) != 2 {
+		t.Fatalf("expected entries to be replaced, got %d clusters, %d users, %d contexts", len(dst.Clusters), len(dst.Users), len(dst.Contexts))
+	}
+	cluster, _ := dst.cluster("tdls-prod")
+	if cluster.Cluster["server"] != "https://198.51.100.7:6443" {
+		t.Errorf("expected the new server, got %v", cluster.Cluster["server"])
+	}
+}
+
+func TestMerge_NoCurrentContext(t *testing.T) {
+	src := &Config{Contexts: []NamedContext{{Name: "a"}, {Name: "b"}}}
+	if err := (&Config{}).Merge(src, "tdls-x"); err == nil || !strings.Contains(err.Error(), "no current context") {
+		t.Errorf("expected an error for an ambiguous source, got %v", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	dir := t.TempDir()
+	cfg := load(t, writeFile(t, dir, "config", existingKubeconfig))
+	if err := cfg.Merge(load(t, writeFile(t, dir, "rke2.yaml", rke2Kubeconfig)), "tdls-prod"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.UseContext("tdls-prod"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !cfg.Remove("tdls-prod") {
+		t.Fatal("expected entries to be removed")
+	}
+	if len(cfg.Clusters) != 1 || len(cfg.Users) != 1 || len(cfg.Contexts) != 1 || cfg.Clusters[0].Name != "other" {
+		t.Errorf("expected only the other entries to remain, got %+v", cfg)
+	}
+	if cfg.CurrentContext != "" {
+		t.Errorf("expected current context to be cleared, got %q", cfg.CurrentContext)
+	}
+	if cfg.Remove("tdls-prod") {
+		t.Error("expected nothing to remove the second time")
+	}
+}
+
+func TestUseContext_Unknown(t *testing.T) {
+	if err := (&Config{}).UseContext("missing"); err == nil {
+		t.Error("expected an error for an unknown context")
+	}
+}
+
+func TestSaveLoad_PreservesUnknownFields(t *testing.T) {
+	dir := t.TempDir()
+	cfg := load(t, writeFile(t, dir, "config", existingKubeconfig))
+
+	path := filepath.Join(dir, "with spaces", "config")
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{"colors: true", "namespace: apps", "command: aws", "insecure-skip-tls-verify: true",
+		"x-cluster-owner: platform", "x-context-note: shared", "x-user-source: sso"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected saved config to keep %q, got:\n%s", want, data)
+		}
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	cfg := load(t, filepath.Join(t.TempDir(), "config"))
+	if cfg.APIVersion != "v1" || cfg.Kind != "Config" || len(cfg.Contexts) != 0 {
+		t.Errorf("expected an empty config, got %+v", cfg)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "ca.crt", "CA")
+	cfg := &Config{
+		Clusters: []NamedCluster{{Name: "c", Cluster: map[string]interface{}{"certificate-authority": "ca.crt"}}},
+		Users:    []NamedUser{{Name: "u", User: map[string]interface{}{"token": "abc"}}},
+	}
+
+	if err := cfg.Flatten(dir); err != nil {
+		t.Fatalf("Flatten failed: %v", err)
+	}
+	if cfg.Clusters[0].Cluster["certificate-authority-data"] != "Q0E=" {
+		t.Errorf("expected embedded CA, got %v", cfg.Clusters[0].Cluster)
+	}
+	if _, ok := cfg.Clusters[0].Cluster["certificate-authority"]; ok {
+		t.Error("expected the file reference to be removed")
+	}
+}
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+
+	if backup, err := Backup(path, time.Now()); err != nil || backup != "" {
+		t.Errorf("expected no backup for a missing file, got %q, %v", backup, err)
+	}
+
+	writeFile(t, dir, "config", "v1")
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	var last string
+	for i := 0; i < BackupsKept+2; i++ {
+		backup, err := Backup(path, start.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		last = backup
+	}
+
+	if filepath.Base(last) != "config.backup-20261018-120006.000000000" {
+		t.Errorf("unexpected backup name %s", last)
+	}
+	backups, _ := filepath.Glob(path + ".backup-*")
+	if len(backups) != BackupsKept {
+		t.Errorf("expected %d backups to be kept, got %d", BackupsKept, len(backups))
+	}
+	if filepath.Base(backups[0]) != "config.backup-20261018-120002.000000000" {
+		t.Errorf("expected the oldest backups to be removed, oldest is %s", backups[0])
+	}
+}
@@ -8,9 +8,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
-	"time"
 
 	"github.com/user/tdls-easy-k8s/internal/config"
 )
@@ -52,6 +52,48 @@ var awsRegions = map[string]bool{
 // instanceTypePattern matches AWS EC2 instance type names (e.g., t3.medium, m5.xlarge, c6i.2xlarge).
 var instanceTypePattern = regexp.MustCompile(`^[a-z][a-z0-9]*\.[a-z0-9]+$`)
 
+func init() {
+	Register(Registration{
+		Name:        "aws",
+		Description: "Amazon Web Services (EC2, NLB, S3)",
+		New:         func() Provider { return NewAWSProvider() },
+		Capabilities: Capabilities{
+			IngressLoadBalancer: true,
+			SpotInstances:       true,
+			SSM:                 true,
+			ObjectStorage:       true,
+			Autoscaling:         true,
+		},
+		Resources: []string{
+			"All EC2 instances (control plane and workers)",
+			"VPC and all networking components (subnets, NAT gateways, IGW)",
+			"Network Load Balancer",
+			"EBS volumes (including etcd data)",
+			"Security groups and IAM roles",
+		},
+	})
+	config.RegisterDefaults("aws", applyAWSDefaults)
+}
+
+// applyAWSDefaults fills in AWS defaults for fields left empty in the config.
+func applyAWSDefaults(cfg *config.ClusterConfig) {
+	if cfg.Provider.AWS.Region == "" {
+		cfg.Provider.AWS.Region = "us-east-1"
+	}
+	if cfg.Provider.AWS.VPC.CIDR == "" && !cfg.Provider.AWS.VPC.Existing() {
+		cfg.Provider.AWS.VPC.CIDR = "10.0.0.0/16"
+	}
+	if cfg.Provider.AWS.AssumeRoleARN != "" && cfg.Provider.AWS.AssumeRoleSessionName == "" {
+		cfg.Provider.AWS.AssumeRoleSessionName = "tdls-easy-k8s"
+	}
+	if cfg.Nodes.ControlPlane.InstanceType == "" {
+		cfg.Nodes.ControlPlane.InstanceType = "t3.medium"
+	}
+	if cfg.Nodes.Workers.InstanceType == "" {
+		cfg.Nodes.Workers.InstanceType = "t3.large"
+	}
+}
+
 // AWSProvider implements the Provider interface for AWS
 type AWSProvider struct {
 	workDir string
@@ -73,31 +115,62 @@ func (p *AWSProvider) ValidateConfig(cfg *config.ClusterConfig) error {
 		return fmt.Errorf("provider type must be 'aws'")
 	}
 
-	if cfg.Provider.Region == "" {
-		return fmt.Errorf("AWS region is required")
+	fields := []struct{ name, value string }{
+		{"provider.aws.region", cfg.Provider.AWS.Region},
+		{"nodes.controlPlane.instanceType", cfg.Nodes.ControlPlane.InstanceType},
+		{"nodes.workers.instanceType", cfg.Nodes.Workers.InstanceType},
 	}
-
-	if !awsRegions[cfg.Provider.Region] {
-		return fmt.Errorf("invalid AWS region %q", cfg.Provider.Region)
+	// An existing VPC brings its own CIDR
+	if !cfg.Provider.AWS.VPC.Existing() {
+		fields = append(fields, struct{ name, value string }{"provider.aws.vpc.cidr", cfg.Provider.AWS.VPC.CIDR})
 	}
-
-	if err := validateVPCCIDR(cfg.Provider.VPC.CIDR); err != nil {
-		return err
+	for _, f := range fields {
+		if err := p.ValidateField(f.name, f.value); err != nil {
+			return err
+		}
 	}
 
-	if err := validateInstanceType("control plane", cfg.Nodes.ControlPlane.InstanceType); err != nil {
+	// Check AWS CLI is available and credentials are configured
+	if err := checkAWSCredentials(cfg); err != nil {
 		return err
 	}
 
-	if err := validateInstanceType("worker", cfg.Nodes.Workers.InstanceType); err != nil {
-		return err
+	if cfg.Provider.AWS.VPC.Existing() {
+		warnings, err := checkExistingNetwork(cfg)
+		if err != nil {
+			return err
+		}
+		for _, warning := range warnings {
+			fmt.Printf("⚠️  %s\n", warning)
+		}
 	}
 
-	// Check AWS CLI is available and credentials are configured
-	if err := checkAWSCredentials(); err != nil {
-		return err
+	return nil
+}
+
+// ValidateField validates a single AWS config value.
+func (p *AWSProvider) ValidateField(field, value string) error {
+	switch field {
+	case "provider.aws.region":
+		return validateAWSRegion(value)
+	case "provider.aws.vpc.cidr":
+		return validateVPCCIDR(value)
+	case "nodes.controlPlane.instanceType":
+		return validateInstanceType("control plane", value)
+	case "nodes.workers.instanceType":
+		return validateInstanceType("worker", value)
 	}
+	return nil
+}
 
+// validateAWSRegion validates that a region is a known AWS commercial region.
+func validateAWSRegion(region string) error {
+	if region == "" {
+		return fmt.Errorf("AWS region is required")
+	}
+	if !awsRegions[region] {
+		return fmt.Errorf("invalid AWS region %q", region)
+	}
 	return nil
 }
 
@@ -148,13 +221,14 @@ func validateInstanceType(role, instanceType string) error {
 	return nil
 }
 
-// checkAWSCredentials verifies that the AWS CLI is installed and credentials are configured.
-func checkAWSCredentials() error {
-	cmd := exec.Command("aws", "sts", "get-caller-identity")
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("AWS credentials check failed: %s\nEnsure AWS CLI is installed and credentials are configured (aws configure)", strings.TrimSpace(string(output)))
+// checkAWSCredentials verifies that the AWS CLI is installed and the cluster's
+// credentials (profile or assumed role) work.
+func checkAWSCredentials(cfg *config.ClusterConfig) error {
+	if err := useAWSCredentials(cfg); err != nil {
+		return err
 	}
-	return nil
+	_, _, err := awsCallerIdentity()
+	return err
 }
 
 // CreateInfrastructure creates the AWS infrastructure for the cluster
@@ -207,16 +281,21 @@ func (p *AWSProvider) CreateInfrastructure(cfg *config.ClusterConfig) error {
 
 	fmt.Println("\n✅ Infrastructure created successfully!")
 
-	// 7. Phase 2: Update TLS certificates with NLB DNS (if NLB is enabled)
-	if err := p.updateTLSCertificatesWithNLB(cfg); err != nil {
-		fmt.Printf("\n⚠️  Warning: Failed to update TLS certificates with NLB DNS: %v\n", err)
-		fmt.Println("You can manually update certificates later if needed.")
-	}
+	if cfg.Kubernetes.APIServer.Hostname != "" {
+		// 7. The API hostname is in the certificates from the start
+		if err := p.verifyAPIHostname(cfg); err != nil {
+			return fmt.Errorf("failed to verify the API server certificate: %w", err)
+		}
+	} else {
+		// 7. Phase 2: Update TLS certificates with NLB DNS (if NLB is enabled)
+		if err := p.updateTLSCertificatesWithNLB(cfg); err != nil {
+			return fmt.Errorf("failed to update TLS certificates with NLB DNS: %w\nRe-run init to retry, or remove the infrastructure, which already exists, with: tdls-easy-k8s destroy --cluster=%s", err, cfg.Name)
+		}
 
-	// 8. Phase 3: Restart worker agents so they reconnect with updated TLS certs
-	if err := p.restartWorkerAgents(cfg); err != nil {
-		fmt.Printf("\n⚠️  Warning: Failed to restart worker agents: %v\n", err)
-		fmt.Println("You can manually restart workers: aws ssm send-command --document-name AWS-RunShellScript --parameters '{\"commands\":[\"sudo systemctl restart rke2-agent\"]}' --instance-ids <id>")
+		// 8. Phase 3: Restart worker agents so they reconnect with updated TLS certs
+		if err := p.restartWorkerAgents(cfg); err != nil {
+			return fmt.Errorf("failed to restart worker agents: %w\nRestart them with: tdls-easy-k8s exec --cluster=%s --nodes=workers -- sudo systemctl restart rke2-agent", err, cfg.Name)
+		}
 	}
 
 	fmt.Println("\n📝 Next steps:")
@@ -314,8 +393,13 @@ func (p *AWSProvider) GetStatus(cfg *config.ClusterConfig) (string, error) {
 	return "unknown", nil
 }
 
-// setupWorkingDirectory creates and sets up the working directory for the cluster
+// setupWorkingDirectory creates and sets up the working directory for the
+// cluster, and makes AWS CLI commands run with the cluster's credentials
 func (p *AWSProvider) setupWorkingDirectory(cfg *config.ClusterConfig) error {
+	if err := useAWSCredentials(cfg); err != nil {
+		return err
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return err
@@ -447,25 +531,75 @@ func (p *AWSProvider) cleanTerraformSourceFiles() error {
 func (p *AWSProvider) generateTerraformVars(cfg *config.ClusterConfig) error {
 	vars := map[string]interface{}{
 		"cluster_name":                cfg.Name,
-		"environment":                 "production",
-		"aws_region":                  cfg.Provider.Region,
-		"vpc_cidr":                    cfg.Provider.VPC.CIDR,
+		"aws_region":                  cfg.Provider.AWS.Region,
 		"control_plane_count":         cfg.Nodes.ControlPlane.Count,
 		"control_plane_instance_type": cfg.Nodes.ControlPlane.InstanceType,
 		"worker_count":                cfg.Nodes.Workers.Count,
 		"worker_instance_type":        cfg.Nodes.Workers.InstanceType,
+		"enable_spot_instances":       cfg.Nodes.Workers.Spot,
+		"worker_on_demand_base":       cfg.Nodes.Workers.OnDemandBase,
+		"worker_autoscaling_min":      cfg.Nodes.Workers.Autoscaling.Min,
+		"worker_autoscaling_max":      cfg.Nodes.Workers.Autoscaling.Max,
 		"kubernetes_version":          cfg.Kubernetes.Version,
 		"rke2_version":                p.getRKE2Version(cfg.Kubernetes.Version),
 		"kubernetes_distribution":     cfg.Kubernetes.Distribution,
 		"state_bucket":                p.getStateBucket(cfg),
 		"enable_nlb":                  true,
-		"enable_cloudwatch_logs":      true,
-		"enable_session_manager":      true,
-		"enable_encryption":           true,
-		"enable_ingress_nlb":          cfg.Components.Traefik.Enabled,
+		"nlb_internal":                cfg.Provider.AWS.Private,
+		"api_server_allowed_cidrs":    awsAPIAllowedCIDRs(cfg),
+		"enable_ingress_nlb":          ingressLoadBalancer(cfg),
 		"enable_secrets_manager":      cfg.Components.ExternalSecrets.Enabled,
+		"api_hostname":                cfg.Kubernetes.APIServer.Hostname,
+		"route53_zone_id":             cfg.Provider.AWS.Route53ZoneID,
+		"aws_profile":                 cfg.Provider.AWS.Profile,
+		"assume_role_arn":             cfg.Provider.AWS.AssumeRoleARN,
+		"assume_role_external_id":     cfg.Provider.AWS.AssumeRoleExternalID,
+		"assume_role_session_name":    cfg.Provider.AWS.AssumeRoleSessionName,
+	}
+
+	// Settings left unset keep the module defaults
+	aws := cfg.Provider.AWS
+	volumes := aws.Volumes
+	optional := map[string]interface{}{
+		"environment":                          aws.Environment,
+		"additional_tags":                      aws.Tags,
+		"ami_id":                               aws.AMI.ID,
+		"ami_owner":                            aws.AMI.Owner,
+		"ami_name_filter":                      aws.AMI.NameFilter,
+		"enable_encryption":                    aws.Encryption,
+		"enable_cloudwatch_logs":               aws.CloudWatchLogs,
+		"enable_session_manager":               aws.SessionManager,
+		"control_plane_root_volume_size":       volumes.ControlPlaneRoot.SizeGB,
+		"control_plane_root_volume_type":       volumes.ControlPlaneRoot.Type,
+		"control_plane_root_volume_iops":       volumes.ControlPlaneRoot.IOPS,
+		"control_plane_root_volume_throughput": volumes.ControlPlaneRoot.Throughput,
+		"worker_root_volume_size":              volumes.WorkerRoot.SizeGB,
+		"worker_root_volume_type":              volumes.WorkerRoot.Type,
+		"worker_root_volume_iops":              volumes.WorkerRoot.IOPS,
+		"worker_root_volume_throughput":        volumes.WorkerRoot.Throughput,
+		"etcd_volume_size":                     volumes.Etcd.SizeGB,
+		"etcd_volume_type":                     volumes.Etcd.Type,
+		"etcd_volume_iops":                     volumes.Etcd.IOPS,
+		"etcd_volume_throughput":               volumes.Etcd.Throughput,
+	}
+	for name, value := range optional {
+		if !reflect.ValueOf(value).IsZero() {
+			vars[name] = value
+		}
+	}
+
+	vpc := aws.VPC
+	if vpc.Existing() {
+		vars["existing_vpc_id"] = vpc.ID
+		vars["existing_public_subnet_ids"] = append([]string{}, vpc.PublicSubnetIDs...)
+		vars["existing_private_subnet_ids"] = vpc.PrivateSubnetIDs
+	} else {
+		vars["vpc_cidr"] = vpc.CIDR
 	}
 
+	// API server settings such as OIDC authentication
+	mergeVars(vars, rke2ServerVars(cfg))
+
 	jsonData, err := json.MarshalIndent(vars, "", "  ")
 	if err != nil {
 		return err
@@ -475,6 +609,21 @@ func (p *AWSProvider) generateTerraformVars(cfg *config.ClusterConfig) error {
 	return os.WriteFile(varFile, jsonData, 0644)
 }
 
+// awsAPIAllowedCIDRs returns the networks allowed to reach the API server: the
+// configured ones, or by default anywhere for a public cluster and the VPC for a
+// private one. An empty list stands for the VPC, whose CIDR Terraform knows.
+func awsAPIAllowedCIDRs(cfg *config.ClusterConfig) []string {
+	aws := cfg.Provider.AWS
+	switch {
+	case len(aws.APIAllowedCIDRs) > 0:
+		return aws.APIAllowedCIDRs
+	case aws.Private:
+		return []string{}
+	default:
+		return []string{"0.0.0.0/0"}
+	}
+}
+
 // runTofu executes a tofu command in the working directory
 func (p *AWSProvider) runTofu(args ...string) error {
 	cmd := exec.Command("tofu", args...)
@@ -504,6 +653,28 @@ func (p *AWSProvider) getStateBucket(cfg *config.ClusterConfig) string {
 	return fmt.Sprintf("tdls-k8s-%s-state", cfg.Name)
 }
 
+// ObjectStorageName returns the name of the S3 bucket holding the cluster's state and kubeconfig
+func (p *AWSProvider) ObjectStorageName(cfg *config.ClusterConfig) string {
+	return p.getStateBucket(cfg)
+}
+
+// DeleteObjectStorage empties and deletes the cluster's S3 bucket
+func (p *AWSProvider) DeleteObjectStorage(cfg *config.ClusterConfig) error {
+	if err := useAWSCredentials(cfg); err != nil {
+		return err
+	}
+	bucket := fmt.Sprintf("s3://%s", p.getStateBucket(cfg))
+	region := cfg.Provider.AWS.Region
+
+	// The bucket must be empty before it can be deleted; a missing bucket is not an error here
+	_ = awsCLI("s3", "rm", bucket, "--recursive", "--region", region).Run()
+
+	if output, err := awsCLI("s3", "rb", bucket, "--region", region).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete S3 bucket: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
 // fixProviderPermissions fixes execute permissions on OpenTofu provider binaries
 func (p *AWSProvider) fixProviderPermissions() error {
 	providersDir := filepath.Join(p.workDir, ".terraform", "providers")
@@ -528,12 +699,12 @@ func (p *AWSProvider) fixProviderPermissions() error {
 // createS3Bucket creates the S3 bucket for cluster state if it doesn't exist
 func (p *AWSProvider) createS3Bucket(cfg *config.ClusterConfig) error {
 	bucketName := p.getStateBucket(cfg)
-	region := cfg.Provider.Region
+	region := cfg.Provider.AWS.Region
 
 	fmt.Printf("[S3] Ensuring bucket exists: %s\n", bucketName)
 
 	// Check if bucket exists
-	checkCmd := exec.Command("aws", "s3", "ls", fmt.Sprintf("s3://%s", bucketName), "--region", region)
+	checkCmd := awsCLI("s3", "ls", fmt.Sprintf("s3://%s", bucketName), "--region", region)
 	if err := checkCmd.Run(); err == nil {
 		fmt.Printf("[S3] Bucket already exists: %s\n", bucketName)
 		return nil
@@ -541,7 +712,7 @@ func (p *AWSProvider) createS3Bucket(cfg *config.ClusterConfig) error {
 
 	// Create bucket
 	fmt.Printf("[S3] Creating bucket: %s\n", bucketName)
-	createCmd := exec.Command("aws", "s3", "mb", fmt.Sprintf("s3://%s", bucketName), "--region", region)
+	createCmd := awsCLI("s3", "mb", fmt.Sprintf("s3://%s", bucketName), "--region", region)
 	createCmd.Stdout = os.Stdout
 	createCmd.Stderr = os.Stderr
 	if err := createCmd.Run(); err != nil {
@@ -550,7 +721,7 @@ func (p *AWSProvider) createS3Bucket(cfg *config.ClusterConfig) error {
 
 	// Enable encryption
 	fmt.Printf("[S3] Enabling encryption on bucket: %s\n", bucketName)
-	encryptCmd := exec.Command("aws", "s3api", "put-bucket-encryption",
+	encryptCmd := awsCLI("s3api", "put-bucket-encryption",
 		"--bucket", bucketName,
 		"--server-side-encryption-configuration", `{"Rules":[{"ApplyServerSideEncryptionByDefault":{"SSEAlgorithm":"AES256"},"BucketKeyEnabled":true}]}`,
 		"--region", region)
@@ -562,7 +733,7 @@ func (p *AWSProvider) createS3Bucket(cfg *config.ClusterConfig) error {
 
 	// Enable versioning
 	fmt.Printf("[S3] Enabling versioning on bucket: %s\n", bucketName)
-	versionCmd := exec.Command("aws", "s3api", "put-bucket-versioning",
+	versionCmd := awsCLI("s3api", "put-bucket-versioning",
 		"--bucket", bucketName,
 		"--versioning-configuration", "Status=Enabled",
 		"--region", region)
@@ -598,48 +769,41 @@ func (p *AWSProvider) getTerraformOutputJSON(outputName string) (string, error)
 	return strings.TrimSpace(string(output)), nil
 }
 
-// updateTLSCertificatesWithNLB updates RKE2 TLS certificates to include NLB DNS name
+// updateTLSCertificatesWithNLB adds the NLB DNS name to the API server certificate
+// of every control plane node. Each node checks that it serves a certificate for
+// the name afterwards, as connections through the NLB may not reach every node.
 func (p *AWSProvider) updateTLSCertificatesWithNLB(cfg *config.ClusterConfig) error {
 	fmt.Println("\n[Phase 2] Updating TLS certificates with NLB DNS...")
 
 	// Get NLB DNS name from Terraform outputs
 	nlbDNS, err := p.getTerraformOutput("nlb_dns_name")
 	if err != nil || nlbDNS == "" {
-		return fmt.Errorf("NLB not enabled or DNS not available: %w", err)
+		fmt.Println("[Phase 2] NLB not enabled, skipping")
+		return nil
 	}
 
 	fmt.Printf("[Phase 2] NLB DNS: %s\n", nlbDNS)
 
-	// Get control plane instance IDs (list output, needs JSON format)
-	controlPlaneIDs, err := p.getTerraformOutputJSON("control_plane_instance_ids")
+	instanceIDs, err := terraformOutputList(p.workDir, "control_plane_instance_ids")
 	if err != nil {
 		return fmt.Errorf("failed to get control plane instance IDs: %w", err)
 	}
-
-	// Parse instance IDs (JSON array format)
-	var instanceIDs []string
-	if err := json.Unmarshal([]byte(controlPlaneIDs), &instanceIDs); err != nil {
-		return fmt.Errorf("failed to parse instance IDs: %w", err)
-	}
-
 	if len(instanceIDs) == 0 {
 		return fmt.Errorf("no control plane instances found")
 	}
 
-	fmt.Printf("[Phase 2] Updating %d control plane nodes...\n", len(instanceIDs))
-
-	// Wait for instances to be ready for SSM
-	fmt.Println("[Phase 2] Waiting for SSM agent to be ready (30s)...")
-	cmd := exec.Command("sleep", "30")
-	cmd.Run()
+	region := cfg.Provider.AWS.Region
+	fmt.Printf("[Phase 2] Waiting for SSM agent on %d control plane nodes...\n", len(instanceIDs))
+	if err := waitForSSMAgents(instanceIDs, region, ssmAgentTimeout); err != nil {
+		return err
+	}
 
-	// Update each control plane node
-	for i, instanceID := range instanceIDs {
-		fmt.Printf("[Phase 2] Updating node %d/%d: %s\n", i+1, len(instanceIDs), instanceID)
-		if err := p.updateNodeTLSCert(instanceID, nlbDNS, cfg.Provider.Region); err != nil {
-			fmt.Printf("Warning: Failed to update node %s: %v\n", instanceID, err)
-			continue
-		}
+	fmt.Printf("[Phase 2] Updating %d control plane nodes (%d at a time)...\n", len(instanceIDs), ssmParallelism)
+	failures := forEachInstance(instanceIDs, ssmParallelism, func(instanceID string) error {
+		return p.updateNodeTLSCert(instanceID, nlbDNS, region)
+	})
+	if err := instanceFailures("update TLS certificates", failures); err != nil {
+		return err
 	}
 
 	fmt.Println("[Phase 2] ✅ TLS certificates updated successfully!")
@@ -648,12 +812,56 @@ func (p *AWSProvider) updateTLSCertificatesWithNLB(cfg *config.ClusterConfig) er
 	return nil
 }
 
-// updateNodeTLSCert updates RKE2 config on a single node and restarts the service
+// verifyAPIHostname checks that every control plane node serves a certificate for
+// the configured API hostname, which the nodes include in tls-san when they are
+// created. The check runs on each node over SSM, as connections through the NLB
+// may not reach every node.
+func (p *AWSProvider) verifyAPIHostname(cfg *config.ClusterConfig) error {
+	hostname := cfg.Kubernetes.APIServer.Hostname
+	fmt.Printf("\n[Phase 2] Verifying the API server certificate for %s...\n", hostname)
+
+	nlbDNS, err := p.getTerraformOutput("nlb_dns_name")
+	if err != nil || nlbDNS == "" {
+		fmt.Println("[Phase 2] NLB not enabled, skipping")
+		return nil
+	}
+
+	instanceIDs, err := terraformOutputList(p.workDir, "control_plane_instance_ids")
+	if err != nil {
+		return fmt.Errorf("failed to get control plane instance IDs: %w", err)
+	}
+	instanceIDs = nonEmpty(instanceIDs)
+	if len(instanceIDs) == 0 {
+		return fmt.Errorf("no control plane instance IDs in Terraform outputs")
+	}
+
+	region := cfg.Provider.AWS.Region
+	if err := waitForSSMAgents(instanceIDs, region, ssmAgentTimeout); err != nil {
+		return err
+	}
+	failures := forEachInstance(instanceIDs, ssmParallelism, func(instanceID string) error {
+		return checkNodeCertificate(instanceID, hostname, region)
+	})
+	if err := instanceFailures("verify the API server certificate", failures); err != nil {
+		return err
+	}
+	fmt.Printf("[Phase 2] ✅ API server certificate is valid for %s\n", hostname)
+
+	if cfg.Provider.AWS.Route53ZoneID == "" {
+		fmt.Printf("[Phase 2] Point %s at %s (e.g. a CNAME record) to reach the cluster\n", hostname, nlbDNS)
+	}
+	return nil
+}
+
+// updateNodeTLSCert updates RKE2 config on a single node, restarts the service and
+// checks that the node serves a certificate for the NLB DNS name
 func (p *AWSProvider) updateNodeTLSCert(instanceID, nlbDNS, region string) error {
-	// Create update script
 	updateScript := fmt.Sprintf(`#!/bin/bash
 set -e
 
+echo "Waiting for cloud-init to finish..."
+cloud-init status --wait >/dev/null || true
+
 echo "Backing up RKE2 config..."
 sudo cp /etc/rancher/rke2/config.yaml /etc/rancher/rke2/config.yaml.backup
 
@@ -670,135 +878,80 @@ echo "Restarting RKE2 to regenerate certificates..."
 sudo systemctl restart rke2-server
 
 echo "Waiting for RKE2 to be ready..."
-for i in {1..60}; do
+ready=false
+for i in $(seq 1 60); do
   if sudo /var/lib/rancher/rke2/bin/kubectl --kubeconfig /etc/rancher/rke2/rke2.yaml get nodes >/dev/null 2>&1; then
-    echo "RKE2 is ready!"
+    ready=true
     break
   fi
   sleep 5
 done
+if [ "$ready" != true ]; then
+  echo "RKE2 did not become ready after the restart" >&2
+  exit 1
+fi
+`, nlbDNS, nlbDNS) + certificateCheckScript(nlbDNS, certVerifyTimeout)
 
-echo "TLS certificate update complete!"
-`, nlbDNS, nlbDNS)
-
-	// Write script to a temp file for SSM to consume
-	tmpFile, err := os.CreateTemp("", "rke2-tls-update-*.sh")
+	commandID, err := sendSSMCommand(instanceID, region, strings.Split(strings.TrimSpace(updateScript), "\n"))
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return err
 	}
-	defer os.Remove(tmpFile.Name())
 
-	if _, err := tmpFile.WriteString(updateScript); err != nil {
-		tmpFile.Close()
-		return fmt.Errorf("failed to write temp file: %w", err)
-	}
-	tmpFile.Close()
-
-	// Build JSON parameters with the script commands as an array of strings
-	lines := strings.Split(strings.TrimSpace(updateScript), "\n")
-	jsonLines, _ := json.Marshal(lines)
-	params := fmt.Sprintf(`{"commands":%s}`, string(jsonLines))
-
-	// Send command via SSM
-	cmd := exec.Command("aws", "ssm", "send-command",
-		"--document-name", "AWS-RunShellScript",
-		"--instance-ids", instanceID,
-		"--parameters", params,
-		"--region", region,
-		"--output", "text",
-		"--query", "Command.CommandId")
-
-	output, err := cmd.Output()
+	fmt.Printf("  %s: waiting for update to complete (command: %s)...\n", instanceID, commandID)
+	inv, err := waitSSMCommand(commandID, instanceID, region)
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return fmt.Errorf("failed to send SSM command: %s", strings.TrimSpace(string(exitErr.Stderr)))
-		}
-		return fmt.Errorf("failed to send SSM command: %w", err)
+		return err
 	}
-
-	commandID := strings.TrimSpace(string(output))
-
-	// Wait for command to complete
-	fmt.Printf("  Waiting for update to complete (command: %s)...\n", commandID)
-	for i := 0; i < 60; i++ {
-		statusCmd := exec.Command("aws", "ssm", "get-command-invocation",
-			"--command-id", commandID,
-			"--instance-id", instanceID,
-			"--region", region,
-			"--query", "Status",
-			"--output", "text")
-
-		statusOutput, err := statusCmd.Output()
-		if err != nil {
-			time.Sleep(5 * time.Second)
-			continue
-		}
-
-		status := strings.TrimSpace(string(statusOutput))
-		if status == "Success" {
-			fmt.Println("  ✓ Update completed successfully")
-			return nil
-		} else if status == "Failed" || status == "Cancelled" || status == "TimedOut" {
-			return fmt.Errorf("command failed with status: %s", status)
-		}
-
-		time.Sleep(5 * time.Second)
+	if err := inv.failure(); err != nil {
+		return err
 	}
 
-	return fmt.Errorf("timeout waiting for update to complete")
+	fmt.Printf("  %s: ✓ update completed\n", instanceID)
+	return nil
 }
 
 // restartWorkerAgents restarts the RKE2 agent on all worker nodes so they
 // reconnect using the updated TLS certificates.
 func (p *AWSProvider) restartWorkerAgents(cfg *config.ClusterConfig) error {
-	workerIDsJSON, err := p.getTerraformOutputJSON("worker_instance_ids")
+	workerIDs, err := terraformOutputList(p.workDir, "worker_instance_ids")
 	if err != nil {
 		return fmt.Errorf("failed to get worker instance IDs: %w", err)
 	}
 
-	var workerIDs []string
-	if err := json.Unmarshal([]byte(workerIDsJSON), &workerIDs); err != nil {
-		return fmt.Errorf("failed to parse worker instance IDs: %w", err)
-	}
-
 	if len(workerIDs) == 0 {
 		fmt.Println("\n[Phase 3] No worker nodes to restart")
 		return nil
 	}
 
-	fmt.Printf("\n[Phase 3] Restarting RKE2 agent on %d worker nodes...\n", len(workerIDs))
-
-	// Wait for SSM agent to be available on workers
-	fmt.Println("[Phase 3] Waiting for SSM agent to be ready (30s)...")
-	time.Sleep(30 * time.Second)
-
-	for i, workerID := range workerIDs {
-		fmt.Printf("[Phase 3] Restarting worker %d/%d: %s\n", i+1, len(workerIDs), workerID)
-
-		params := `{"commands":["sudo systemctl restart rke2-agent"]}`
-		cmd := exec.Command("aws", "ssm", "send-command",
-			"--document-name", "AWS-RunShellScript",
-			"--instance-ids", workerID,
-			"--parameters", params,
-			"--region", cfg.Provider.Region,
-			"--output", "text",
-			"--query", "Command.CommandId")
+	region := cfg.Provider.AWS.Region
+	fmt.Printf("\n[Phase 3] Waiting for SSM agent on %d worker nodes...\n", len(workerIDs))
+	if err := waitForSSMAgents(workerIDs, region, ssmAgentTimeout); err != nil {
+		return err
+	}
 
-		output, err := cmd.Output()
+	fmt.Printf("[Phase 3] Restarting RKE2 agent on %d worker nodes...\n", len(workerIDs))
+	failures := forEachInstance(workerIDs, ssmParallelism, func(workerID string) error {
+		commandID, err := sendSSMCommand(workerID, region, []string{
+			"cloud-init status --wait >/dev/null || true",
+			"sudo systemctl restart rke2-agent",
+		})
 		if err != nil {
-			if exitErr, ok := err.(*exec.ExitError); ok {
-				fmt.Printf("  Warning: Failed to restart worker %s: %s\n", workerID, strings.TrimSpace(string(exitErr.Stderr)))
-			} else {
-				fmt.Printf("  Warning: Failed to restart worker %s: %v\n", workerID, err)
-			}
-			continue
+			return err
 		}
-
-		commandID := strings.TrimSpace(string(output))
-		fmt.Printf("  Sent restart command: %s\n", commandID)
+		inv, err := waitSSMCommand(commandID, workerID, region)
+		if err != nil {
+			return err
+		}
+		if err := inv.failure(); err != nil {
+			return err
+		}
+		fmt.Printf("  %s: ✓ restarted\n", workerID)
+		return nil
+	})
+	if err := instanceFailures("restart worker agents", failures); err != nil {
+		return err
 	}
 
-	fmt.Println("[Phase 3] Worker agent restart commands sent")
 	fmt.Println("[Phase 3] Workers will rejoin the cluster within 1-2 minutes")
 	return nil
 }
@@ -843,29 +996,30 @@ func (p *AWSProvider) downloadKubeconfig(cfg *config.ClusterConfig) (string, err
 
 	// Download from S3
 	s3Path := fmt.Sprintf("s3://%s/kubeconfig/%s/rke2.yaml", p.getStateBucket(cfg), cfg.Name)
-	cmd := exec.Command("aws", "s3", "cp", s3Path, tmpFile.Name(), "--region", cfg.Provider.Region)
+	cmd := awsCLI("s3", "cp", s3Path, tmpFile.Name(), "--region", cfg.Provider.AWS.Region)
 	if err := cmd.Run(); err != nil {
 		os.Remove(tmpFile.Name())
 		return "", fmt.Errorf("failed to download kubeconfig: %w", err)
 	}
 
-	// Update server URL to use NLB
-	nlbDNS, _ := p.getTerraformOutput("nlb_dns_name")
-	if nlbDNS != "" {
+	// Update server URL to use the API hostname or the NLB, or the local end of
+	// the SSM tunnel for a private cluster
+	apiHost, apiPort := cfg.Kubernetes.APIServer.Hostname, 6443
+	if cfg.Provider.AWS.Private {
+		port, err := p.openAPITunnel(cfg)
+		if err != nil {
+			os.Remove(tmpFile.Name())
+			return "", err
+		}
+		apiHost, apiPort = "127.0.0.1", port
+	}
+	if apiHost == "" {
+		apiHost, _ = p.getTerraformOutput("nlb_dns_name")
+	}
+	if apiHost != "" {
 		content, err := os.ReadFile(tmpFile.Name())
 		if err == nil {
-			// Replace private IP with NLB DNS
-			updated := strings.ReplaceAll(string(content), "https://10.0.", "https://10.0.")
-			// Find and replace the IP
-			lines := strings.Split(string(content), "\n")
-			for i, line := range lines {
-				if strings.Contains(line, "server: https://") {
-					lines[i] = fmt.Sprintf("    server: https://%s:6443", nlbDNS)
-					break
-				}
-			}
-			updated = strings.Join(lines, "\n")
-			os.WriteFile(tmpFile.Name(), []byte(updated), 0600)
+			os.WriteFile(tmpFile.Name(), []byte(setKubeconfigServer(string(content), apiHost, apiPort)), 0600)
 		}
 	}
 
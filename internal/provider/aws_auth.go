@@ -0,0 +1,201 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+)
+
+// credentialRefreshMargin is how long before they expire assumed role
+// credentials are renewed
+const credentialRefreshMargin = 5 * time.Minute
+
+// awsIdentity is who AWS CLI commands run as: a profile, a role assumed with
+// the profile's (or the ambient) credentials, or neither
+type awsIdentity struct {
+	Profile     string
+	RoleARN     string
+	ExternalID  string
+	SessionName string
+}
+
+// awsIdentityOf returns the identity configured for a cluster
+func awsIdentityOf(c config.AWSConfig) awsIdentity {
+	return awsIdentity{
+		Profile:     c.Profile,
+		RoleARN:     c.AssumeRoleARN,
+		ExternalID:  c.AssumeRoleExternalID,
+		SessionName: c.AssumeRoleSessionName,
+	}
+}
+
+// awsSession holds the environment AWS CLI commands run with
+type awsSession struct {
+	identity awsIdentity
+	ready    bool
+	env      []string  // nil: the ambient environment
+	expires  time.Time // zero unless a role was assumed
+}
+
+// expiring reports whether assumed role credentials need to be renewed
+func (s *awsSession) expiring() bool {
+	return !s.expires.IsZero() && time.Until(s.expires) < credentialRefreshMargin
+}
+
+// renew sets the session up for an identity, assuming its role if it has one
+func (s *awsSession) renew(identity awsIdentity) error {
+	var env []string
+	if identity.Profile != "" {
+		env = environWith("AWS_PROFILE=" + identity.Profile)
+	}
+
+	var expires time.Time
+	if identity.RoleARN != "" {
+		cmd := exec.Command("aws", identity.assumeRoleArgs()...)
+		cmd.Env = env
+		output, err := cmd.Output()
+		if err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				return fmt.Errorf("failed to assume role %s: %s", identity.RoleARN, strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return fmt.Errorf("failed to assume role %s: %w", identity.RoleARN, err)
+		}
+		vars, exp, err := parseAssumedRole(output)
+		if err != nil {
+			return err
+		}
+		env, expires = environWith(vars...), exp
+	}
+
+	*s = awsSession{identity: identity, ready: true, env: env, expires: expires}
+	return nil
+}
+
+var (
+	awsSessionMu sync.Mutex
+	// currentAWSSession is set by useAWSCredentials for the cluster being worked on
+	currentAWSSession awsSession
+)
+
+// useAWSCredentials makes the following AWS CLI commands run as the cluster's
+// identity
+func useAWSCredentials(cfg *config.ClusterConfig) error {
+	identity := awsIdentityOf(cfg.Provider.AWS)
+
+	awsSessionMu.Lock()
+	defer awsSessionMu.Unlock()
+	if currentAWSSession.ready && currentAWSSession.identity == identity && !currentAWSSession.expiring() {
+		return nil
+	}
+	return currentAWSSession.renew(identity)
+}
+
+// awsCLI returns an AWS CLI command running as the current cluster's identity.
+// Assumed role credentials about to expire, e.g. during a long create, are
+// renewed first.
+func awsCLI(args ...string) *exec.Cmd {
+	awsSessionMu.Lock()
+	if currentAWSSession.expiring() {
+		if err := currentAWSSession.renew(currentAWSSession.identity); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+	env := currentAWSSession.env
+	awsSessionMu.Unlock()
+
+	cmd := exec.Command("aws", args...)
+	cmd.Env = env
+	return cmd
+}
+
+// environWith returns the process environment with the given variables set.
+// Credentials set here replace the ambient ones, including AWS_PROFILE.
+func environWith(vars ...string) []string {
+	replaced := map[string]bool{"AWS_PROFILE": true}
+	for _, v := range vars {
+		key, _, _ := strings.Cut(v, "=")
+		replaced[key] = true
+	}
+
+	var env []string
+	for _, v := range os.Environ() {
+		key, _, _ := strings.Cut(v, "=")
+		if !replaced[key] {
+			env = append(env, v)
+		}
+	}
+	return append(env, vars...)
+}
+
+// assumeRoleArgs returns the AWS CLI arguments that assume the identity's role
+func (id awsIdentity) assumeRoleArgs() []string {
+	args := []string{"sts", "assume-role",
+		"--role-arn", id.RoleARN,
+		"--role-session-name", id.SessionName,
+		"--output", "json"}
+	if id.ExternalID != "" {
+		args = append(args, "--external-id", id.ExternalID)
+	}
+	return args
+}
+
+// parseAssumedRole turns `aws sts assume-role` output into the environment that
+// makes the AWS CLI use the role's credentials, and returns when they expire
+func parseAssumedRole(data []byte) ([]string, time.Time, error) {
+	var out struct {
+		Credentials struct {
+			AccessKeyID     string    `json:"AccessKeyId"`
+			SecretAccessKey string    `json:"SecretAccessKey"`
+			SessionToken    string    `json:"SessionToken"`
+			Expiration      time.Time `json:"Expiration"`
+		} `json:"Credentials"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to parse assume-role output: %w", err)
+	}
+	creds := out.Credentials
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, time.Time{}, fmt.Errorf("assume-role returned no credentials")
+	}
+
+	return []string{
+		"AWS_ACCESS_KEY_ID=" + creds.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY=" + creds.SecretAccessKey,
+		"AWS_SESSION_TOKEN=" + creds.SessionToken,
+	}, creds.Expiration, nil
+}
+
+// awsCallerIdentity returns the account and ARN AWS CLI commands run as
+func awsCallerIdentity() (account, arn string, err error) {
+	output, err := awsCLI("sts", "get-caller-identity", "--output", "json").CombinedOutput()
+	if err != nil {
+		return "", "", fmt.Errorf("AWS credentials check failed: %s\nEnsure AWS CLI is installed and credentials are configured (aws configure)", strings.TrimSpace(string(output)))
+	}
+	var identity struct {
+		Account string `json:"Account"`
+		Arn     string `json:"Arn"`
+	}
+	if err := json.Unmarshal(output, &identity); err != nil {
+		return "", "", fmt.Errorf("failed to parse get-caller-identity output: %w", err)
+	}
+	return identity.Account, identity.Arn, nil
+}
+
+// Account returns the AWS account the cluster is deployed to, with the ARN of
+// the identity deploying it
+func (p *AWSProvider) Account(cfg *config.ClusterConfig) (string, error) {
+	if err := useAWSCredentials(cfg); err != nil {
+		return "", err
+	}
+	account, arn, err := awsCallerIdentity()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%s)", account, arn), nil
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// asgInstance is a running instance of the worker Auto Scaling Group
+type asgInstance struct {
+	ID         string
+	PrivateIP  string
+	LaunchTime string
+}
+
+// autoscalingGroupInstances returns the pending and running instances of an Auto
+// Scaling Group. The Terraform outputs only hold the instances at apply time; the
+// cluster autoscaler adds and removes instances since.
+func autoscalingGroupInstances(group, region string) ([]asgInstance, error) {
+	output, err := awsCLI("ec2", "describe-instances",
+		"--region", region,
+		"--filters",
+		"Name=tag:aws:autoscaling:groupName,Values="+group,
+		"Name=instance-state-name,Values=pending,running",
+		"--query", "Reservations[].Instances[].[InstanceId,PrivateIpAddress,LaunchTime]",
+		"--output", "json").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances of %s: %s", group, strings.TrimSpace(string(output)))
+	}
+	return parseASGInstances(output)
+}
+
+// parseASGInstances decodes the describe-instances query output, oldest first
+func parseASGInstances(data []byte) ([]asgInstance, error) {
+	var rows [][]*string
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse describe-instances output: %w", err)
+	}
+
+	var instances []asgInstance
+	for _, row := range rows {
+		if len(row) < 3 || row[0] == nil {
+			continue
+		}
+		instance := asgInstance{ID: *row[0]}
+		if row[1] != nil {
+			instance.PrivateIP = *row[1]
+		}
+		if row[2] != nil {
+			instance.LaunchTime = *row[2]
+		}
+		instances = append(instances, instance)
+	}
+	sort.SliceStable(instances, func(i, j int) bool {
+		return instances[i].LaunchTime < instances[j].LaunchTime
+	})
+	return instances, nil
+}
+
+// asgNodes names the instances of the worker Auto Scaling Group by instance ID,
+// as they come and go
+func asgNodes(clusterName string, instances []asgInstance) []Node {
+	var nodes []Node
+	for _, instance := range instances {
+		nodes = append(nodes, Node{
+			Name:       fmt.Sprintf("%s-worker-%s", clusterName, instance.ID),
+			Role:       RoleWorker,
+			InstanceID: instance.ID,
+			IP:         instance.PrivateIP,
+			PrivateIP:  instance.PrivateIP,
+			Autoscaled: true,
+		})
+	}
+	return nodes
+}
@@ -0,0 +1,227 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+)
+
+// ec2Subnet is the part of `aws ec2 describe-subnets` output used here
+type ec2Subnet struct {
+	SubnetID         string   `json:"SubnetId"`
+	VpcID            string   `json:"VpcId"`
+	AvailabilityZone string   `json:"AvailabilityZone"`
+	Tags             []ec2Tag `json:"Tags"`
+}
+
+// ec2Tag is an EC2 resource tag
+type ec2Tag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+// ec2RouteTable is the part of `aws ec2 describe-route-tables` output used here
+type ec2RouteTable struct {
+	RouteTableID string `json:"RouteTableId"`
+	Associations []struct {
+		SubnetID string `json:"SubnetId"`
+		Main     bool   `json:"Main"`
+	} `json:"Associations"`
+	Routes []ec2Route `json:"Routes"`
+}
+
+// ec2Route is a route table entry
+type ec2Route struct {
+	DestinationCidrBlock string `json:"DestinationCidrBlock"`
+	GatewayID            string `json:"GatewayId"`
+	NatGatewayID         string `json:"NatGatewayId"`
+	TransitGatewayID     string `json:"TransitGatewayId"`
+	State                string `json:"State"`
+}
+
+// existingNetwork is what the AWS CLI reports about an existing VPC
+type existingNetwork struct {
+	Subnets     []ec2Subnet
+	RouteTables []ec2RouteTable
+	NatStates   map[string]string // NAT gateway ID to state
+}
+
+// awsEC2 runs an `aws ec2` command and decodes its JSON output into v
+func awsEC2(region string, v interface{}, args ...string) error {
+	args = append(append([]string{"ec2"}, args...), "--region", region, "--output", "json")
+	output, err := awsCLI(args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("aws ec2 %s failed: %s", args[1], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return fmt.Errorf("aws ec2 %s failed: %w", args[1], err)
+	}
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("failed to parse aws ec2 %s output: %w", args[1], err)
+	}
+	return nil
+}
+
+// checkExistingNetwork checks that the configured VPC and subnets exist and can
+// host the cluster. Problems that stop the cluster from working are errors;
+// missing Kubernetes tags are returned as warnings.
+func checkExistingNetwork(cfg *config.ClusterConfig) ([]string, error) {
+	vpc := cfg.Provider.AWS.VPC
+	region := cfg.Provider.AWS.Region
+
+	var vpcs struct {
+		Vpcs []struct {
+			VpcID string `json:"VpcId"`
+		} `json:"Vpcs"`
+	}
+	if err := awsEC2(region, &vpcs, "describe-vpcs", "--vpc-ids", vpc.ID); err != nil {
+		return nil, fmt.Errorf("VPC %s not found: %w", vpc.ID, err)
+	}
+
+	subnetIDs := append(append([]string{}, vpc.PublicSubnetIDs...), vpc.PrivateSubnetIDs...)
+	var subnets struct {
+		Subnets []ec2Subnet `json:"Subnets"`
+	}
+	if err := awsEC2(region, &subnets, append([]string{"describe-subnets", "--subnet-ids"}, subnetIDs...)...); err != nil {
+		return nil, err
+	}
+
+	var routeTables struct {
+		RouteTables []ec2RouteTable `json:"RouteTables"`
+	}
+	if err := awsEC2(region, &routeTables, "describe-route-tables", "--filters", "Name=vpc-id,Values="+vpc.ID); err != nil {
+		return nil, err
+	}
+
+	var nats struct {
+		NatGateways []struct {
+			NatGatewayID string `json:"NatGatewayId"`
+			State        string `json:"State"`
+		} `json:"NatGateways"`
+	}
+	if err := awsEC2(region, &nats, "describe-nat-gateways", "--filter", "Name=vpc-id,Values="+vpc.ID); err != nil {
+		return nil, err
+	}
+
+	network := existingNetwork{
+		Subnets:     subnets.Subnets,
+		RouteTables: routeTables.RouteTables,
+		NatStates:   map[string]string{},
+	}
+	for _, nat := range nats.NatGateways {
+		network.NatStates[nat.NatGatewayID] = nat.State
+	}
+	return network.check(cfg.Name, vpc)
+}
+
+// check validates the subnets of an existing VPC: they must be in the VPC,
+// public subnets must route to an internet gateway and private subnets need a
+// working default route for the nodes to download RKE2
+func (n existingNetwork) check(clusterName string, vpc config.VPCConfig) ([]string, error) {
+	byID := map[string]ec2Subnet{}
+	for _, subnet := range n.Subnets {
+		byID[subnet.SubnetID] = subnet
+	}
+
+	var warnings []string
+	zones := map[string]bool{}
+
+	check := func(id string, public bool) error {
+		subnet, ok := byID[id]
+		if !ok {
+			return fmt.Errorf("subnet %s not found", id)
+		}
+		if subnet.VpcID != vpc.ID {
+			return fmt.Errorf("subnet %s is in %s, not in VPC %s", id, subnet.VpcID, vpc.ID)
+		}
+
+		route, ok := n.defaultRoute(id)
+		switch {
+		case !ok:
+			return fmt.Errorf("subnet %s has no default route (0.0.0.0/0); nodes need outbound access to install RKE2", id)
+		case route.State == "blackhole":
+			return fmt.Errorf("the default route of subnet %s is a blackhole; its target no longer exists", id)
+		case public && !strings.HasPrefix(route.GatewayID, "igw-"):
+			return fmt.Errorf("public subnet %s does not route to an internet gateway", id)
+		case route.NatGatewayID != "" && n.NatStates[route.NatGatewayID] != "available":
+			state := n.NatStates[route.NatGatewayID]
+			if state == "" {
+				state = "not found"
+			}
+			return fmt.Errorf("NAT gateway %s used by subnet %s is %s", route.NatGatewayID, id, state)
+		}
+
+		roleTag := "kubernetes.io/role/internal-elb"
+		if public {
+			roleTag = "kubernetes.io/role/elb"
+		}
+		var missing []string
+		for _, key := range []string{roleTag, "kubernetes.io/cluster/" + clusterName} {
+			if !hasTag(subnet.Tags, key) {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			warnings = append(warnings, fmt.Sprintf("subnet %s is missing the tags %s; load balancers for Kubernetes services may not find it", id, strings.Join(missing, ", ")))
+		}
+		return nil
+	}
+
+	for _, id := range vpc.PublicSubnetIDs {
+		if err := check(id, true); err != nil {
+			return nil, err
+		}
+		zones[byID[id].AvailabilityZone] = true
+	}
+	for _, id := range vpc.PrivateSubnetIDs {
+		if err := check(id, false); err != nil {
+			return nil, err
+		}
+		if len(vpc.PublicSubnetIDs) == 0 {
+			zones[byID[id].AvailabilityZone] = true
+		}
+	}
+
+	if len(zones) == 1 {
+		warnings = append(warnings, "the control plane subnets are all in one availability zone; the cluster does not survive losing it")
+	}
+	return warnings, nil
+}
+
+// defaultRoute returns the 0.0.0.0/0 route of a subnet's route table, which is
+// the table associated with the subnet or else the VPC's main table
+func (n existingNetwork) defaultRoute(subnetID string) (ec2Route, bool) {
+	var table *ec2RouteTable
+	for i, rt := range n.RouteTables {
+		for _, assoc := range rt.Associations {
+			if assoc.SubnetID == subnetID {
+				table = &n.RouteTables[i]
+			} else if assoc.Main && table == nil {
+				table = &n.RouteTables[i]
+			}
+		}
+	}
+	if table == nil {
+		return ec2Route{}, false
+	}
+
+	for _, route := range table.Routes {
+		if route.DestinationCidrBlock == "0.0.0.0/0" {
+			return route, true
+		}
+	}
+	return ec2Route{}, false
+}
+
+// hasTag reports whether a tag key is present
+func hasTag(tags []ec2Tag, key string) bool {
+	for _, tag := range tags {
+		if tag.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,483 @@
+package provider
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+)
+
+const (
+	// ssmParallelism is how many instances are updated over SSM at once
+	ssmParallelism = 5
+	// ssmAgentTimeout bounds the wait for new instances to register with SSM
+	ssmAgentTimeout = 10 * time.Minute
+	// certVerifyTimeout bounds the wait for a node's API server to serve a
+	// certificate for the NLB DNS name or API hostname
+	certVerifyTimeout = 5 * time.Minute
+	// ssmCommandTimeout bounds the wait for a Run Command script to finish
+	ssmCommandTimeout = 15 * time.Minute
+)
+
+var ssmPollInterval = 10 * time.Second
+
+// ssmInvocation is the part of `aws ssm get-command-invocation` output used here
+type ssmInvocation struct {
+	Status                string `json:"Status"`
+	ResponseCode          int    `json:"ResponseCode"`
+	StandardOutputContent string `json:"StandardOutputContent"`
+	StandardErrorContent  string `json:"StandardErrorContent"`
+}
+
+// output returns stdout followed by stderr
+func (inv *ssmInvocation) output() []byte {
+	return []byte(inv.StandardOutputContent + inv.StandardErrorContent)
+}
+
+// done reports whether the command has reached a final status
+func (inv *ssmInvocation) done() bool {
+	switch inv.Status {
+	case "Success", "Failed", "Cancelled", "TimedOut":
+		return true
+	}
+	return false
+}
+
+// err reports a command that did not succeed
+func (inv *ssmInvocation) err() error {
+	if inv.Status == "Success" {
+		return nil
+	}
+	return fmt.Errorf("command %s with exit code %d", strings.ToLower(inv.Status), inv.ResponseCode)
+}
+
+// failure is err with the last line the command wrote to stderr, if any
+func (inv *ssmInvocation) failure() error {
+	err := inv.err()
+	if err == nil {
+		return nil
+	}
+	if line := lastLine(inv.StandardErrorContent); line != "" {
+		return fmt.Errorf("%w: %s", err, line)
+	}
+	return err
+}
+
+// sendSSMCommand starts a shell script on an instance with SSM Run Command and
+// returns the command ID
+func sendSSMCommand(instanceID, region string, commands []string) (string, error) {
+	params, err := json.Marshal(map[string][]string{"commands": commands})
+	if err != nil {
+		return "", err
+	}
+
+	cmd := awsCLI("ssm", "send-command",
+		"--document-name", "AWS-RunShellScript",
+		"--instance-ids", instanceID,
+		"--parameters", string(params),
+		"--region", region,
+		"--output", "text",
+		"--query", "Command.CommandId")
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("failed to send SSM command: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("failed to send SSM command: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// waitSSMCommand waits for a command to finish on an instance and returns its result
+func waitSSMCommand(commandID, instanceID, region string) (*ssmInvocation, error) {
+	return pollSSMCommand(func() (*ssmInvocation, error) {
+		cmd := awsCLI("ssm", "get-command-invocation",
+			"--command-id", commandID,
+			"--instance-id", instanceID,
+			"--region", region,
+			"--output", "json")
+		output, err := cmd.Output()
+		if err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				return nil, fmt.Errorf("failed to get SSM command result: %s", strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return nil, fmt.Errorf("failed to get SSM command result: %w", err)
+		}
+		return parseSSMInvocation(output)
+	}, ssmCommandTimeout)
+}
+
+// pollSSMCommand reads a command invocation until it reaches a final status. The
+// invocation may not exist for a moment after the command is sent, so errors are
+// retried until the timeout.
+func pollSSMCommand(get func() (*ssmInvocation, error), timeout time.Duration) (*ssmInvocation, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		inv, err := get()
+		if err == nil {
+			if inv.done() {
+				return inv, nil
+			}
+			err = fmt.Errorf("command still %s", strings.ToLower(inv.Status))
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for SSM command: %w", timeout, err)
+		}
+		time.Sleep(ssmPollInterval)
+	}
+}
+
+// parseSSMInvocation decodes get-command-invocation JSON output
+func parseSSMInvocation(data []byte) (*ssmInvocation, error) {
+	var inv ssmInvocation
+	if err := json.Unmarshal(data, &inv); err != nil {
+		return nil, fmt.Errorf("failed to parse SSM command result: %w", err)
+	}
+	return &inv, nil
+}
+
+// onlineSSMInstances returns which of the instances have an SSM agent that is online
+func onlineSSMInstances(instanceIDs []string, region string) (map[string]bool, error) {
+	cmd := awsCLI("ssm", "describe-instance-information",
+		"--filters", "Key=InstanceIds,Values="+strings.Join(instanceIDs, ","),
+		"--query", "InstanceInformationList[?PingStatus=='Online'].InstanceId",
+		"--region", region,
+		"--output", "json")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("failed to describe SSM instances: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to describe SSM instances: %w", err)
+	}
+
+	var ids []string
+	if err := json.Unmarshal(output, &ids); err != nil {
+		return nil, fmt.Errorf("failed to parse SSM instances: %w", err)
+	}
+	online := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		online[id] = true
+	}
+	return online, nil
+}
+
+// waitForSSMAgents waits until the SSM agent on every instance has registered and
+// is online, so Run Command can reach them
+func waitForSSMAgents(instanceIDs []string, region string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		online, err := onlineSSMInstances(instanceIDs, region)
+		var pending []string
+		if err == nil {
+			for _, id := range instanceIDs {
+				if !online[id] {
+					pending = append(pending, id)
+				}
+			}
+			if len(pending) == 0 {
+				return nil
+			}
+			err = fmt.Errorf("SSM agent not online on %s", strings.Join(pending, ", "))
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+		time.Sleep(ssmPollInterval)
+	}
+}
+
+// forEachInstance calls fn for every instance, at most limit at a time, and
+// returns the errors by instance ID
+func forEachInstance(instanceIDs []string, limit int, fn func(instanceID string) error) map[string]error {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		errs   = map[string]error{}
+		tokens = make(chan struct{}, limit)
+	)
+
+	for _, id := range instanceIDs {
+		wg.Add(1)
+		tokens <- struct{}{}
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-tokens }()
+			if err := fn(id); err != nil {
+				mu.Lock()
+				errs[id] = err
+				mu.Unlock()
+			}
+		}(id)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+// instanceFailures turns the errors from forEachInstance into a single error
+func instanceFailures(action string, failures map[string]error) error {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(failures))
+	for id := range failures {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var details []string
+	for _, id := range ids {
+		details = append(details, fmt.Sprintf("%s: %v", id, failures[id]))
+	}
+	return fmt.Errorf("failed to %s on %d node(s):\n  %s", action, len(ids), strings.Join(details, "\n  "))
+}
+
+// lastLine returns the last non-empty line of command output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// certificateCheckScript returns shell lines that wait up to timeout for the API
+// server on the node itself to present a certificate valid for name
+func certificateCheckScript(name string, timeout time.Duration) string {
+	return fmt.Sprintf(`
+echo "Checking the API server certificate for %[1]s..."
+for i in $(seq 1 %[2]d); do
+  if echo | openssl s_client -connect 127.0.0.1:6443 -servername %[1]s 2>/dev/null | openssl x509 -noout -checkhost %[1]s 2>/dev/null | grep -q "does match"; then
+    echo "API server certificate is valid for %[1]s"
+    exit 0
+  fi
+  sleep 5
+done
+echo "API server certificate on this node is not valid for %[1]s" >&2
+exit 1
+`, name, max(int(timeout/(5*time.Second)), 1))
+}
+
+// checkNodeCertificate checks over SSM that a control plane node serves a
+// certificate valid for name, once RKE2 is installed
+func checkNodeCertificate(instanceID, name, region string) error {
+	script := "#!/bin/bash\ncloud-init status --wait >/dev/null || true\n" + certificateCheckScript(name, certVerifyTimeout)
+	commandID, err := sendSSMCommand(instanceID, region, strings.Split(strings.TrimSpace(script), "\n"))
+	if err != nil {
+		return err
+	}
+	inv, err := waitSSMCommand(commandID, instanceID, region)
+	if err != nil {
+		return err
+	}
+	if err := inv.failure(); err != nil {
+		return err
+	}
+	fmt.Printf("  %s: ✓ certificate valid for %s\n", instanceID, name)
+	return nil
+}
+
+// checkCertificateName connects to addr and checks that its certificate covers
+// name. The chain is not verified; only the names the server presents matter here.
+func checkCertificateName(addr, name string) error {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+		ServerName:         name,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return fmt.Errorf("no certificate presented")
+	}
+	return certs[0].VerifyHostname(name)
+}
+
+// awsNodes names instances by their position in the control-plane and worker
+// output lists, matching their Name tags
+func awsNodes(clusterName string, cpIDs, cpPrivateIPs, cpPublicIPs, workerIDs, workerPrivateIPs []string) []Node {
+	at := func(values []string, i int) string {
+		if i < len(values) {
+			return values[i]
+		}
+		return ""
+	}
+
+	var nodes []Node
+	for i, id := range cpIDs {
+		node := Node{
+			Name:       fmt.Sprintf("%s-control-plane-%d", clusterName, i),
+			Role:       RoleControlPlane,
+			InstanceID: id,
+			IP:         at(cpPublicIPs, i),
+			PrivateIP:  at(cpPrivateIPs, i),
+		}
+		if node.IP == "" {
+			node.IP = node.PrivateIP
+		}
+		nodes = append(nodes, node)
+	}
+	for i, id := range workerIDs {
+		nodes = append(nodes, Node{
+			Name:       fmt.Sprintf("%s-worker-%d", clusterName, i),
+			Role:       RoleWorker,
+			InstanceID: id,
+			IP:         at(workerPrivateIPs, i),
+			PrivateIP:  at(workerPrivateIPs, i),
+		})
+	}
+	return nodes
+}
+
+// ListNodes returns the cluster's EC2 instances from the Terraform outputs and,
+// when autoscaled, the worker Auto Scaling Group
+func (p *AWSProvider) ListNodes(cfg *config.ClusterConfig) ([]Node, error) {
+	if err := p.setupWorkingDirectory(cfg); err != nil {
+		return nil, err
+	}
+
+	lists := map[string][]string{}
+	for _, name := range []string{
+		"control_plane_instance_ids",
+		"control_plane_private_ips",
+		"control_plane_public_ips",
+		"worker_instance_ids",
+		"worker_private_ips",
+	} {
+		values, err := terraformOutputList(p.workDir, name)
+		if err != nil {
+			return nil, err
+		}
+		lists[name] = values
+	}
+
+	// Autoscaled workers are listed from their group, which the Terraform
+	// outputs only show as of the last apply
+	group, _ := p.getTerraformOutput("worker_autoscaling_group")
+	if group == "" {
+		return awsNodes(cfg.Name,
+			lists["control_plane_instance_ids"],
+			lists["control_plane_private_ips"],
+			lists["control_plane_public_ips"],
+			lists["worker_instance_ids"],
+			lists["worker_private_ips"]), nil
+	}
+
+	instances, err := autoscalingGroupInstances(group, cfg.Provider.AWS.Region)
+	if err != nil {
+		return nil, err
+	}
+	nodes := awsNodes(cfg.Name,
+		lists["control_plane_instance_ids"],
+		lists["control_plane_private_ips"],
+		lists["control_plane_public_ips"],
+		nil, nil)
+	return append(nodes, asgNodes(cfg.Name, instances)...), nil
+}
+
+// Shell opens an SSM Session Manager session on a node. It needs the Session
+// Manager plugin for the AWS CLI.
+func (p *AWSProvider) Shell(cfg *config.ClusterConfig, node Node) error {
+	if err := useAWSCredentials(cfg); err != nil {
+		return err
+	}
+	cmd := awsCLI("ssm", "start-session",
+		"--target", node.InstanceID,
+		"--region", cfg.Provider.AWS.Region)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// CommandRunner runs commands on nodes with SSM Run Command
+func (p *AWSProvider) CommandRunner(cfg *config.ClusterConfig) (CommandRunner, error) {
+	if err := useAWSCredentials(cfg); err != nil {
+		return nil, err
+	}
+	region := cfg.Provider.AWS.Region
+	return func(node Node, command string) ([]byte, error) {
+		commandID, err := sendSSMCommand(node.InstanceID, region, []string{command})
+		if err != nil {
+			return nil, err
+		}
+		inv, err := waitSSMCommand(commandID, node.InstanceID, region)
+		if err != nil {
+			return nil, err
+		}
+		return inv.output(), inv.err()
+	}, nil
+}
+
+// awsMachines are the resources holding the cluster's instances
+var awsMachines = machineResources{
+	ControlPlane: "module.control_plane.aws_instance.control_plane",
+	Worker:       "module.worker.aws_instance.worker",
+	ControlPlaneDependents: []dependentResource{
+		{Address: "module.control_plane.aws_volume_attachment.etcd"},
+		{Address: "module.loadbalancer.aws_lb_target_group_attachment.api_server", Offset: 1},
+		{Address: "module.loadbalancer.aws_lb_target_group_attachment.rke2_register", Offset: 1},
+	},
+	WorkerDependents: []dependentResource{
+		{Address: "module.loadbalancer.aws_lb_target_group_attachment.ingress_http"},
+		{Address: "module.loadbalancer.aws_lb_target_group_attachment.ingress_https"},
+	},
+}
+
+// CanReplace checks that a node's instance can be recreated on its own
+func (p *AWSProvider) CanReplace(node Node) error {
+	return awsMachines.canReplace(node)
+}
+
+// ReplaceNode recreates a node's instance. Without an API hostname, a new control
+// plane instance also gets the NLB DNS name added to its certificate, as after
+// cluster creation.
+func (p *AWSProvider) ReplaceNode(cfg *config.ClusterConfig, node Node) error {
+	addresses, err := awsMachines.addresses(node)
+	if err != nil {
+		return err
+	}
+	if err := p.setupWorkingDirectory(cfg); err != nil {
+		return err
+	}
+	if err := replaceMachine(p.runTofu, addresses); err != nil {
+		return err
+	}
+
+	nlbDNS, _ := p.getTerraformOutput("nlb_dns_name")
+	if node.Role != RoleControlPlane || nlbDNS == "" || cfg.Kubernetes.APIServer.Hostname != "" {
+		return nil
+	}
+
+	nodes, err := p.ListNodes(cfg)
+	if err != nil {
+		return err
+	}
+	for _, replacement := range nodes {
+		if replacement.Name == node.Name {
+			region := cfg.Provider.AWS.Region
+			fmt.Println("Waiting for the SSM agent on the replacement...")
+			if err := waitForSSMAgents([]string{replacement.InstanceID}, region, ssmAgentTimeout); err != nil {
+				return err
+			}
+			// The update script checks the certificate the node serves
+			return p.updateNodeTLSCert(replacement.InstanceID, nlbDNS, region)
+		}
+	}
+	return fmt.Errorf("replacement for %s not found in the Terraform outputs", node.Name)
+}
@@ -1,9 +1,17 @@
 package provider
 
 import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/user/tdls-easy-k8s/internal/config"
 )
@@ -19,9 +27,11 @@ func TestAWSProvider_Name(t *testing.T) {
 func validAWSConfig() *config.ClusterConfig {
 	return &config.ClusterConfig{
 		Provider: config.ProviderConfig{
-			Type:   "aws",
-			Region: "us-east-1",
-			VPC:    config.VPCConfig{CIDR: "10.0.0.0/16"},
+			Type: "aws",
+			AWS: config.AWSConfig{
+				Region: "us-east-1",
+				VPC:    config.VPCConfig{CIDR: "10.0.0.0/16"},
+			},
 		},
 		Nodes: config.NodesConfig{
 			ControlPlane: config.NodeGroupConfig{Count: 3, InstanceType: "t3.medium"},
@@ -50,7 +60,7 @@ func TestAWSProvider_ValidateConfig_WrongType(t *testing.T) {
 func TestAWSProvider_ValidateConfig_MissingRegion(t *testing.T) {
 	p := NewAWSProvider()
 	cfg := validAWSConfig()
-	cfg.Provider.Region = ""
+	cfg.Provider.AWS.Region = ""
 	if err := p.ValidateConfig(cfg); err == nil {
 		t.Error("expected error for missing region")
 	}
@@ -59,12 +69,45 @@ func TestAWSProvider_ValidateConfig_MissingRegion(t *testing.T) {
 func TestAWSProvider_ValidateConfig_InvalidRegion(t *testing.T) {
 	p := NewAWSProvider()
 	cfg := validAWSConfig()
-	cfg.Provider.Region = "us-east-11"
+	cfg.Provider.AWS.Region = "us-east-11"
 	if err := p.ValidateConfig(cfg); err == nil {
 		t.Error("expected error for invalid region")
 	}
 }
 
+func TestApplyAWSDefaults(t *testing.T) {
+	cfg := &config.ClusterConfig{Provider: config.ProviderConfig{Type: "aws"}}
+	config.ApplyDefaults(cfg)
+	if cfg.Provider.AWS.Region != "us-east-1" {
+		t.Errorf("expected default AWS region 'us-east-1', got %q", cfg.Provider.AWS.Region)
+	}
+	if cfg.Provider.AWS.VPC.CIDR != "10.0.0.0/16" {
+		t.Errorf("expected default VPC CIDR '10.0.0.0/16', got %q", cfg.Provider.AWS.VPC.CIDR)
+	}
+	if cfg.Nodes.ControlPlane.InstanceType != "t3.medium" {
+		t.Errorf("expected default control plane instance type 't3.medium', got %q", cfg.Nodes.ControlPlane.InstanceType)
+	}
+	if cfg.Nodes.Workers.InstanceType != "t3.large" {
+		t.Errorf("expected default worker instance type 't3.large', got %q", cfg.Nodes.Workers.InstanceType)
+	}
+}
+
+func TestApplyAWSDefaults_AssumeRoleSessionName(t *testing.T) {
+	cfg := &config.ClusterConfig{Provider: config.ProviderConfig{Type: "aws"}}
+	config.ApplyDefaults(cfg)
+	if cfg.Provider.AWS.AssumeRoleSessionName != "" {
+		t.Errorf("expected no session name without a role, got %q", cfg.Provider.AWS.AssumeRoleSessionName)
+	}
+
+	cfg = &config.ClusterConfig{Provider: config.ProviderConfig{Type: "aws", AWS: config.AWSConfig{
+		AssumeRoleARN: "arn:aws:iam::123456789012:role/deploy",
+	}}}
+	config.ApplyDefaults(cfg)
+	if cfg.Provider.AWS.AssumeRoleSessionName != "tdls-easy-k8s" {
+		t.Errorf("expected default session name 'tdls-easy-k8s', got %q", cfg.Provider.AWS.AssumeRoleSessionName)
+	}
+}
+
 func TestValidateVPCCIDR(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -124,7 +167,7 @@ func TestAWSProvider_CreateInfrastructure_MissingName(t *testing.T) {
 	p := NewAWSProvider()
 	cfg := &config.ClusterConfig{
 		Name:     "", // Missing name should cause validation error
-		Provider: config.ProviderConfig{Type: "aws", Region: "us-east-1"},
+		Provider: config.ProviderConfig{Type: "aws", AWS: config.AWSConfig{Region: "us-east-1"}},
 		Nodes: config.NodesConfig{
 			ControlPlane: config.NodeGroupConfig{Count: 1},
 			Workers:      config.NodeGroupConfig{Count: 1},
@@ -140,7 +183,7 @@ func TestAWSProvider_DestroyInfrastructure_NoState(t *testing.T) {
 	p := NewAWSProvider()
 	cfg := &config.ClusterConfig{
 		Name:     "nonexistent-cluster",
-		Provider: config.ProviderConfig{Type: "aws", Region: "us-east-1"},
+		Provider: config.ProviderConfig{Type: "aws", AWS: config.AWSConfig{Region: "us-east-1"}},
 	}
 	// Clean up any directory created by setupWorkingDirectory
 	t.Cleanup(func() {
@@ -158,7 +201,7 @@ func TestAWSProvider_GetKubeconfig_MissingCluster(t *testing.T) {
 	p := NewAWSProvider()
 	cfg := &config.ClusterConfig{
 		Name:     "nonexistent-cluster",
-		Provider: config.ProviderConfig{Type: "aws", Region: "us-east-1"},
+		Provider: config.ProviderConfig{Type: "aws", AWS: config.AWSConfig{Region: "us-east-1"}},
 	}
 	_, err := p.GetKubeconfig(cfg)
 	if err == nil {
@@ -170,7 +213,7 @@ func TestAWSProvider_GetStatus_MissingWorkDir(t *testing.T) {
 	p := NewAWSProvider()
 	cfg := &config.ClusterConfig{
 		Name:     "nonexistent-cluster",
-		Provider: config.ProviderConfig{Type: "aws", Region: "us-east-1"},
+		Provider: config.ProviderConfig{Type: "aws", AWS: config.AWSConfig{Region: "us-east-1"}},
 	}
 	status, err := p.GetStatus(cfg)
 	// Should return unknown status when working directory doesn't exist
@@ -237,3 +280,544 @@ func TestCleanTerraformSourceFiles_NoWorkDir(t *testing.T) {
 
 // Verify AWSProvider satisfies the Provider interface at compile time.
 var _ Provider = (*AWSProvider)(nil)
+
+func TestAWSNodes(t *testing.T) {
+	nodes := awsNodes("prod",
+		[]string{"i-cp0", "i-cp1"}, []string{"10.0.1.10", "10.0.1.11"}, []string{"203.0.113.10", ""},
+		[]string{"i-w0"}, []string{"10.0.2.10"})
+
+	want := []Node{
+		{Name: "prod-control-plane-0", Role: RoleControlPlane, InstanceID: "i-cp0", IP: "203.0.113.10", PrivateIP: "10.0.1.10"},
+		{Name: "prod-control-plane-1", Role: RoleControlPlane, InstanceID: "i-cp1", IP: "10.0.1.11", PrivateIP: "10.0.1.11"},
+		{Name: "prod-worker-0", Role: RoleWorker, InstanceID: "i-w0", IP: "10.0.2.10", PrivateIP: "10.0.2.10"},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("expected %+v, got %+v", want, nodes)
+	}
+}
+
+func TestParseSSMInvocation(t *testing.T) {
+	inv, err := parseSSMInvocation([]byte(`{
+  "CommandId": "abc",
+  "InstanceId": "i-cp0",
+  "Status": "Failed",
+  "ResponseCode": 3,
+  "StandardOutputContent": "inactive\n",
+  "StandardErrorContent": "failed to run commands: exit status 3\n"
+}`))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if string(inv.output()) != "inactive\nfailed to run commands: exit status 3\n" {
+		t.Errorf("unexpected output %q", inv.output())
+	}
+	if err := inv.err(); err == nil || err.Error() != "command failed with exit code 3" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if (&ssmInvocation{Status: "Success"}).err() != nil {
+		t.Error("expected no error for a successful command")
+	}
+}
+
+func TestPollSSMCommand_InProgress(t *testing.T) {
+	ssmPollInterval = time.Millisecond
+	t.Cleanup(func() { ssmPollInterval = 10 * time.Second })
+
+	statuses := []string{"Pending", "InProgress", "InProgress", "Success"}
+	calls := 0
+	get := func() (*ssmInvocation, error) {
+		status := statuses[calls]
+		calls++
+		return &ssmInvocation{Status: status}, nil
+	}
+	inv, err := pollSSMCommand(get, time.Minute)
+	if err != nil || inv.Status != "Success" || calls != 4 {
+		t.Errorf("expected Success after 4 polls, got %+v, %v after %d", inv, err, calls)
+	}
+
+	_, err = pollSSMCommand(func() (*ssmInvocation, error) {
+		return &ssmInvocation{Status: "InProgress"}, nil
+	}, 0)
+	if err == nil || !strings.Contains(err.Error(), "command still inprogress") {
+		t.Errorf("expected a timeout while the command runs, got %v", err)
+	}
+}
+
+func TestForEachInstance(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		running int
+		peak    int
+	)
+	ids := []string{"i-1", "i-2", "i-3", "i-4", "i-5", "i-6"}
+	failures := forEachInstance(ids, 2, func(id string) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if id == "i-2" || id == "i-5" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent calls, got %d", peak)
+	}
+	if len(failures) != 2 || failures["i-2"] == nil || failures["i-5"] == nil {
+		t.Errorf("unexpected failures: %v", failures)
+	}
+
+	err := instanceFailures("update", failures)
+	if err == nil || !strings.Contains(err.Error(), "2 node(s)") || !strings.Contains(err.Error(), "i-2: boom") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := instanceFailures("update", map[string]error{}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestLastLine(t *testing.T) {
+	if got := lastLine("one\ntwo\n\n"); got != "two" {
+		t.Errorf("expected 'two', got %q", got)
+	}
+	if got := lastLine(""); got != "" {
+		t.Errorf("expected empty, got %q", got)
+	}
+}
+
+func TestSSMInvocation_Failure(t *testing.T) {
+	inv := &ssmInvocation{Status: "Failed", ResponseCode: 1, StandardErrorContent: "RKE2 did not become ready after the restart\n"}
+	if err := inv.failure(); err == nil || err.Error() != "command failed with exit code 1: RKE2 did not become ready after the restart" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// Without stderr the error does not end in a dangling colon
+	inv.StandardErrorContent = ""
+	if err := inv.failure(); err == nil || err.Error() != "command failed with exit code 1" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if (&ssmInvocation{Status: "Success"}).failure() != nil {
+		t.Error("expected no error for a successful command")
+	}
+}
+
+func TestCertificateCheckScript(t *testing.T) {
+	script := certificateCheckScript("my-nlb.elb.amazonaws.com", time.Minute)
+	for _, want := range []string{
+		"openssl s_client -connect 127.0.0.1:6443 -servername my-nlb.elb.amazonaws.com",
+		"openssl x509 -noout -checkhost my-nlb.elb.amazonaws.com",
+		"seq 1 12",
+		"exit 1",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected the script to contain %q, got:\n%s", want, script)
+		}
+	}
+}
+
+func TestTunnelFailure(t *testing.T) {
+	if got := tunnelFailure("starting\nSessionManagerPlugin is not found\n", errors.New("exit status 255")); got != "SessionManagerPlugin is not found" {
+		t.Errorf("expected the last stderr line, got %q", got)
+	}
+	if got := tunnelFailure("", errors.New("exit status 255")); got != "exit status 255" {
+		t.Errorf("expected the exit error without stderr, got %q", got)
+	}
+	if got := tunnelFailure("", nil); !strings.HasPrefix(got, "no connection within") {
+		t.Errorf("expected a timeout without stderr or exit, got %q", got)
+	}
+}
+
+func TestTunnelPort(t *testing.T) {
+	port := tunnelPort("production")
+	if port < 20000 || port >= 30000 {
+		t.Errorf("expected a port in 20000-29999, got %d", port)
+	}
+	if tunnelPort("production") != port {
+		t.Error("expected the same port for the same cluster")
+	}
+	if tunnelPort("staging") == port {
+		t.Error("expected different clusters to get different ports")
+	}
+}
+
+func TestTunnelCommand(t *testing.T) {
+	got := tunnelCommand("i-0abc", "eu-west-1", 24321)
+	want := []string{"aws", "ssm", "start-session",
+		"--target", "i-0abc",
+		"--document-name", "AWS-StartPortForwardingSession",
+		"--parameters", "portNumber=6443,localPortNumber=24321",
+		"--region", "eu-west-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCheckTunnel(t *testing.T) {
+	// httptest's certificate is valid for 127.0.0.1, standing in for a node IP
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+	addr := server.Listener.Addr().String()
+
+	if err := checkTunnel(addr, []string{"10.0.1.10", "127.0.0.1"}); err != nil {
+		t.Errorf("expected the tunnel to be accepted, got %v", err)
+	}
+	if err := checkTunnel(addr, []string{"10.0.1.10", "10.0.2.10"}); err == nil {
+		t.Error("expected a tunnel to another server to be rejected")
+	}
+
+	// A listener that does not speak TLS is not a tunnel to the API server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	if err := checkTunnel(listener.Addr().String(), []string{"127.0.0.1"}); err == nil {
+		t.Error("expected a plain listener to be rejected")
+	}
+}
+
+func TestAWSAPIAllowedCIDRs(t *testing.T) {
+	cases := []struct {
+		name string
+		aws  config.AWSConfig
+		want []string
+	}{
+		{"public", config.AWSConfig{VPC: config.VPCConfig{CIDR: "10.0.0.0/16"}}, []string{"0.0.0.0/0"}},
+		{"private", config.AWSConfig{VPC: config.VPCConfig{CIDR: "10.0.0.0/16"}, Private: true}, []string{}},
+		{"explicit", config.AWSConfig{Private: true, APIAllowedCIDRs: []string{"192.168.0.0/16"}}, []string{"192.168.0.0/16"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.ClusterConfig{Provider: config.ProviderConfig{Type: "aws", AWS: tc.aws}}
+			if got := awsAPIAllowedCIDRs(cfg); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExistingNetwork_Check(t *testing.T) {
+	const data = `{
+  "Subnets": [
+    {"SubnetId": "subnet-0000000a", "VpcId": "vpc-0000000a", "AvailabilityZone": "eu-west-1a",
+     "Tags": [{"Key": "kubernetes.io/role/elb", "Value": "1"}, {"Key": "kubernetes.io/cluster/prod", "Value": "shared"}]},
+    {"SubnetId": "subnet-0000000b", "VpcId": "vpc-0000000a", "AvailabilityZone": "eu-west-1b",
+     "Tags": [{"Key": "kubernetes.io/role/elb", "Value": "1"}, {"Key": "kubernetes.io/cluster/prod", "Value": "shared"}]},
+    {"SubnetId": "subnet-0000000c", "VpcId": "vpc-0000000a", "AvailabilityZone": "eu-west-1a"},
+    {"SubnetId": "subnet-0000000d", "VpcId": "vpc-0000000b", "AvailabilityZone": "eu-west-1a"}
+  ],
+  "RouteTables": [
+    {"RouteTableId": "rtb-public", "Associations": [{"SubnetId": "subnet-0000000a"}, {"SubnetId": "subnet-0000000b"}],
+     "Routes": [{"DestinationCidrBlock": "0.0.0.0/0", "GatewayId": "igw-1", "State": "active"}]},
+    {"RouteTableId": "rtb-main", "Associations": [{"Main": true}],
+     "Routes": [{"DestinationCidrBlock": "10.0.0.0/16", "GatewayId": "local", "State": "active"},
+                {"DestinationCidrBlock": "0.0.0.0/0", "NatGatewayId": "nat-1", "State": "active"}]}
+  ]
+}`
+	var network existingNetwork
+	if err := json.Unmarshal([]byte(data), &network); err != nil {
+		t.Fatal(err)
+	}
+
+	vpc := config.VPCConfig{
+		ID:               "vpc-0000000a",
+		PublicSubnetIDs:  []string{"subnet-0000000a", "subnet-0000000b"},
+		PrivateSubnetIDs: []string{"subnet-0000000c"},
+	}
+
+	network.NatStates = map[string]string{"nat-1": "available"}
+	warnings, err := network.check("prod", vpc)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "subnet-0000000c is missing the tags kubernetes.io/role/internal-elb, kubernetes.io/cluster/prod") {
+		t.Errorf("expected a missing tags warning for the private subnet, got %v", warnings)
+	}
+
+	network.NatStates = map[string]string{"nat-1": "deleted"}
+	if _, err := network.check("prod", vpc); err == nil || !strings.Contains(err.Error(), "NAT gateway nat-1 used by subnet subnet-0000000c is deleted") {
+		t.Errorf("expected a NAT gateway error, got %v", err)
+	}
+	network.NatStates = map[string]string{"nat-1": "available"}
+
+	cases := []struct {
+		name string
+		vpc  config.VPCConfig
+		want string
+	}{
+		{"unknown subnet", config.VPCConfig{ID: "vpc-0000000a", PrivateSubnetIDs: []string{"subnet-0000000f"}}, "subnet subnet-0000000f not found"},
+		{"other VPC", config.VPCConfig{ID: "vpc-0000000a", PrivateSubnetIDs: []string{"subnet-0000000d"}}, "is in vpc-0000000b"},
+		{"public without IGW", config.VPCConfig{ID: "vpc-0000000a", PublicSubnetIDs: []string{"subnet-0000000c"}, PrivateSubnetIDs: []string{"subnet-0000000c"}}, "does not route to an internet gateway"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := network.check("prod", tc.vpc); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+
+	// Without a main table default route, the private subnet has no way out
+	network.RouteTables[1].Routes = network.RouteTables[1].Routes[:1]
+	if _, err := network.check("prod", vpc); err == nil || !strings.Contains(err.Error(), "has no default route") {
+		t.Errorf("expected a default route error, got %v", err)
+	}
+}
+
+func TestAWSProvider_GenerateTerraformVars_ExistingVPC(t *testing.T) {
+	p := &AWSProvider{workDir: t.TempDir()}
+	cfg := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "aws", AWS: config.AWSConfig{
+		Region: "eu-west-1",
+		VPC: config.VPCConfig{
+			ID:               "vpc-0000000a",
+			PublicSubnetIDs:  []string{"subnet-0000000a"},
+			PrivateSubnetIDs: []string{"subnet-0000000b"},
+		},
+	}}}
+	if err := p.generateTerraformVars(cfg); err != nil {
+		t.Fatalf("generateTerraformVars failed: %v", err)
+	}
+
+	vars := readTerraformVars(t, p.workDir)
+	if vars["existing_vpc_id"] != "vpc-0000000a" {
+		t.Errorf("expected existing_vpc_id vpc-0000000a, got %v", vars["existing_vpc_id"])
+	}
+	if public, _ := vars["existing_public_subnet_ids"].([]interface{}); len(public) != 1 || public[0] != "subnet-0000000a" {
+		t.Errorf("expected existing_public_subnet_ids [subnet-0000000a], got %v", vars["existing_public_subnet_ids"])
+	}
+	if private, _ := vars["existing_private_subnet_ids"].([]interface{}); len(private) != 1 || private[0] != "subnet-0000000b" {
+		t.Errorf("expected existing_private_subnet_ids [subnet-0000000b], got %v", vars["existing_private_subnet_ids"])
+	}
+	if _, ok := vars["vpc_cidr"]; ok {
+		t.Error("expected no vpc_cidr for an existing VPC")
+	}
+}
+
+func TestAWSIdentity_AssumeRoleArgs(t *testing.T) {
+	id := awsIdentity{RoleARN: "arn:aws:iam::123456789012:role/deploy", SessionName: "ci"}
+	want := []string{"sts", "assume-role", "--role-arn", "arn:aws:iam::123456789012:role/deploy", "--role-session-name", "ci", "--output", "json"}
+	if got := id.assumeRoleArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	id.ExternalID = "secret"
+	want = append(want, "--external-id", "secret")
+	if got := id.assumeRoleArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestParseAssumedRole(t *testing.T) {
+	output := []byte(`{"Credentials": {"AccessKeyId": "AKIA1", "SecretAccessKey": "s3cr3t", "SessionToken": "token", "Expiration": "2026-01-02T15:04:05+00:00"}}`)
+	env, expires, err := parseAssumedRole(output)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	want := []string{"AWS_ACCESS_KEY_ID=AKIA1", "AWS_SECRET_ACCESS_KEY=s3cr3t", "AWS_SESSION_TOKEN=token"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("expected %v, got %v", want, env)
+	}
+	if !expires.Equal(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("unexpected expiration %v", expires)
+	}
+
+	if _, _, err := parseAssumedRole([]byte(`{"Credentials": {}}`)); err == nil {
+		t.Error("expected error for output without credentials")
+	}
+}
+
+func TestEnvironWith(t *testing.T) {
+	t.Setenv("AWS_PROFILE", "ambient")
+	t.Setenv("AWS_ACCESS_KEY_ID", "ambient-key")
+	t.Setenv("TDLS_TEST_KEEP", "1")
+
+	env := environWith("AWS_ACCESS_KEY_ID=role-key")
+	set := map[string][]string{}
+	for _, v := range env {
+		key, value, _ := strings.Cut(v, "=")
+		set[key] = append(set[key], value)
+	}
+	if _, ok := set["AWS_PROFILE"]; ok {
+		t.Error("expected the ambient AWS_PROFILE to be dropped")
+	}
+	if got := set["AWS_ACCESS_KEY_ID"]; !reflect.DeepEqual(got, []string{"role-key"}) {
+		t.Errorf("expected AWS_ACCESS_KEY_ID to be replaced, got %v", got)
+	}
+	if got := set["TDLS_TEST_KEEP"]; !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("expected other variables to be kept, got %v", got)
+	}
+}
+
+func TestUseAWSCredentials_Profile(t *testing.T) {
+	t.Cleanup(func() { currentAWSSession = awsSession{} })
+
+	cfg := &config.ClusterConfig{Provider: config.ProviderConfig{Type: "aws", AWS: config.AWSConfig{Profile: "staging"}}}
+	if err := useAWSCredentials(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	cmd := awsCLI("sts", "get-caller-identity")
+	if len(cmd.Env) == 0 || cmd.Env[len(cmd.Env)-1] != "AWS_PROFILE=staging" {
+		t.Errorf("expected AWS CLI commands to use the staging profile, got env %v", cmd.Env)
+	}
+
+	// Without a profile or role the ambient credentials are used
+	cfg.Provider.AWS.Profile = ""
+	if err := useAWSCredentials(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cmd := awsCLI("sts", "get-caller-identity"); cmd.Env != nil {
+		t.Errorf("expected the ambient environment, got %v", cmd.Env)
+	}
+}
+
+func TestAWSProvider_GenerateTerraformVars_Credentials(t *testing.T) {
+	p := &AWSProvider{workDir: t.TempDir()}
+	cfg := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "aws", AWS: config.AWSConfig{
+		Region:                "eu-west-1",
+		Profile:               "prod-admin",
+		AssumeRoleARN:         "arn:aws:iam::123456789012:role/deploy",
+		AssumeRoleExternalID:  "secret",
+		AssumeRoleSessionName: "ci",
+	}}}
+	if err := p.generateTerraformVars(cfg); err != nil {
+		t.Fatalf("generateTerraformVars failed: %v", err)
+	}
+
+	vars := readTerraformVars(t, p.workDir)
+	want := map[string]string{
+		"aws_profile":              "prod-admin",
+		"assume_role_arn":          "arn:aws:iam::123456789012:role/deploy",
+		"assume_role_external_id":  "secret",
+		"assume_role_session_name": "ci",
+	}
+	for name, value := range want {
+		if vars[name] != value {
+			t.Errorf("expected %s %q, got %v", name, value, vars[name])
+		}
+	}
+}
+
+func TestAWSProvider_GenerateTerraformVars_Spot(t *testing.T) {
+	p := &AWSProvider{workDir: t.TempDir()}
+	cfg := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "aws"}, Nodes: config.NodesConfig{
+		Workers: config.NodeGroupConfig{Count: 3, Spot: true, OnDemandBase: 1},
+	}}
+	if err := p.generateTerraformVars(cfg); err != nil {
+		t.Fatalf("generateTerraformVars failed: %v", err)
+	}
+
+	vars := readTerraformVars(t, p.workDir)
+	if vars["enable_spot_instances"] != true {
+		t.Errorf("expected enable_spot_instances true, got %v", vars["enable_spot_instances"])
+	}
+	if vars["worker_on_demand_base"] != float64(1) {
+		t.Errorf("expected worker_on_demand_base 1, got %v", vars["worker_on_demand_base"])
+	}
+}
+
+func TestAWSProvider_GenerateTerraformVars_Settings(t *testing.T) {
+	p := &AWSProvider{workDir: t.TempDir()}
+	cfg := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "aws"}}
+	if err := p.generateTerraformVars(cfg); err != nil {
+		t.Fatalf("generateTerraformVars failed: %v", err)
+	}
+	vars := readTerraformVars(t, p.workDir)
+	for _, name := range []string{"environment", "enable_encryption", "ami_id", "etcd_volume_size", "additional_tags"} {
+		if _, ok := vars[name]; ok {
+			t.Errorf("expected unset %s to keep the module default, got %v", name, vars[name])
+		}
+	}
+
+	disabled := false
+	cfg.Provider.AWS = config.AWSConfig{
+		Environment: "staging",
+		Tags:        map[string]string{"Team": "platform"},
+		AMI:         config.AMIConfig{ID: "ami-0123456789abcdef0"},
+		Encryption:  &disabled,
+		Volumes: config.AWSVolumesConfig{
+			WorkerRoot: config.EBSVolumeConfig{SizeGB: 200, Type: "gp2"},
+			Etcd:       config.EBSVolumeConfig{IOPS: 6000, Throughput: 500},
+		},
+	}
+	if err := p.generateTerraformVars(cfg); err != nil {
+		t.Fatalf("generateTerraformVars failed: %v", err)
+	}
+	vars = readTerraformVars(t, p.workDir)
+	want := map[string]interface{}{
+		"environment":             "staging",
+		"additional_tags":         map[string]interface{}{"Team": "platform"},
+		"ami_id":                  "ami-0123456789abcdef0",
+		"enable_encryption":       false,
+		"worker_root_volume_size": float64(200),
+		"worker_root_volume_type": "gp2",
+		"etcd_volume_iops":        float64(6000),
+		"etcd_volume_throughput":  float64(500),
+	}
+	for name, value := range want {
+		if !reflect.DeepEqual(vars[name], value) {
+			t.Errorf("expected %s %v, got %v", name, value, vars[name])
+		}
+	}
+	if _, ok := vars["etcd_volume_size"]; ok {
+		t.Error("expected unset etcd_volume_size to keep the module default")
+	}
+}
+
+func TestAWSProvider_GenerateTerraformVars_Autoscaling(t *testing.T) {
+	p := &AWSProvider{workDir: t.TempDir()}
+	cfg := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "aws"}}
+	cfg.Nodes.Workers = config.NodeGroupConfig{Count: 3, Autoscaling: config.AutoscalingConfig{Min: 2, Max: 10}}
+
+	if err := p.generateTerraformVars(cfg); err != nil {
+		t.Fatalf("generateTerraformVars failed: %v", err)
+	}
+	vars := readTerraformVars(t, p.workDir)
+	if vars["worker_count"] != float64(3) || vars["worker_autoscaling_min"] != float64(2) || vars["worker_autoscaling_max"] != float64(10) {
+		t.Errorf("expected worker count and autoscaling bounds, got %v, %v, %v",
+			vars["worker_count"], vars["worker_autoscaling_min"], vars["worker_autoscaling_max"])
+	}
+}
+
+func TestParseASGInstances(t *testing.T) {
+	data := []byte(`[
+		["i-0bbb", "10.0.11.5", "2026-03-02T10:00:00+00:00"],
+		["i-0aaa", "10.0.10.4", "2026-03-01T10:00:00+00:00"],
+		["i-0ccc", null, "2026-03-03T10:00:00+00:00"]
+	]`)
+	instances, err := parseASGInstances(data)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	nodes := asgNodes("prod", instances)
+	want := []Node{
+		{Name: "prod-worker-i-0aaa", Role: RoleWorker, InstanceID: "i-0aaa", IP: "10.0.10.4", PrivateIP: "10.0.10.4", Autoscaled: true},
+		{Name: "prod-worker-i-0bbb", Role: RoleWorker, InstanceID: "i-0bbb", IP: "10.0.11.5", PrivateIP: "10.0.11.5", Autoscaled: true},
+		{Name: "prod-worker-i-0ccc", Role: RoleWorker, InstanceID: "i-0ccc", Autoscaled: true},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("expected oldest first\n%+v\ngot\n%+v", want, nodes)
+	}
+
+	if _, err := parseASGInstances([]byte("not json")); err == nil {
+		t.Error("expected an error for invalid output")
+	}
+}
@@ -0,0 +1,233 @@
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+)
+
+// tunnelWaitTimeout bounds the wait for an SSM port-forwarding session to accept
+// connections
+var tunnelWaitTimeout = 30 * time.Second
+
+// apiTunnel is an SSM port-forwarding session started by this process
+type apiTunnel struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+	err  error // how the session exited, once done is closed
+}
+
+var (
+	tunnelsMu  sync.Mutex
+	apiTunnels []*apiTunnel
+)
+
+// startTunnel starts a port-forwarding session, given as an AWS CLI command, and
+// tracks when it exits
+func startTunnel(args []string) (*apiTunnel, *bytes.Buffer, error) {
+	var stderr bytes.Buffer
+	cmd := awsCLI(args[1:]...)
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	t := &apiTunnel{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		t.err = cmd.Wait()
+		close(t.done)
+	}()
+	return t, &stderr, nil
+}
+
+// close ends the session
+func (t *apiTunnel) close() {
+	t.cmd.Process.Kill()
+	<-t.done
+}
+
+// CloseTunnels ends the API tunnels opened by this process
+func CloseTunnels() {
+	tunnelsMu.Lock()
+	defer tunnelsMu.Unlock()
+	for _, t := range apiTunnels {
+		t.close()
+	}
+	apiTunnels = nil
+}
+
+// tunnelPort returns the local port for a private cluster's API tunnel. It is
+// derived from the cluster name, so a saved kubeconfig keeps working with a
+// tunnel opened later and several clusters can be tunnelled at once.
+func tunnelPort(clusterName string) int {
+	h := fnv.New32a()
+	h.Write([]byte(clusterName))
+	return 20000 + int(h.Sum32()%10000)
+}
+
+// tunnelCommand returns the AWS CLI command that forwards localPort to the API
+// server on a control plane instance
+func tunnelCommand(instanceID, region string, localPort int) []string {
+	return []string{"aws", "ssm", "start-session",
+		"--target", instanceID,
+		"--document-name", "AWS-StartPortForwardingSession",
+		"--parameters", fmt.Sprintf("portNumber=6443,localPortNumber=%d", localPort),
+		"--region", region}
+}
+
+// portOpen reports whether something accepts connections on a local port
+func portOpen(port int) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// waitForTunnel waits until a session accepts connections on port, and reports
+// false if it exits or the timeout passes first
+func waitForTunnel(t *apiTunnel, port int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-t.done:
+			return false
+		default:
+		}
+		if portOpen(port) {
+			return true
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return false
+}
+
+// checkTunnel checks that the server at addr presents a certificate for one of
+// the control plane private IPs, which RKE2 always includes, so a listener of
+// another program or a tunnel to another cluster is not mistaken for ours
+func checkTunnel(addr string, privateIPs []string) error {
+	var lastErr error
+	for _, ip := range privateIPs {
+		lastErr = checkCertificateName(addr, ip)
+		if lastErr == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+// openAPITunnel makes the API server of a private cluster reachable on
+// 127.0.0.1 and returns the port. An open tunnel, e.g. one the user started with
+// the command printed by `kubeconfig`, is reused once its certificate shows it
+// leads to this cluster; otherwise an SSM session is started to the first
+// control plane instance that accepts one.
+func (p *AWSProvider) openAPITunnel(cfg *config.ClusterConfig) (int, error) {
+	port := tunnelPort(cfg.Name)
+	if portOpen(port) {
+		privateIPs, err := terraformOutputList(p.workDir, "control_plane_private_ips")
+		if err != nil {
+			return 0, fmt.Errorf("failed to get control plane private IPs: %w", err)
+		}
+		privateIPs = nonEmpty(privateIPs)
+		if len(privateIPs) == 0 {
+			return 0, fmt.Errorf("no control plane private IPs in Terraform outputs")
+		}
+		addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+		if err := checkTunnel(addr, privateIPs); err != nil {
+			return 0, fmt.Errorf("port %d is in use, but not by a tunnel to the API server of %s (%v); close it and try again", port, cfg.Name, err)
+		}
+		return port, nil
+	}
+
+	instanceIDs, err := terraformOutputList(p.workDir, "control_plane_instance_ids")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get control plane instance IDs: %w", err)
+	}
+	instanceIDs = nonEmpty(instanceIDs)
+	if len(instanceIDs) == 0 {
+		return 0, fmt.Errorf("no control plane instance IDs in Terraform outputs")
+	}
+
+	var lastErr error
+	for _, id := range instanceIDs {
+		t, stderr, err := startTunnel(tunnelCommand(id, cfg.Provider.AWS.Region, port))
+		if err != nil {
+			return 0, fmt.Errorf("failed to start SSM session: %w", err)
+		}
+		if waitForTunnel(t, port, tunnelWaitTimeout) {
+			tunnelsMu.Lock()
+			apiTunnels = append(apiTunnels, t)
+			tunnelsMu.Unlock()
+			return port, nil
+		}
+		var exitErr error
+		select {
+		case <-t.done:
+			exitErr = t.err
+		default:
+		}
+		t.close()
+		lastErr = fmt.Errorf("%s: %s", id, tunnelFailure(stderr.String(), exitErr))
+	}
+	return 0, fmt.Errorf("failed to open an SSM tunnel to the API server (%v); it needs the Session Manager plugin for the AWS CLI", lastErr)
+}
+
+// tunnelFailure describes why a session did not accept connections: the last
+// line it wrote to stderr, or how it exited when it wrote nothing
+func tunnelFailure(stderr string, exitErr error) string {
+	if line := lastLine(stderr); line != "" {
+		return line
+	}
+	if exitErr != nil {
+		return exitErr.Error()
+	}
+	return fmt.Sprintf("no connection within %s", tunnelWaitTimeout)
+}
+
+// apiAddress returns the address the CLI reaches the API server on: the NLB, or
+// the local end of an SSM tunnel for a private cluster
+func (p *AWSProvider) apiAddress(cfg *config.ClusterConfig, nlbDNS string) (string, error) {
+	if !cfg.Provider.AWS.Private {
+		return net.JoinHostPort(nlbDNS, "6443"), nil
+	}
+	port, err := p.openAPITunnel(cfg)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), nil
+}
+
+// TunnelCommand returns the command that opens the SSM tunnel the kubeconfig of
+// a private cluster points at, or nil for a public cluster
+func (p *AWSProvider) TunnelCommand(cfg *config.ClusterConfig) ([]string, error) {
+	if !cfg.Provider.AWS.Private {
+		return nil, nil
+	}
+	if err := p.setupWorkingDirectory(cfg); err != nil {
+		return nil, err
+	}
+
+	instanceIDs, err := terraformOutputList(p.workDir, "control_plane_instance_ids")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get control plane instance IDs: %w", err)
+	}
+	instanceIDs = nonEmpty(instanceIDs)
+	if len(instanceIDs) == 0 {
+		return nil, fmt.Errorf("no control plane instance IDs in Terraform outputs")
+	}
+	command := tunnelCommand(instanceIDs[0], cfg.Provider.AWS.Region, tunnelPort(cfg.Name))
+	// An assumed role cannot be passed on the command line; the user runs the
+	// command with the role's credentials
+	if aws := cfg.Provider.AWS; aws.Profile != "" && aws.AssumeRoleARN == "" {
+		command = append(command, "--profile", aws.Profile)
+	}
+	return command, nil
+}
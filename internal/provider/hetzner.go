@@ -21,6 +21,43 @@ var hetznerLocations = map[string]bool{
 	"hil":  true, // Hillsboro, USA
 }
 
+func init() {
+	Register(Registration{
+		Name:        "hetzner",
+		Description: "Hetzner Cloud",
+		New:         func() Provider { return NewHetznerProvider() },
+		Capabilities: Capabilities{
+			IngressLoadBalancer: true,
+			Autoscaling:         true,
+		},
+		Resources: []string{
+			"All servers (control plane and workers)",
+			"Private network and subnets",
+			"Load balancer",
+			"Firewall rules",
+			"SSH keys",
+		},
+	})
+	config.RegisterDefaults("hetzner", applyHetznerDefaults)
+}
+
+// applyHetznerDefaults fills in Hetzner defaults for fields left empty in the config.
+// The values match the defaults of the Hetzner Terraform module.
+func applyHetznerDefaults(cfg *config.ClusterConfig) {
+	if cfg.Provider.Hetzner.Location == "" {
+		cfg.Provider.Hetzner.Location = "fsn1"
+	}
+	if cfg.Provider.Hetzner.Network.CIDR == "" {
+		cfg.Provider.Hetzner.Network.CIDR = "10.0.0.0/16"
+	}
+	if cfg.Nodes.ControlPlane.InstanceType == "" {
+		cfg.Nodes.ControlPlane.InstanceType = "cpx22"
+	}
+	if cfg.Nodes.Workers.InstanceType == "" {
+		cfg.Nodes.Workers.InstanceType = "cpx32"
+	}
+}
+
 // HetznerProvider implements the Provider interface for Hetzner Cloud
 type HetznerProvider struct {
 	workDir string
@@ -42,22 +79,18 @@ func (p *HetznerProvider) ValidateConfig(cfg *config.ClusterConfig) error {
 		return fmt.Errorf("provider type must be 'hetzner'")
 	}
 
-	// Determine location from Location or Region field
-	location := p.getLocation(cfg)
-	if location == "" {
-		return fmt.Errorf("Hetzner location is required (set provider.location or provider.region)")
-	}
-
-	if !hetznerLocations[location] {
-		return fmt.Errorf("invalid Hetzner location %q (valid: fsn1, nbg1, hel1, ash, hil)", location)
+	if err := p.ValidateField("provider.hetzner.location", cfg.Provider.Hetzner.Location); err != nil {
+		return err
 	}
 
-	if cfg.Nodes.ControlPlane.InstanceType == "" {
-		return fmt.Errorf("control plane server type is required (e.g., cx22)")
+	if err := p.ValidateField("nodes.controlPlane.instanceType", cfg.Nodes.ControlPlane.InstanceType); err != nil {
+		return err
 	}
 
-	if cfg.Nodes.Workers.Count > 0 && cfg.Nodes.Workers.InstanceType == "" {
-		return fmt.Errorf("worker server type is required (e.g., cx32)")
+	if cfg.Nodes.Workers.Count > 0 {
+		if err := p.ValidateField("nodes.workers.instanceType", cfg.Nodes.Workers.InstanceType); err != nil {
+			return err
+		}
 	}
 
 	// Check HCLOUD_TOKEN is set
@@ -65,15 +98,37 @@ func (p *HetznerProvider) ValidateConfig(cfg *config.ClusterConfig) error {
 		return fmt.Errorf("HCLOUD_TOKEN environment variable is required\nGet a token from: https://console.hetzner.cloud → API tokens")
 	}
 
+	warnings, err := hetznerPreflight(newHCloudClient(hcloudAPIEndpoint(), os.Getenv("HCLOUD_TOKEN")), cfg)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+
 	return nil
 }
 
-// getLocation returns the Hetzner location from config, preferring Location over Region.
-func (p *HetznerProvider) getLocation(cfg *config.ClusterConfig) string {
-	if cfg.Provider.Location != "" {
-		return cfg.Provider.Location
+// ValidateField validates a single Hetzner config value.
+func (p *HetznerProvider) ValidateField(field, value string) error {
+	switch field {
+	case "provider.hetzner.location":
+		if value == "" {
+			return fmt.Errorf("Hetzner location is required (set provider.hetzner.location)")
+		}
+		if !hetznerLocations[value] {
+			return fmt.Errorf("invalid Hetzner location %q (valid: fsn1, nbg1, hel1, ash, hil)", value)
+		}
+	case "nodes.controlPlane.instanceType":
+		if value == "" {
+			return fmt.Errorf("control plane server type is required (e.g., cpx22)")
+		}
+	case "nodes.workers.instanceType":
+		if value == "" {
+			return fmt.Errorf("worker server type is required (e.g., cpx32)")
+		}
 	}
-	return cfg.Provider.Region
+	return nil
 }
 
 // CreateInfrastructure creates the Hetzner infrastructure for the cluster
@@ -121,6 +176,18 @@ func (p *HetznerProvider) CreateInfrastructure(cfg *config.ClusterConfig) error
 
 	fmt.Println("\n✅ Infrastructure created successfully!")
 
+	// 7. Give the cloud controller manager, CSI driver and cluster autoscaler the
+	// API token, and the autoscaler the user data of new workers
+	if hcloudSecretNeeded(cfg) {
+		if err := p.createHCloudSecret(cfg); err != nil {
+			create := fmt.Sprintf("kubectl -n kube-system create secret generic %s --from-literal=token=\"$HCLOUD_TOKEN\" --from-literal=network=%s-network", HCloudSecretName, cfg.Name)
+			if cfg.Nodes.Workers.Autoscaling.Enabled() {
+				create += fmt.Sprintf(" \\\n    --from-literal=cloudInit=\"$(tofu -chdir=%s output -raw autoscaler_cloud_init | base64 -w0)\"", p.workDir)
+			}
+			return fmt.Errorf("%w\nThe servers are up, but the Hetzner components cannot run without the secret.\nRun init again, or create it with the kubeconfig from 'tdls-easy-k8s kubeconfig --cluster=%s' once the cluster is up:\n  %s\nTo remove the servers instead: tdls-easy-k8s destroy --cluster=%s", err, cfg.Name, create, cfg.Name)
+		}
+	}
+
 	fmt.Println("\n📝 Next steps:")
 	fmt.Println("  1. Wait for RKE2 to complete installation (~5 minutes)")
 	fmt.Println("  2. Download and configure kubeconfig:")
@@ -151,6 +218,13 @@ func (p *HetznerProvider) DestroyInfrastructure(cfg *config.ClusterConfig) error
 		return nil
 	}
 
+	// Servers added by the cluster autoscaler are not in the Terraform state
+	if cfg.Nodes.Workers.Autoscaling.Enabled() {
+		if err := p.deleteAutoscaledServers(cfg); err != nil {
+			return fmt.Errorf("failed to delete autoscaled workers: %w", err)
+		}
+	}
+
 	// Run tofu destroy
 	fmt.Println("\n[OpenTofu] Destroying infrastructure...")
 	fmt.Println("This may take 2-5 minutes...")
@@ -217,6 +291,51 @@ func (p *HetznerProvider) GetClusterStatus(cfg *config.ClusterConfig) (*ClusterS
 	return kubectlGetClusterStatus(kubeconfigPath, apiEndpoint)
 }
 
+// hetznerMachines are the resources holding the cluster's servers
+var hetznerMachines = machineResources{
+	ControlPlane:           "hcloud_server.control_plane_join",
+	Worker:                 "hcloud_server.worker",
+	ControlPlaneDependents: []dependentResource{{Address: "hcloud_load_balancer_target.cp_join"}},
+	WorkerDependents:       []dependentResource{{Address: "hcloud_load_balancer_target.ingress_worker"}},
+}
+
+// CanReplace checks that a node's server can be recreated on its own
+func (p *HetznerProvider) CanReplace(node Node) error {
+	return hetznerMachines.canReplace(node)
+}
+
+// ReplaceNode recreates a node's server
+func (p *HetznerProvider) ReplaceNode(cfg *config.ClusterConfig, node Node) error {
+	if err := p.setupWorkingDirectory(cfg); err != nil {
+		return err
+	}
+	return replaceSSHMachine(p.workDir, p.runTofu, hetznerMachines, node)
+}
+
+// ListNodes returns the servers of the cluster from the Terraform outputs
+func (p *HetznerProvider) ListNodes(cfg *config.ClusterConfig) ([]Node, error) {
+	if err := p.setupWorkingDirectory(cfg); err != nil {
+		return nil, err
+	}
+	return sshNodes(p.workDir, cfg.Name)
+}
+
+// Shell opens an SSH session on a node
+func (p *HetznerProvider) Shell(cfg *config.ClusterConfig, node Node) error {
+	if err := p.setupWorkingDirectory(cfg); err != nil {
+		return err
+	}
+	return sshShell(p.workDir, node)
+}
+
+// CommandRunner runs commands on nodes over SSH
+func (p *HetznerProvider) CommandRunner(cfg *config.ClusterConfig) (CommandRunner, error) {
+	if err := p.setupWorkingDirectory(cfg); err != nil {
+		return nil, err
+	}
+	return sshCommandRunner(p.workDir)
+}
+
 // --- Validation methods (delegate to common kubectl logic) ---
 
 func (p *HetznerProvider) ValidateAPIServer(cfg *config.ClusterConfig) (string, error) {
@@ -395,9 +514,9 @@ func (p *HetznerProvider) cleanTerraformSourceFiles() error {
 }
 
 func (p *HetznerProvider) generateTerraformVars(cfg *config.ClusterConfig) error {
-	location := p.getLocation(cfg)
+	location := cfg.Provider.Hetzner.Location
 
-	networkCIDR := cfg.Provider.VPC.CIDR
+	networkCIDR := cfg.Provider.Hetzner.Network.CIDR
 	if networkCIDR == "" {
 		networkCIDR = "10.0.0.0/16"
 	}
@@ -411,9 +530,19 @@ func (p *HetznerProvider) generateTerraformVars(cfg *config.ClusterConfig) error
 		"worker_count":       cfg.Nodes.Workers.Count,
 		"network_cidr":       networkCIDR,
 		"kubernetes_version": cfg.Kubernetes.Version,
-		"enable_ingress_lb":  cfg.Components.Traefik.Enabled,
+		"enable_ingress_lb":  ingressLoadBalancer(cfg),
+		"worker_autoscaling": cfg.Nodes.Workers.Autoscaling.Enabled(),
+		// The cloud controller manager initializes the nodes
+		"cloud_provider_external": cfg.Components.HCloudCCM.Enabled,
 	}
 
+	if cfg.Provider.Hetzner.OSImage != "" {
+		vars["os_image"] = cfg.Provider.Hetzner.OSImage
+	}
+
+	// API server settings such as OIDC authentication
+	mergeVars(vars, rke2ServerVars(cfg))
+
 	jsonData, err := json.MarshalIndent(vars, "", "  ")
 	if err != nil {
 		return err
@@ -464,7 +593,8 @@ func (p *HetznerProvider) fixProviderPermissions() error {
 	})
 }
 
-// downloadKubeconfig retrieves kubeconfig via SSH from the first control plane node.
+// downloadKubeconfig retrieves kubeconfig via SSH from the first reachable control plane
+// node and points it at the API hostname or the load balancer.
 func (p *HetznerProvider) downloadKubeconfig(cfg *config.ClusterConfig) (string, error) {
 	if p.workDir == "" {
 		if err := p.setupWorkingDirectory(cfg); err != nil {
@@ -472,78 +602,9 @@ func (p *HetznerProvider) downloadKubeconfig(cfg *config.ClusterConfig) (string,
 		}
 	}
 
-	// Get the first control plane IP
-	firstCPIP, err := p.getTerraformOutput("first_cp_ip")
-	if err != nil || firstCPIP == "" {
-		return "", fmt.Errorf("failed to get control plane IP: %w", err)
-	}
-
-	// Get the SSH private key from terraform output
-	sshKeyCmd := exec.Command("tofu", "output", "-raw", "ssh_private_key")
-	sshKeyCmd.Dir = p.workDir
-	sshKeyOutput, err := sshKeyCmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to get SSH private key: %w", err)
-	}
-
-	// Write SSH key to temp file
-	sshKeyFile, err := os.CreateTemp("", "hetzner-ssh-key-*")
-	if err != nil {
-		return "", err
-	}
-	defer os.Remove(sshKeyFile.Name())
-
-	if _, err := sshKeyFile.Write(sshKeyOutput); err != nil {
-		sshKeyFile.Close()
-		return "", err
-	}
-	sshKeyFile.Close()
-	os.Chmod(sshKeyFile.Name(), 0600)
-
-	// SSH into the first control plane node and download kubeconfig
-	sshCmd := exec.Command("ssh",
-		"-i", sshKeyFile.Name(),
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
-		"-o", "ConnectTimeout=10",
-		fmt.Sprintf("root@%s", firstCPIP),
-		"cat /etc/rancher/rke2/rke2.yaml",
-	)
-
-	kubeconfigData, err := sshCmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to retrieve kubeconfig via SSH: %w", err)
-	}
-
-	// Get LB IP to patch server URL
-	lbIP, _ := p.getTerraformOutput("lb_ipv4")
-
-	// Patch server URL: replace 127.0.0.1 with LB IP
-	kubeconfig := string(kubeconfigData)
-	if lbIP != "" {
-		lines := strings.Split(kubeconfig, "\n")
-		for i, line := range lines {
-			if strings.Contains(line, "server: https://") {
-				lines[i] = fmt.Sprintf("    server: https://%s:6443", lbIP)
-				break
-			}
-		}
-		kubeconfig = strings.Join(lines, "\n")
-	}
-
-	// Write to temp file
-	tmpFile, err := os.CreateTemp("", "kubeconfig-*.yaml")
-	if err != nil {
-		return "", err
+	apiHost := cfg.Kubernetes.APIServer.Hostname
+	if apiHost == "" {
+		apiHost, _ = p.getTerraformOutput("lb_ipv4")
 	}
-
-	if _, err := tmpFile.WriteString(kubeconfig); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpFile.Name())
-		return "", err
-	}
-	tmpFile.Close()
-	os.Chmod(tmpFile.Name(), 0600)
-
-	return tmpFile.Name(), nil
+	return fetchKubeconfig(p.workDir, apiHost)
 }
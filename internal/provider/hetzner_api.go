@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hcloudEndpoint is the Hetzner Cloud API
+const hcloudEndpoint = "https://api.hetzner.cloud/v1"
+
+// hcloudAPIEndpoint returns the API to call: HCLOUD_ENDPOINT when set, which
+// the hcloud Terraform provider also honors, else the Hetzner Cloud API
+func hcloudAPIEndpoint() string {
+	if endpoint := os.Getenv("HCLOUD_ENDPOINT"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/")
+	}
+	return hcloudEndpoint
+}
+
+// hcloudClient calls the Hetzner Cloud API for what Terraform does not manage,
+// such as the servers the cluster autoscaler creates
+type hcloudClient struct {
+	endpoint string
+	token    string
+	http     *http.Client
+}
+
+// newHCloudClient creates a client for the API at endpoint
+func newHCloudClient(endpoint, token string) *hcloudClient {
+	return &hcloudClient{
+		endpoint: endpoint,
+		token:    token,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// hcloudServer is a server as returned by the API
+type hcloudServer struct {
+	ID     int64             `json:"id"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+}
+
+// hcloudServerType is a server type as returned by the API
+type hcloudServerType struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Deprecation *struct {
+		UnavailableAfter string `json:"unavailable_after"`
+	} `json:"deprecation"`
+}
+
+// hcloudDatacenter is a datacenter as returned by the API, with the IDs of the
+// server types it offers and of those that can currently be created in it
+type hcloudDatacenter struct {
+	Name     string `json:"name"`
+	Location struct {
+		Name string `json:"name"`
+	} `json:"location"`
+	ServerTypes struct {
+		Supported []int64 `json:"supported"`
+		Available []int64 `json:"available"`
+	} `json:"server_types"`
+}
+
+// hcloudError is an error response of the API
+type hcloudError struct {
+	Method, Path string
+	StatusCode   int
+	Code         string
+	Message      string
+}
+
+func (e *hcloudError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Hetzner Cloud API %s %s: %s", e.Method, e.Path, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("Hetzner Cloud API %s %s: %s (%s)", e.Method, e.Path, e.Message, e.Code)
+}
+
+// do sends a request and decodes the JSON response into out, when not nil
+func (c *hcloudClient) do(method, path string, query url.Values, out interface{}) error {
+	u := c.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("Hetzner Cloud API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read Hetzner Cloud API response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		// A body that is not an API error leaves the code and message empty
+		json.Unmarshal(body, &apiErr)
+		return &hcloudError{
+			Method:     method,
+			Path:       path,
+			StatusCode: resp.StatusCode,
+			Code:       apiErr.Error.Code,
+			Message:    apiErr.Error.Message,
+		}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse Hetzner Cloud API response: %w", err)
+	}
+	return nil
+}
+
+// serversByLabel lists the servers matching a label selector, following pagination
+func (c *hcloudClient) serversByLabel(selector string) ([]hcloudServer, error) {
+	var servers []hcloudServer
+	for page := 1; page != 0; {
+		var resp struct {
+			Servers []hcloudServer `json:"servers"`
+			Meta    struct {
+				Pagination struct {
+					NextPage int `json:"next_page"`
+				} `json:"pagination"`
+			} `json:"meta"`
+		}
+		query := url.Values{
+			"label_selector": {selector},
+			"page":           {strconv.Itoa(page)},
+			"per_page":       {"50"},
+		}
+		if err := c.do(http.MethodGet, "/servers", query, &resp); err != nil {
+			return nil, err
+		}
+		servers = append(servers, resp.Servers...)
+		page = resp.Meta.Pagination.NextPage
+	}
+	return servers, nil
+}
+
+// deleteServer deletes a server. The deletion completes asynchronously.
+func (c *hcloudClient) deleteServer(id int64) error {
+	return c.do(http.MethodDelete, "/servers/"+strconv.FormatInt(id, 10), nil, nil)
+}
+
+// serverType looks up a server type by name, returning nil when there is none
+func (c *hcloudClient) serverType(name string) (*hcloudServerType, error) {
+	var resp struct {
+		ServerTypes []hcloudServerType `json:"server_types"`
+	}
+	if err := c.do(http.MethodGet, "/server_types", url.Values{"name": {name}}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.ServerTypes) == 0 {
+		return nil, nil
+	}
+	return &resp.ServerTypes[0], nil
+}
+
+// datacenters lists all datacenters. There are few enough for a single page.
+func (c *hcloudClient) datacenters() ([]hcloudDatacenter, error) {
+	var resp struct {
+		Datacenters []hcloudDatacenter `json:"datacenters"`
+	}
+	if err := c.do(http.MethodGet, "/datacenters", url.Values{"per_page": {"50"}}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Datacenters, nil
+}
+
+// count returns the number of resources in a collection such as /servers,
+// optionally matching a label selector, from the pagination metadata
+func (c *hcloudClient) count(path, selector string) (int, error) {
+	var resp struct {
+		Meta struct {
+			Pagination struct {
+				TotalEntries int `json:"total_entries"`
+			} `json:"pagination"`
+		} `json:"meta"`
+	}
+	query := url.Values{"per_page": {"1"}}
+	if selector != "" {
+		query.Set("label_selector", selector)
+	}
+	if err := c.do(http.MethodGet, path, query, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Meta.Pagination.TotalEntries, nil
+}
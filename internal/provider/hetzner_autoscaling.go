@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+)
+
+// HCloudSecretName is the kube-system secret holding the Hetzner Cloud API token
+// and network for the cloud controller manager and CSI driver, and what the
+// cluster autoscaler needs to create workers. Both charts read it by default.
+const HCloudSecretName = "hcloud"
+
+const (
+	hcloudSecretTimeout  = 15 * time.Minute
+	hcloudSecretInterval = 15 * time.Second
+	serverDeleteTimeout  = 5 * time.Minute
+)
+
+// HetznerNodeGroup returns the name of the cluster autoscaler's node group that
+// holds a cluster's workers. Servers in it carry the hcloud/node-group label.
+func HetznerNodeGroup(clusterName string) string {
+	return clusterName + "-workers"
+}
+
+// hcloudSecretNeeded reports whether a component that reads the hcloud secret
+// is enabled
+func hcloudSecretNeeded(cfg *config.ClusterConfig) bool {
+	return cfg.Nodes.Workers.Autoscaling.Enabled() || cfg.Components.HCloudCCM.Enabled || cfg.Components.HCloudCSI.Enabled
+}
+
+// hcloudSecretYAML renders the hcloud secret with the given keys
+func hcloudSecretYAML(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: %s\n  namespace: kube-system\ntype: Opaque\ndata:\n", HCloudSecretName)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "  %s: %s\n", key, base64.StdEncoding.EncodeToString([]byte(data[key])))
+	}
+	return b.String()
+}
+
+// hcloudSecretData returns the keys of the hcloud secret: the API token, the
+// cluster network and, for the cluster autoscaler, the base64-encoded user data
+// of new workers
+func (p *HetznerProvider) hcloudSecretData(cfg *config.ClusterConfig) (map[string]string, error) {
+	data := map[string]string{
+		"token":   os.Getenv("HCLOUD_TOKEN"),
+		"network": cfg.Name + "-network",
+	}
+	if cfg.Nodes.Workers.Autoscaling.Enabled() {
+		cloudInit, err := p.getTerraformOutput("autoscaler_cloud_init")
+		if err != nil {
+			return nil, err
+		}
+		data["cloudInit"] = base64.StdEncoding.EncodeToString([]byte(cloudInit))
+	}
+	return data, nil
+}
+
+// createHCloudSecret stores the hcloud secret in the cluster, waiting for the
+// Kubernetes API to come up after the servers are created
+func (p *HetznerProvider) createHCloudSecret(cfg *config.ClusterConfig) error {
+	data, err := p.hcloudSecretData(cfg)
+	if err != nil {
+		return err
+	}
+	manifest := hcloudSecretYAML(data)
+
+	fmt.Printf("\n[Post-provisioning] Waiting for the Kubernetes API to create the %s secret (up to %s)...\n", HCloudSecretName, hcloudSecretTimeout)
+	deadline := time.Now().Add(hcloudSecretTimeout)
+	for {
+		err := p.applyManifest(cfg, manifest)
+		if err == nil {
+			fmt.Printf("[Post-provisioning] ✓ Secret kube-system/%s created\n", HCloudSecretName)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out creating the %s secret: %w", HCloudSecretName, err)
+		}
+		time.Sleep(hcloudSecretInterval)
+	}
+}
+
+// applyManifest applies a manifest with kubectl, passing it on stdin so secrets
+// are not written to disk
+func (p *HetznerProvider) applyManifest(cfg *config.ClusterConfig, manifest string) error {
+	kubeconfigPath, err := p.downloadKubeconfig(cfg)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(kubeconfigPath)
+
+	cmd := exec.Command("kubectl", "apply", "-f", "-")
+	cmd.Env = append(os.Environ(), fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath))
+	cmd.Stdin = strings.NewReader(manifest)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("kubectl apply failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// deleteAutoscaledServers deletes the servers of the worker node group, which
+// Terraform does not know about when the cluster autoscaler created them. They
+// hold on to the network and firewall, so they go before terraform destroy.
+func (p *HetznerProvider) deleteAutoscaledServers(cfg *config.ClusterConfig) error {
+	client := newHCloudClient(hcloudAPIEndpoint(), os.Getenv("HCLOUD_TOKEN"))
+	selector := "hcloud/node-group=" + HetznerNodeGroup(cfg.Name)
+
+	servers, err := client.serversByLabel(selector)
+	if err != nil {
+		return err
+	}
+	if len(servers) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\n[Hetzner] Deleting %d servers of node group %s...\n", len(servers), HetznerNodeGroup(cfg.Name))
+	for _, server := range servers {
+		if err := client.deleteServer(server.ID); err != nil {
+			return fmt.Errorf("failed to delete server %s: %w", server.Name, err)
+		}
+		fmt.Printf("  %s: deleting\n", server.Name)
+	}
+
+	deadline := time.Now().Add(serverDeleteTimeout)
+	for {
+		servers, err := client.serversByLabel(selector)
+		if err != nil {
+			return err
+		}
+		if len(servers) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %d servers of node group %s to be deleted", len(servers), HetznerNodeGroup(cfg.Name))
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+)
+
+// hetznerUsage is how many resources of a kind the cluster needs, against the
+// project limit set in provider.hetzner.limits
+type hetznerUsage struct {
+	Name   string // as in provider.hetzner.limits
+	Path   string // API collection
+	Needed int
+	Limit  int
+}
+
+// hetznerProjectedUsage returns the servers, load balancers and networks the
+// cluster creates. Autoscaled workers count at the maximum of the node group, and
+// the load balancers the CCM creates for LoadBalancer services are included.
+func hetznerProjectedUsage(cfg *config.ClusterConfig) []hetznerUsage {
+	workers := cfg.Nodes.Workers.Count
+	if scaling := cfg.Nodes.Workers.Autoscaling; scaling.Enabled() && scaling.Max > workers {
+		workers = scaling.Max
+	}
+	loadBalancers := 1 // Kubernetes API
+	if cfg.Components.Traefik.Enabled {
+		loadBalancers++
+	}
+	if cfg.Components.HCloudCCM.Enabled {
+		loadBalancers += cfg.Components.HCloudCCM.LoadBalancers
+	}
+
+	limits := cfg.Provider.Hetzner.Limits
+	return []hetznerUsage{
+		{"servers", "/servers", cfg.Nodes.ControlPlane.Count + workers, limits.Servers},
+		{"loadBalancers", "/load_balancers", loadBalancers, limits.LoadBalancers},
+		{"networks", "/networks", 1, limits.Networks},
+	}
+}
+
+// hetznerPreflight checks with the Hetzner Cloud API what tofu apply would
+// otherwise find out minutes in: that the token works, that the server types
+// exist and can be created in the location, and that the cluster fits in the
+// project limits. Deprecated server types and limits that are not set are
+// returned as warnings.
+func hetznerPreflight(client *hcloudClient, cfg *config.ClusterConfig) ([]string, error) {
+	location := cfg.Provider.Hetzner.Location
+
+	datacenters, err := client.datacenters()
+	if err != nil {
+		var apiErr *hcloudError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("HCLOUD_TOKEN was rejected by the Hetzner Cloud API\nCreate a Read & Write token in: https://console.hetzner.cloud → Security → API tokens")
+		}
+		return nil, err
+	}
+	var local []hcloudDatacenter
+	for _, dc := range datacenters {
+		if dc.Location.Name == location {
+			local = append(local, dc)
+		}
+	}
+	if len(local) == 0 {
+		return nil, fmt.Errorf("the Hetzner Cloud API reports no datacenters in location %s", location)
+	}
+
+	types := []struct{ field, name string }{
+		{"nodes.controlPlane.instanceType", cfg.Nodes.ControlPlane.InstanceType},
+	}
+	workers := cfg.Nodes.Workers
+	if (workers.Count > 0 || workers.Autoscaling.Enabled()) && workers.InstanceType != cfg.Nodes.ControlPlane.InstanceType {
+		types = append(types, struct{ field, name string }{"nodes.workers.instanceType", workers.InstanceType})
+	}
+
+	var warnings []string
+	for _, t := range types {
+		serverType, err := client.serverType(t.name)
+		if err != nil {
+			return nil, err
+		}
+		if serverType == nil {
+			return nil, fmt.Errorf("%s: Hetzner server type %q does not exist", t.field, t.name)
+		}
+		if serverType.Deprecation != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: server type %q is deprecated and can be created until %s", t.field, t.name, serverType.Deprecation.UnavailableAfter))
+		}
+
+		var supported, available bool
+		for _, dc := range local {
+			supported = supported || containsID(dc.ServerTypes.Supported, serverType.ID)
+			available = available || containsID(dc.ServerTypes.Available, serverType.ID)
+		}
+		if !supported {
+			return nil, fmt.Errorf("%s: server type %q is not offered in %s", t.field, t.name, location)
+		}
+		if !available {
+			return nil, fmt.Errorf("%s: server type %q is currently unavailable in %s; choose another server type or location", t.field, t.name, location)
+		}
+	}
+
+	var exceeded, unset []string
+	for _, usage := range hetznerProjectedUsage(cfg) {
+		if usage.Limit == 0 {
+			unset = append(unset, "provider.hetzner.limits."+usage.Name)
+			continue
+		}
+		// Resources of an earlier run for this cluster are replaced, not added to
+		total, err := client.count(usage.Path, "")
+		if err != nil {
+			return nil, err
+		}
+		own, err := client.count(usage.Path, "cluster="+cfg.Name)
+		if err != nil {
+			return nil, err
+		}
+		if inUse := total - own; inUse+usage.Needed > usage.Limit {
+			exceeded = append(exceeded, fmt.Sprintf("  %s: %d in use + %d needed exceeds the limit of %d", usage.Name, inUse, usage.Needed, usage.Limit))
+		}
+	}
+	if len(exceeded) > 0 {
+		return nil, fmt.Errorf("the cluster does not fit in the Hetzner Cloud project limits:\n%s\nRequest a limit increase in: https://console.hetzner.cloud → Limits", strings.Join(exceeded, "\n"))
+	}
+	if len(unset) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%s not set, so the cluster is not checked against these project limits; copy them from: https://console.hetzner.cloud → Limits", strings.Join(unset, ", ")))
+	}
+
+	return warnings, nil
+}
+
+// containsID reports whether ids contains id
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
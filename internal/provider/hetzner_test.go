@@ -0,0 +1,375 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+)
+
+func TestApplyHetznerDefaults(t *testing.T) {
+	cfg := &config.ClusterConfig{Provider: config.ProviderConfig{Type: "hetzner"}}
+	config.ApplyDefaults(cfg)
+	if cfg.Provider.Hetzner.Location != "fsn1" {
+		t.Errorf("expected default location 'fsn1', got %q", cfg.Provider.Hetzner.Location)
+	}
+	if !reflect.DeepEqual(cfg.Provider.AWS, config.AWSConfig{}) {
+		t.Errorf("expected no AWS defaults for hetzner, got %+v", cfg.Provider.AWS)
+	}
+	if cfg.Nodes.ControlPlane.InstanceType != "cpx22" {
+		t.Errorf("expected default control plane server type 'cpx22', got %q", cfg.Nodes.ControlPlane.InstanceType)
+	}
+	if cfg.Nodes.Workers.InstanceType != "cpx32" {
+		t.Errorf("expected default worker server type 'cpx32', got %q", cfg.Nodes.Workers.InstanceType)
+	}
+}
+
+func TestHetznerProvider_GenerateTerraformVars_OSImage(t *testing.T) {
+	p := &HetznerProvider{workDir: t.TempDir()}
+	cfg := &config.ClusterConfig{
+		Name: "hetzner-image",
+		Provider: config.ProviderConfig{
+			Type:    "hetzner",
+			Hetzner: config.HetznerConfig{Location: "nbg1", OSImage: "ubuntu-24.04"},
+		},
+	}
+
+	if err := p.generateTerraformVars(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.workDir, "terraform.tfvars.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vars map[string]interface{}
+	if err := json.Unmarshal(data, &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars["os_image"] != "ubuntu-24.04" || vars["location"] != "nbg1" {
+		t.Errorf("expected os_image and location in tfvars, got %v", vars)
+	}
+}
+
+func TestLoadFromFile_HetznerDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluster.yaml")
+	content := `name: hetzner-defaults
+provider:
+  type: hetzner
+kubernetes:
+  version: "1.30"
+nodes:
+  controlPlane:
+    count: 1
+  workers:
+    count: 2
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Provider.Hetzner.Location != "fsn1" || cfg.Nodes.Workers.InstanceType != "cpx32" {
+		t.Errorf("expected hetzner defaults to be applied on load, got location %q and worker type %q",
+			cfg.Provider.Hetzner.Location, cfg.Nodes.Workers.InstanceType)
+	}
+}
+
+func TestHetznerProvider_GenerateTerraformVars_Autoscaling(t *testing.T) {
+	p := &HetznerProvider{workDir: t.TempDir()}
+	cfg := &config.ClusterConfig{Name: "scaled", Provider: config.ProviderConfig{Type: "hetzner"}}
+	cfg.Nodes.Workers = config.NodeGroupConfig{Count: 2, Autoscaling: config.AutoscalingConfig{Min: 1, Max: 5}}
+
+	if err := p.generateTerraformVars(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if vars := readTerraformVars(t, p.workDir); vars["worker_autoscaling"] != true {
+		t.Errorf("expected worker_autoscaling, got %v", vars["worker_autoscaling"])
+	}
+}
+
+func TestHetznerProvider_GenerateTerraformVars_CloudControllerManager(t *testing.T) {
+	p := &HetznerProvider{workDir: t.TempDir()}
+	cfg := &config.ClusterConfig{Name: "ccm", Provider: config.ProviderConfig{Type: "hetzner"}}
+
+	if err := p.generateTerraformVars(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if vars := readTerraformVars(t, p.workDir); vars["cloud_provider_external"] != false {
+		t.Errorf("expected the built-in cloud provider without the CCM, got %v", vars["cloud_provider_external"])
+	}
+
+	cfg.Components.HCloudCCM.Enabled = true
+	if err := p.generateTerraformVars(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if vars := readTerraformVars(t, p.workDir); vars["cloud_provider_external"] != true {
+		t.Errorf("expected cloud_provider_external with the CCM, got %v", vars["cloud_provider_external"])
+	}
+}
+
+func TestHCloudSecretNeeded(t *testing.T) {
+	cfg := &config.ClusterConfig{Provider: config.ProviderConfig{Type: "hetzner"}}
+	if hcloudSecretNeeded(cfg) {
+		t.Error("expected no hcloud secret without Hetzner components or autoscaling")
+	}
+	cfg.Components.HCloudCSI.Enabled = true
+	if !hcloudSecretNeeded(cfg) {
+		t.Error("expected the CSI driver to need the hcloud secret")
+	}
+}
+
+func TestHCloudSecretYAML(t *testing.T) {
+	got := hcloudSecretYAML(map[string]string{"token": "secret", "network": "prod-network"})
+	want := `apiVersion: v1
+kind: Secret
+metadata:
+  name: hcloud
+  namespace: kube-system
+type: Opaque
+data:
+  network: cHJvZC1uZXR3b3Jr
+  token: c2VjcmV0
+`
+	if got != want {
+		t.Errorf("unexpected secret:\n%s", got)
+	}
+}
+
+func TestHCloudClient_ServersByLabel(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": {"code": "unauthorized", "message": "unable to authenticate"}}`)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/servers":
+			if r.URL.Query().Get("label_selector") != "hcloud/node-group=prod-workers" {
+				t.Errorf("unexpected label selector %q", r.URL.Query().Get("label_selector"))
+			}
+			if r.URL.Query().Get("page") == "1" {
+				fmt.Fprint(w, `{"servers": [{"id": 1, "name": "prod-workers-a"}], "meta": {"pagination": {"next_page": 2}}}`)
+			} else {
+				fmt.Fprint(w, `{"servers": [{"id": 2, "name": "prod-workers-b"}], "meta": {"pagination": {"next_page": null}}}`)
+			}
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			fmt.Fprint(w, `{"action": {"id": 10}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newHCloudClient(server.URL, "test-token")
+	servers, err := client.serversByLabel("hcloud/node-group=prod-workers")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(servers) != 2 || servers[0].Name != "prod-workers-a" || servers[1].ID != 2 {
+		t.Errorf("expected both pages of servers, got %+v", servers)
+	}
+
+	if err := client.deleteServer(2); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(deleted, []string{"/servers/2"}) {
+		t.Errorf("expected server 2 to be deleted, got %v", deleted)
+	}
+
+	_, err = newHCloudClient(server.URL, "wrong").serversByLabel("x=y")
+	if err == nil || !strings.Contains(err.Error(), "unable to authenticate (unauthorized)") {
+		t.Errorf("expected the API error message, got %v", err)
+	}
+}
+
+// hcloudPreflightStub serves the API calls of the preflight checks: cpx22 (ID 1)
+// is available in fsn1, cpx32 (ID 2) is supported but sold out, the deprecated
+// cx21 (ID 3) is only offered in hel1, and the project has three of each
+// resource, one of them from an earlier run for cluster "prod".
+func hcloudPreflightStub(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": {"code": "unauthorized", "message": "unable to authenticate"}}`)
+			return
+		}
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/datacenters":
+			fmt.Fprint(w, `{"datacenters": [
+				{"name": "fsn1-dc14", "location": {"name": "fsn1"}, "server_types": {"supported": [1, 2], "available": [1]}},
+				{"name": "hel1-dc2", "location": {"name": "hel1"}, "server_types": {"supported": [1, 2, 3], "available": [1, 2, 3]}}]}`)
+		case "/server_types":
+			switch query.Get("name") {
+			case "cpx22":
+				fmt.Fprint(w, `{"server_types": [{"id": 1, "name": "cpx22", "deprecation": null}]}`)
+			case "cpx32":
+				fmt.Fprint(w, `{"server_types": [{"id": 2, "name": "cpx32", "deprecation": null}]}`)
+			case "cx21":
+				fmt.Fprint(w, `{"server_types": [{"id": 3, "name": "cx21", "deprecation": {"unavailable_after": "2026-12-31T00:00:00Z"}}]}`)
+			default:
+				fmt.Fprint(w, `{"server_types": []}`)
+			}
+		case "/servers", "/load_balancers", "/networks":
+			total := 3
+			if query.Get("label_selector") == "cluster=prod" {
+				total = 1
+			}
+			fmt.Fprintf(w, `{"meta": {"pagination": {"total_entries": %d}}}`, total)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestHetznerProvider_ValidateConfig_Preflight(t *testing.T) {
+	t.Setenv("HCLOUD_ENDPOINT", hcloudPreflightStub(t))
+
+	newConfig := func() *config.ClusterConfig {
+		cfg := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "hetzner"}}
+		cfg.Nodes.ControlPlane.Count = 3
+		cfg.Nodes.Workers.Count = 2
+		config.ApplyDefaults(cfg)
+		cfg.Nodes.Workers.InstanceType = "cpx22"
+		return cfg
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		modify  func(cfg *config.ClusterConfig)
+		wantErr string
+	}{
+		{name: "valid"},
+		{name: "rejected token", token: "wrong", wantErr: "HCLOUD_TOKEN was rejected"},
+		{
+			name:    "unknown server type",
+			modify:  func(cfg *config.ClusterConfig) { cfg.Nodes.ControlPlane.InstanceType = "cpx99" },
+			wantErr: `nodes.controlPlane.instanceType: Hetzner server type "cpx99" does not exist`,
+		},
+		{
+			name:    "sold out",
+			modify:  func(cfg *config.ClusterConfig) { cfg.Nodes.Workers.InstanceType = "cpx32" },
+			wantErr: `nodes.workers.instanceType: server type "cpx32" is currently unavailable in fsn1`,
+		},
+		{
+			name: "available in another location",
+			modify: func(cfg *config.ClusterConfig) {
+				cfg.Provider.Hetzner.Location = "hel1"
+				cfg.Nodes.Workers.InstanceType = "cpx32"
+			},
+		},
+		{
+			name:    "not offered",
+			modify:  func(cfg *config.ClusterConfig) { cfg.Nodes.Workers.InstanceType = "cx21" },
+			wantErr: `server type "cx21" is not offered in fsn1`,
+		},
+		{
+			// 2 servers of other clusters + 5 fit in 7
+			name: "within limits",
+			modify: func(cfg *config.ClusterConfig) {
+				cfg.Provider.Hetzner.Limits = config.HetznerLimitsConfig{Servers: 7, LoadBalancers: 10}
+			},
+		},
+		{
+			name: "autoscaled workers count at max",
+			modify: func(cfg *config.ClusterConfig) {
+				cfg.Nodes.Workers.Autoscaling = config.AutoscalingConfig{Min: 1, Max: 6}
+				cfg.Provider.Hetzner.Limits.Servers = 10
+			},
+			wantErr: "servers: 2 in use + 9 needed exceeds the limit of 10",
+		},
+		{
+			name: "load balancers",
+			modify: func(cfg *config.ClusterConfig) {
+				cfg.Components.Traefik.Enabled = true
+				cfg.Provider.Hetzner.Limits.LoadBalancers = 3
+			},
+			wantErr: "loadBalancers: 2 in use + 2 needed exceeds the limit of 3",
+		},
+		{
+			name: "CCM load balancers",
+			modify: func(cfg *config.ClusterConfig) {
+				cfg.Components.HCloudCCM = config.HCloudCCMConfig{Enabled: true, LoadBalancers: 2}
+				cfg.Provider.Hetzner.Limits.LoadBalancers = 4
+			},
+			wantErr: "loadBalancers: 2 in use + 3 needed exceeds the limit of 4",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			token := tc.token
+			if token == "" {
+				token = "test-token"
+			}
+			t.Setenv("HCLOUD_TOKEN", token)
+
+			cfg := newConfig()
+			if tc.modify != nil {
+				tc.modify(cfg)
+			}
+			err := NewHetznerProvider().ValidateConfig(cfg)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHetznerPreflight_DeprecatedServerType(t *testing.T) {
+	client := newHCloudClient(hcloudPreflightStub(t), "test-token")
+	cfg := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "hetzner"}}
+	cfg.Nodes.ControlPlane.Count = 1
+	config.ApplyDefaults(cfg)
+	cfg.Provider.Hetzner.Location = "hel1"
+	cfg.Nodes.ControlPlane.InstanceType = "cx21"
+	cfg.Provider.Hetzner.Limits = config.HetznerLimitsConfig{Servers: 10, LoadBalancers: 10, Networks: 10}
+
+	warnings, err := hetznerPreflight(client, cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	want := []string{`nodes.controlPlane.instanceType: server type "cx21" is deprecated and can be created until 2026-12-31T00:00:00Z`}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("expected %v, got %v", want, warnings)
+	}
+}
+
+func TestHetznerPreflight_UnsetLimits(t *testing.T) {
+	client := newHCloudClient(hcloudPreflightStub(t), "test-token")
+	cfg := &config.ClusterConfig{Name: "prod", Provider: config.ProviderConfig{Type: "hetzner"}}
+	cfg.Nodes.ControlPlane.Count = 1
+	config.ApplyDefaults(cfg)
+	cfg.Provider.Hetzner.Limits.Servers = 10
+
+	warnings, err := hetznerPreflight(client, cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "provider.hetzner.limits.loadBalancers, provider.hetzner.limits.networks not set") {
+		t.Errorf("expected a warning about the unset limits, got %v", warnings)
+	}
+}
@@ -0,0 +1,259 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+	"github.com/user/tdls-easy-k8s/pkg/plugin"
+)
+
+// PluginDirs returns the directories searched for plugins, in order of precedence:
+// ~/.tdls-k8s/plugins followed by the PATH
+func PluginDirs() []string {
+	var dirs []string
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(homeDir, ".tdls-k8s", "plugins"))
+	}
+	return append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+// discovered holds the plugin executables found by LoadPlugins that have not been
+// described yet, keyed by provider name
+var discovered = map[string]string{}
+
+// pluginErrors holds why a discovered plugin could not be registered
+var pluginErrors = map[string]error{}
+
+// LoadPlugins makes the plugin providers found in dirs available. Plugins are only
+// started, to describe them, when they are first looked up; the returned warnings
+// name plugins that were skipped.
+func LoadPlugins(dirs []string) []string {
+	var warnings []string
+	for _, path := range findPlugins(dirs) {
+		name := plugin.NewClient(path).Name()
+
+		if existing, ok := registry[name]; ok {
+			if existing.Plugin == "" {
+				warnings = append(warnings, fmt.Sprintf("plugin %s ignored: %q is a built-in provider", path, name))
+			}
+			continue
+		}
+		if _, ok := discovered[name]; ok {
+			continue
+		}
+
+		discovered[name] = path
+		config.RegisterPluginType(name)
+	}
+	return warnings
+}
+
+// LoadPlugin describes and registers a plugin found by LoadPlugins. It returns nil
+// for built-in providers, plugins registered before and unknown names.
+func LoadPlugin(name string) error {
+	path, ok := discovered[name]
+	if !ok {
+		return pluginErrors[name]
+	}
+	delete(discovered, name)
+
+	if err := registerPlugin(name, path); err != nil {
+		pluginErrors[name] = err
+		return err
+	}
+	return nil
+}
+
+// registerPlugin asks a plugin for its description and registers it
+func registerPlugin(name, path string) error {
+	client := plugin.NewClient(path)
+	desc, err := client.Describe()
+	if err != nil {
+		return fmt.Errorf("plugin %s: %w", path, err)
+	}
+	if desc.Name != name {
+		return fmt.Errorf("plugin %s describes itself as %q", path, desc.Name)
+	}
+
+	capabilities := Capabilities{
+		IngressLoadBalancer: desc.Capabilities.IngressLoadBalancer,
+		VIP:                 desc.Capabilities.VIP,
+		SpotInstances:       desc.Capabilities.SpotInstances,
+		SSM:                 desc.Capabilities.SSM,
+		ObjectStorage:       desc.Capabilities.ObjectStorage,
+		Autoscaling:         desc.Capabilities.Autoscaling,
+	}
+	Register(Registration{
+		Name:        name,
+		Description: desc.Description,
+		New: func() Provider {
+			p := &PluginProvider{name: name, client: client}
+			if capabilities.ObjectStorage {
+				return &pluginStorageProvider{p}
+			}
+			return p
+		},
+		Capabilities: capabilities,
+		Resources:    desc.Resources,
+		Plugin:       path,
+	})
+	return nil
+}
+
+// findPlugins returns the plugin executables in dirs. When several directories hold
+// a plugin with the same name, the first one wins.
+func findPlugins(dirs []string) []string {
+	var paths []string
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !strings.HasPrefix(entry.Name(), plugin.ExecutablePrefix) || entry.IsDir() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || info.Mode()&0111 == 0 {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			name := plugin.NewClient(path).Name()
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// PluginProvider implements the Provider interface by calling a plugin executable
+type PluginProvider struct {
+	name   string
+	client *plugin.Client
+}
+
+// Name returns the provider name
+func (p *PluginProvider) Name() string {
+	return p.name
+}
+
+// ValidateField validates a single config value
+func (p *PluginProvider) ValidateField(field, value string) error {
+	return p.client.ValidateField(field, value)
+}
+
+// ValidateConfig validates the provider-specific configuration
+func (p *PluginProvider) ValidateConfig(cfg *config.ClusterConfig) error {
+	return p.client.Call(plugin.MethodValidateConfig, cfg, nil)
+}
+
+// CreateInfrastructure creates the cluster infrastructure
+func (p *PluginProvider) CreateInfrastructure(cfg *config.ClusterConfig) error {
+	return p.client.Call(plugin.MethodCreateInfrastructure, cfg, nil)
+}
+
+// DestroyInfrastructure destroys the cluster infrastructure
+func (p *PluginProvider) DestroyInfrastructure(cfg *config.ClusterConfig) error {
+	return p.client.Call(plugin.MethodDestroyInfrastructure, cfg, nil)
+}
+
+// GetKubeconfig returns the path of the cluster's kubeconfig
+func (p *PluginProvider) GetKubeconfig(cfg *config.ClusterConfig) (string, error) {
+	return p.callString(plugin.MethodGetKubeconfig, cfg)
+}
+
+// GetStatus returns the current status of the infrastructure
+func (p *PluginProvider) GetStatus(cfg *config.ClusterConfig) (string, error) {
+	return p.callString(plugin.MethodGetStatus, cfg)
+}
+
+// GetClusterStatus returns detailed cluster status
+func (p *PluginProvider) GetClusterStatus(cfg *config.ClusterConfig) (*ClusterStatus, error) {
+	var status plugin.ClusterStatus
+	if err := p.client.Call(plugin.MethodGetClusterStatus, cfg, &status); err != nil {
+		return nil, err
+	}
+
+	result := &ClusterStatus{
+		Ready:             status.Ready,
+		Message:           status.Message,
+		APIEndpoint:       status.APIEndpoint,
+		ControlPlaneTotal: status.ControlPlaneTotal,
+		ControlPlaneReady: status.ControlPlaneReady,
+		WorkerTotal:       status.WorkerTotal,
+		WorkerReady:       status.WorkerReady,
+		CreatedAt:         status.CreatedAt,
+	}
+	for _, c := range status.Components {
+		result.Components = append(result.Components, ComponentStatus{Name: c.Name, Status: c.Status, Message: c.Message})
+	}
+	return result, nil
+}
+
+// ValidateAPIServer checks that the API server is reachable
+func (p *PluginProvider) ValidateAPIServer(cfg *config.ClusterConfig) (string, error) {
+	return p.callString(plugin.MethodValidateAPIServer, cfg)
+}
+
+// ValidateNodes checks that all nodes are ready
+func (p *PluginProvider) ValidateNodes(cfg *config.ClusterConfig) (string, error) {
+	return p.callString(plugin.MethodValidateNodes, cfg)
+}
+
+// ValidateSystemPods checks that the system pods are running
+func (p *PluginProvider) ValidateSystemPods(cfg *config.ClusterConfig) (string, error) {
+	return p.callString(plugin.MethodValidateSystemPods, cfg)
+}
+
+// ValidateEtcd checks etcd health
+func (p *PluginProvider) ValidateEtcd(cfg *config.ClusterConfig) (string, error) {
+	return p.callString(plugin.MethodValidateEtcd, cfg)
+}
+
+// ValidateDNS checks cluster DNS
+func (p *PluginProvider) ValidateDNS(cfg *config.ClusterConfig) (string, error) {
+	return p.callString(plugin.MethodValidateDNS, cfg)
+}
+
+// ValidateNetworking checks pod networking
+func (p *PluginProvider) ValidateNetworking(cfg *config.ClusterConfig) (string, error) {
+	return p.callString(plugin.MethodValidateNetworking, cfg)
+}
+
+// ValidatePodScheduling checks that pods can be scheduled
+func (p *PluginProvider) ValidatePodScheduling(cfg *config.ClusterConfig) (string, error) {
+	return p.callString(plugin.MethodValidatePodScheduling, cfg)
+}
+
+func (p *PluginProvider) callString(method string, cfg *config.ClusterConfig) (string, error) {
+	var result string
+	err := p.client.Call(method, cfg, &result)
+	return result, err
+}
+
+// pluginStorageProvider is a plugin provider with the ObjectStorage capability
+type pluginStorageProvider struct {
+	*PluginProvider
+}
+
+// ObjectStorageName returns the name of the cluster's bucket
+func (p *pluginStorageProvider) ObjectStorageName(cfg *config.ClusterConfig) string {
+	name, err := p.callString(plugin.MethodObjectStorageName, cfg)
+	if err != nil {
+		return fmt.Sprintf("(unknown: %v)", err)
+	}
+	return name
+}
+
+// DeleteObjectStorage empties and deletes the cluster's bucket
+func (p *pluginStorageProvider) DeleteObjectStorage(cfg *config.ClusterConfig) error {
+	return p.client.Call(plugin.MethodDeleteObjectStorage, cfg, nil)
+}
@@ -0,0 +1,205 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+	"github.com/user/tdls-easy-k8s/pkg/plugin"
+)
+
+// writePlugin writes a shell script plugin that answers every request with response
+func writePlugin(t *testing.T, dir, name, response string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugins need a POSIX shell")
+	}
+	path := filepath.Join(dir, "tdls-easy-k8s-provider-"+name)
+	script := "#!/bin/sh\ncat >/dev/null\necho '" + response + "'\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// unregister removes providers registered by a test
+func unregister(t *testing.T, names ...string) {
+	t.Cleanup(func() {
+		for _, name := range names {
+			delete(registry, name)
+			delete(discovered, name)
+			delete(pluginErrors, name)
+		}
+	})
+}
+
+func describeResponse(name string) string {
+	return `{"result":{"protocolVersion":1,"name":"` + name + `","description":"Test ` + name + `","capabilities":{"vip":true},"resources":["Servers"]}}`
+}
+
+func TestLoadPlugins(t *testing.T) {
+	dir := t.TempDir()
+	path := writePlugin(t, dir, "testcloud", describeResponse("testcloud"))
+	unregister(t, "testcloud")
+
+	if warnings := LoadPlugins([]string{dir}); len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+	if _, ok := registry["testcloud"]; ok {
+		t.Fatal("expected the plugin to be described on first lookup, not when it is found")
+	}
+	if names := strings.Join(Names(), ","); !strings.Contains(names, "testcloud") {
+		t.Errorf("expected the plugin in the provider names, got %s", names)
+	}
+
+	reg, ok := Lookup("testcloud")
+	if !ok {
+		t.Fatal("expected plugin to be registered")
+	}
+	if reg.Plugin != path || reg.Description != "Test testcloud" || !reg.Capabilities.VIP || len(reg.Resources) != 1 {
+		t.Errorf("unexpected registration: %+v", reg)
+	}
+	if !config.IsPluginType("testcloud") {
+		t.Error("expected plugin type to be valid in configs")
+	}
+
+	p, err := GetProvider("testcloud")
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+	if p.Name() != "testcloud" {
+		t.Errorf("expected name 'testcloud', got %q", p.Name())
+	}
+
+	if _, ok := p.(ObjectStorage); ok {
+		t.Error("expected a plugin without the objectStorage capability not to implement ObjectStorage")
+	}
+
+	// Loading again keeps the registration
+	if warnings := LoadPlugins([]string{dir}); len(warnings) != 0 {
+		t.Errorf("unexpected warnings on reload: %v", warnings)
+	}
+}
+
+func TestLoadPlugins_Capabilities(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "bucketcloud", `{"result":{"protocolVersion":1,"name":"bucketcloud","description":"Test","capabilities":{"objectStorage":true,"autoscaling":true},"resources":["Servers"]}}`)
+	unregister(t, "bucketcloud")
+
+	if warnings := LoadPlugins([]string{dir}); len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+	reg, _ := Lookup("bucketcloud")
+	if !reg.Capabilities.ObjectStorage || !reg.Capabilities.Autoscaling {
+		t.Errorf("expected objectStorage and autoscaling capabilities, got %+v", reg.Capabilities)
+	}
+	if _, ok := reg.New().(ObjectStorage); !ok {
+		t.Error("expected a plugin with the objectStorage capability to implement ObjectStorage")
+	}
+}
+
+func TestLoadPlugins_Skipped(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "aws", describeResponse("aws"))
+	writePlugin(t, dir, "renamed", describeResponse("other"))
+	writePlugin(t, dir, "oldproto", `{"result":{"protocolVersion":0,"name":"oldproto"}}`)
+	writePlugin(t, dir, "broken", "not json")
+	unregister(t, "renamed", "oldproto", "broken")
+
+	warnings := strings.Join(LoadPlugins([]string{dir}), "\n")
+	if !strings.Contains(warnings, `"aws" is a built-in provider`) {
+		t.Errorf("expected a warning for the built-in name, got:\n%s", warnings)
+	}
+
+	for name, want := range map[string]string{
+		"renamed":  `describes itself as "other"`,
+		"oldproto": "speaks protocol version 0",
+		"broken":   "invalid response",
+	} {
+		if _, err := GetProvider(name); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing %q, got %v", name, want, err)
+		}
+		if _, ok := Lookup(name); ok {
+			t.Errorf("expected %q not to be registered", name)
+		}
+	}
+}
+
+func TestFindPlugins(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	want := writePlugin(t, first, "dup", "{}")
+	writePlugin(t, second, "dup", "{}")
+	other := writePlugin(t, second, "other", "{}")
+
+	// Not executable, wrong prefix
+	os.WriteFile(filepath.Join(second, "tdls-easy-k8s-provider-noexec"), []byte("#!/bin/sh\n"), 0644)
+	os.WriteFile(filepath.Join(second, "kubectl-plugin"), []byte("#!/bin/sh\n"), 0755)
+
+	got := findPlugins([]string{first, filepath.Join(first, "missing"), second})
+	if len(got) != 2 || got[0] != want || got[1] != other {
+		t.Errorf("expected [%s %s], got %v", want, other, got)
+	}
+}
+
+func TestPluginProvider_Calls(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "echo", `{"result":{"ready":true,"controlPlaneTotal":3,"components":[{"name":"etcd","status":"healthy"}]}}`)
+	p := &PluginProvider{name: "echo", client: plugin.NewClient(filepath.Join(dir, "tdls-easy-k8s-provider-echo"))}
+
+	cfg := &config.ClusterConfig{Name: "demo", Provider: config.ProviderConfig{Type: "echo"}}
+	status, err := p.GetClusterStatus(cfg)
+	if err != nil {
+		t.Fatalf("GetClusterStatus failed: %v", err)
+	}
+	if !status.Ready || status.ControlPlaneTotal != 3 || len(status.Components) != 1 || status.Components[0].Status != "healthy" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	writePlugin(t, dir, "echo", `{"error":"quota exceeded"}`)
+	if err := p.CreateInfrastructure(cfg); err == nil || err.Error() != "quota exceeded" {
+		t.Errorf("expected the plugin's error, got %v", err)
+	}
+}
+
+func TestPluginConfig_MatchesClusterConfig(t *testing.T) {
+	cfg := &config.ClusterConfig{
+		Name:       "demo",
+		Provider:   config.ProviderConfig{Type: "echo", Settings: map[string]interface{}{"zone": "a"}},
+		Kubernetes: config.KubernetesConfig{Version: "1.30", Distribution: "rke2", APIServer: config.APIServerConfig{Hostname: "k8s.example.com"}},
+		Nodes: config.NodesConfig{
+			ControlPlane: config.NodeGroupConfig{Count: 3, InstanceType: "large", CPU: 4, MemoryMB: 8192, DiskGB: 50},
+			Workers:      config.NodeGroupConfig{Count: 2, Spot: true, OnDemandBase: 1, Autoscaling: config.AutoscalingConfig{Min: 1, Max: 5}},
+		},
+		GitOps: config.GitOpsConfig{Path: "clusters/demo"},
+	}
+
+	raw, err := plugin.EncodeConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := plugin.DecodeConfig(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := plugin.ClusterConfig{
+		Name:       "demo",
+		Provider:   plugin.ProviderConfig{Type: "echo", Settings: map[string]interface{}{"zone": "a"}},
+		Kubernetes: plugin.KubernetesConfig{Version: "1.30", Distribution: "rke2", APIServer: plugin.APIServerConfig{Hostname: "k8s.example.com"}},
+		Nodes: plugin.NodesConfig{
+			ControlPlane: plugin.NodeGroupConfig{Count: 3, InstanceType: "large", CPU: 4, MemoryMB: 8192, DiskGB: 50},
+			Workers:      plugin.NodeGroupConfig{Count: 2, Spot: true, OnDemandBase: 1, Autoscaling: plugin.AutoscalingConfig{Min: 1, Max: 5}},
+		},
+	}
+	if got.Name != want.Name || got.Provider.Type != want.Provider.Type || got.Provider.Settings["zone"] != "a" ||
+		got.Kubernetes.Version != want.Kubernetes.Version || got.Kubernetes.Distribution != want.Kubernetes.Distribution ||
+		got.Kubernetes.APIServer.Hostname != want.Kubernetes.APIServer.Hostname || got.Nodes != want.Nodes {
+		t.Errorf("plugin config does not match the cluster config:\n got: %+v\nwant: %+v", got, want)
+	}
+	if gitops, _ := got.Other["gitops"].(map[string]interface{}); gitops["path"] != "clusters/demo" {
+		t.Errorf("expected the gitops section in Other, got %v", got.Other)
+	}
+}
@@ -39,6 +39,74 @@ type Provider interface {
 	ValidatePodScheduling(config *config.ClusterConfig) (string, error)
 }
 
+// FieldValidator is implemented by providers that can validate a single config
+// value, identified by its YAML path (e.g. "provider.aws.region"). It applies the
+// same rules as ValidateConfig but does not check credentials, so answers can be
+// checked one at a time while a config is being built.
+type FieldValidator interface {
+	ValidateField(field, value string) error
+}
+
+// NodeAccessor is implemented by providers whose machines can be listed and reached
+// directly, for debugging nodes without going through Kubernetes.
+type NodeAccessor interface {
+	// ListNodes returns the cluster's machines as recorded in the infrastructure state
+	ListNodes(config *config.ClusterConfig) ([]Node, error)
+
+	// Shell opens an interactive shell on a node, attached to the terminal
+	Shell(config *config.ClusterConfig, node Node) error
+
+	// CommandRunner prepares a runner for shell commands on the cluster's nodes
+	CommandRunner(config *config.ClusterConfig) (CommandRunner, error)
+}
+
+// NodeReplacer is implemented by providers that can recreate a single machine
+type NodeReplacer interface {
+	// CanReplace returns why a node's machine cannot be recreated on its own,
+	// e.g. because it bootstraps the cluster. It is checked before the node is
+	// drained and removed from the cluster.
+	CanReplace(node Node) error
+
+	// ReplaceNode destroys a node's machine and creates a new one in its place.
+	// Removing the node from Kubernetes is up to the caller.
+	ReplaceNode(config *config.ClusterConfig, node Node) error
+}
+
+// APITunneler is implemented by providers whose clusters can keep the API server
+// private, reachable only through a tunnel
+type APITunneler interface {
+	// TunnelCommand returns the command that opens the tunnel a private cluster's
+	// kubeconfig points at, or nil if the API server is reachable directly
+	TunnelCommand(config *config.ClusterConfig) ([]string, error)
+}
+
+// AccountReporter is implemented by providers that can tell which cloud account
+// a cluster would be deployed to, so it can be checked before anything is created
+type AccountReporter interface {
+	// Account describes the account the configured credentials resolve to
+	Account(config *config.ClusterConfig) (string, error)
+}
+
+// CommandRunner runs a shell command on a node and returns its combined output.
+// It is safe for concurrent use.
+type CommandRunner func(node Node, command string) ([]byte, error)
+
+// Node roles
+const (
+	RoleControlPlane = "control-plane"
+	RoleWorker       = "worker"
+)
+
+// Node is a machine of the cluster
+type Node struct {
+	Name       string // machine name, e.g. "prod-cp-0"
+	Role       string // RoleControlPlane or RoleWorker
+	InstanceID string // cloud instance ID, when the provider has one
+	IP         string // address used to reach the node
+	PrivateIP  string // address inside the cluster network, when different from IP
+	Autoscaled bool   // in a group resized by the cluster autoscaler, which replaces it
+}
+
 // ClusterStatus represents the overall status of a cluster
 type ClusterStatus struct {
 	Ready             bool
@@ -59,22 +127,6 @@ type ComponentStatus struct {
 	Message string
 }
 
-// GetProvider returns a provider instance based on the provider type
-func GetProvider(providerType string) (Provider, error) {
-	switch providerType {
-	case "aws":
-		return NewAWSProvider(), nil
-	case "vsphere":
-		return NewVSphereProvider(), nil
-	case "hetzner":
-		return NewHetznerProvider(), nil
-	case "proxmox":
-		return NewProxmoxProvider(), nil
-	default:
-		return nil, ErrUnsupportedProvider
-	}
-}
-
 // Error definitions
 var (
 	ErrUnsupportedProvider = &ProviderError{Message: "unsupported provider type"}
@@ -1,6 +1,11 @@
 package provider
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+)
 
 func TestGetProvider_AWS(t *testing.T) {
 	p, err := GetProvider("aws")
@@ -65,3 +70,125 @@ func TestProviderError_Error(t *testing.T) {
 		t.Errorf("expected 'test error', got %q", err.Error())
 	}
 }
+
+func TestRegistry_Names(t *testing.T) {
+	names := strings.Join(Names(), ",")
+	if names != "aws,hetzner,proxmox,vsphere" {
+		t.Errorf("expected all built-in providers in order, got %q", names)
+	}
+}
+
+func TestRegistry_Capabilities(t *testing.T) {
+	cases := []struct {
+		name string
+		want Capabilities
+	}{
+		{"aws", Capabilities{IngressLoadBalancer: true, SpotInstances: true, SSM: true, ObjectStorage: true, Autoscaling: true}},
+		{"hetzner", Capabilities{IngressLoadBalancer: true, Autoscaling: true}},
+		{"proxmox", Capabilities{VIP: true}},
+		{"vsphere", Capabilities{}},
+	}
+	for _, tc := range cases {
+		reg, ok := Lookup(tc.name)
+		if !ok {
+			t.Errorf("expected %q to be registered", tc.name)
+			continue
+		}
+		if reg.Capabilities != tc.want {
+			t.Errorf("%s: expected capabilities %+v, got %+v", tc.name, tc.want, reg.Capabilities)
+		}
+		if len(reg.Resources) == 0 {
+			t.Errorf("%s: expected destroy resources to be listed", tc.name)
+		}
+	}
+}
+
+func TestRegistry_ObjectStorageCapabilityImplemented(t *testing.T) {
+	for _, name := range Names() {
+		reg, _ := Lookup(name)
+		_, implements := reg.New().(ObjectStorage)
+		if reg.Capabilities.ObjectStorage != implements {
+			t.Errorf("%s: ObjectStorage capability is %v but interface implemented is %v", name, reg.Capabilities.ObjectStorage, implements)
+		}
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic when registering a provider twice")
+		}
+	}()
+	Register(Registration{Name: "aws", New: func() Provider { return NewAWSProvider() }})
+}
+
+func TestCapabilities_String(t *testing.T) {
+	if got := (Capabilities{VIP: true, SSM: true}).String(); got != "vip, ssm" {
+		t.Errorf("expected 'vip, ssm', got %q", got)
+	}
+	if got := (Capabilities{}).String(); got != "none" {
+		t.Errorf("expected 'none', got %q", got)
+	}
+}
+
+func TestNodeAccessors(t *testing.T) {
+	for _, name := range []string{"aws", "hetzner", "proxmox"} {
+		p, err := GetProvider(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := p.(NodeAccessor); !ok {
+			t.Errorf("expected %s to implement NodeAccessor", name)
+		}
+		if _, ok := p.(NodeReplacer); !ok {
+			t.Errorf("expected %s to implement NodeReplacer", name)
+		}
+	}
+}
+
+func TestAPITunneler(t *testing.T) {
+	p, err := GetProvider("aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tunneler, ok := p.(APITunneler)
+	if !ok {
+		t.Fatal("expected aws to implement APITunneler")
+	}
+
+	cfg := &config.ClusterConfig{Name: "public", Provider: config.ProviderConfig{Type: "aws"}}
+	command, err := tunneler.TunnelCommand(cfg)
+	if err != nil || command != nil {
+		t.Errorf("expected no tunnel for a public cluster, got %v, %v", command, err)
+	}
+}
+
+func TestAccountReporter(t *testing.T) {
+	p, err := GetProvider("aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(AccountReporter); !ok {
+		t.Error("expected aws to implement AccountReporter")
+	}
+}
+
+func TestIngressLoadBalancer(t *testing.T) {
+	cases := []struct {
+		providerType string
+		traefik      bool
+		want         bool
+	}{
+		{"hetzner", true, true},
+		{"aws", true, true},
+		{"hetzner", false, false},
+		{"proxmox", true, false},
+	}
+	for _, tc := range cases {
+		cfg := &config.ClusterConfig{Provider: config.ProviderConfig{Type: tc.providerType}}
+		cfg.Components.Traefik.Enabled = tc.traefik
+		if got := ingressLoadBalancer(cfg); got != tc.want {
+			t.Errorf("%s with traefik=%v: expected %v, got %v", tc.providerType, tc.traefik, tc.want, got)
+		}
+	}
+}
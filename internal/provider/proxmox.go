@@ -8,11 +8,54 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/user/tdls-easy-k8s/internal/config"
 )
 
+func init() {
+	Register(Registration{
+		Name:        "proxmox",
+		Description: "Proxmox VE (on-premises VMs with kube-vip)",
+		New:         func() Provider { return NewProxmoxProvider() },
+		Capabilities: Capabilities{
+			VIP: true,
+		},
+		Resources: []string{
+			"All virtual machines (control plane and workers)",
+			"Cloud-init snippets and VM disks",
+		},
+	})
+	config.RegisterDefaults("proxmox", applyProxmoxDefaults)
+}
+
+// applyProxmoxDefaults fills in Proxmox defaults for fields left empty in the config.
+// The values match the defaults of the Proxmox Terraform module.
+func applyProxmoxDefaults(cfg *config.ClusterConfig) {
+	if cfg.Provider.Proxmox.Bridge == "" {
+		cfg.Provider.Proxmox.Bridge = "vmbr0"
+	}
+	if cfg.Provider.Proxmox.Datastore == "" {
+		cfg.Provider.Proxmox.Datastore = "local-lvm"
+	}
+	applyVMSizeDefaults(&cfg.Nodes.ControlPlane, 4, 8192, 50)
+	applyVMSizeDefaults(&cfg.Nodes.Workers, 4, 8192, 100)
+}
+
+// applyVMSizeDefaults sets the CPU, memory and disk of a node group where unset.
+func applyVMSizeDefaults(group *config.NodeGroupConfig, cpu, memoryMB, diskGB int) {
+	if group.CPU == 0 {
+		group.CPU = cpu
+	}
+	if group.MemoryMB == 0 {
+		group.MemoryMB = memoryMB
+	}
+	if group.DiskGB == 0 {
+		group.DiskGB = diskGB
+	}
+}
+
 // ProxmoxProvider implements the Provider interface for Proxmox VE
 type ProxmoxProvider struct {
 	workDir string
@@ -34,23 +77,44 @@ func (p *ProxmoxProvider) ValidateConfig(cfg *config.ClusterConfig) error {
 		return fmt.Errorf("provider type must be 'proxmox'")
 	}
 
-	if cfg.Provider.Node == "" {
-		return fmt.Errorf("Proxmox node name is required (set provider.node, e.g. 'pve')")
-	}
-
-	// VIP is required (no cloud LB available)
-	if cfg.Provider.VIP == "" {
-		return fmt.Errorf("kube-vip VIP address is required (set provider.vip)\nThis must be a free IP on your network for the Kubernetes API endpoint")
+	if err := p.ValidateField("provider.proxmox.node", cfg.Provider.Proxmox.Node); err != nil {
+		return err
 	}
 
-	if net.ParseIP(cfg.Provider.VIP) == nil {
-		return fmt.Errorf("invalid VIP address %q: must be a valid IPv4 address", cfg.Provider.VIP)
+	if err := p.ValidateField("provider.proxmox.vip", cfg.Provider.Proxmox.VIP); err != nil {
+		return err
 	}
 
 	if cfg.Nodes.ControlPlane.Count < 1 {
 		return fmt.Errorf("at least one control plane node is required")
 	}
 
+	// Unset sizes take the defaults of the Terraform module
+	groups := []struct {
+		prefix string
+		group  config.NodeGroupConfig
+	}{
+		{"nodes.controlPlane", cfg.Nodes.ControlPlane},
+		{"nodes.workers", cfg.Nodes.Workers},
+	}
+	for _, g := range groups {
+		if g.group.Count == 0 {
+			continue
+		}
+		sizes := []struct {
+			field string
+			value int
+		}{{".cpu", g.group.CPU}, {".memoryMB", g.group.MemoryMB}, {".diskGB", g.group.DiskGB}}
+		for _, size := range sizes {
+			if size.value == 0 {
+				continue
+			}
+			if err := p.ValidateField(g.prefix+size.field, strconv.Itoa(size.value)); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Check Proxmox API credentials
 	if os.Getenv("PROXMOX_VE_ENDPOINT") == "" {
 		return fmt.Errorf("PROXMOX_VE_ENDPOINT environment variable is required (e.g. https://proxmox.local:8006)")
@@ -63,6 +127,43 @@ func (p *ProxmoxProvider) ValidateConfig(cfg *config.ClusterConfig) error {
 	return nil
 }
 
+// ValidateField validates a single Proxmox config value.
+func (p *ProxmoxProvider) ValidateField(field, value string) error {
+	switch field {
+	case "provider.proxmox.node":
+		if value == "" {
+			return fmt.Errorf("Proxmox node name is required (set provider.proxmox.node, e.g. 'pve')")
+		}
+	case "provider.proxmox.vip":
+		// With the VIP capability kube-vip replaces a cloud load balancer
+		if value == "" && capabilitiesOf("proxmox").VIP {
+			return fmt.Errorf("kube-vip VIP address is required (set provider.proxmox.vip)\nThis must be a free IP on your network for the Kubernetes API endpoint")
+		}
+		if net.ParseIP(value) == nil {
+			return fmt.Errorf("invalid VIP address %q: must be a valid IPv4 address", value)
+		}
+	case "nodes.controlPlane.cpu", "nodes.workers.cpu":
+		return validateVMSize(field, value, 1)
+	case "nodes.controlPlane.memoryMB", "nodes.workers.memoryMB":
+		return validateVMSize(field, value, 1024)
+	case "nodes.controlPlane.diskGB", "nodes.workers.diskGB":
+		return validateVMSize(field, value, 10)
+	}
+	return nil
+}
+
+// validateVMSize checks a CPU, memory or disk size of a VM against its minimum
+func validateVMSize(field, value string, min int) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a number", field, value)
+	}
+	if n < min {
+		return fmt.Errorf("%s must be at least %d, got %d", field, min, n)
+	}
+	return nil
+}
+
 // CreateInfrastructure creates the Proxmox infrastructure for the cluster
 func (p *ProxmoxProvider) CreateInfrastructure(cfg *config.ClusterConfig) error {
 	fmt.Println("[Proxmox] Creating infrastructure for cluster:", cfg.Name)
@@ -201,6 +302,49 @@ func (p *ProxmoxProvider) GetClusterStatus(cfg *config.ClusterConfig) (*ClusterS
 	return kubectlGetClusterStatus(kubeconfigPath, apiEndpoint)
 }
 
+// proxmoxMachines are the resources holding the cluster's VMs
+var proxmoxMachines = machineResources{
+	ControlPlane: "proxmox_virtual_environment_vm.control_plane_join",
+	Worker:       "proxmox_virtual_environment_vm.worker",
+}
+
+// CanReplace checks that a node's VM can be recreated on its own
+func (p *ProxmoxProvider) CanReplace(node Node) error {
+	return proxmoxMachines.canReplace(node)
+}
+
+// ReplaceNode recreates a node's VM
+func (p *ProxmoxProvider) ReplaceNode(cfg *config.ClusterConfig, node Node) error {
+	if err := p.setupWorkingDirectory(cfg); err != nil {
+		return err
+	}
+	return replaceSSHMachine(p.workDir, p.runTofu, proxmoxMachines, node)
+}
+
+// ListNodes returns the servers of the cluster from the Terraform outputs
+func (p *ProxmoxProvider) ListNodes(cfg *config.ClusterConfig) ([]Node, error) {
+	if err := p.setupWorkingDirectory(cfg); err != nil {
+		return nil, err
+	}
+	return sshNodes(p.workDir, cfg.Name)
+}
+
+// Shell opens an SSH session on a node
+func (p *ProxmoxProvider) Shell(cfg *config.ClusterConfig, node Node) error {
+	if err := p.setupWorkingDirectory(cfg); err != nil {
+		return err
+	}
+	return sshShell(p.workDir, node)
+}
+
+// CommandRunner runs commands on nodes over SSH
+func (p *ProxmoxProvider) CommandRunner(cfg *config.ClusterConfig) (CommandRunner, error) {
+	if err := p.setupWorkingDirectory(cfg); err != nil {
+		return nil, err
+	}
+	return sshCommandRunner(p.workDir)
+}
+
 // --- Validation methods (delegate to common kubectl logic) ---
 
 func (p *ProxmoxProvider) ValidateAPIServer(cfg *config.ClusterConfig) (string, error) {
@@ -379,31 +523,53 @@ func (p *ProxmoxProvider) cleanTerraformSourceFiles() error {
 }
 
 func (p *ProxmoxProvider) generateTerraformVars(cfg *config.ClusterConfig) error {
-	bridge := cfg.Provider.Bridge
+	bridge := cfg.Provider.Proxmox.Bridge
 	if bridge == "" {
 		bridge = "vmbr0"
 	}
 
-	datastore := cfg.Provider.Datastore
+	datastore := cfg.Provider.Proxmox.Datastore
 	if datastore == "" {
 		datastore = "local-lvm"
 	}
 
 	vars := map[string]interface{}{
 		"cluster_name":       cfg.Name,
-		"proxmox_node":       cfg.Provider.Node,
+		"proxmox_node":       cfg.Provider.Proxmox.Node,
 		"bridge":             bridge,
 		"datastore":          datastore,
-		"vip_address":        cfg.Provider.VIP,
+		"vip_address":        cfg.Provider.Proxmox.VIP,
 		"cp_count":           cfg.Nodes.ControlPlane.Count,
 		"worker_count":       cfg.Nodes.Workers.Count,
 		"kubernetes_version": cfg.Kubernetes.Version,
 	}
 
-	if cfg.Provider.VlanTag > 0 {
-		vars["vlan_tag"] = cfg.Provider.VlanTag
+	// VM sizing falls back to the module defaults when unset
+	sizes := map[string]int{
+		"cp_cpu":           cfg.Nodes.ControlPlane.CPU,
+		"cp_memory_mb":     cfg.Nodes.ControlPlane.MemoryMB,
+		"cp_disk_gb":       cfg.Nodes.ControlPlane.DiskGB,
+		"worker_cpu":       cfg.Nodes.Workers.CPU,
+		"worker_memory_mb": cfg.Nodes.Workers.MemoryMB,
+		"worker_disk_gb":   cfg.Nodes.Workers.DiskGB,
+	}
+	for name, value := range sizes {
+		if value > 0 {
+			vars[name] = value
+		}
+	}
+
+	if cfg.Provider.Proxmox.VlanTag > 0 {
+		vars["vlan_tag"] = cfg.Provider.Proxmox.VlanTag
 	}
 
+	if cfg.Provider.Proxmox.SnippetsDatastore != "" {
+		vars["snippets_datastore"] = cfg.Provider.Proxmox.SnippetsDatastore
+	}
+
+	// API server settings such as OIDC authentication
+	mergeVars(vars, rke2ServerVars(cfg))
+
 	jsonData, err := json.MarshalIndent(vars, "", "  ")
 	if err != nil {
 		return err
@@ -454,7 +620,8 @@ func (p *ProxmoxProvider) fixProviderPermissions() error {
 	})
 }
 
-// downloadKubeconfig retrieves kubeconfig via SSH from the first control plane node.
+// downloadKubeconfig retrieves kubeconfig via SSH from the first reachable control plane
+// node and points it at the API hostname or the VIP.
 func (p *ProxmoxProvider) downloadKubeconfig(cfg *config.ClusterConfig) (string, error) {
 	if p.workDir == "" {
 		if err := p.setupWorkingDirectory(cfg); err != nil {
@@ -462,78 +629,9 @@ func (p *ProxmoxProvider) downloadKubeconfig(cfg *config.ClusterConfig) (string,
 		}
 	}
 
-	// Get the first control plane IP
-	firstCPIP, err := p.getTerraformOutput("first_cp_ip")
-	if err != nil || firstCPIP == "" {
-		return "", fmt.Errorf("failed to get control plane IP: %w", err)
-	}
-
-	// Get the SSH private key from terraform output
-	sshKeyCmd := exec.Command("tofu", "output", "-raw", "ssh_private_key")
-	sshKeyCmd.Dir = p.workDir
-	sshKeyOutput, err := sshKeyCmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to get SSH private key: %w", err)
-	}
-
-	// Write SSH key to temp file
-	sshKeyFile, err := os.CreateTemp("", "proxmox-ssh-key-*")
-	if err != nil {
-		return "", err
-	}
-	defer os.Remove(sshKeyFile.Name())
-
-	if _, err := sshKeyFile.Write(sshKeyOutput); err != nil {
-		sshKeyFile.Close()
-		return "", err
-	}
-	sshKeyFile.Close()
-	os.Chmod(sshKeyFile.Name(), 0600)
-
-	// SSH into the first control plane node and download kubeconfig
-	sshCmd := exec.Command("ssh",
-		"-i", sshKeyFile.Name(),
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
-		"-o", "ConnectTimeout=10",
-		fmt.Sprintf("root@%s", firstCPIP),
-		"cat /etc/rancher/rke2/rke2.yaml",
-	)
-
-	kubeconfigData, err := sshCmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to retrieve kubeconfig via SSH: %w", err)
-	}
-
-	// Get VIP to patch server URL
-	vipIP, _ := p.getTerraformOutput("vip_address")
-
-	// Patch server URL: replace 127.0.0.1 with VIP
-	kubeconfig := string(kubeconfigData)
-	if vipIP != "" {
-		lines := strings.Split(kubeconfig, "\n")
-		for i, line := range lines {
-			if strings.Contains(line, "server: https://") {
-				lines[i] = fmt.Sprintf("    server: https://%s:6443", vipIP)
-				break
-			}
-		}
-		kubeconfig = strings.Join(lines, "\n")
-	}
-
-	// Write to temp file
-	tmpFile, err := os.CreateTemp("", "kubeconfig-*.yaml")
-	if err != nil {
-		return "", err
-	}
-
-	if _, err := tmpFile.WriteString(kubeconfig); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpFile.Name())
-		return "", err
+	apiHost := cfg.Kubernetes.APIServer.Hostname
+	if apiHost == "" {
+		apiHost, _ = p.getTerraformOutput("vip_address")
 	}
-	tmpFile.Close()
-	os.Chmod(tmpFile.Name(), 0600)
-
-	return tmpFile.Name(), nil
+	return fetchKubeconfig(p.workDir, apiHost)
 }
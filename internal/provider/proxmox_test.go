@@ -1,8 +1,10 @@
 package provider
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/user/tdls-easy-k8s/internal/config"
@@ -19,11 +21,13 @@ func validProxmoxConfig() *config.ClusterConfig {
 	return &config.ClusterConfig{
 		Name: "test-proxmox-cluster",
 		Provider: config.ProviderConfig{
-			Type:      "proxmox",
-			Node:      "pve",
-			Bridge:    "vmbr0",
-			Datastore: "local-lvm",
-			VIP:       "192.168.1.200",
+			Type: "proxmox",
+			Proxmox: config.ProxmoxConfig{
+				Node:      "pve",
+				Bridge:    "vmbr0",
+				Datastore: "local-lvm",
+				VIP:       "192.168.1.200",
+			},
 		},
 		Kubernetes: config.KubernetesConfig{
 			Version:      "1.30",
@@ -48,7 +52,7 @@ func TestProxmoxProvider_ValidateConfig_WrongType(t *testing.T) {
 func TestProxmoxProvider_ValidateConfig_MissingNode(t *testing.T) {
 	p := NewProxmoxProvider()
 	cfg := validProxmoxConfig()
-	cfg.Provider.Node = ""
+	cfg.Provider.Proxmox.Node = ""
 	// Set env vars so we don't fail on those checks first
 	t.Setenv("PROXMOX_VE_ENDPOINT", "https://proxmox.local:8006")
 	t.Setenv("PROXMOX_VE_API_TOKEN", "test@pve!provider=xxx")
@@ -61,7 +65,7 @@ func TestProxmoxProvider_ValidateConfig_MissingNode(t *testing.T) {
 func TestProxmoxProvider_ValidateConfig_MissingVIP(t *testing.T) {
 	p := NewProxmoxProvider()
 	cfg := validProxmoxConfig()
-	cfg.Provider.VIP = ""
+	cfg.Provider.Proxmox.VIP = ""
 	t.Setenv("PROXMOX_VE_ENDPOINT", "https://proxmox.local:8006")
 	t.Setenv("PROXMOX_VE_API_TOKEN", "test@pve!provider=xxx")
 	err := p.ValidateConfig(cfg)
@@ -73,7 +77,7 @@ func TestProxmoxProvider_ValidateConfig_MissingVIP(t *testing.T) {
 func TestProxmoxProvider_ValidateConfig_InvalidVIP(t *testing.T) {
 	p := NewProxmoxProvider()
 	cfg := validProxmoxConfig()
-	cfg.Provider.VIP = "not-an-ip"
+	cfg.Provider.Proxmox.VIP = "not-an-ip"
 	t.Setenv("PROXMOX_VE_ENDPOINT", "https://proxmox.local:8006")
 	t.Setenv("PROXMOX_VE_API_TOKEN", "test@pve!provider=xxx")
 	err := p.ValidateConfig(cfg)
@@ -82,6 +86,22 @@ func TestProxmoxProvider_ValidateConfig_InvalidVIP(t *testing.T) {
 	}
 }
 
+func TestProxmoxProvider_ValidateConfig_VMSize(t *testing.T) {
+	p := NewProxmoxProvider()
+	cfg := validProxmoxConfig()
+	cfg.Nodes.ControlPlane.MemoryMB = 512
+	t.Setenv("PROXMOX_VE_ENDPOINT", "https://proxmox.local:8006")
+	t.Setenv("PROXMOX_VE_API_TOKEN", "test@pve!provider=xxx")
+	err := p.ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "nodes.controlPlane.memoryMB must be at least 1024") {
+		t.Errorf("expected error for too little memory, got %v", err)
+	}
+
+	if err := p.ValidateField("nodes.workers.diskGB", "big"); err == nil {
+		t.Error("expected error for a disk size that is not a number")
+	}
+}
+
 func TestProxmoxProvider_ValidateConfig_MissingEndpoint(t *testing.T) {
 	p := NewProxmoxProvider()
 	cfg := validProxmoxConfig()
@@ -115,6 +135,53 @@ func TestProxmoxProvider_ValidateConfig_Valid(t *testing.T) {
 	}
 }
 
+func TestApplyProxmoxDefaults(t *testing.T) {
+	cfg := &config.ClusterConfig{Provider: config.ProviderConfig{Type: "proxmox"}}
+	config.ApplyDefaults(cfg)
+	if cfg.Provider.Proxmox.Bridge != "vmbr0" {
+		t.Errorf("expected default bridge 'vmbr0', got %q", cfg.Provider.Proxmox.Bridge)
+	}
+	if cfg.Provider.Proxmox.Datastore != "local-lvm" {
+		t.Errorf("expected default datastore 'local-lvm', got %q", cfg.Provider.Proxmox.Datastore)
+	}
+	if cfg.Nodes.ControlPlane.CPU != 4 || cfg.Nodes.ControlPlane.MemoryMB != 8192 || cfg.Nodes.ControlPlane.DiskGB != 50 {
+		t.Errorf("unexpected control plane sizing defaults: %+v", cfg.Nodes.ControlPlane)
+	}
+	if cfg.Nodes.Workers.DiskGB != 100 {
+		t.Errorf("expected default worker disk 100 GB, got %d", cfg.Nodes.Workers.DiskGB)
+	}
+	if cfg.Nodes.ControlPlane.InstanceType != "" {
+		t.Errorf("expected no instance type default for proxmox, got %q", cfg.Nodes.ControlPlane.InstanceType)
+	}
+}
+
+func TestProxmoxProvider_GenerateTerraformVars_Sizing(t *testing.T) {
+	p := &ProxmoxProvider{workDir: t.TempDir()}
+	cfg := validProxmoxConfig()
+	cfg.Nodes.ControlPlane.CPU = 2
+	cfg.Nodes.ControlPlane.MemoryMB = 4096
+	cfg.Nodes.Workers.DiskGB = 200
+
+	if err := p.generateTerraformVars(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.workDir, "terraform.tfvars.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vars map[string]interface{}
+	if err := json.Unmarshal(data, &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars["cp_cpu"] != float64(2) || vars["cp_memory_mb"] != float64(4096) || vars["worker_disk_gb"] != float64(200) {
+		t.Errorf("expected sizing variables in tfvars, got %v", vars)
+	}
+	if _, ok := vars["worker_cpu"]; ok {
+		t.Error("expected unset sizing to fall back to the module default")
+	}
+}
+
 func TestProxmoxProvider_DestroyInfrastructure_NoState(t *testing.T) {
 	p := NewProxmoxProvider()
 	cfg := &config.ClusterConfig{
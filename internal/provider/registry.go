@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+)
+
+// Capabilities describes the optional features a provider supports. Commands consult
+// these instead of switching on the provider name.
+type Capabilities struct {
+	IngressLoadBalancer bool // A cloud load balancer in front of the ingress controller
+	VIP                 bool // A virtual IP (kube-vip) instead of a cloud load balancer for the API server
+	SpotInstances       bool // Workers can run on spot/preemptible instances
+	SSM                 bool // Nodes are reachable through AWS Systems Manager
+	ObjectStorage       bool // Cluster state and kubeconfig are kept in an object storage bucket
+	Autoscaling         bool // The cluster autoscaler can resize the worker group
+}
+
+// Registration describes a provider in the registry
+type Registration struct {
+	// Name is the provider type used in cluster configs (e.g., "aws")
+	Name string
+
+	// Description is a short human-readable description
+	Description string
+
+	// New creates a provider instance
+	New func() Provider
+
+	// Capabilities lists the optional features the provider supports
+	Capabilities Capabilities
+
+	// Resources lists the infrastructure removed by destroy, shown before confirmation
+	Resources []string
+
+	// Plugin is the path of the plugin executable; empty for built-in providers
+	Plugin string
+}
+
+// ObjectStorage is implemented by providers with the ObjectStorage capability
+type ObjectStorage interface {
+	// ObjectStorageName returns the name of the cluster's bucket
+	ObjectStorageName(config *config.ClusterConfig) string
+
+	// DeleteObjectStorage empties and deletes the cluster's bucket
+	DeleteObjectStorage(config *config.ClusterConfig) error
+}
+
+var registry = map[string]Registration{}
+
+// Register adds a provider to the registry. Providers call this from their init function.
+func Register(r Registration) {
+	if r.Name == "" || r.New == nil {
+		panic("provider: Register requires a name and a constructor")
+	}
+	if _, exists := registry[r.Name]; exists {
+		panic(fmt.Sprintf("provider: %q registered twice", r.Name))
+	}
+	registry[r.Name] = r
+}
+
+// Lookup returns the registration for a provider type
+func Lookup(name string) (Registration, bool) {
+	if err := LoadPlugin(name); err != nil {
+		return Registration{}, false
+	}
+	r, ok := registry[name]
+	return r, ok
+}
+
+// Names returns the registered provider types and the plugins found by LoadPlugins,
+// in alphabetical order
+func Names() []string {
+	names := make([]string, 0, len(registry)+len(discovered))
+	for name := range registry {
+		names = append(names, name)
+	}
+	for name := range discovered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetProvider returns a provider instance based on the provider type
+func GetProvider(providerType string) (Provider, error) {
+	if err := LoadPlugin(providerType); err != nil {
+		return nil, err
+	}
+	r, ok := registry[providerType]
+	if !ok {
+		return nil, ErrUnsupportedProvider
+	}
+	return r.New(), nil
+}
+
+// capabilitiesOf returns the capabilities registered for a provider type
+func capabilitiesOf(providerType string) Capabilities {
+	return registry[providerType].Capabilities
+}
+
+// ingressLoadBalancer reports whether the cluster gets a cloud load balancer in
+// front of the ingress controller
+func ingressLoadBalancer(cfg *config.ClusterConfig) bool {
+	return cfg.Components.Traefik.Enabled && capabilitiesOf(cfg.Provider.Type).IngressLoadBalancer
+}
+
+// List returns the names of the enabled capabilities
+func (c Capabilities) List() []string {
+	var names []string
+	if c.IngressLoadBalancer {
+		names = append(names, "ingress-lb")
+	}
+	if c.VIP {
+		names = append(names, "vip")
+	}
+	if c.SpotInstances {
+		names = append(names, "spot")
+	}
+	if c.SSM {
+		names = append(names, "ssm")
+	}
+	if c.ObjectStorage {
+		names = append(names, "object-storage")
+	}
+	if c.Autoscaling {
+		names = append(names, "autoscaling")
+	}
+	return names
+}
+
+// String returns the enabled capabilities as a comma-separated list
+func (c Capabilities) String() string {
+	names := c.List()
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
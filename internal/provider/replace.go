@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/user/tdls-easy-k8s/internal/sshclient"
+)
+
+// machineResources names the Terraform resources that hold a cluster's machines.
+// The bootstrap control-plane node is a single resource; the other control-plane
+// nodes and the workers are counted resources.
+type machineResources struct {
+	ControlPlane string // e.g. hcloud_server.control_plane_join
+	Worker       string // e.g. hcloud_server.worker
+
+	// Resources that refer to a machine, such as load balancer targets and volume
+	// attachments, are applied along with it
+	ControlPlaneDependents []dependentResource
+	WorkerDependents       []dependentResource
+}
+
+// dependentResource is a counted resource with an entry per machine
+type dependentResource struct {
+	Address string // e.g. hcloud_load_balancer_target.cp_join
+	Offset  int    // added to the machine's index, for lists that start with the bootstrap node
+}
+
+// address returns the Terraform address of a node's machine
+func (r machineResources) address(node Node) (string, error) {
+	addresses, err := r.addresses(node)
+	if err != nil {
+		return "", err
+	}
+	return addresses[0], nil
+}
+
+// addresses returns the Terraform address of a node's machine followed by those
+// of the resources that refer to it
+func (r machineResources) addresses(node Node) ([]string, error) {
+	index, err := nodeIndex(node.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var machine string
+	var dependents []dependentResource
+	switch node.Role {
+	case RoleControlPlane:
+		if index == 0 {
+			return nil, fmt.Errorf("%s bootstraps the cluster and cannot be replaced on its own: the other nodes join through it", node.Name)
+		}
+		index--
+		machine, dependents = r.ControlPlane, r.ControlPlaneDependents
+	case RoleWorker:
+		machine, dependents = r.Worker, r.WorkerDependents
+	default:
+		return nil, fmt.Errorf("node %s has unknown role %q", node.Name, node.Role)
+	}
+
+	addresses := []string{fmt.Sprintf("%s[%d]", machine, index)}
+	for _, d := range dependents {
+		addresses = append(addresses, fmt.Sprintf("%s[%d]", d.Address, index+d.Offset))
+	}
+	return addresses, nil
+}
+
+// canReplace checks that a node's machine can be recreated on its own
+func (r machineResources) canReplace(node Node) error {
+	if node.Autoscaled {
+		return fmt.Errorf("%s is in an autoscaled group, which replaces it: drain it with 'kubectl drain' and terminate the instance", node.Name)
+	}
+	_, err := r.address(node)
+	return err
+}
+
+// nodeIndex returns the position of a machine from the number its name ends with
+func nodeIndex(name string) (int, error) {
+	i := strings.LastIndex(name, "-")
+	index, err := strconv.Atoi(name[i+1:])
+	if i < 0 || err != nil || index < 0 {
+		return 0, fmt.Errorf("cannot tell the position of node %q from its name", name)
+	}
+	return index, nil
+}
+
+// replaceMachine recreates the machine at addresses[0]. Only the machine, the
+// resources that refer to it (the rest of addresses) and what they depend on are
+// applied, so unrelated changes to the configuration are not rolled out along
+// with it.
+func replaceMachine(runTofu func(args ...string) error, addresses []string) error {
+	fmt.Println("\n[OpenTofu] Initializing...")
+	if err := runTofu("init"); err != nil {
+		return fmt.Errorf("terraform init failed: %w", err)
+	}
+
+	fmt.Printf("\n[OpenTofu] Replacing %s...\n", addresses[0])
+	args := []string{"apply", "-auto-approve", "-replace=" + addresses[0]}
+	for _, address := range addresses {
+		args = append(args, "-target="+address)
+	}
+	if err := runTofu(args...); err != nil {
+		return fmt.Errorf("terraform apply failed: %w", err)
+	}
+	return nil
+}
+
+// replaceSSHMachine recreates a machine that is reached over SSH and forgets its
+// pinned host key, since the new machine may get the same address
+func replaceSSHMachine(workDir string, runTofu func(args ...string) error, resources machineResources, node Node) error {
+	addresses, err := resources.addresses(node)
+	if err != nil {
+		return err
+	}
+	if err := replaceMachine(runTofu, addresses); err != nil {
+		return err
+	}
+	return sshclient.RemoveHost(knownHostsFile(workDir), node.IP)
+}
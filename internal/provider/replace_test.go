@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMachineResources_Address(t *testing.T) {
+	cases := []struct {
+		node Node
+		want string
+		err  string
+	}{
+		{Node{Name: "prod-cp-1", Role: RoleControlPlane}, "hcloud_server.control_plane_join[0]", ""},
+		{Node{Name: "prod-cp-2", Role: RoleControlPlane}, "hcloud_server.control_plane_join[1]", ""},
+		{Node{Name: "prod-worker-0", Role: RoleWorker}, "hcloud_server.worker[0]", ""},
+		{Node{Name: "prod-cp-0", Role: RoleControlPlane}, "", "bootstraps the cluster"},
+		{Node{Name: "prod-worker", Role: RoleWorker}, "", "cannot tell the position"},
+	}
+
+	for _, tc := range cases {
+		address, err := hetznerMachines.address(tc.node)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: expected error containing %q, got %v", tc.node.Name, tc.err, err)
+			}
+			continue
+		}
+		if err != nil || address != tc.want {
+			t.Errorf("%s: expected %s, got %s, %v", tc.node.Name, tc.want, address, err)
+		}
+	}
+
+	address, err := awsMachines.address(Node{Name: "prod-control-plane-2", Role: RoleControlPlane})
+	if err != nil || address != "module.control_plane.aws_instance.control_plane[1]" {
+		t.Errorf("unexpected AWS address %s, %v", address, err)
+	}
+}
+
+func TestReplaceMachine(t *testing.T) {
+	var calls []string
+	runTofu := func(args ...string) error {
+		calls = append(calls, strings.Join(args, " "))
+		return nil
+	}
+
+	addresses, err := hetznerMachines.addresses(Node{Name: "prod-worker-1", Role: RoleWorker})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceMachine(runTofu, addresses); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"init", "apply -auto-approve -replace=hcloud_server.worker[1] -target=hcloud_server.worker[1] -target=hcloud_load_balancer_target.ingress_worker[1]"}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected tofu calls %q, got %q", want, calls)
+	}
+}
+
+func TestMachineResources_Dependents(t *testing.T) {
+	cases := []struct {
+		resources machineResources
+		node      Node
+		want      []string
+	}{
+		{hetznerMachines, Node{Name: "prod-cp-2", Role: RoleControlPlane}, []string{
+			"hcloud_server.control_plane_join[1]",
+			"hcloud_load_balancer_target.cp_join[1]",
+		}},
+		// The NLB attachments list the bootstrap instance first
+		{awsMachines, Node{Name: "prod-control-plane-2", Role: RoleControlPlane}, []string{
+			"module.control_plane.aws_instance.control_plane[1]",
+			"module.control_plane.aws_volume_attachment.etcd[1]",
+			"module.loadbalancer.aws_lb_target_group_attachment.api_server[2]",
+			"module.loadbalancer.aws_lb_target_group_attachment.rke2_register[2]",
+		}},
+		{awsMachines, Node{Name: "prod-worker-0", Role: RoleWorker}, []string{
+			"module.worker.aws_instance.worker[0]",
+			"module.loadbalancer.aws_lb_target_group_attachment.ingress_http[0]",
+			"module.loadbalancer.aws_lb_target_group_attachment.ingress_https[0]",
+		}},
+		{proxmoxMachines, Node{Name: "prod-worker-3", Role: RoleWorker}, []string{
+			"proxmox_virtual_environment_vm.worker[3]",
+		}},
+	}
+	for _, tc := range cases {
+		got, err := tc.resources.addresses(tc.node)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %v, got %v, %v", tc.node.Name, tc.want, got, err)
+		}
+	}
+}
+
+func TestMachineResources_CanReplace(t *testing.T) {
+	if err := hetznerMachines.canReplace(Node{Name: "prod-worker-1", Role: RoleWorker}); err != nil {
+		t.Errorf("expected a worker to be replaceable, got: %v", err)
+	}
+	if err := hetznerMachines.canReplace(Node{Name: "prod-cp-0", Role: RoleControlPlane}); err == nil {
+		t.Error("expected the bootstrap control plane node to be refused")
+	}
+	if err := awsMachines.canReplace(Node{Name: "prod-worker-i-0abc", Role: RoleWorker, Autoscaled: true}); err == nil || !strings.Contains(err.Error(), "autoscaled") {
+		t.Errorf("expected an autoscaled worker to be refused, got %v", err)
+	}
+}
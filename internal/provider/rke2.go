@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+)
+
+// oidcCAFile is where the control-plane user-data writes the OIDC issuer's CA
+const oidcCAFile = "/etc/rancher/rke2/oidc-ca.pem"
+
+// rke2ServerVars returns the Terraform variables that extend the RKE2 server config
+// on every control-plane node: tls_sans are added to tls-san, rke2_server_config
+// is appended to /etc/rancher/rke2/config.yaml and oidc_ca_pem is written to
+// oidcCAFile.
+func rke2ServerVars(cfg *config.ClusterConfig) map[string]interface{} {
+	vars := map[string]interface{}{}
+	if sans := cfg.Kubernetes.APIServer.SANs(); len(sans) > 0 {
+		vars["tls_sans"] = sans
+	}
+	if serverConfig := rke2ServerConfig(cfg); serverConfig != "" {
+		vars["rke2_server_config"] = serverConfig
+	}
+	if cfg.Auth.OIDC.Enabled() && cfg.Auth.OIDC.CA != "" {
+		vars["oidc_ca_pem"] = cfg.Auth.OIDC.CA
+	}
+	return vars
+}
+
+// rke2ServerConfig renders the RKE2 server settings derived from the cluster config
+func rke2ServerConfig(cfg *config.ClusterConfig) string {
+	oidc := cfg.Auth.OIDC
+	if !oidc.Enabled() {
+		return ""
+	}
+
+	args := []string{
+		"oidc-issuer-url=" + oidc.IssuerURL,
+		"oidc-client-id=" + oidc.ClientID,
+	}
+	optional := []struct{ flag, value string }{
+		{"oidc-username-claim", oidc.UsernameClaim},
+		{"oidc-username-prefix", oidc.UsernamePrefix},
+		{"oidc-groups-claim", oidc.GroupsClaim},
+		{"oidc-groups-prefix", oidc.GroupsPrefix},
+	}
+	for _, o := range optional {
+		if o.value != "" {
+			args = append(args, o.flag+"="+o.value)
+		}
+	}
+	if oidc.CA != "" {
+		args = append(args, "oidc-ca-file="+oidcCAFile)
+	}
+
+	var b strings.Builder
+	b.WriteString("kube-apiserver-arg:\n")
+	for _, arg := range args {
+		fmt.Fprintf(&b, "  - %q\n", arg)
+	}
+	return b.String()
+}
+
+// mergeVars copies extra Terraform variables into vars
+func mergeVars(vars, extra map[string]interface{}) {
+	for name, value := range extra {
+		vars[name] = value
+	}
+}
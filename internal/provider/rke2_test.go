@@ -0,0 +1,125 @@
+package provider
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/user/tdls-easy-k8s/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRKE2ServerConfig_OIDC(t *testing.T) {
+	cfg := &config.ClusterConfig{Auth: config.AuthConfig{OIDC: config.OIDCConfig{
+		IssuerURL:      "https://login.example.com",
+		ClientID:       "kubernetes",
+		UsernameClaim:  "email",
+		UsernamePrefix: "oidc:",
+		GroupsClaim:    "groups",
+		CA:             "-----BEGIN CERTIFICATE-----\n...",
+	}}}
+
+	var rendered struct {
+		Args []string `yaml:"kube-apiserver-arg"`
+	}
+	if err := yaml.Unmarshal([]byte(rke2ServerConfig(cfg)), &rendered); err != nil {
+		t.Fatalf("rendered config is not valid YAML: %v", err)
+	}
+
+	want := []string{
+		"oidc-issuer-url=https://login.example.com",
+		"oidc-client-id=kubernetes",
+		"oidc-username-claim=email",
+		"oidc-username-prefix=oidc:",
+		"oidc-groups-claim=groups",
+		"oidc-ca-file=/etc/rancher/rke2/oidc-ca.pem",
+	}
+	if len(rendered.Args) != len(want) {
+		t.Fatalf("expected %v, got %v", want, rendered.Args)
+	}
+	for i := range want {
+		if rendered.Args[i] != want[i] {
+			t.Errorf("arg %d: expected %q, got %q", i, want[i], rendered.Args[i])
+		}
+	}
+}
+
+func TestRKE2ServerVars_Disabled(t *testing.T) {
+	if vars := rke2ServerVars(&config.ClusterConfig{}); len(vars) != 0 {
+		t.Errorf("expected no variables without OIDC, got %v", vars)
+	}
+}
+
+// terraformVarGenerators writes terraform.tfvars.json with each built-in provider
+var terraformVarGenerators = []struct {
+	name     string
+	generate func(cfg *config.ClusterConfig, workDir string) error
+}{
+	{"aws", func(cfg *config.ClusterConfig, workDir string) error {
+		p := &AWSProvider{workDir: workDir}
+		return p.generateTerraformVars(cfg)
+	}},
+	{"hetzner", func(cfg *config.ClusterConfig, workDir string) error {
+		p := &HetznerProvider{workDir: workDir}
+		return p.generateTerraformVars(cfg)
+	}},
+	{"proxmox", func(cfg *config.ClusterConfig, workDir string) error {
+		p := &ProxmoxProvider{workDir: workDir}
+		return p.generateTerraformVars(cfg)
+	}},
+}
+
+// readTerraformVars reads the terraform.tfvars.json written to workDir
+func readTerraformVars(t *testing.T, workDir string) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(workDir, "terraform.tfvars.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vars map[string]interface{}
+	if err := json.Unmarshal(data, &vars); err != nil {
+		t.Fatal(err)
+	}
+	return vars
+}
+
+func TestGenerateTerraformVars_OIDC(t *testing.T) {
+	oidc := config.OIDCConfig{IssuerURL: "https://login.example.com", ClientID: "kubernetes", CA: "PEM"}
+	for _, tc := range terraformVarGenerators {
+		t.Run(tc.name, func(t *testing.T) {
+			workDir := t.TempDir()
+			cfg := &config.ClusterConfig{Name: "sso", Provider: config.ProviderConfig{Type: tc.name}, Auth: config.AuthConfig{OIDC: oidc}}
+			if err := tc.generate(cfg, workDir); err != nil {
+				t.Fatalf("generateTerraformVars failed: %v", err)
+			}
+
+			vars := readTerraformVars(t, workDir)
+			if vars["rke2_server_config"] != rke2ServerConfig(cfg) || vars["oidc_ca_pem"] != "PEM" {
+				t.Errorf("expected OIDC variables in tfvars, got %v", vars)
+			}
+		})
+	}
+}
+
+func TestGenerateTerraformVars_APIServer(t *testing.T) {
+	apiServer := config.APIServerConfig{Hostname: "k8s.example.com", ExtraSANs: []string{"10.0.0.10"}}
+	for _, tc := range terraformVarGenerators {
+		t.Run(tc.name, func(t *testing.T) {
+			workDir := t.TempDir()
+			cfg := &config.ClusterConfig{
+				Name:       "prod",
+				Provider:   config.ProviderConfig{Type: tc.name},
+				Kubernetes: config.KubernetesConfig{APIServer: apiServer},
+			}
+			if err := tc.generate(cfg, workDir); err != nil {
+				t.Fatalf("generateTerraformVars failed: %v", err)
+			}
+
+			sans, _ := readTerraformVars(t, workDir)["tls_sans"].([]interface{})
+			if len(sans) != 2 || sans[0] != "k8s.example.com" || sans[1] != "10.0.0.10" {
+				t.Errorf("expected tls_sans [k8s.example.com 10.0.0.10], got %v", sans)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/user/tdls-easy-k8s/internal/sshclient"
+)
+
+// rke2KubeconfigPath is the admin kubeconfig written by the RKE2 server
+const rke2KubeconfigPath = "/etc/rancher/rke2/rke2.yaml"
+
+// knownHostsFile is where node host keys are pinned: the cluster directory that
+// contains the terraform working directory
+func knownHostsFile(workDir string) string {
+	return filepath.Join(filepath.Dir(workDir), "known_hosts")
+}
+
+// nodeSSHClient creates an SSH client for the nodes of the cluster in workDir using
+// the ssh_private_key output. The key is only held in memory.
+func nodeSSHClient(workDir string) (*sshclient.Client, error) {
+	cmd := exec.Command("tofu", "output", "-raw", "ssh_private_key")
+	cmd.Dir = workDir
+	key, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SSH private key: %w", err)
+	}
+
+	client, err := sshclient.New(key, knownHostsFile(workDir))
+	if err != nil {
+		return nil, err
+	}
+	client.Log = os.Stderr
+	return client, nil
+}
+
+// terraformOutputList reads a list output of the configuration in workDir
+func terraformOutputList(workDir, outputName string) ([]string, error) {
+	cmd := exec.Command("tofu", "output", "-json", outputName)
+	cmd.Dir = workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get output %s: %w", outputName, err)
+	}
+	return parseOutputList(output)
+}
+
+// parseOutputList decodes a JSON list of strings. Entries keep their positions, so
+// lists of IDs and IPs of the same machines line up.
+func parseOutputList(data []byte) ([]string, error) {
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse output list: %w", err)
+	}
+	return values, nil
+}
+
+// nonEmpty returns values without empty strings
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// sshNodes lists the machines of a cluster built from control_plane_ips and
+// worker_ips outputs, named <cluster>-cp-<n> and <cluster>-worker-<n>
+func sshNodes(workDir, clusterName string) ([]Node, error) {
+	cpIPs, err := terraformOutputList(workDir, "control_plane_ips")
+	if err != nil {
+		return nil, err
+	}
+	workerIPs, err := terraformOutputList(workDir, "worker_ips")
+	if err != nil {
+		return nil, err
+	}
+	return ipNodes(clusterName, cpIPs, workerIPs), nil
+}
+
+// ipNodes names machines by their position in the control-plane and worker lists
+func ipNodes(clusterName string, cpIPs, workerIPs []string) []Node {
+	var nodes []Node
+	for i, ip := range cpIPs {
+		nodes = append(nodes, Node{Name: fmt.Sprintf("%s-cp-%d", clusterName, i), Role: RoleControlPlane, IP: ip})
+	}
+	for i, ip := range workerIPs {
+		nodes = append(nodes, Node{Name: fmt.Sprintf("%s-worker-%d", clusterName, i), Role: RoleWorker, IP: ip})
+	}
+	return nodes
+}
+
+// sshShell opens an interactive shell on node as root
+func sshShell(workDir string, node Node) error {
+	client, err := nodeSSHClient(workDir)
+	if err != nil {
+		return err
+	}
+	return client.Shell(node.IP)
+}
+
+// sshCommandRunner runs commands on nodes over SSH with a single copy of the key
+func sshCommandRunner(workDir string) (CommandRunner, error) {
+	client, err := nodeSSHClient(workDir)
+	if err != nil {
+		return nil, err
+	}
+	return func(node Node, command string) ([]byte, error) {
+		return client.CombinedOutput(node.IP, command)
+	}, nil
+}
+
+// fetchKubeconfig reads the RKE2 admin kubeconfig over SSH, trying every
+// control-plane node in turn, and writes it to a temp file with the server URL
+// pointed at apiHost (when set)
+func fetchKubeconfig(workDir, apiHost string) (string, error) {
+	ips, err := terraformOutputList(workDir, "control_plane_ips")
+	if err != nil {
+		return "", fmt.Errorf("failed to get control plane IPs: %w", err)
+	}
+	ips = nonEmpty(ips)
+	if len(ips) == 0 {
+		return "", fmt.Errorf("no control plane IPs in Terraform outputs")
+	}
+
+	client, err := nodeSSHClient(workDir)
+	if err != nil {
+		return "", err
+	}
+
+	kubeconfigData, _, err := client.Output(ips, "cat "+rke2KubeconfigPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve kubeconfig via SSH: %w", err)
+	}
+
+	kubeconfig := string(kubeconfigData)
+	if apiHost != "" {
+		kubeconfig = setKubeconfigServer(kubeconfig, apiHost, 6443)
+	}
+
+	// Write to temp file
+	tmpFile, err := os.CreateTemp("", "kubeconfig-*.yaml")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tmpFile.WriteString(kubeconfig); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	tmpFile.Close()
+	os.Chmod(tmpFile.Name(), 0600)
+
+	return tmpFile.Name(), nil
+}
+
+// setKubeconfigServer replaces the first server URL (https://127.0.0.1:6443 in
+// rke2.yaml) with one on host and port
+func setKubeconfigServer(kubeconfig, host string, port int) string {
+	lines := strings.Split(kubeconfig, "\n")
+	for i, line := range lines {
+		if strings.Contains(line, "server: https://") {
+			lines[i] = fmt.Sprintf("    server: https://%s", net.JoinHostPort(host, strconv.Itoa(port)))
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseOutputList(t *testing.T) {
+	ips, err := parseOutputList([]byte(`["10.0.0.1","","10.0.0.2"]`))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if want := []string{"10.0.0.1", "", "10.0.0.2"}; !reflect.DeepEqual(ips, want) {
+		t.Errorf("expected %v, got %v", want, ips)
+	}
+	if want := []string{"10.0.0.1", "10.0.0.2"}; !reflect.DeepEqual(nonEmpty(ips), want) {
+		t.Errorf("expected %v, got %v", want, nonEmpty(ips))
+	}
+
+	if _, err := parseOutputList([]byte(`"10.0.0.1"`)); err == nil {
+		t.Error("expected an error for a non-list output")
+	}
+}
+
+func TestSetKubeconfigServer(t *testing.T) {
+	in := "clusters:\n- cluster:\n    certificate-authority-data: Q0E=\n    server: https://127.0.0.1:6443\n  name: default\n"
+	want := "clusters:\n- cluster:\n    certificate-authority-data: Q0E=\n    server: https://203.0.113.10:6443\n  name: default\n"
+	if got := setKubeconfigServer(in, "203.0.113.10", 6443); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	want = "clusters:\n- cluster:\n    certificate-authority-data: Q0E=\n    server: https://127.0.0.1:24321\n  name: default\n"
+	if got := setKubeconfigServer(in, "127.0.0.1", 24321); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestKnownHostsFile(t *testing.T) {
+	workDir := filepath.Join("home", ".tdls-k8s", "clusters", "prod", "terraform")
+	if got, want := knownHostsFile(workDir), filepath.Join("home", ".tdls-k8s", "clusters", "prod", "known_hosts"); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestIPNodes(t *testing.T) {
+	nodes := ipNodes("prod", []string{"10.0.0.1", "10.0.0.2"}, []string{"10.0.1.1"})
+
+	want := []Node{
+		{Name: "prod-cp-0", Role: RoleControlPlane, IP: "10.0.0.1"},
+		{Name: "prod-cp-1", Role: RoleControlPlane, IP: "10.0.0.2"},
+		{Name: "prod-worker-0", Role: RoleWorker, IP: "10.0.1.1"},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("expected %+v, got %+v", want, nodes)
+	}
+}
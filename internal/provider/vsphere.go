@@ -6,6 +6,17 @@ import (
 	"github.com/user/tdls-easy-k8s/internal/config"
 )
 
+func init() {
+	Register(Registration{
+		Name:        "vsphere",
+		Description: "VMware vSphere (not yet implemented)",
+		New:         func() Provider { return NewVSphereProvider() },
+		Resources: []string{
+			"All virtual machines (control plane and workers)",
+		},
+	})
+}
+
 // VSphereProvider implements the Provider interface for vSphere
 type VSphereProvider struct{}
 
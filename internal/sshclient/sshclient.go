@@ -0,0 +1,279 @@
+// Package sshclient runs commands on cluster nodes over SSH in-process. Host keys
+// are pinned on first use in a known_hosts file, so later connections fail if a
+// node's key changes instead of silently trusting it.
+package sshclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/term"
+)
+
+// ErrHostKeyChanged is returned when a node presents a different host key than the
+// one pinned for it. Connections that fail with it are never retried.
+var ErrHostKeyChanged = errors.New("host key changed")
+
+// Client connects to nodes with a private key held in memory
+type Client struct {
+	// User is the login user (default "root")
+	User string
+	// KnownHostsFile is where host keys are pinned
+	KnownHostsFile string
+	// Attempts is how many times each command is tried across all hosts
+	Attempts int
+	// RetryDelay is the wait between attempts
+	RetryDelay time.Duration
+	// DialTimeout bounds connecting to a single host
+	DialTimeout time.Duration
+	// Log receives a line for every failed attempt; nil disables it
+	Log io.Writer
+
+	signer ssh.Signer
+	mu     sync.Mutex
+}
+
+// New creates a client from a PEM or OpenSSH encoded private key
+func New(privateKey []byte, knownHostsFile string) (*Client, error) {
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH private key: %w", err)
+	}
+
+	return &Client{
+		User:           "root",
+		KnownHostsFile: knownHostsFile,
+		Attempts:       6,
+		RetryDelay:     10 * time.Second,
+		DialTimeout:    10 * time.Second,
+		signer:         signer,
+	}, nil
+}
+
+// Dial opens a connection to host, which may omit the port (default 22)
+func (c *Client) Dial(host string) (*ssh.Client, error) {
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(host, "22")
+	}
+
+	return ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            c.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(c.signer)},
+		HostKeyCallback: c.checkHostKey,
+		Timeout:         c.DialTimeout,
+	})
+}
+
+// Output runs command on the first host that answers and returns its stdout and
+// the host that ran it. Hosts are tried in order; if all fail, the whole list is
+// retried after RetryDelay, up to Attempts times.
+func (c *Client) Output(hosts []string, command string) ([]byte, string, error) {
+	if len(hosts) == 0 {
+		return nil, "", fmt.Errorf("no hosts to connect to")
+	}
+
+	attempts := c.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		for _, host := range hosts {
+			output, err := c.run(host, command)
+			if err == nil {
+				return output, host, nil
+			}
+			if errors.Is(err, ErrHostKeyChanged) {
+				return nil, host, err
+			}
+			lastErr = fmt.Errorf("%s: %w", host, err)
+			if c.Log != nil {
+				fmt.Fprintf(c.Log, "  SSH attempt %d/%d failed: %v\n", attempt, attempts, lastErr)
+			}
+		}
+		if attempt < attempts {
+			time.Sleep(c.RetryDelay)
+		}
+	}
+
+	return nil, "", lastErr
+}
+
+// CombinedOutput runs command once on host and returns its stdout and stderr
+// interleaved. Unlike Output it does not retry.
+func (c *Client) CombinedOutput(host, command string) ([]byte, error) {
+	conn, err := c.Dial(host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	return session.CombinedOutput(command)
+}
+
+// Shell opens an interactive login shell on host attached to the local terminal.
+// The shell's own exit status is not treated as an error.
+func (c *Client) Shell(host string) error {
+	conn, err := c.Dial(host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	session, err := conn.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	session.Stdin = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		termType := os.Getenv("TERM")
+		if termType == "" {
+			termType = "xterm-256color"
+		}
+		if err := session.RequestPty(termType, height, width, ssh.TerminalModes{ssh.ECHO: 1}); err != nil {
+			return fmt.Errorf("failed to request a terminal: %w", err)
+		}
+
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(fd, state)
+	}
+
+	if err := session.Shell(); err != nil {
+		return err
+	}
+	err = session.Wait()
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return nil
+	}
+	return err
+}
+
+// run executes command once on host
+func (c *Client) run(host, command string) ([]byte, error) {
+	conn, err := c.Dial(host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run(command); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// checkHostKey accepts keys already pinned in KnownHostsFile, pins keys of hosts
+// seen for the first time and rejects anything else
+func (c *Client) checkHostKey(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := os.Stat(c.KnownHostsFile); err == nil {
+		callback, err := knownhosts.New(c.KnownHostsFile)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", c.KnownHostsFile, err)
+		}
+
+		err = callback(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if err == nil || !errors.As(err, &keyErr) {
+			return err
+		}
+		if len(keyErr.Want) > 0 {
+			return fmt.Errorf("%w for %s: it no longer matches the key pinned in %s; if the node was replaced, remove its line from that file",
+				ErrHostKeyChanged, hostname, c.KnownHostsFile)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return c.pin(hostname, key)
+}
+
+// pin appends a host key to KnownHostsFile
+func (c *Client) pin(hostname string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(c.KnownHostsFile), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(c.KnownHostsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+	return err
+}
+
+// RemoveHost deletes the pinned key of host from a known_hosts file, so a rebuilt
+// node with the same address is accepted again. A missing file is not an error.
+func RemoveHost(knownHostsFile, host string) error {
+	data, err := os.ReadFile(knownHostsFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pattern := knownhosts.Normalize(host)
+	var kept []string
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == pattern {
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	content := strings.Join(kept, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	return os.WriteFile(knownHostsFile, []byte(content), 0600)
+}
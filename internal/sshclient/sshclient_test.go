@@ -0,0 +1,247 @@
+package sshclient
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// newKey returns a fresh ed25519 key as a signer and its OpenSSH PEM encoding
+func newKey(t *testing.T) (ssh.Signer, []byte) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, pem.EncodeToMemory(block)
+}
+
+// startServer runs an SSH server on a random local port that accepts clientKey
+// and answers exec requests with handle. It returns the server address.
+func startServer(t *testing.T, hostKey ssh.Signer, clientKey ssh.PublicKey, handle func(command string) (string, uint32)) string {
+	t.Helper()
+
+	serverConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, errors.New("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	serverConfig.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveConn(conn, serverConfig, handle)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func serveConn(conn net.Conn, serverConfig *ssh.ServerConfig, handle func(string) (string, uint32)) {
+	_, channels, requests, err := ssh.NewServerConn(conn, serverConfig)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		for req := range channelRequests {
+			if req.Type != "exec" {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			command := string(req.Payload[4:])
+			output, status := handle(command)
+			channel.Write([]byte(output))
+			channel.SendRequest("exit-status", false, binary.BigEndian.AppendUint32(nil, status))
+			channel.Close()
+		}
+	}
+}
+
+func TestOutput_PinsHostKey(t *testing.T) {
+	hostKey, _ := newKey(t)
+	clientSigner, clientPEM := newKey(t)
+	addr := startServer(t, hostKey, clientSigner.PublicKey(), func(command string) (string, uint32) {
+		return "ran " + command, 0
+	})
+
+	knownHosts := filepath.Join(t.TempDir(), "cluster", "known_hosts")
+	client, err := New(clientPEM, knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, host, err := client.Output([]string{addr}, "uptime")
+	if err != nil {
+		t.Fatalf("Output failed: %v", err)
+	}
+	if string(output) != "ran uptime" || host != addr {
+		t.Errorf("unexpected result %q from %s", output, host)
+	}
+
+	data, err := os.ReadFile(knownHosts)
+	if err != nil {
+		t.Fatalf("expected host key to be pinned: %v", err)
+	}
+	if strings.Count(string(data), "\n") != 1 || !strings.Contains(string(data), "ssh-ed25519") {
+		t.Errorf("unexpected known_hosts:\n%s", data)
+	}
+
+	// A second connection uses the pinned key and adds nothing
+	if output, err := client.CombinedOutput(addr, "hostname"); err != nil || string(output) != "ran hostname" {
+		t.Fatalf("CombinedOutput returned %q, %v", output, err)
+	}
+	if again, _ := os.ReadFile(knownHosts); string(again) != string(data) {
+		t.Errorf("expected known_hosts to be unchanged, got:\n%s", again)
+	}
+}
+
+func TestOutput_RejectsChangedHostKey(t *testing.T) {
+	hostKey, _ := newKey(t)
+	otherHostKey, _ := newKey(t)
+	clientSigner, clientPEM := newKey(t)
+
+	var calls atomic.Int32
+	handle := func(string) (string, uint32) {
+		calls.Add(1)
+		return "ok", 0
+	}
+	addr := startServer(t, hostKey, clientSigner.PublicKey(), handle)
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	client, err := New(clientPEM, knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.Output([]string{addr}, "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pin the first server's key under the address of a server with another key
+	impostor := startServer(t, otherHostKey, clientSigner.PublicKey(), handle)
+	pinned, _ := os.ReadFile(knownHosts)
+	_, port, _ := net.SplitHostPort(addr)
+	_, impostorPort, _ := net.SplitHostPort(impostor)
+	os.WriteFile(knownHosts, []byte(strings.Replace(string(pinned), port, impostorPort, 1)), 0600)
+
+	client.Attempts = 3
+	client.RetryDelay = 0
+	_, _, err = client.Output([]string{impostor}, "true")
+	if !errors.Is(err, ErrHostKeyChanged) {
+		t.Fatalf("expected ErrHostKeyChanged, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected no command to run on the impostor, got %d calls", calls.Load())
+	}
+}
+
+func TestOutput_RetriesAndFallsBack(t *testing.T) {
+	hostKey, _ := newKey(t)
+	clientSigner, clientPEM := newKey(t)
+
+	// Not listening: connections are refused
+	down, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downAddr := down.Addr().String()
+	down.Close()
+
+	// Fails until the third call, like a node whose cloud-init is still running
+	var calls atomic.Int32
+	addr := startServer(t, hostKey, clientSigner.PublicKey(), func(string) (string, uint32) {
+		if calls.Add(1) < 3 {
+			return "", 1
+		}
+		return "kubeconfig", 0
+	})
+
+	client, err := New(clientPEM, filepath.Join(t.TempDir(), "known_hosts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.RetryDelay = 0
+
+	output, host, err := client.Output([]string{downAddr, addr}, "cat /etc/rancher/rke2/rke2.yaml")
+	if err != nil {
+		t.Fatalf("Output failed: %v", err)
+	}
+	if string(output) != "kubeconfig" || host != addr {
+		t.Errorf("unexpected result %q from %s", output, host)
+	}
+
+	client.Attempts = 2
+	if _, _, err := client.Output([]string{downAddr}, "true"); err == nil || !strings.Contains(err.Error(), downAddr) {
+		t.Errorf("expected an error naming %s, got %v", downAddr, err)
+	}
+}
+
+func TestNew_InvalidKey(t *testing.T) {
+	if _, err := New([]byte("not a key"), "known_hosts"); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
+
+func TestRemoveHost(t *testing.T) {
+	hostKey, _ := newKey(t)
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	lines := []string{
+		knownhosts.Line([]string{knownhosts.Normalize("203.0.113.10")}, hostKey.PublicKey()),
+		knownhosts.Line([]string{knownhosts.Normalize("203.0.113.11")}, hostKey.PublicKey()),
+		knownhosts.Line([]string{knownhosts.Normalize("127.0.0.1:2222")}, hostKey.PublicKey()),
+	}
+	os.WriteFile(knownHosts, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+
+	if err := RemoveHost(knownHosts, "203.0.113.10"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveHost(knownHosts, "127.0.0.1:2222"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(knownHosts)
+	if string(data) != lines[1]+"\n" {
+		t.Errorf("expected only 203.0.113.11 to remain, got:\n%s", data)
+	}
+
+	if err := RemoveHost(filepath.Join(t.TempDir(), "missing"), "203.0.113.10"); err != nil {
+		t.Errorf("expected no error for a missing file, got: %v", err)
+	}
+}
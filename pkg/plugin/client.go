@@ -0,0 +1,120 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DescribeTimeout bounds the describe call made when a plugin is discovered
+const DescribeTimeout = 10 * time.Second
+
+// Client calls a plugin executable
+type Client struct {
+	// Path is the plugin executable
+	Path string
+
+	// Stderr receives the plugin's progress output (default os.Stderr)
+	Stderr io.Writer
+}
+
+// NewClient creates a client for the plugin executable at path
+func NewClient(path string) *Client {
+	return &Client{Path: path}
+}
+
+// Name returns the provider name taken from the executable's file name
+func (c *Client) Name() string {
+	name := strings.TrimPrefix(filepath.Base(c.Path), ExecutablePrefix)
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// Describe asks the plugin for its name, description and capabilities
+func (c *Client) Describe() (*Description, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DescribeTimeout)
+	defer cancel()
+
+	var desc Description
+	if err := c.call(ctx, &Request{Method: MethodDescribe}, &desc); err != nil {
+		return nil, err
+	}
+	if desc.ProtocolVersion != ProtocolVersion {
+		return nil, fmt.Errorf("plugin %s speaks protocol version %d, expected %d", c.Path, desc.ProtocolVersion, ProtocolVersion)
+	}
+	return &desc, nil
+}
+
+// Call runs a provider method with the cluster config and decodes the result into
+// result, which may be nil for methods without a result. cfg is encoded with EncodeConfig.
+func (c *Client) Call(method string, cfg interface{}, result interface{}) error {
+	raw, err := EncodeConfig(cfg)
+	if err != nil {
+		return err
+	}
+	return c.call(context.Background(), &Request{Method: method, Config: raw}, result)
+}
+
+// ValidateField asks the plugin to validate a single config value
+func (c *Client) ValidateField(field, value string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DescribeTimeout)
+	defer cancel()
+
+	return c.call(ctx, &Request{Method: MethodValidateField, Field: field, Value: value}, nil)
+}
+
+// Send writes a request as is and returns the plugin's response. It is meant for
+// testing plugins; Call and Describe fill in the protocol version.
+func (c *Client) Send(ctx context.Context, req *Request) (*Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	stderr := c.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Path)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+	runErr := cmd.Run()
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("plugin %s failed: %w", c.Path, runErr)
+		}
+		return nil, fmt.Errorf("plugin %s returned an invalid response: %w", c.Path, err)
+	}
+	return &resp, nil
+}
+
+func (c *Client) call(ctx context.Context, req *Request, result interface{}) error {
+	req.ProtocolVersion = ProtocolVersion
+
+	resp, err := c.Send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return &RemoteError{Method: req.Method, Message: resp.Error}
+	}
+
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("plugin %s returned an invalid %s result: %w", c.Path, req.Method, err)
+	}
+	return nil
+}
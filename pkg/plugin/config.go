@@ -0,0 +1,63 @@
+package plugin
+
+// ClusterConfig is the cluster configuration passed to plugins, in the shape of
+// cluster.yaml. The sections a provider works with are typed; the remaining
+// top-level sections (gitops, components, auth, ...) are kept in Other.
+type ClusterConfig struct {
+	Name       string           `yaml:"name"`
+	Provider   ProviderConfig   `yaml:"provider"`
+	Kubernetes KubernetesConfig `yaml:"kubernetes"`
+	Nodes      NodesConfig      `yaml:"nodes"`
+
+	Other map[string]interface{} `yaml:",inline"`
+}
+
+// ProviderConfig is the provider section. Settings holds the plugin's own
+// settings from provider.settings.
+type ProviderConfig struct {
+	Type     string                 `yaml:"type"`
+	Settings map[string]interface{} `yaml:"settings,omitempty"`
+}
+
+// KubernetesConfig is the kubernetes section
+type KubernetesConfig struct {
+	Version      string          `yaml:"version"`      // e.g., "1.30"
+	Distribution string          `yaml:"distribution"` // rke2, k3s
+	APIServer    APIServerConfig `yaml:"apiServer,omitempty"`
+}
+
+// APIServerConfig contains the names the Kubernetes API server is reached by
+type APIServerConfig struct {
+	Hostname  string   `yaml:"hostname,omitempty"`
+	ExtraSANs []string `yaml:"extraSANs,omitempty"`
+}
+
+// NodesConfig is the nodes section
+type NodesConfig struct {
+	ControlPlane NodeGroupConfig `yaml:"controlPlane"`
+	Workers      NodeGroupConfig `yaml:"workers"`
+}
+
+// NodeGroupConfig represents a group of nodes
+type NodeGroupConfig struct {
+	Count        int    `yaml:"count"`
+	InstanceType string `yaml:"instanceType,omitempty"`
+
+	// VM sizing for providers without instance types
+	CPU      int `yaml:"cpu,omitempty"`
+	MemoryMB int `yaml:"memoryMB,omitempty"`
+	DiskGB   int `yaml:"diskGB,omitempty"`
+
+	// Spot and OnDemandBase are set for providers with the spot capability
+	Spot         bool `yaml:"spot,omitempty"`
+	OnDemandBase int  `yaml:"onDemandBase,omitempty"`
+
+	// Autoscaling is set for providers with the autoscaling capability
+	Autoscaling AutoscalingConfig `yaml:"autoscaling,omitempty"`
+}
+
+// AutoscalingConfig contains the size bounds of an autoscaled node group
+type AutoscalingConfig struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+}
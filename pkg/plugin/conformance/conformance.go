@@ -0,0 +1,211 @@
+// Package conformance checks that a provider plugin speaks the plugin protocol
+// correctly. Plugin authors call Run from a test in their own repository:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, conformance.Options{
+//			Path:   "./bin/tdls-easy-k8s-provider-openstack",
+//			Config: cfg,
+//		})
+//	}
+//
+// By default only read-only methods are called. Set Lifecycle to also create and
+// destroy the cluster described by Config.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/user/tdls-easy-k8s/pkg/plugin"
+)
+
+// Options configures a conformance run
+type Options struct {
+	// Path is the plugin executable
+	Path string
+
+	// Config is a valid cluster config for the plugin
+	Config *plugin.ClusterConfig
+
+	// Lifecycle also creates and destroys the cluster
+	Lifecycle bool
+}
+
+// Run runs the conformance checks against a plugin
+func Run(t *testing.T, opts Options) {
+	t.Helper()
+	if opts.Config == nil {
+		t.Fatal("conformance: Options.Config is required")
+	}
+
+	client := &plugin.Client{Path: opts.Path, Stderr: logWriter{t}}
+
+	t.Run("describe", func(t *testing.T) {
+		desc, err := client.Describe()
+		if err != nil {
+			t.Fatalf("describe failed: %v", err)
+		}
+		if desc.Name != client.Name() {
+			t.Errorf("describe returned name %q, but the executable name implies %q", desc.Name, client.Name())
+		}
+		if desc.Name != opts.Config.Provider.Type {
+			t.Errorf("describe returned name %q, but the config uses provider type %q", desc.Name, opts.Config.Provider.Type)
+		}
+		if desc.Description == "" {
+			t.Error("describe returned an empty description")
+		}
+		if len(desc.Resources) == 0 {
+			t.Error("describe returned no resources; destroy shows them before confirmation")
+		}
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), plugin.DescribeTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, opts.Path)
+		cmd.Stdin = strings.NewReader("not json")
+		cmd.Stderr = logWriter{t}
+		out, err := cmd.Output()
+		if ctx.Err() != nil {
+			t.Fatal("plugin did not exit after an invalid request")
+		}
+		if err == nil && !strings.Contains(string(out), `"error"`) {
+			t.Errorf("plugin accepted an invalid request: %s", out)
+		}
+	})
+
+	t.Run("unsupported protocol version", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), plugin.DescribeTimeout)
+		defer cancel()
+
+		resp, err := client.Send(ctx, &plugin.Request{ProtocolVersion: plugin.ProtocolVersion + 1, Method: plugin.MethodDescribe})
+		if err != nil {
+			t.Fatalf("plugin did not respond: %v", err)
+		}
+		if resp.Error == "" {
+			t.Error("plugin accepted a request with an unsupported protocol version")
+		}
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		err := client.Call("noSuchMethod", opts.Config, nil)
+		requireRemoteError(t, err, "an unknown method")
+	})
+
+	t.Run("validate config", func(t *testing.T) {
+		if err := client.Call(plugin.MethodValidateConfig, opts.Config, nil); err != nil {
+			t.Fatalf("validateConfig rejected the config: %v", err)
+		}
+
+		other := *opts.Config
+		other.Provider.Type = "not-" + opts.Config.Provider.Type
+		err := client.Call(plugin.MethodValidateConfig, &other, nil)
+		requireRemoteError(t, err, "a config for another provider type")
+	})
+
+	t.Run("validate field", func(t *testing.T) {
+		err := client.ValidateField("no.such.field", "value")
+		var remote *plugin.RemoteError
+		if err != nil && !errors.As(err, &remote) {
+			t.Errorf("validateField failed: %v", err)
+		}
+	})
+
+	t.Run("read-only methods respond", func(t *testing.T) {
+		for _, method := range readOnlyMethods {
+			var result interface{}
+			err := client.Call(method, opts.Config, &result)
+			var remote *plugin.RemoteError
+			if err != nil && !errors.As(err, &remote) {
+				t.Errorf("%s failed: %v", method, err)
+			}
+		}
+	})
+
+	if !opts.Lifecycle {
+		return
+	}
+
+	t.Run("lifecycle", func(t *testing.T) {
+		if err := client.Call(plugin.MethodCreateInfrastructure, opts.Config, nil); err != nil {
+			t.Fatalf("createInfrastructure failed: %v", err)
+		}
+		destroyed := false
+		t.Cleanup(func() {
+			if !destroyed {
+				_ = client.Call(plugin.MethodDestroyInfrastructure, opts.Config, nil)
+			}
+		})
+
+		var status string
+		if err := client.Call(plugin.MethodGetStatus, opts.Config, &status); err != nil || status == "" {
+			t.Errorf("getStatus after create returned %q, %v", status, err)
+		}
+
+		var kubeconfig string
+		if err := client.Call(plugin.MethodGetKubeconfig, opts.Config, &kubeconfig); err != nil {
+			t.Errorf("getKubeconfig failed: %v", err)
+		} else if _, err := os.Stat(kubeconfig); err != nil {
+			t.Errorf("getKubeconfig returned %q, which is not a readable file: %v", kubeconfig, err)
+		}
+
+		var clusterStatus plugin.ClusterStatus
+		if err := client.Call(plugin.MethodGetClusterStatus, opts.Config, &clusterStatus); err != nil {
+			t.Errorf("getClusterStatus failed: %v", err)
+		} else if clusterStatus.ControlPlaneTotal != opts.Config.Nodes.ControlPlane.Count {
+			t.Errorf("getClusterStatus reported %d control plane nodes, expected %d", clusterStatus.ControlPlaneTotal, opts.Config.Nodes.ControlPlane.Count)
+		}
+
+		for _, method := range validationMethods {
+			var message string
+			if err := client.Call(method, opts.Config, &message); err != nil {
+				t.Errorf("%s failed on a created cluster: %v", method, err)
+			}
+		}
+
+		destroyed = true
+		if err := client.Call(plugin.MethodDestroyInfrastructure, opts.Config, nil); err != nil {
+			t.Fatalf("destroyInfrastructure failed: %v", err)
+		}
+	})
+}
+
+// validationMethods are the checks run by the validate command
+var validationMethods = []string{
+	plugin.MethodValidateAPIServer,
+	plugin.MethodValidateNodes,
+	plugin.MethodValidateSystemPods,
+	plugin.MethodValidateEtcd,
+	plugin.MethodValidateDNS,
+	plugin.MethodValidateNetworking,
+	plugin.MethodValidatePodScheduling,
+}
+
+// readOnlyMethods may be called whether or not the cluster exists
+var readOnlyMethods = append([]string{plugin.MethodGetStatus, plugin.MethodGetClusterStatus}, validationMethods...)
+
+// requireRemoteError fails unless err is an error reported by the plugin
+func requireRemoteError(t *testing.T, err error, what string) {
+	t.Helper()
+	var remote *plugin.RemoteError
+	if err == nil {
+		t.Errorf("plugin accepted %s", what)
+	} else if !errors.As(err, &remote) {
+		t.Errorf("plugin failed instead of reporting an error for %s: %v", what, err)
+	}
+}
+
+// logWriter sends the plugin's stderr to the test log
+type logWriter struct {
+	t *testing.T
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
@@ -0,0 +1,178 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeProvider records the config it receives
+type fakeProvider struct {
+	got *ClusterConfig
+}
+
+func (p *fakeProvider) Describe() Description {
+	return Description{Name: "fake", Description: "Fake provider", Capabilities: Capabilities{VIP: true}}
+}
+func (p *fakeProvider) ValidateConfig(cfg *ClusterConfig) error {
+	p.got = cfg
+	return fmt.Errorf("invalid %s", cfg.Name)
+}
+func (p *fakeProvider) CreateInfrastructure(cfg *ClusterConfig) error  { panic("boom") }
+func (p *fakeProvider) DestroyInfrastructure(cfg *ClusterConfig) error { return nil }
+func (p *fakeProvider) GetKubeconfig(cfg *ClusterConfig) (string, error) {
+	return "/tmp/kubeconfig", nil
+}
+func (p *fakeProvider) GetStatus(cfg *ClusterConfig) (string, error) {
+	p.got = cfg
+	return "deployed", nil
+}
+func (p *fakeProvider) GetClusterStatus(cfg *ClusterConfig) (*ClusterStatus, error) {
+	return &ClusterStatus{Ready: true, ControlPlaneTotal: cfg.Nodes.ControlPlane.Count}, nil
+}
+func (p *fakeProvider) ValidateAPIServer(cfg *ClusterConfig) (string, error)     { return "ok", nil }
+func (p *fakeProvider) ValidateNodes(cfg *ClusterConfig) (string, error)         { return "ok", nil }
+func (p *fakeProvider) ValidateSystemPods(cfg *ClusterConfig) (string, error)    { return "ok", nil }
+func (p *fakeProvider) ValidateEtcd(cfg *ClusterConfig) (string, error)          { return "ok", nil }
+func (p *fakeProvider) ValidateDNS(cfg *ClusterConfig) (string, error)           { return "ok", nil }
+func (p *fakeProvider) ValidateNetworking(cfg *ClusterConfig) (string, error)    { return "ok", nil }
+func (p *fakeProvider) ValidatePodScheduling(cfg *ClusterConfig) (string, error) { return "ok", nil }
+
+func testConfig() *ClusterConfig {
+	return &ClusterConfig{
+		Name: "demo",
+		Provider: ProviderConfig{
+			Type:     "fake",
+			Settings: map[string]interface{}{"region": "eu-1", "flavors": []interface{}{"small", "large"}},
+		},
+		Nodes: NodesConfig{ControlPlane: NodeGroupConfig{Count: 3, MemoryMB: 8192}},
+		Other: map[string]interface{}{"gitops": map[string]interface{}{"path": "clusters/demo"}},
+	}
+}
+
+// serve sends one request through ServeIO and decodes the response
+func serve(t *testing.T, p Provider, req interface{}) Response {
+	t.Helper()
+	var in bytes.Buffer
+	if s, ok := req.(string); ok {
+		in.WriteString(s)
+	} else if err := json.NewEncoder(&in).Encode(req); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := ServeIO(&in, &out, p); err != nil {
+		t.Fatalf("ServeIO failed: %v", err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", out.String(), err)
+	}
+	return resp
+}
+
+func TestEncodeDecodeConfig(t *testing.T) {
+	raw, err := EncodeConfig(testConfig())
+	if err != nil {
+		t.Fatalf("EncodeConfig failed: %v", err)
+	}
+	if !strings.Contains(string(raw), `"controlPlane":{"count":3`) {
+		t.Errorf("expected cluster.yaml field names in the encoded config, got %s", raw)
+	}
+
+	cfg, err := DecodeConfig(raw)
+	if err != nil {
+		t.Fatalf("DecodeConfig failed: %v", err)
+	}
+	if cfg.Nodes.ControlPlane.Count != 3 || cfg.Nodes.ControlPlane.MemoryMB != 8192 {
+		t.Errorf("node sizes were not preserved: %+v", cfg.Nodes.ControlPlane)
+	}
+	if cfg.Provider.Settings["region"] != "eu-1" {
+		t.Errorf("settings were not preserved: %v", cfg.Provider.Settings)
+	}
+	if gitops, _ := cfg.Other["gitops"].(map[string]interface{}); gitops["path"] != "clusters/demo" {
+		t.Errorf("other sections were not preserved: %v", cfg.Other)
+	}
+}
+
+func TestServeIO_Describe(t *testing.T) {
+	resp := serve(t, &fakeProvider{}, Request{ProtocolVersion: ProtocolVersion, Method: MethodDescribe})
+	if resp.Error != "" {
+		t.Fatalf("unexpected error: %s", resp.Error)
+	}
+
+	var desc Description
+	if err := json.Unmarshal(resp.Result, &desc); err != nil {
+		t.Fatal(err)
+	}
+	if desc.Name != "fake" || !desc.Capabilities.VIP || desc.ProtocolVersion != ProtocolVersion {
+		t.Errorf("unexpected description: %+v", desc)
+	}
+}
+
+func TestServeIO_PassesConfig(t *testing.T) {
+	raw, err := EncodeConfig(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &fakeProvider{}
+	resp := serve(t, p, Request{ProtocolVersion: ProtocolVersion, Method: MethodGetStatus, Config: raw})
+	if resp.Error != "" || string(resp.Result) != `"deployed"` {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if p.got == nil || p.got.Name != "demo" || p.got.Provider.Settings["region"] != "eu-1" {
+		t.Errorf("provider did not receive the config: %+v", p.got)
+	}
+}
+
+func TestServeIO_Errors(t *testing.T) {
+	raw, err := EncodeConfig(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		req  interface{}
+		want string
+	}{
+		{"provider error", Request{ProtocolVersion: ProtocolVersion, Method: MethodValidateConfig, Config: raw}, "invalid demo"},
+		{"panic", Request{ProtocolVersion: ProtocolVersion, Method: MethodCreateInfrastructure, Config: raw}, "plugin panicked in createInfrastructure: boom"},
+		{"unknown method", Request{ProtocolVersion: ProtocolVersion, Method: "reboot"}, `unknown method "reboot"`},
+		{"missing config", Request{ProtocolVersion: ProtocolVersion, Method: MethodGetStatus}, "getStatus requires a config"},
+		{"no object storage", Request{ProtocolVersion: ProtocolVersion, Method: MethodDeleteObjectStorage, Config: raw}, "deleteObjectStorage is not supported"},
+		{"protocol version", Request{ProtocolVersion: 99, Method: MethodDescribe}, "unsupported protocol version 99"},
+		{"invalid JSON", "{", "invalid request"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := serve(t, &fakeProvider{}, tc.req)
+			if !strings.Contains(resp.Error, tc.want) {
+				t.Errorf("expected error containing %q, got %q", tc.want, resp.Error)
+			}
+		})
+	}
+}
+
+func TestServeIO_ValidateFieldWithoutValidator(t *testing.T) {
+	resp := serve(t, &fakeProvider{}, Request{ProtocolVersion: ProtocolVersion, Method: MethodValidateField, Field: "provider.settings.region", Value: "x"})
+	if resp.Error != "" {
+		t.Errorf("expected plugins without a field validator to accept every value, got %q", resp.Error)
+	}
+}
+
+func TestClient_Name(t *testing.T) {
+	cases := map[string]string{
+		"/usr/local/bin/tdls-easy-k8s-provider-openstack": "openstack",
+		"plugins/tdls-easy-k8s-provider-openstack.exe":    "openstack",
+	}
+	for path, want := range cases {
+		if got := NewClient(path).Name(); got != want {
+			t.Errorf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+}
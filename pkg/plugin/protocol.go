@@ -0,0 +1,167 @@
+// Package plugin implements the protocol between tdls-easy-k8s and out-of-process
+// provider plugins.
+//
+// A plugin is an executable named tdls-easy-k8s-provider-<name>, installed in
+// ~/.tdls-k8s/plugins or on the PATH. For every provider call the CLI starts the
+// plugin, writes one JSON Request to its stdin and reads one JSON Response from its
+// stdout. Anything the plugin writes to stderr is shown to the user, so progress
+// output of long operations belongs there.
+//
+// Plugins written in Go implement Provider and call Serve from main. Plugins in
+// other languages follow the same messages:
+//
+//	{"protocolVersion": 1, "method": "getStatus", "config": {...}}
+//	{"result": "deployed"}
+//	{"error": "cluster not found"}
+//
+// The config is the cluster config in the same shape as cluster.yaml; settings for
+// the plugin are in provider.settings.
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProtocolVersion is the version of the protocol spoken by this package. The CLI
+// rejects plugins that report a different version.
+const ProtocolVersion = 1
+
+// ExecutablePrefix is the file name prefix of plugin executables
+const ExecutablePrefix = "tdls-easy-k8s-provider-"
+
+// Methods of the protocol. Apart from describe and validateField they mirror the
+// methods of the built-in providers and receive the cluster config.
+const (
+	MethodDescribe              = "describe"              // result: Description
+	MethodValidateField         = "validateField"         // uses Field and Value; no result
+	MethodValidateConfig        = "validateConfig"        // no result
+	MethodCreateInfrastructure  = "createInfrastructure"  // no result
+	MethodDestroyInfrastructure = "destroyInfrastructure" // no result
+	MethodGetKubeconfig         = "getKubeconfig"         // result: path of the kubeconfig file
+	MethodGetStatus             = "getStatus"             // result: status string
+	MethodGetClusterStatus      = "getClusterStatus"      // result: ClusterStatus
+	MethodValidateAPIServer     = "validateAPIServer"     // result: message
+	MethodValidateNodes         = "validateNodes"         // result: message
+	MethodValidateSystemPods    = "validateSystemPods"    // result: message
+	MethodValidateEtcd          = "validateEtcd"          // result: message
+	MethodValidateDNS           = "validateDNS"           // result: message
+	MethodValidateNetworking    = "validateNetworking"    // result: message
+	MethodValidatePodScheduling = "validatePodScheduling" // result: message
+	MethodObjectStorageName     = "objectStorageName"     // result: bucket name; objectStorage capability
+	MethodDeleteObjectStorage   = "deleteObjectStorage"   // no result; objectStorage capability
+)
+
+// Request is the message sent to a plugin on stdin
+type Request struct {
+	ProtocolVersion int             `json:"protocolVersion"`
+	Method          string          `json:"method"`
+	Config          json.RawMessage `json:"config,omitempty"`
+
+	// Field and Value are set for validateField
+	Field string `json:"field,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// Response is the message a plugin writes to stdout. Error is set when the call failed.
+type Response struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Description is the result of describe. It is used to register the plugin.
+type Description struct {
+	ProtocolVersion int          `json:"protocolVersion"`
+	Name            string       `json:"name"`
+	Description     string       `json:"description"`
+	Capabilities    Capabilities `json:"capabilities"`
+
+	// Resources lists the infrastructure removed by destroy, shown before confirmation
+	Resources []string `json:"resources"`
+}
+
+// Capabilities describes the optional features a plugin provider supports
+type Capabilities struct {
+	IngressLoadBalancer bool `json:"ingressLoadBalancer,omitempty"`
+	VIP                 bool `json:"vip,omitempty"`
+	SpotInstances       bool `json:"spotInstances,omitempty"`
+	SSM                 bool `json:"ssm,omitempty"`
+
+	// ObjectStorage keeps the cluster state in a bucket that destroy --cleanup
+	// deletes. The plugin implements ObjectStorage.
+	ObjectStorage bool `json:"objectStorage,omitempty"`
+
+	// Autoscaling lets configs set nodes.workers.autoscaling; the plugin runs
+	// the cluster autoscaler
+	Autoscaling bool `json:"autoscaling,omitempty"`
+}
+
+// ClusterStatus is the result of getClusterStatus
+type ClusterStatus struct {
+	Ready             bool              `json:"ready"`
+	Message           string            `json:"message,omitempty"`
+	APIEndpoint       string            `json:"apiEndpoint,omitempty"`
+	ControlPlaneTotal int               `json:"controlPlaneTotal"`
+	ControlPlaneReady int               `json:"controlPlaneReady"`
+	WorkerTotal       int               `json:"workerTotal"`
+	WorkerReady       int               `json:"workerReady"`
+	Components        []ComponentStatus `json:"components,omitempty"`
+	CreatedAt         time.Time         `json:"createdAt"`
+}
+
+// ComponentStatus is the status of a system component
+type ComponentStatus struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// RemoteError is an error reported by a plugin in its response, as opposed to a
+// failure to run the plugin or to read its response
+type RemoteError struct {
+	Method  string
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+// EncodeConfig converts a cluster config to JSON with the field names used in
+// cluster.yaml. cfg is a *ClusterConfig or any other type with cluster.yaml tags,
+// such as the CLI's own config.
+func EncodeConfig(cfg interface{}) (json.RawMessage, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	var tree map[string]interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	return json.Marshal(tree)
+}
+
+// DecodeConfig converts a config encoded by EncodeConfig back to a cluster config
+func DecodeConfig(raw json.RawMessage) (*ClusterConfig, error) {
+	var tree interface{}
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	data, err := yaml.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	var cfg ClusterConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	return &cfg, nil
+}
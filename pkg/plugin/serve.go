@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Provider is implemented by plugins. It mirrors the provider interface of the CLI,
+// with Describe in place of Name.
+type Provider interface {
+	// Describe returns the plugin's name, description and capabilities
+	Describe() Description
+
+	ValidateConfig(cfg *ClusterConfig) error
+	CreateInfrastructure(cfg *ClusterConfig) error
+	DestroyInfrastructure(cfg *ClusterConfig) error
+	GetKubeconfig(cfg *ClusterConfig) (string, error)
+	GetStatus(cfg *ClusterConfig) (string, error)
+	GetClusterStatus(cfg *ClusterConfig) (*ClusterStatus, error)
+
+	ValidateAPIServer(cfg *ClusterConfig) (string, error)
+	ValidateNodes(cfg *ClusterConfig) (string, error)
+	ValidateSystemPods(cfg *ClusterConfig) (string, error)
+	ValidateEtcd(cfg *ClusterConfig) (string, error)
+	ValidateDNS(cfg *ClusterConfig) (string, error)
+	ValidateNetworking(cfg *ClusterConfig) (string, error)
+	ValidatePodScheduling(cfg *ClusterConfig) (string, error)
+}
+
+// FieldValidator is implemented by plugins that validate single config values, used
+// by the init wizard. Plugins without it accept every value.
+type FieldValidator interface {
+	ValidateField(field, value string) error
+}
+
+// ObjectStorage is implemented by plugins with the objectStorage capability
+type ObjectStorage interface {
+	// ObjectStorageName returns the name of the cluster's bucket
+	ObjectStorageName(cfg *ClusterConfig) (string, error)
+
+	// DeleteObjectStorage empties and deletes the cluster's bucket
+	DeleteObjectStorage(cfg *ClusterConfig) error
+}
+
+// Serve handles one request from stdin and writes the response to stdout. Plugins
+// call it from main. While the provider runs, os.Stdout points at stderr so that
+// progress output cannot corrupt the response.
+func Serve(p Provider) {
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	if err := ServeIO(os.Stdin, out, p); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// ServeIO handles one request read from in and writes the response to out. It only
+// returns an error when the response cannot be written; failures of the provider
+// are reported in the response.
+func ServeIO(in io.Reader, out io.Writer, p Provider) error {
+	var resp Response
+
+	var req Request
+	if err := json.NewDecoder(in).Decode(&req); err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else if req.ProtocolVersion != ProtocolVersion {
+		resp.Error = fmt.Sprintf("unsupported protocol version %d (plugin speaks %d)", req.ProtocolVersion, ProtocolVersion)
+	} else {
+		result, err := dispatch(p, &req)
+		if err != nil {
+			resp.Error = err.Error()
+		} else if result != nil {
+			if resp.Result, err = json.Marshal(result); err != nil {
+				resp.Error = fmt.Sprintf("failed to encode result: %v", err)
+			}
+		}
+	}
+
+	return json.NewEncoder(out).Encode(&resp)
+}
+
+// dispatch calls the provider method named in the request
+func dispatch(p Provider, req *Request) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("plugin panicked in %s: %v", req.Method, r)
+		}
+	}()
+
+	switch req.Method {
+	case MethodDescribe:
+		desc := p.Describe()
+		desc.ProtocolVersion = ProtocolVersion
+		return desc, nil
+	case MethodValidateField:
+		if fv, ok := p.(FieldValidator); ok {
+			return nil, fv.ValidateField(req.Field, req.Value)
+		}
+		return nil, nil
+	}
+
+	var call func(*ClusterConfig) (interface{}, error)
+	switch req.Method {
+	case MethodValidateConfig:
+		call = noResult(p.ValidateConfig)
+	case MethodCreateInfrastructure:
+		call = noResult(p.CreateInfrastructure)
+	case MethodDestroyInfrastructure:
+		call = noResult(p.DestroyInfrastructure)
+	case MethodGetKubeconfig:
+		call = stringResult(p.GetKubeconfig)
+	case MethodGetStatus:
+		call = stringResult(p.GetStatus)
+	case MethodGetClusterStatus:
+		call = func(cfg *ClusterConfig) (interface{}, error) { return p.GetClusterStatus(cfg) }
+	case MethodValidateAPIServer:
+		call = stringResult(p.ValidateAPIServer)
+	case MethodValidateNodes:
+		call = stringResult(p.ValidateNodes)
+	case MethodValidateSystemPods:
+		call = stringResult(p.ValidateSystemPods)
+	case MethodValidateEtcd:
+		call = stringResult(p.ValidateEtcd)
+	case MethodValidateDNS:
+		call = stringResult(p.ValidateDNS)
+	case MethodValidateNetworking:
+		call = stringResult(p.ValidateNetworking)
+	case MethodValidatePodScheduling:
+		call = stringResult(p.ValidatePodScheduling)
+	case MethodObjectStorageName, MethodDeleteObjectStorage:
+		storage, ok := p.(ObjectStorage)
+		if !ok {
+			return nil, fmt.Errorf("%s is not supported: the plugin has no object storage", req.Method)
+		}
+		if req.Method == MethodObjectStorageName {
+			call = stringResult(storage.ObjectStorageName)
+		} else {
+			call = noResult(storage.DeleteObjectStorage)
+		}
+	default:
+		return nil, fmt.Errorf("unknown method %q", req.Method)
+	}
+
+	if len(req.Config) == 0 {
+		return nil, fmt.Errorf("%s requires a config", req.Method)
+	}
+	cfg, err := DecodeConfig(req.Config)
+	if err != nil {
+		return nil, err
+	}
+	return call(cfg)
+}
+
+func noResult(fn func(*ClusterConfig) error) func(*ClusterConfig) (interface{}, error) {
+	return func(cfg *ClusterConfig) (interface{}, error) { return nil, fn(cfg) }
+}
+
+func stringResult(fn func(*ClusterConfig) (string, error)) func(*ClusterConfig) (interface{}, error) {
+	return func(cfg *ClusterConfig) (interface{}, error) { return fn(cfg) }
+}
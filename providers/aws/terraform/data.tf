@@ -8,6 +8,17 @@ data "aws_availability_zones" "available" {
   }
 }
 
+# Existing VPC and control plane subnets, when not creating the networking
+data "aws_vpc" "existing" {
+  count = var.existing_vpc_id != "" ? 1 : 0
+  id    = var.existing_vpc_id
+}
+
+data "aws_subnet" "existing_control_plane" {
+  count = var.existing_vpc_id != "" ? length(local.control_plane_subnet_ids) : 0
+  id    = local.control_plane_subnet_ids[count.index]
+}
+
 # Fetch the latest Ubuntu 22.04 LTS AMI
 data "aws_ami" "ubuntu" {
   count       = var.ami_id == "" ? 1 : 0
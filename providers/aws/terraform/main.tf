@@ -3,8 +3,26 @@
 # =============================================================================
 
 locals {
-  # Use provided AZs or auto-detect
-  availability_zones = length(var.availability_zones) > 0 ? var.availability_zones : slice(data.aws_availability_zones.available.names, 0, 3)
+  create_vpc = var.existing_vpc_id == ""
+
+  # Networking created by the networking module, or the existing VPC and subnets
+  vpc_id             = local.create_vpc ? module.networking[0].vpc_id : var.existing_vpc_id
+  vpc_cidr           = local.create_vpc ? module.networking[0].vpc_cidr : data.aws_vpc.existing[0].cidr_block
+  public_subnet_ids  = local.create_vpc ? module.networking[0].public_subnet_ids : var.existing_public_subnet_ids
+  private_subnet_ids = local.create_vpc ? module.networking[0].private_subnet_ids : var.existing_private_subnet_ids
+
+  # Control plane and NLB subnets; a private cluster in an existing VPC may have no public subnets
+  control_plane_subnet_ids = length(local.public_subnet_ids) > 0 ? local.public_subnet_ids : local.private_subnet_ids
+
+  # Use provided AZs or auto-detect. In an existing VPC the etcd volumes must be
+  # in the AZs of the control plane subnets.
+  availability_zones = (
+    !local.create_vpc ? data.aws_subnet.existing_control_plane[*].availability_zone :
+    length(var.availability_zones) > 0 ? var.availability_zones : slice(data.aws_availability_zones.available.names, 0, 3)
+  )
+
+  # Workers in an Auto Scaling Group resized by the cluster autoscaler
+  worker_autoscaling = var.worker_autoscaling_max > 0
 
   # Use provided AMI or auto-detected Ubuntu AMI
   ami_id = var.ami_id != "" ? var.ami_id : data.aws_ami.ubuntu[0].id
@@ -12,8 +30,8 @@ locals {
   # Use provided cluster token or generated one
   cluster_token = var.cluster_token != "" ? var.cluster_token : random_password.cluster_token[0].result
 
-  # Determine API endpoint (NLB DNS or first control plane IP)
-  api_endpoint = var.enable_nlb ? module.loadbalancer[0].nlb_dns_name : module.control_plane.first_node_ip
+  # Determine API endpoint (custom hostname, NLB DNS or first control plane IP)
+  api_endpoint = var.api_hostname != "" ? var.api_hostname : (var.enable_nlb ? module.loadbalancer[0].nlb_dns_name : module.control_plane.first_node_ip)
 
   # Common tags
   common_tags = merge(
@@ -31,6 +49,7 @@ locals {
 # =============================================================================
 
 module "networking" {
+  count  = local.create_vpc ? 1 : 0
   source = "./modules/networking"
 
   cluster_name         = var.cluster_name
@@ -53,9 +72,9 @@ module "security" {
   source = "./modules/security"
 
   cluster_name             = var.cluster_name
-  vpc_id                   = module.networking.vpc_id
-  vpc_cidr                 = module.networking.vpc_cidr
-  api_server_allowed_cidrs = var.api_server_allowed_cidrs
+  vpc_id                   = local.vpc_id
+  vpc_cidr                 = local.vpc_cidr
+  api_server_allowed_cidrs = length(var.api_server_allowed_cidrs) > 0 ? var.api_server_allowed_cidrs : [local.vpc_cidr]
   enable_nlb               = var.enable_nlb
   enable_ingress_nlb       = var.enable_ingress_nlb
 
@@ -79,6 +98,8 @@ module "iam" {
   enable_cloudwatch_logs  = var.enable_cloudwatch_logs
   enable_secrets_manager  = var.enable_secrets_manager
 
+  enable_cluster_autoscaler = local.worker_autoscaling
+
   tags = local.common_tags
 }
 
@@ -114,12 +135,14 @@ module "control_plane" {
   control_plane_count       = var.control_plane_count
   instance_type             = var.control_plane_instance_type
   ami_id                    = local.ami_id
-  subnet_ids                = module.networking.public_subnet_ids
+  subnet_ids                = local.control_plane_subnet_ids
   security_group_ids        = [module.security.control_plane_sg_id]
   iam_instance_profile_name = module.iam.control_plane_instance_profile_name
   ssh_key_name              = var.ssh_key_name
   root_volume_size          = var.control_plane_root_volume_size
   root_volume_type          = var.control_plane_root_volume_type
+  root_volume_iops          = var.control_plane_root_volume_iops
+  root_volume_throughput    = var.control_plane_root_volume_throughput
   etcd_volume_ids           = module.storage.etcd_volume_ids
   cluster_token             = local.cluster_token
   rke2_version              = var.rke2_version
@@ -131,6 +154,10 @@ module "control_plane" {
   nlb_dns_name              = "" # Not needed during instance creation
   enable_encryption         = var.enable_encryption
   kms_key_id                = var.enable_encryption ? module.iam.kms_key_arn : null
+  tls_sans                  = var.tls_sans
+  rke2_server_config        = var.rke2_server_config
+  oidc_ca_pem               = var.oidc_ca_pem
+  metadata_hop_limit        = local.worker_autoscaling ? 2 : 1
 
   tags = local.common_tags
 
@@ -151,18 +178,37 @@ module "loadbalancer" {
   source = "./modules/loadbalancer"
 
   cluster_name               = var.cluster_name
-  vpc_id                     = module.networking.vpc_id
-  subnet_ids                 = module.networking.public_subnet_ids
+  vpc_id                     = local.vpc_id
+  subnet_ids                 = local.control_plane_subnet_ids
   control_plane_instance_ids = module.control_plane.instance_ids
   nlb_internal               = var.nlb_internal
   enable_ingress             = var.enable_ingress_nlb
   worker_instance_ids        = module.worker.instance_ids
+  worker_autoscaling_group   = module.worker.autoscaling_group_name
 
   tags = local.common_tags
 
   depends_on = [module.control_plane, module.worker]
 }
 
+# =============================================================================
+# API DNS Record
+# =============================================================================
+
+resource "aws_route53_record" "api" {
+  count = var.enable_nlb && var.api_hostname != "" && var.route53_zone_id != "" ? 1 : 0
+
+  zone_id = var.route53_zone_id
+  name    = var.api_hostname
+  type    = "A"
+
+  alias {
+    name                   = module.loadbalancer[0].nlb_dns_name
+    zone_id                = module.loadbalancer[0].nlb_zone_id
+    evaluate_target_health = true
+  }
+}
+
 # =============================================================================
 # Worker Module
 # =============================================================================
@@ -174,16 +220,22 @@ module "worker" {
   worker_count              = var.worker_count
   instance_type             = var.worker_instance_type
   ami_id                    = local.ami_id
-  subnet_ids                = module.networking.private_subnet_ids
+  subnet_ids                = local.private_subnet_ids
   security_group_ids        = [module.security.worker_sg_id]
   iam_instance_profile_name = module.iam.worker_instance_profile_name
   ssh_key_name              = var.ssh_key_name
   root_volume_size          = var.worker_root_volume_size
   root_volume_type          = var.worker_root_volume_type
+  root_volume_iops          = var.worker_root_volume_iops
+  root_volume_throughput    = var.worker_root_volume_throughput
   cluster_token             = local.cluster_token
   rke2_version              = var.rke2_version
   api_endpoint              = module.control_plane.first_node_ip
   enable_spot_instances     = var.enable_spot_instances
+  on_demand_base            = var.worker_on_demand_base
+  enable_autoscaling        = local.worker_autoscaling
+  min_size                  = var.worker_autoscaling_min
+  max_size                  = var.worker_autoscaling_max
   enable_encryption         = var.enable_encryption
   kms_key_id                = var.enable_encryption ? module.iam.kms_key_arn : null
 
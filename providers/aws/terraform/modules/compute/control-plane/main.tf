@@ -16,6 +16,8 @@ resource "aws_instance" "control_plane_first" {
   root_block_device {
     volume_size           = var.root_volume_size
     volume_type           = var.root_volume_type
+    iops                  = contains(["gp3", "io1", "io2"], var.root_volume_type) ? var.root_volume_iops : null
+    throughput            = var.root_volume_type == "gp3" ? var.root_volume_throughput : null
     encrypted             = var.enable_encryption
     kms_key_id            = var.kms_key_id
     delete_on_termination = true
@@ -29,24 +31,27 @@ resource "aws_instance" "control_plane_first" {
   }
 
   user_data = base64encode(templatefile("${path.module}/user-data.tpl", {
-    cluster_name  = var.cluster_name
-    cluster_token = var.cluster_token
-    rke2_version  = var.rke2_version
-    cni_plugin    = var.cni_plugin
-    cluster_cidr  = var.cluster_cidr
-    service_cidr  = var.service_cidr
-    cluster_dns   = var.cluster_dns
-    state_bucket  = var.state_bucket
-    nlb_dns_name  = var.nlb_dns_name
-    is_first_node = "true"
-    first_node_ip = ""
-    node_index    = 0
+    cluster_name       = var.cluster_name
+    cluster_token      = var.cluster_token
+    rke2_version       = var.rke2_version
+    cni_plugin         = var.cni_plugin
+    cluster_cidr       = var.cluster_cidr
+    service_cidr       = var.service_cidr
+    cluster_dns        = var.cluster_dns
+    state_bucket       = var.state_bucket
+    nlb_dns_name       = var.nlb_dns_name
+    is_first_node      = "true"
+    first_node_ip      = ""
+    node_index         = 0
+    tls_sans           = var.tls_sans
+    rke2_server_config = var.rke2_server_config
+    oidc_ca_pem        = var.oidc_ca_pem
   }))
 
   metadata_options {
     http_endpoint               = "enabled"
     http_tokens                 = "required"
-    http_put_response_hop_limit = 1
+    http_put_response_hop_limit = var.metadata_hop_limit
   }
 
   tags = merge(
@@ -77,6 +82,8 @@ resource "aws_instance" "control_plane" {
   root_block_device {
     volume_size           = var.root_volume_size
     volume_type           = var.root_volume_type
+    iops                  = contains(["gp3", "io1", "io2"], var.root_volume_type) ? var.root_volume_iops : null
+    throughput            = var.root_volume_type == "gp3" ? var.root_volume_throughput : null
     encrypted             = var.enable_encryption
     kms_key_id            = var.kms_key_id
     delete_on_termination = true
@@ -90,24 +97,27 @@ resource "aws_instance" "control_plane" {
   }
 
   user_data = base64encode(templatefile("${path.module}/user-data.tpl", {
-    cluster_name  = var.cluster_name
-    cluster_token = var.cluster_token
-    rke2_version  = var.rke2_version
-    cni_plugin    = var.cni_plugin
-    cluster_cidr  = var.cluster_cidr
-    service_cidr  = var.service_cidr
-    cluster_dns   = var.cluster_dns
-    state_bucket  = var.state_bucket
-    nlb_dns_name  = var.nlb_dns_name
-    is_first_node = "false"
-    first_node_ip = aws_instance.control_plane_first[0].private_ip
-    node_index    = count.index + 1
+    cluster_name       = var.cluster_name
+    cluster_token      = var.cluster_token
+    rke2_version       = var.rke2_version
+    cni_plugin         = var.cni_plugin
+    cluster_cidr       = var.cluster_cidr
+    service_cidr       = var.service_cidr
+    cluster_dns        = var.cluster_dns
+    state_bucket       = var.state_bucket
+    nlb_dns_name       = var.nlb_dns_name
+    is_first_node      = "false"
+    first_node_ip      = aws_instance.control_plane_first[0].private_ip
+    node_index         = count.index + 1
+    tls_sans           = var.tls_sans
+    rke2_server_config = var.rke2_server_config
+    oidc_ca_pem        = var.oidc_ca_pem
   }))
 
   metadata_options {
     http_endpoint               = "enabled"
     http_tokens                 = "required"
-    http_put_response_hop_limit = 1
+    http_put_response_hop_limit = var.metadata_hop_limit
   }
 
   tags = merge(
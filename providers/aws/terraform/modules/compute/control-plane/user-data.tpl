@@ -124,6 +124,12 @@ if [ -n "$NLB_DNS_NAME" ]; then
   - $NLB_DNS_NAME"
 fi
 
+# Add the API hostname and extra SANs from the cluster config
+%{ for san in tls_sans ~}
+TLS_SANS="$TLS_SANS
+  - ${san}"
+%{ endfor ~}
+
 # Configure based on whether this is the first node
 if [ "$IS_FIRST_NODE" = "true" ]; then
   echo "[$(date)] Configuring as first control plane node..."
@@ -157,6 +163,18 @@ EOF
 
 fi
 
+# API server settings rendered by tdls-easy-k8s (e.g. OIDC authentication)
+%{ if oidc_ca_pem != "" ~}
+cat <<'EOF' > /etc/rancher/rke2/oidc-ca.pem
+${chomp(oidc_ca_pem)}
+EOF
+%{ endif ~}
+%{ if rke2_server_config != "" ~}
+cat <<'EOF' >> /etc/rancher/rke2/config.yaml
+${chomp(rke2_server_config)}
+EOF
+%{ endif ~}
+
 # =============================================================================
 # Start RKE2
 # =============================================================================
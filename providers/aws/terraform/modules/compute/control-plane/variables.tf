@@ -48,6 +48,18 @@ variable "root_volume_type" {
   type        = string
 }
 
+variable "root_volume_iops" {
+  description = "IOPS of root volume (gp3/io1/io2 only)"
+  type        = number
+  default     = 3000
+}
+
+variable "root_volume_throughput" {
+  description = "Throughput of root volume in MB/s (gp3 only)"
+  type        = number
+  default     = 125
+}
+
 variable "etcd_volume_ids" {
   description = "List of etcd EBS volume IDs"
   type        = list(string)
@@ -105,8 +117,36 @@ variable "kms_key_id" {
   default     = null
 }
 
+variable "metadata_hop_limit" {
+  description = "Instance metadata hop limit; 2 lets pods such as the cluster autoscaler use the instance role"
+  type        = number
+  default     = 1
+}
+
 variable "tags" {
   description = "Additional tags for resources"
   type        = map(string)
   default     = {}
 }
+
+# =============================================================================
+# API Server Configuration
+# =============================================================================
+
+variable "rke2_server_config" {
+  description = "Extra RKE2 server settings (YAML) appended to config.yaml on control plane nodes"
+  type        = string
+  default     = ""
+}
+
+variable "tls_sans" {
+  description = "Extra names and IPs added to the API server certificate (tls-san)"
+  type        = list(string)
+  default     = []
+}
+
+variable "oidc_ca_pem" {
+  description = "PEM CA bundle of the OIDC issuer, written to /etc/rancher/rke2/oidc-ca.pem"
+  type        = string
+  default     = ""
+}
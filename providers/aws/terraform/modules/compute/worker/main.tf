@@ -2,8 +2,12 @@
 # Worker EC2 Instances
 # =============================================================================
 
+locals {
+  spot = [for i in range(var.worker_count) : var.enable_spot_instances && i >= var.on_demand_base]
+}
+
 resource "aws_instance" "worker" {
-  count = var.worker_count
+  count = var.enable_autoscaling ? 0 : var.worker_count
 
   ami                    = var.ami_id
   instance_type          = var.instance_type
@@ -12,12 +16,12 @@ resource "aws_instance" "worker" {
   iam_instance_profile   = var.iam_instance_profile_name
   key_name               = var.ssh_key_name != "" ? var.ssh_key_name : null
 
-  # Spot instance configuration
+  # Spot instance configuration; the first on_demand_base workers stay on-demand
   instance_market_options {
-    market_type = var.enable_spot_instances ? "spot" : null
+    market_type = local.spot[count.index] ? "spot" : null
 
     dynamic "spot_options" {
-      for_each = var.enable_spot_instances ? [1] : []
+      for_each = local.spot[count.index] ? [1] : []
       content {
         spot_instance_type             = "one-time"
         instance_interruption_behavior = "terminate"
@@ -28,6 +32,8 @@ resource "aws_instance" "worker" {
   root_block_device {
     volume_size           = var.root_volume_size
     volume_type           = var.root_volume_type
+    iops                  = contains(["gp3", "io1", "io2"], var.root_volume_type) ? var.root_volume_iops : null
+    throughput            = var.root_volume_type == "gp3" ? var.root_volume_throughput : null
     encrypted             = var.enable_encryption
     kms_key_id            = var.kms_key_id
     delete_on_termination = true
@@ -46,6 +52,8 @@ resource "aws_instance" "worker" {
     rke2_version  = var.rke2_version
     api_endpoint  = var.api_endpoint
     node_index    = count.index
+    spot          = local.spot[count.index]
+    detect_spot   = false
   }))
 
   metadata_options {
@@ -67,3 +75,135 @@ resource "aws_instance" "worker" {
     ignore_changes = [ami]
   }
 }
+
+# =============================================================================
+# Autoscaled Workers (Auto Scaling Group resized by the cluster autoscaler)
+# =============================================================================
+
+resource "aws_launch_template" "worker" {
+  count = var.enable_autoscaling ? 1 : 0
+
+  name_prefix            = "${var.cluster_name}-worker-"
+  image_id               = var.ami_id
+  instance_type          = var.instance_type
+  vpc_security_group_ids = var.security_group_ids
+  key_name               = var.ssh_key_name != "" ? var.ssh_key_name : null
+
+  iam_instance_profile {
+    name = var.iam_instance_profile_name
+  }
+
+  block_device_mappings {
+    device_name = "/dev/sda1"
+
+    ebs {
+      volume_size           = var.root_volume_size
+      volume_type           = var.root_volume_type
+      iops                  = contains(["gp3", "io1", "io2"], var.root_volume_type) ? var.root_volume_iops : null
+      throughput            = var.root_volume_type == "gp3" ? var.root_volume_throughput : null
+      encrypted             = var.enable_encryption
+      kms_key_id            = var.kms_key_id
+      delete_on_termination = true
+    }
+  }
+
+  # Instances of a mixed group learn whether they are spot from the metadata
+  user_data = base64encode(templatefile("${path.module}/user-data.tpl", {
+    cluster_name  = var.cluster_name
+    cluster_token = var.cluster_token
+    rke2_version  = var.rke2_version
+    api_endpoint  = var.api_endpoint
+    node_index    = "autoscaled"
+    spot          = false
+    detect_spot   = var.enable_spot_instances
+  }))
+
+  metadata_options {
+    http_endpoint               = "enabled"
+    http_tokens                 = "required"
+    http_put_response_hop_limit = 1
+  }
+
+  tag_specifications {
+    resource_type = "volume"
+    tags = merge(
+      {
+        Name = "${var.cluster_name}-worker-root"
+      },
+      var.tags
+    )
+  }
+
+  tags = var.tags
+}
+
+resource "aws_autoscaling_group" "worker" {
+  count = var.enable_autoscaling ? 1 : 0
+
+  name                = "${var.cluster_name}-workers"
+  min_size            = var.min_size
+  max_size            = var.max_size
+  desired_capacity    = var.worker_count
+  vpc_zone_identifier = var.subnet_ids
+
+  # Spot workers: the first on_demand_base instances stay on-demand
+  dynamic "mixed_instances_policy" {
+    for_each = var.enable_spot_instances ? [1] : []
+    content {
+      instances_distribution {
+        on_demand_base_capacity                  = var.on_demand_base
+        on_demand_percentage_above_base_capacity = 0
+        spot_allocation_strategy                 = "price-capacity-optimized"
+      }
+
+      launch_template {
+        launch_template_specification {
+          launch_template_id = aws_launch_template.worker[0].id
+          version            = aws_launch_template.worker[0].latest_version
+        }
+      }
+    }
+  }
+
+  dynamic "launch_template" {
+    for_each = var.enable_spot_instances ? [] : [1]
+    content {
+      id      = aws_launch_template.worker[0].id
+      version = aws_launch_template.worker[0].latest_version
+    }
+  }
+
+  # The cluster autoscaler discovers the group by the k8s.io/cluster-autoscaler tags
+  dynamic "tag" {
+    for_each = merge(
+      var.tags,
+      {
+        Name                                            = "${var.cluster_name}-worker"
+        Role                                            = "worker"
+        "kubernetes.io/cluster/${var.cluster_name}"     = "owned"
+        "k8s.io/cluster-autoscaler/enabled"             = "true"
+        "k8s.io/cluster-autoscaler/${var.cluster_name}" = "owned"
+      }
+    )
+    content {
+      key                 = tag.key
+      value               = tag.value
+      propagate_at_launch = true
+    }
+  }
+
+  lifecycle {
+    # The cluster autoscaler owns the size after creation
+    ignore_changes = [desired_capacity]
+  }
+}
+
+# The group's instances at apply time, for the post-provisioning steps
+data "aws_instances" "worker" {
+  count = var.enable_autoscaling ? 1 : 0
+
+  instance_tags = {
+    "aws:autoscaling:groupName" = aws_autoscaling_group.worker[0].name
+  }
+  instance_state_names = ["pending", "running"]
+}
@@ -1,9 +1,14 @@
 output "instance_ids" {
   description = "List of worker instance IDs"
-  value       = aws_instance.worker[*].id
+  value       = var.enable_autoscaling ? data.aws_instances.worker[0].ids : aws_instance.worker[*].id
 }
 
 output "private_ips" {
   description = "List of worker private IPs"
-  value       = aws_instance.worker[*].private_ip
+  value       = var.enable_autoscaling ? data.aws_instances.worker[0].private_ips : aws_instance.worker[*].private_ip
+}
+
+output "autoscaling_group_name" {
+  description = "Name of the worker Auto Scaling Group (empty unless autoscaling)"
+  value       = var.enable_autoscaling ? aws_autoscaling_group.worker[0].name : ""
 }
@@ -84,7 +84,25 @@ node-label:
   - "topology.kubernetes.io/zone=$AVAILABILITY_ZONE"
   - "topology.kubernetes.io/region=$REGION"
   - "node.tdls-easy-k8s.io/instance-id=$INSTANCE_ID"
+%{ if spot ~}
+  - "node.kubernetes.io/instance-lifecycle=spot"
+%{ endif ~}
 EOF
+%{ if detect_spot ~}
+
+# Instances of a mixed Auto Scaling Group are spot or on-demand. The label goes
+# into a drop-in file, as appending to config.yaml would depend on node-label
+# being its last key; node-label+ adds to the labels above instead of replacing them.
+IMDS_TOKEN=$(curl -s -X PUT http://169.254.169.254/latest/api/token -H "X-aws-ec2-metadata-token-ttl-seconds: 300")
+LIFECYCLE=$(curl -s -H "X-aws-ec2-metadata-token: $IMDS_TOKEN" http://169.254.169.254/latest/meta-data/instance-life-cycle)
+if [ "$LIFECYCLE" = "spot" ]; then
+  mkdir -p /etc/rancher/rke2/config.yaml.d
+  cat <<EOF > /etc/rancher/rke2/config.yaml.d/50-spot.yaml
+node-label+:
+  - "node.kubernetes.io/instance-lifecycle=spot"
+EOF
+fi
+%{ endif ~}
 
 # =============================================================================
 # Start RKE2 Agent
@@ -48,6 +48,18 @@ variable "root_volume_type" {
   type        = string
 }
 
+variable "root_volume_iops" {
+  description = "IOPS of root volume (gp3/io1/io2 only)"
+  type        = number
+  default     = 3000
+}
+
+variable "root_volume_throughput" {
+  description = "Throughput of root volume in MB/s (gp3 only)"
+  type        = number
+  default     = 125
+}
+
 variable "cluster_token" {
   description = "Cluster join token"
   type        = string
@@ -69,6 +81,30 @@ variable "enable_spot_instances" {
   type        = bool
 }
 
+variable "on_demand_base" {
+  description = "Number of workers kept on-demand when spot instances are enabled"
+  type        = number
+  default     = 0
+}
+
+variable "enable_autoscaling" {
+  description = "Run the workers in an Auto Scaling Group resized by the cluster autoscaler"
+  type        = bool
+  default     = false
+}
+
+variable "min_size" {
+  description = "Minimum number of workers when autoscaling"
+  type        = number
+  default     = 0
+}
+
+variable "max_size" {
+  description = "Maximum number of workers when autoscaling"
+  type        = number
+  default     = 0
+}
+
 variable "enable_encryption" {
   description = "Enable EBS encryption"
   type        = bool
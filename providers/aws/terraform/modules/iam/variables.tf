@@ -34,6 +34,12 @@ variable "enable_secrets_manager" {
   default     = false
 }
 
+variable "enable_cluster_autoscaler" {
+  description = "Allow the cluster autoscaler, which runs on the control plane, to resize the worker Auto Scaling Group"
+  type        = bool
+  default     = false
+}
+
 variable "tags" {
   description = "Additional tags for resources"
   type        = map(string)
@@ -223,7 +223,7 @@ resource "aws_lb_target_group" "ingress_https" {
 # =============================================================================
 
 resource "aws_lb_target_group_attachment" "ingress_http" {
-  count = var.enable_ingress ? length(var.worker_instance_ids) : 0
+  count = var.enable_ingress && var.worker_autoscaling_group == "" ? length(var.worker_instance_ids) : 0
 
   target_group_arn = aws_lb_target_group.ingress_http[0].arn
   target_id        = var.worker_instance_ids[count.index]
@@ -231,7 +231,7 @@ resource "aws_lb_target_group_attachment" "ingress_http" {
 }
 
 resource "aws_lb_target_group_attachment" "ingress_https" {
-  count = var.enable_ingress ? length(var.worker_instance_ids) : 0
+  count = var.enable_ingress && var.worker_autoscaling_group == "" ? length(var.worker_instance_ids) : 0
 
   target_group_arn = aws_lb_target_group.ingress_https[0].arn
   target_id        = var.worker_instance_ids[count.index]
@@ -281,3 +281,18 @@ resource "aws_lb_listener" "ingress_https" {
     var.tags
   )
 }
+
+# Autoscaled workers are registered by their Auto Scaling Group
+resource "aws_autoscaling_attachment" "ingress_http" {
+  count = var.enable_ingress && var.worker_autoscaling_group != "" ? 1 : 0
+
+  autoscaling_group_name = var.worker_autoscaling_group
+  lb_target_group_arn    = aws_lb_target_group.ingress_http[0].arn
+}
+
+resource "aws_autoscaling_attachment" "ingress_https" {
+  count = var.enable_ingress && var.worker_autoscaling_group != "" ? 1 : 0
+
+  autoscaling_group_name = var.worker_autoscaling_group
+  lb_target_group_arn    = aws_lb_target_group.ingress_https[0].arn
+}
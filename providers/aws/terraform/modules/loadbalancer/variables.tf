@@ -35,6 +35,12 @@ variable "worker_instance_ids" {
   default     = []
 }
 
+variable "worker_autoscaling_group" {
+  description = "Name of the worker Auto Scaling Group, whose instances are the ingress NLB targets (empty to use worker_instance_ids)"
+  type        = string
+  default     = ""
+}
+
 variable "tags" {
   description = "Additional tags for resources"
   type        = map(string)
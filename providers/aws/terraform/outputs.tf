@@ -23,27 +23,27 @@ output "kubeconfig_download_command" {
 
 output "vpc_id" {
   description = "VPC ID"
-  value       = module.networking.vpc_id
+  value       = local.vpc_id
 }
 
 output "vpc_cidr" {
   description = "VPC CIDR block"
-  value       = module.networking.vpc_cidr
+  value       = local.vpc_cidr
 }
 
 output "public_subnet_ids" {
   description = "Public subnet IDs"
-  value       = module.networking.public_subnet_ids
+  value       = local.public_subnet_ids
 }
 
 output "private_subnet_ids" {
   description = "Private subnet IDs"
-  value       = module.networking.private_subnet_ids
+  value       = local.private_subnet_ids
 }
 
 output "nat_gateway_public_ips" {
   description = "NAT Gateway public IPs"
-  value       = module.networking.nat_gateway_public_ips
+  value       = local.create_vpc ? module.networking[0].nat_gateway_public_ips : []
 }
 
 # =============================================================================
@@ -79,6 +79,11 @@ output "worker_private_ips" {
   value       = module.worker.private_ips
 }
 
+output "worker_autoscaling_group" {
+  description = "Worker Auto Scaling Group name (empty unless autoscaling)"
+  value       = module.worker.autoscaling_group_name
+}
+
 # =============================================================================
 # Load Balancer Outputs
 # =============================================================================
@@ -157,7 +162,7 @@ output "cluster_info" {
     api_endpoint        = local.api_endpoint
     cni_plugin          = var.cni_plugin
     load_balancer       = var.enable_nlb ? "AWS NLB" : "Disabled"
-    vpc_id              = module.networking.vpc_id
+    vpc_id              = local.vpc_id
     availability_zones  = local.availability_zones
   }
 }
@@ -32,6 +32,30 @@ variable "aws_region" {
   type        = string
 }
 
+variable "aws_profile" {
+  description = "AWS CLI profile to use (empty for the default credential chain)"
+  type        = string
+  default     = ""
+}
+
+variable "assume_role_arn" {
+  description = "IAM role to assume for all AWS API calls (empty to use the credentials as they are)"
+  type        = string
+  default     = ""
+}
+
+variable "assume_role_external_id" {
+  description = "External ID required by the trust policy of the assumed role"
+  type        = string
+  default     = ""
+}
+
+variable "assume_role_session_name" {
+  description = "Session name for the assumed role"
+  type        = string
+  default     = "tdls-easy-k8s"
+}
+
 variable "availability_zones" {
   description = "List of availability zones (must be 3 for HA, empty for auto-detection)"
   type        = list(string)
@@ -98,6 +122,29 @@ variable "enable_vpc_endpoints" {
   default     = true
 }
 
+variable "existing_vpc_id" {
+  description = "ID of an existing VPC to use instead of creating one (empty: create a VPC)"
+  type        = string
+  default     = ""
+}
+
+variable "existing_public_subnet_ids" {
+  description = "Existing subnets for the control plane and NLB (empty with an existing VPC: use the private subnets)"
+  type        = list(string)
+  default     = []
+}
+
+variable "existing_private_subnet_ids" {
+  description = "Existing subnets for the workers (required with an existing VPC)"
+  type        = list(string)
+  default     = []
+
+  validation {
+    condition     = var.existing_vpc_id == "" || length(var.existing_private_subnet_ids) > 0
+    error_message = "existing_private_subnet_ids is required when existing_vpc_id is set."
+  }
+}
+
 # =============================================================================
 # Load Balancer Configuration
 # =============================================================================
@@ -115,7 +162,7 @@ variable "nlb_internal" {
 }
 
 variable "api_server_allowed_cidrs" {
-  description = "CIDR blocks allowed to access Kubernetes API"
+  description = "CIDR blocks allowed to access Kubernetes API (empty: the VPC CIDR)"
   type        = list(string)
   default     = ["0.0.0.0/0"]
 }
@@ -169,6 +216,18 @@ variable "control_plane_root_volume_type" {
   default     = "gp3"
 }
 
+variable "control_plane_root_volume_iops" {
+  description = "IOPS of root EBS volume for control plane (gp3/io1/io2 only)"
+  type        = number
+  default     = 3000
+}
+
+variable "control_plane_root_volume_throughput" {
+  description = "Throughput of root EBS volume for control plane in MB/s (gp3 only)"
+  type        = number
+  default     = 125
+}
+
 # =============================================================================
 # Compute Configuration - Workers
 # =============================================================================
@@ -202,12 +261,42 @@ variable "worker_root_volume_type" {
   default     = "gp3"
 }
 
+variable "worker_root_volume_iops" {
+  description = "IOPS of root EBS volume for workers (gp3/io1/io2 only)"
+  type        = number
+  default     = 3000
+}
+
+variable "worker_root_volume_throughput" {
+  description = "Throughput of root EBS volume for workers in MB/s (gp3 only)"
+  type        = number
+  default     = 125
+}
+
 variable "enable_spot_instances" {
   description = "Use EC2 spot instances for workers (cost optimization)"
   type        = bool
   default     = false
 }
 
+variable "worker_on_demand_base" {
+  description = "Number of workers kept on-demand when spot instances are enabled"
+  type        = number
+  default     = 0
+}
+
+variable "worker_autoscaling_min" {
+  description = "Minimum number of workers the cluster autoscaler keeps"
+  type        = number
+  default     = 0
+}
+
+variable "worker_autoscaling_max" {
+  description = "Maximum number of workers the cluster autoscaler scales to (0 disables autoscaling)"
+  type        = number
+  default     = 0
+}
+
 # =============================================================================
 # AMI Configuration
 # =============================================================================
@@ -425,3 +514,37 @@ variable "additional_tags" {
   type        = map(string)
   default     = {}
 }
+
+# =============================================================================
+# API Server Configuration
+# =============================================================================
+
+variable "rke2_server_config" {
+  description = "Extra RKE2 server settings (YAML) appended to config.yaml on control plane nodes"
+  type        = string
+  default     = ""
+}
+
+variable "tls_sans" {
+  description = "Extra names and IPs added to the API server certificate (tls-san)"
+  type        = list(string)
+  default     = []
+}
+
+variable "api_hostname" {
+  description = "DNS name for the Kubernetes API server (e.g. k8s-prod.example.com)"
+  type        = string
+  default     = ""
+}
+
+variable "route53_zone_id" {
+  description = "Route53 hosted zone in which to create the api_hostname record (empty: no record)"
+  type        = string
+  default     = ""
+}
+
+variable "oidc_ca_pem" {
+  description = "PEM CA bundle of the OIDC issuer, written to /etc/rancher/rke2/oidc-ca.pem"
+  type        = string
+  default     = ""
+}
@@ -30,7 +30,17 @@ terraform {
 }
 
 provider "aws" {
-  region = var.aws_region
+  region  = var.aws_region
+  profile = var.aws_profile != "" ? var.aws_profile : null
+
+  dynamic "assume_role" {
+    for_each = var.assume_role_arn != "" ? [1] : []
+    content {
+      role_arn     = var.assume_role_arn
+      external_id  = var.assume_role_external_id != "" ? var.assume_role_external_id : null
+      session_name = var.assume_role_session_name
+    }
+  }
 
   default_tags {
     tags = {
@@ -7,6 +7,22 @@ locals {
     cluster    = var.cluster_name
     managed_by = "tdls-easy-k8s"
   }
+
+  # The cluster autoscaler's node group; it owns the servers with this label
+  autoscaler_node_group = "${var.cluster_name}-workers"
+  worker_labels = merge(
+    local.common_labels,
+    { role = "worker" },
+    var.worker_autoscaling ? { "hcloud/node-group" = local.autoscaler_node_group } : {}
+  )
+
+  worker_user_data = {
+    cluster_name            = var.cluster_name
+    cluster_token           = random_password.cluster_token.result
+    rke2_version            = var.rke2_version
+    api_endpoint            = hcloud_server.control_plane_init.ipv4_address
+    cloud_provider_external = var.cloud_provider_external
+  }
 }
 
 # =============================================================================
@@ -169,17 +185,21 @@ resource "hcloud_server" "control_plane_init" {
   firewall_ids = [hcloud_firewall.cluster.id]
 
   user_data = templatefile("${path.module}/user-data-cp.tpl", {
-    cluster_name  = var.cluster_name
-    cluster_token = random_password.cluster_token.result
-    rke2_version  = var.rke2_version
-    cni_plugin    = var.cni_plugin
-    cluster_cidr  = var.cluster_cidr
-    service_cidr  = var.service_cidr
-    cluster_dns   = var.cluster_dns
-    lb_ipv4       = hcloud_load_balancer.api.ipv4
-    is_first_node = "true"
-    first_node_ip = ""
-    node_index    = 0
+    cluster_name            = var.cluster_name
+    cluster_token           = random_password.cluster_token.result
+    rke2_version            = var.rke2_version
+    cni_plugin              = var.cni_plugin
+    cluster_cidr            = var.cluster_cidr
+    service_cidr            = var.service_cidr
+    cluster_dns             = var.cluster_dns
+    lb_ipv4                 = hcloud_load_balancer.api.ipv4
+    is_first_node           = "true"
+    first_node_ip           = ""
+    node_index              = 0
+    tls_sans                = var.tls_sans
+    rke2_server_config      = var.rke2_server_config
+    oidc_ca_pem             = var.oidc_ca_pem
+    cloud_provider_external = var.cloud_provider_external
   })
 
   network {
@@ -213,17 +233,21 @@ resource "hcloud_server" "control_plane_join" {
   firewall_ids = [hcloud_firewall.cluster.id]
 
   user_data = templatefile("${path.module}/user-data-cp.tpl", {
-    cluster_name  = var.cluster_name
-    cluster_token = random_password.cluster_token.result
-    rke2_version  = var.rke2_version
-    cni_plugin    = var.cni_plugin
-    cluster_cidr  = var.cluster_cidr
-    service_cidr  = var.service_cidr
-    cluster_dns   = var.cluster_dns
-    lb_ipv4       = hcloud_load_balancer.api.ipv4
-    is_first_node = "false"
-    first_node_ip = hcloud_server.control_plane_init.ipv4_address
-    node_index    = count.index + 1
+    cluster_name            = var.cluster_name
+    cluster_token           = random_password.cluster_token.result
+    rke2_version            = var.rke2_version
+    cni_plugin              = var.cni_plugin
+    cluster_cidr            = var.cluster_cidr
+    service_cidr            = var.service_cidr
+    cluster_dns             = var.cluster_dns
+    lb_ipv4                 = hcloud_load_balancer.api.ipv4
+    is_first_node           = "false"
+    first_node_ip           = hcloud_server.control_plane_init.ipv4_address
+    node_index              = count.index + 1
+    tls_sans                = var.tls_sans
+    rke2_server_config      = var.rke2_server_config
+    oidc_ca_pem             = var.oidc_ca_pem
+    cloud_provider_external = var.cloud_provider_external
   })
 
   network {
@@ -252,17 +276,13 @@ resource "hcloud_server" "worker" {
   image       = var.os_image
   location    = var.location
   ssh_keys    = [hcloud_ssh_key.cluster.id]
-  labels      = merge(local.common_labels, { role = "worker" })
+  labels      = local.worker_labels
 
   firewall_ids = [hcloud_firewall.cluster.id]
 
-  user_data = templatefile("${path.module}/user-data-worker.tpl", {
-    cluster_name  = var.cluster_name
-    cluster_token = random_password.cluster_token.result
-    rke2_version  = var.rke2_version
-    api_endpoint  = hcloud_server.control_plane_init.ipv4_address
-    node_index    = count.index
-  })
+  user_data = templatefile("${path.module}/user-data-worker.tpl", merge(local.worker_user_data, {
+    node_index = count.index
+  }))
 
   network {
     network_id = hcloud_network.cluster.id
@@ -357,7 +377,7 @@ resource "hcloud_load_balancer_network" "ingress" {
 }
 
 resource "hcloud_load_balancer_target" "ingress_worker" {
-  count            = var.enable_ingress_lb ? var.worker_count : 0
+  count            = var.enable_ingress_lb && !var.worker_autoscaling ? var.worker_count : 0
   type             = "server"
   load_balancer_id = hcloud_load_balancer.ingress[0].id
   server_id        = hcloud_server.worker[count.index].id
@@ -366,6 +386,18 @@ resource "hcloud_load_balancer_target" "ingress_worker" {
   depends_on = [hcloud_load_balancer_network.ingress]
 }
 
+# Autoscaled workers are targeted by their node group label, so servers the
+# cluster autoscaler adds receive traffic too
+resource "hcloud_load_balancer_target" "ingress_node_group" {
+  count            = var.enable_ingress_lb && var.worker_autoscaling ? 1 : 0
+  type             = "label_selector"
+  load_balancer_id = hcloud_load_balancer.ingress[0].id
+  label_selector   = "hcloud/node-group=${local.autoscaler_node_group}"
+  use_private_ip   = true
+
+  depends_on = [hcloud_load_balancer_network.ingress]
+}
+
 resource "hcloud_load_balancer_service" "ingress_http" {
   count            = var.enable_ingress_lb ? 1 : 0
   load_balancer_id = hcloud_load_balancer.ingress[0].id
@@ -381,7 +413,10 @@ resource "hcloud_load_balancer_service" "ingress_http" {
     retries  = 3
   }
 
-  depends_on = [hcloud_load_balancer_target.ingress_worker]
+  depends_on = [
+    hcloud_load_balancer_target.ingress_worker,
+    hcloud_load_balancer_target.ingress_node_group,
+  ]
 }
 
 resource "hcloud_load_balancer_service" "ingress_https" {
@@ -399,5 +434,8 @@ resource "hcloud_load_balancer_service" "ingress_https" {
     retries  = 3
   }
 
-  depends_on = [hcloud_load_balancer_target.ingress_worker]
+  depends_on = [
+    hcloud_load_balancer_target.ingress_worker,
+    hcloud_load_balancer_target.ingress_node_group,
+  ]
 }
@@ -25,6 +25,14 @@ output "worker_ips" {
   value       = hcloud_server.worker[*].ipv4_address
 }
 
+output "autoscaler_cloud_init" {
+  description = "User data of the servers the cluster autoscaler adds to the worker node group"
+  value = templatefile("${path.module}/user-data-worker.tpl", merge(local.worker_user_data, {
+    node_index = "autoscaled"
+  }))
+  sensitive = true
+}
+
 output "ssh_private_key" {
   description = "SSH private key for accessing nodes"
   value       = tls_private_key.ssh.private_key_openssh
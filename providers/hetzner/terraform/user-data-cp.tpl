@@ -83,6 +83,7 @@ mkdir -p /etc/rancher/rke2
 # Private IP from Hetzner private network (not eth0 — that's the public interface)
 PRIVATE_IP=$(curl -s http://169.254.169.254/hetzner/v1/metadata/private-networks | grep -oP '(?<=ip: )\S+' | head -1)
 PUBLIC_IP=$(curl -s http://169.254.169.254/hetzner/v1/metadata/public-ipv4 2>/dev/null || hostname -I | awk '{print $1}')
+SERVER_ID=$(curl -s http://169.254.169.254/hetzner/v1/metadata/instance-id)
 
 if [ -z "$PRIVATE_IP" ]; then
   echo "[$(date)] WARNING: Could not detect private network IP, falling back to public IP"
@@ -127,6 +128,9 @@ tls-san:
   - $PUBLIC_IP
   - $PRIVATE_IP
   - $LB_IPV4
+%{ for san in tls_sans ~}
+  - ${san}
+%{ endfor ~}
 EOF
 
 else
@@ -142,10 +146,58 @@ tls-san:
   - $PUBLIC_IP
   - $PRIVATE_IP
   - $LB_IPV4
+%{ for san in tls_sans ~}
+  - ${san}
+%{ endfor ~}
 EOF
 
 fi
 
+%{ if cloud_provider_external ~}
+# The Hetzner cloud controller manager initializes the nodes. Until it runs,
+# they carry the node.cloudprovider.kubernetes.io/uninitialized taint, which
+# CoreDNS must tolerate for Flux to deploy the cloud controller manager.
+cat <<EOF >> /etc/rancher/rke2/config.yaml
+cloud-provider-name: external
+kubelet-arg:
+  - "provider-id=hcloud://$SERVER_ID"
+EOF
+
+cat <<EOF > /var/lib/rancher/rke2/server/manifests/rke2-coredns-config.yaml
+apiVersion: helm.cattle.io/v1
+kind: HelmChartConfig
+metadata:
+  name: rke2-coredns
+  namespace: kube-system
+spec:
+  valuesContent: |-
+    tolerations:
+      - key: CriticalAddonsOnly
+        operator: Exists
+      - key: node-role.kubernetes.io/control-plane
+        operator: Exists
+        effect: NoSchedule
+      - key: node-role.kubernetes.io/etcd
+        operator: Exists
+        effect: NoExecute
+      - key: node.cloudprovider.kubernetes.io/uninitialized
+        operator: Exists
+        effect: NoSchedule
+EOF
+%{ endif ~}
+
+# API server settings rendered by tdls-easy-k8s (e.g. OIDC authentication)
+%{ if oidc_ca_pem != "" ~}
+cat <<'EOF' > /etc/rancher/rke2/oidc-ca.pem
+${chomp(oidc_ca_pem)}
+EOF
+%{ endif ~}
+%{ if rke2_server_config != "" ~}
+cat <<'EOF' >> /etc/rancher/rke2/config.yaml
+${chomp(rke2_server_config)}
+EOF
+%{ endif ~}
+
 # =============================================================================
 # Start RKE2
 # =============================================================================
@@ -74,6 +74,7 @@ mkdir -p /etc/rancher/rke2
 PUBLIC_IP=$(curl -s http://169.254.169.254/hetzner/v1/metadata/public-ipv4 2>/dev/null || hostname -I | awk '{print $1}')
 PRIVATE_IP=$(curl -s http://169.254.169.254/hetzner/v1/metadata/private-networks | grep -oP '(?<=ip: )\S+' | head -1)
 LOCATION=$(curl -s http://169.254.169.254/hetzner/v1/metadata/availability-zone 2>/dev/null || echo "unknown")
+SERVER_ID=$(curl -s http://169.254.169.254/hetzner/v1/metadata/instance-id)
 
 if [ -z "$PRIVATE_IP" ]; then
   echo "[$(date)] WARNING: Could not detect private network IP, falling back to public IP"
@@ -88,6 +89,11 @@ node-ip: $PRIVATE_IP
 node-label:
   - "topology.kubernetes.io/zone=$LOCATION"
   - "node.tdls-easy-k8s.io/public-ip=$PUBLIC_IP"
+kubelet-arg:
+  - "provider-id=hcloud://$SERVER_ID"
+%{ if cloud_provider_external ~}
+cloud-provider-name: external
+%{ endif ~}
 EOF
 
 # =============================================================================
@@ -79,6 +79,18 @@ variable "worker_count" {
   }
 }
 
+variable "worker_autoscaling" {
+  description = "Put the workers in a node group of the cluster autoscaler, which adds and removes servers"
+  type        = bool
+  default     = false
+}
+
+variable "cloud_provider_external" {
+  description = "Run the kubelets with cloud-provider=external for the Hetzner cloud controller manager"
+  type        = bool
+  default     = false
+}
+
 variable "server_type_worker" {
   description = "Hetzner server type for worker nodes (e.g., cpx21, cpx31, cx23)"
   type        = string
@@ -149,3 +161,25 @@ variable "enable_ingress_lb" {
   type        = bool
   default     = false
 }
+
+# =============================================================================
+# API Server Configuration
+# =============================================================================
+
+variable "rke2_server_config" {
+  description = "Extra RKE2 server settings (YAML) appended to config.yaml on control plane nodes"
+  type        = string
+  default     = ""
+}
+
+variable "tls_sans" {
+  description = "Extra names and IPs added to the API server certificate (tls-san)"
+  type        = list(string)
+  default     = []
+}
+
+variable "oidc_ca_pem" {
+  description = "PEM CA bundle of the OIDC issuer, written to /etc/rancher/rke2/oidc-ca.pem"
+  type        = string
+  default     = ""
+}
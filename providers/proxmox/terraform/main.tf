@@ -46,18 +46,21 @@ resource "proxmox_virtual_environment_file" "cloudinit_cp_init" {
 
   source_raw {
     data = templatefile("${path.module}/user-data-cp.tpl", {
-      cluster_name   = var.cluster_name
-      cluster_token  = random_password.cluster_token.result
-      rke2_version   = var.rke2_version
-      cni_plugin     = var.cni_plugin
-      cluster_cidr   = var.cluster_cidr
-      service_cidr   = var.service_cidr
-      cluster_dns    = var.cluster_dns
-      vip_address    = var.vip_address
-      is_first_node  = "true"
-      first_node_ip  = ""
-      node_index     = 0
-      ssh_public_key = tls_private_key.ssh.public_key_openssh
+      cluster_name       = var.cluster_name
+      cluster_token      = random_password.cluster_token.result
+      rke2_version       = var.rke2_version
+      cni_plugin         = var.cni_plugin
+      cluster_cidr       = var.cluster_cidr
+      service_cidr       = var.service_cidr
+      cluster_dns        = var.cluster_dns
+      vip_address        = var.vip_address
+      is_first_node      = "true"
+      first_node_ip      = ""
+      node_index         = 0
+      tls_sans           = var.tls_sans
+      rke2_server_config = var.rke2_server_config
+      oidc_ca_pem        = var.oidc_ca_pem
+      ssh_public_key     = tls_private_key.ssh.public_key_openssh
     })
     file_name = "${var.cluster_name}-cp-init-userdata.yaml"
   }
@@ -71,18 +74,21 @@ resource "proxmox_virtual_environment_file" "cloudinit_cp_join" {
 
   source_raw {
     data = templatefile("${path.module}/user-data-cp.tpl", {
-      cluster_name   = var.cluster_name
-      cluster_token  = random_password.cluster_token.result
-      rke2_version   = var.rke2_version
-      cni_plugin     = var.cni_plugin
-      cluster_cidr   = var.cluster_cidr
-      service_cidr   = var.service_cidr
-      cluster_dns    = var.cluster_dns
-      vip_address    = var.vip_address
-      is_first_node  = "false"
-      first_node_ip  = proxmox_virtual_environment_vm.control_plane_init.ipv4_addresses[1][0]
-      node_index     = count.index + 1
-      ssh_public_key = tls_private_key.ssh.public_key_openssh
+      cluster_name       = var.cluster_name
+      cluster_token      = random_password.cluster_token.result
+      rke2_version       = var.rke2_version
+      cni_plugin         = var.cni_plugin
+      cluster_cidr       = var.cluster_cidr
+      service_cidr       = var.service_cidr
+      cluster_dns        = var.cluster_dns
+      vip_address        = var.vip_address
+      is_first_node      = "false"
+      first_node_ip      = proxmox_virtual_environment_vm.control_plane_init.ipv4_addresses[1][0]
+      node_index         = count.index + 1
+      tls_sans           = var.tls_sans
+      rke2_server_config = var.rke2_server_config
+      oidc_ca_pem        = var.oidc_ca_pem
+      ssh_public_key     = tls_private_key.ssh.public_key_openssh
     })
     file_name = "${var.cluster_name}-cp-join-${count.index + 1}-userdata.yaml"
   }
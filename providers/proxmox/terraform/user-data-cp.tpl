@@ -139,6 +139,9 @@ tls-san:
   - $NODE_IP
   - $VIP_ADDRESS
   - 127.0.0.1
+%{ for san in tls_sans ~}
+  - ${san}
+%{ endfor ~}
 EOF
 
 else
@@ -154,10 +157,25 @@ tls-san:
   - $NODE_IP
   - $VIP_ADDRESS
   - 127.0.0.1
+%{ for san in tls_sans ~}
+  - ${san}
+%{ endfor ~}
 EOF
 
 fi
 
+# API server settings rendered by tdls-easy-k8s (e.g. OIDC authentication)
+%{ if oidc_ca_pem != "" ~}
+cat <<'EOF' > /etc/rancher/rke2/oidc-ca.pem
+${chomp(oidc_ca_pem)}
+EOF
+%{ endif ~}
+%{ if rke2_server_config != "" ~}
+cat <<'EOF' >> /etc/rancher/rke2/config.yaml
+${chomp(rke2_server_config)}
+EOF
+%{ endif ~}
+
 # =============================================================================
 # kube-vip Static Pod (API load balancing via ARP)
 # =============================================================================
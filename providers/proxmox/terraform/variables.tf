@@ -164,3 +164,25 @@ variable "cluster_dns" {
   type        = string
   default     = "10.43.0.10"
 }
+
+# =============================================================================
+# API Server Configuration
+# =============================================================================
+
+variable "rke2_server_config" {
+  description = "Extra RKE2 server settings (YAML) appended to config.yaml on control plane nodes"
+  type        = string
+  default     = ""
+}
+
+variable "tls_sans" {
+  description = "Extra names and IPs added to the API server certificate (tls-san)"
+  type        = list(string)
+  default     = []
+}
+
+variable "oidc_ca_pem" {
+  description = "PEM CA bundle of the OIDC issuer, written to /etc/rancher/rke2/oidc-ca.pem"
+  type        = string
+  default     = ""
+}